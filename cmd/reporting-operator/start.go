@@ -16,6 +16,7 @@ import (
 	"github.com/spf13/pflag"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	"github.com/operator-framework/operator-metering/pkg/operator"
 )
 
@@ -27,6 +28,8 @@ var (
 	// cfg is the config for our operator
 	cfg                            operator.Config
 	prometheusDataSourceImportFrom string
+	defaultRetentionDuration       time.Duration
+	defaultRetentionPeriods        int64
 
 	logLevelStr         string
 	logFullTimestamp    bool
@@ -90,12 +93,26 @@ func init() {
 	startCmd.Flags().DurationVar(&cfg.PrometheusQueryConfig.QueryInterval.Duration, "promsum-interval", operator.DefaultPrometheusQueryInterval, "controls how often the operator polls Prometheus for metrics")
 	startCmd.Flags().DurationVar(&cfg.PrometheusQueryConfig.StepSize.Duration, "promsum-step-size", operator.DefaultPrometheusQueryStepSize, "the query step size for Promethus query. This controls resolution of results")
 	startCmd.Flags().DurationVar(&cfg.PrometheusQueryConfig.ChunkSize.Duration, "promsum-chunk-size", operator.DefaultPrometheusQueryChunkSize, "controls how much the range query window sizeby limiting the range query to a range of time no longer than this duration")
+	startCmd.Flags().IntVar(&cfg.ReportRunHistoryLimit, "report-run-history-limit", operator.DefaultReportRunHistoryLimit, "The maximum number of runs recorded in each Report's status.runs. If zero, no run history is recorded.")
 	startCmd.Flags().IntVar(&cfg.PrestoMaxQueryLength, "presto-max-query-length", 0, "If a non-zero positive value, specifies the max length a Presto query can be. This is used to control buffer sizes used for queries.")
 
 	startCmd.Flags().DurationVar(&cfg.PrometheusDataSourceMaxQueryRangeDuration, "prometheus-datasource-max-query-range-duration", operator.DefaultPrometheusDataSourceMaxQueryRangeDuration, "If non-zero specifies the maximum duration of time to query from Prometheus. When backfilling, this value is used for the ChunkSize when querying Prometheus.")
 	startCmd.Flags().DurationVar(&cfg.PrometheusDataSourceMaxBackfillImportDuration, "prometheus-datasource-max-import-backfill-duration", operator.DefaultPrometheusDataSourceMaxBackfillImportDuration, "If non-zero specifies the maximum duration of time before the current to look back for data when backfilling. Has no effect if prometheus-datasource-import-from is set.")
+	startCmd.Flags().DurationVar(&cfg.PrometheusDataSourceGapCheckInterval, "prometheus-datasource-gap-check-interval", operator.DefaultPrometheusDataSourceGapCheckInterval, "controls how often Prometheus ReportDataSource tables are checked for gaps in their data. If zero, gaps are not checked for.")
+	startCmd.Flags().DurationVar(&cfg.PrometheusDataSourceRetention, "prometheus-datasource-retention", operator.DefaultPrometheusDataSourceRetention, "the retention of Prometheus. Gaps in Prometheus ReportDataSource tables older than this are recorded, but not backfilled.")
 	startCmd.Flags().StringVar(&prometheusDataSourceImportFrom, "prometheus-datasource-import-from", "", "If non-empty, expects an RFC3339 timestamp indicating when Prometheus ReportDataSource data should be backfilled from.")
 
+	startCmd.Flags().DurationVar(&cfg.RetentionSweepInterval, "retention-sweep-interval", operator.DefaultRetentionSweepInterval, "controls how often expired data is removed from the tables of Reports and Prometheus ReportDataSources. If zero, expired data is never removed.")
+	startCmd.Flags().DurationVar(&cfg.ViewSweepInterval, "view-sweep-interval", operator.DefaultViewSweepInterval, "controls how often Presto views named like the views of ReportGenerationQueries, but without a ReportGenerationQuery, are dropped. If zero, they're never dropped.")
+	startCmd.Flags().DurationVar(&cfg.OrphanedTableSweepInterval, "orphaned-table-sweep-interval", operator.DefaultOrphanedTableSweepInterval, "controls how often tables named like the tables of Reports, ReportDataSources and RateCards, but without a resource owning them, are looked for. If zero, they're never looked for.")
+	startCmd.Flags().DurationVar(&cfg.OrphanedTableGracePeriod, "orphaned-table-grace-period", operator.DefaultOrphanedTableGracePeriod, "how long a table, or a view without a ReportGenerationQuery, has to be orphaned for before it's dropped.")
+	startCmd.Flags().BoolVar(&cfg.OrphanedTableDryRun, "orphaned-table-dry-run", operator.DefaultOrphanedTableDryRun, "If true, orphaned tables are reported, but never dropped.")
+	startCmd.Flags().DurationVar(&defaultRetentionDuration, "default-retention", 0, "If non-zero, the data of Reports and Prometheus ReportDataSources without a spec.retention is removed once it's older than this duration.")
+	startCmd.Flags().Int64Var(&defaultRetentionPeriods, "default-retention-periods", 0, "If non-zero, only this many of the most recent periods of data are kept for Reports and Prometheus ReportDataSources without a spec.retention. Cannot be used with default-retention.")
+
+	startCmd.Flags().StringVar(&cfg.ExportDirectory, "export-directory", "", "The directory Reports with local exports write to, in a subdirectory for each namespace. If empty, local exports are disabled.")
+	startCmd.Flags().StringSliceVar(&cfg.ExportAllowedHosts, "export-allowed-hosts", nil, "The hosts the HTTP exports and S3 endpoints of Reports can send results to, optionally with a port. Hosts starting with '*.' allow any subdomain. If empty, HTTP exports and S3 endpoints are disabled.")
+
 	startCmd.Flags().DurationVar(&cfg.LeaderLeaseDuration, "lease-duration", defaultLeaseDuration, "controls how much time elapses before declaring leader")
 
 	startCmd.Flags().BoolVar(&cfg.APITLSConfig.UseTLS, "use-tls", false, "If true, uses TLS to secure HTTP API traffix")
@@ -105,6 +122,11 @@ func init() {
 	startCmd.Flags().BoolVar(&cfg.MetricsTLSConfig.UseTLS, "metrics-use-tls", false, "If true, uses TLS to secure Prometheus Metrics endpoint traffix")
 	startCmd.Flags().StringVar(&cfg.MetricsTLSConfig.TLSCert, "metrics-tls-cert", "", "If metrics-use-tls is true, specifies the path to the TLS certificate to use for the Metrics endpoint.")
 	startCmd.Flags().StringVar(&cfg.MetricsTLSConfig.TLSKey, "metrics-tls-key", "", "If metrics-use-tls is true, specifies the path to the TLS private key to use for the Metrics endpoint.")
+
+	startCmd.Flags().BoolVar(&cfg.EnableWebhook, "enable-webhook", false, "If true, runs a validating admission webhook server for metering resources. Requires webhook-tls-cert and webhook-tls-key.")
+	startCmd.Flags().StringVar(&cfg.WebhookListen, "webhook-listen", "0.0.0.0:8443", "ip:port to listen on for the validating admission webhook")
+	startCmd.Flags().StringVar(&cfg.WebhookTLSConfig.TLSCert, "webhook-tls-cert", "", "If enable-webhook is true, specifies the path to the TLS certificate to use for the validating admission webhook.")
+	startCmd.Flags().StringVar(&cfg.WebhookTLSConfig.TLSKey, "webhook-tls-key", "", "If enable-webhook is true, specifies the path to the TLS private key to use for the validating admission webhook.")
 }
 
 func main() {
@@ -153,6 +175,16 @@ func startReporting(cmd *cobra.Command, args []string) {
 		cfg.PrometheusDataSourceGlobalImportFromTime = &importFrom
 	}
 
+	if defaultRetentionDuration != 0 && defaultRetentionPeriods != 0 {
+		log.Fatalf("only one of --default-retention or --default-retention-periods can be set")
+	}
+	if defaultRetentionDuration != 0 {
+		cfg.DefaultRetention = &cbTypes.RetentionPolicy{Duration: &meta.Duration{Duration: defaultRetentionDuration}}
+	}
+	if defaultRetentionPeriods != 0 {
+		cfg.DefaultRetention = &cbTypes.RetentionPolicy{Periods: &defaultRetentionPeriods}
+	}
+
 	signalStopCtx := setupSignals()
 	runReporting(logger, cfg, signalStopCtx)
 }
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type RateCardList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []*RateCard `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type RateCard struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RateCardSpec   `json:"spec"`
+	Status RateCardStatus `json:"status"`
+}
+
+type RateCardSpec struct {
+	// Currency is the currency all prices in this RateCard are in, for
+	// example "USD".
+	Currency string `json:"currency"`
+
+	// Rates is the list of prices for each unit of usage.
+	Rates []Rate `json:"rates"`
+
+	// Storage controls where the table containing the rates is stored.
+	Storage *StorageLocationRef `json:"storage,omitempty"`
+}
+
+// Rate is the price of a single unit of a ReportGenerationQueryColumn's
+// Unit, optionally limited to a namespace, nodes with specific labels, or a
+// time range.
+type Rate struct {
+	// Unit is the unit being priced, and matches the Unit of
+	// ReportGenerationQueryColumns, for example "cpu_core_seconds".
+	Unit string `json:"unit"`
+
+	// Price is the decimal price of a single Unit, for example "0.000012".
+	Price string `json:"price"`
+
+	// Namespace limits this rate to usage from a single namespace. Rates
+	// with a namespace take precedence over rates without one.
+	Namespace string `json:"namespace,omitempty"`
+
+	// NodeLabels limits this rate to usage on nodes with all of the labels
+	// specified.
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// EffectiveFrom is when this rate starts applying. If unset, the rate
+	// applies to all usage before EffectiveTo.
+	EffectiveFrom *meta.Time `json:"effectiveFrom,omitempty"`
+
+	// EffectiveTo is when this rate stops applying. If unset, the rate
+	// applies to all usage after EffectiveFrom.
+	EffectiveTo *meta.Time `json:"effectiveTo,omitempty"`
+}
+
+type RateCardStatus struct {
+	// TableName is the name of the table containing the rates.
+	TableName string `json:"tableName,omitempty"`
+
+	// RatesHash is a hash of the rates last stored in the table, which is
+	// used to only replace the rates in the table when they change.
+	RatesHash string `json:"ratesHash,omitempty"`
+}
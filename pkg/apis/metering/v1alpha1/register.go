@@ -39,6 +39,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&StorageLocationList{},
 		&PrestoTable{},
 		&PrestoTableList{},
+		&ReportRerun{},
+		&ReportRerunList{},
+		&RateCard{},
+		&RateCardList{},
+		&ReportNotification{},
+		&ReportNotificationList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
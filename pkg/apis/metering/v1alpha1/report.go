@@ -58,6 +58,113 @@ type ReportSpec struct {
 
 	// Output is the storage location where results are sent.
 	Output *StorageLocationRef `json:"output,omitempty"`
+
+	// RequireCompleteData prevents the Report from running for a reporting
+	// period overlapping a gap recorded in the status of a ReportDataSource
+	// it depends on. The Report waits until the gap is backfilled instead.
+	RequireCompleteData bool `json:"requireCompleteData,omitempty"`
+
+	// Retention controls how long the Report's results are kept before
+	// they're deleted. If unset, the reporting-operator's default retention
+	// is used.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+
+	// Exports are destinations the results of each reporting period are
+	// written to after the Report runs successfully.
+	Exports []ReportExport `json:"exports,omitempty"`
+
+	// TableSchemaChangePolicy controls what happens when the columns of the
+	// ReportGenerationQuery change in a way adding columns to the Report's
+	// table can't handle. Defaults to Fail.
+	TableSchemaChangePolicy ReportTableSchemaChangePolicy `json:"tableSchemaChangePolicy,omitempty"`
+
+	// PartitionByPeriod creates the Report's table partitioned by reporting
+	// period, so generating a reporting period again replaces only its
+	// partition, and reading a reporting period only reads its partition.
+	// It only takes effect when the table is created.
+	PartitionByPeriod bool `json:"partitionByPeriod,omitempty"`
+}
+
+// ReportTableSchemaChangePolicy controls how a Report's table is updated when
+// its ReportGenerationQuery's columns change. Columns added after the
+// existing columns are always added to the table.
+type ReportTableSchemaChangePolicy string
+
+const (
+	// ReportTableSchemaChangeFail stops the Report from running until the
+	// ReportGenerationQuery's columns are compatible with the table again.
+	ReportTableSchemaChangeFail ReportTableSchemaChangePolicy = "Fail"
+	// ReportTableSchemaChangeAddColumns adds the new columns to the table,
+	// and keeps the columns which were removed from the
+	// ReportGenerationQuery, which are null in new results. Changing the
+	// type of a column still fails.
+	ReportTableSchemaChangeAddColumns ReportTableSchemaChangePolicy = "AddColumns"
+	// ReportTableSchemaChangeRecreate drops and recreates the table, and
+	// generates the results of every reporting period again.
+	ReportTableSchemaChangeRecreate ReportTableSchemaChangePolicy = "Recreate"
+)
+
+// ReportExport configures where and how the results of a reporting period
+// are exported. Exactly one of S3, Local or HTTP must be set.
+type ReportExport struct {
+	// Name identifies the export in the Report's status.
+	Name string `json:"name"`
+	// Format is the format results are exported in: csv, json, ndjson or
+	// parquet. Defaults to csv.
+	Format string `json:"format,omitempty"`
+	// Path is a Go template for the path of the exported file, relative to
+	// the target. It's rendered with .Namespace, .Report, .PeriodStart,
+	// .PeriodEnd and .Extension. PeriodStart and PeriodEnd are UTC times,
+	// which can be formatted using their Format method. Defaults to
+	// DefaultReportExportPath.
+	Path string `json:"path,omitempty"`
+
+	S3    *S3ReportExportTarget    `json:"s3,omitempty"`
+	Local *LocalReportExportTarget `json:"local,omitempty"`
+	HTTP  *HTTPReportExportTarget  `json:"http,omitempty"`
+}
+
+const DefaultReportExportPath = `{{.Namespace}}/{{.Report}}/{{.PeriodStart.Format "20060102T150405Z"}}-{{.PeriodEnd.Format "20060102T150405Z"}}.{{.Extension}}`
+
+// S3ReportExportTarget exports results to a bucket of Amazon S3, or another
+// S3 compatible object store.
+type S3ReportExportTarget struct {
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to the export's path to form the object's key.
+	Prefix string `json:"prefix,omitempty"`
+	Region string `json:"region,omitempty"`
+	// Endpoint is the URL of an S3 compatible object store. If set, requests
+	// use path style addressing.
+	Endpoint string `json:"endpoint,omitempty"`
+	// CredentialsSecretName is the name of a Secret in the Report's
+	// namespace containing the aws-access-key-id and aws-secret-access-key
+	// keys. If unset, the reporting-operator's own AWS credentials are used.
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+}
+
+// LocalReportExportTarget exports results to a filesystem path, relative to
+// the reporting-operator's configured export directory, which is usually a
+// mounted PersistentVolumeClaim.
+type LocalReportExportTarget struct {
+	// Directory is prepended to the export's path.
+	Directory string `json:"directory,omitempty"`
+}
+
+// HTTPReportExportTarget exports results by sending them as the body of a
+// POST request.
+type HTTPReportExportTarget struct {
+	URL string `json:"url"`
+}
+
+// RetentionPolicy controls how long data is kept. Only one of Duration or
+// Periods may be set.
+type RetentionPolicy struct {
+	// Duration is how long data is kept, relative to the current time.
+	Duration *meta.Duration `json:"duration,omitempty"`
+	// Periods is how many of the most recent periods of data are kept. For
+	// a ReportDataSource a period is a day, for a Report it's a reporting
+	// period.
+	Periods *int64 `json:"periods,omitempty"`
 }
 
 type ReportPeriod string
@@ -78,6 +185,18 @@ type ReportSchedule struct {
 	Daily   *ReportScheduleDaily   `json:"daily,omitempty"`
 	Weekly  *ReportScheduleWeekly  `json:"weekly,omitempty"`
 	Monthly *ReportScheduleMonthly `json:"monthly,omitempty"`
+
+	// TimeZone is the IANA time zone name, such as "America/New_York", the
+	// schedule is evaluated in. Reporting periods start and end at the
+	// scheduled wall clock time in this time zone, including across
+	// daylight saving time transitions. If unset, UTC is used.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Window is the duration of data each run reports on, ending at the
+	// scheduled time. This allows a rolling window such as the trailing 7
+	// days of data refreshed daily. If unset, each run reports on the time
+	// since the previous scheduled time.
+	Window *meta.Duration `json:"window,omitempty"`
 }
 
 type ReportScheduleCron struct {
@@ -114,6 +233,65 @@ type ReportStatus struct {
 	LastReportTime *meta.Time        `json:"lastReportTime,omitempty"`
 	NextReportTime *meta.Time        `json:"nextReportTime,omitempty"`
 	TableName      string            `json:"tableName"`
+
+	// TablePartitionedByPeriod is true if the Report's table was created
+	// partitioned by reporting period.
+	TablePartitionedByPeriod bool `json:"tablePartitionedByPeriod,omitempty"`
+
+	// Runs contains a record of the most recent executions of this Report,
+	// ordered from oldest to newest. The number of runs retained is bounded
+	// by the reporting-operator's configured report run history limit.
+	Runs []ReportRun `json:"runs,omitempty"`
+
+	// Exports contains the result of the most recent run of each of the
+	// Report's spec.exports.
+	Exports []ReportExportStatus `json:"exports,omitempty"`
+}
+
+// ReportExportStatus records the most recent run of a ReportExport.
+type ReportExportStatus struct {
+	// Name is the name of the ReportExport.
+	Name string `json:"name"`
+	// PeriodStart and PeriodEnd are the bounds of the reporting period
+	// which was exported.
+	PeriodStart meta.Time `json:"periodStart"`
+	PeriodEnd   meta.Time `json:"periodEnd"`
+	// ExportTime is when the export finished.
+	ExportTime meta.Time `json:"exportTime"`
+	// Location is where the results were exported to.
+	Location string `json:"location,omitempty"`
+	// Outcome is whether the export Succeeded or Failed.
+	Outcome ReportRunOutcome `json:"outcome"`
+	// Error contains the error message if the export Failed.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+type ReportRunOutcome string
+
+const (
+	ReportRunSucceeded ReportRunOutcome = "Succeeded"
+	ReportRunFailed    ReportRunOutcome = "Failed"
+)
+
+// ReportRun records a single execution of a Report for a reporting period.
+type ReportRun struct {
+	// PeriodStart and PeriodEnd are the bounds of the reporting period
+	// this run generated results for.
+	PeriodStart meta.Time `json:"periodStart"`
+	PeriodEnd   meta.Time `json:"periodEnd"`
+	// StartTime and FinishTime are when the run began and completed.
+	StartTime  meta.Time `json:"startTime"`
+	FinishTime meta.Time `json:"finishTime"`
+	// Duration is how long the run took to generate results.
+	Duration meta.Duration `json:"duration"`
+	// RowCount is the number of rows stored by this run.
+	RowCount int64 `json:"rowCount"`
+	// Outcome is whether the run Succeeded or Failed.
+	Outcome ReportRunOutcome `json:"outcome"`
+	// Error contains the error message if the run Failed.
+	// +optional
+	Error string `json:"error,omitempty"`
 }
 
 type ReportCondition struct {
@@ -139,4 +317,7 @@ type ReportConditionType string
 
 const (
 	ReportRunning ReportConditionType = "Running"
+	// ReportTableSchemaUpToDate is whether the Report's table has the
+	// columns of its ReportGenerationQuery.
+	ReportTableSchemaUpToDate ReportConditionType = "TableSchemaUpToDate"
 )
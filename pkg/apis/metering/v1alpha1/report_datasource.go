@@ -29,6 +29,10 @@ type ReportDataSourceSpec struct {
 	// AWSBilling represents a datasource which points to a pre-existing S3
 	// bucket.
 	AWSBilling *AWSBillingDataSource `json:"awsBilling"`
+	// Retention controls how long the data imported into the table of a
+	// Prometheus ReportDataSource is kept before it's deleted. If unset,
+	// the reporting-operator's default retention is used.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
 }
 
 type AWSBillingDataSource struct {
@@ -52,15 +56,110 @@ type PrometheusConnectionConfig struct {
 }
 
 type PrometheusMetricsDataSource struct {
-	Query            string                      `json:"query"`
-	QueryConfig      *PrometheusQueryConfig      `json:"queryConfig,omitempty"`
-	Storage          *StorageLocationRef         `json:"storage,omitempty"`
-	PrometheusConfig *PrometheusConnectionConfig `json:"prometheusConfig,omitempty"`
+	Query            string                       `json:"query"`
+	QueryConfig      *PrometheusQueryConfig       `json:"queryConfig,omitempty"`
+	Storage          *StorageLocationRef          `json:"storage,omitempty"`
+	PrometheusConfig *PrometheusConnectionConfig  `json:"prometheusConfig,omitempty"`
+	RemoteWrite      *PrometheusRemoteWriteConfig `json:"remoteWrite,omitempty"`
+	// RelabelConfigs are applied in order to the labels of each series
+	// before it's stored, the same as relabel_configs in Prometheus.
+	RelabelConfigs []PrometheusRelabelConfig `json:"relabelConfigs,omitempty"`
+	// Rollups configures tables the imported metrics are aggregated into
+	// at a coarser resolution, which are cheaper to query for long
+	// reporting periods.
+	Rollups *PrometheusMetricsRollups `json:"rollups,omitempty"`
+}
+
+type PrometheusMetricsRollupGranularity string
+
+const (
+	PrometheusMetricsRollupHourly PrometheusMetricsRollupGranularity = "hourly"
+	PrometheusMetricsRollupDaily  PrometheusMetricsRollupGranularity = "daily"
+)
+
+// PrometheusMetricsRollups enables aggregating the metrics of a
+// ReportDataSource into an hourly table, a daily table, or both.
+type PrometheusMetricsRollups struct {
+	Hourly bool `json:"hourly,omitempty"`
+	Daily  bool `json:"daily,omitempty"`
+}
+
+type PrometheusRelabelAction string
+
+const (
+	PrometheusRelabelReplace   PrometheusRelabelAction = "replace"
+	PrometheusRelabelKeep      PrometheusRelabelAction = "keep"
+	PrometheusRelabelDrop      PrometheusRelabelAction = "drop"
+	PrometheusRelabelHashMod   PrometheusRelabelAction = "hashmod"
+	PrometheusRelabelLabelDrop PrometheusRelabelAction = "labeldrop"
+	PrometheusRelabelLabelKeep PrometheusRelabelAction = "labelkeep"
+)
+
+// PrometheusRelabelConfig is a rule for rewriting the labels of a series or
+// dropping it, with the same fields and defaults as a Prometheus
+// relabel_config.
+type PrometheusRelabelConfig struct {
+	// SourceLabels are the labels whose values are joined with Separator
+	// and matched against Regex.
+	SourceLabels []string `json:"sourceLabels,omitempty"`
+	// Separator defaults to ";".
+	Separator *string `json:"separator,omitempty"`
+	// Regex is an anchored regular expression, which defaults to "(.*)".
+	Regex string `json:"regex,omitempty"`
+	// Modulus is used by the hashmod action.
+	Modulus uint64 `json:"modulus,omitempty"`
+	// TargetLabel is the label set by the replace and hashmod actions.
+	TargetLabel string `json:"targetLabel,omitempty"`
+	// Replacement is the value TargetLabel is set to by the replace
+	// action, which may refer to Regex's capture groups. Defaults to "$1".
+	Replacement *string `json:"replacement,omitempty"`
+	// Action defaults to "replace".
+	Action PrometheusRelabelAction `json:"action,omitempty"`
+}
+
+// PrometheusRemoteWriteConfig configures a ReportDataSource to receive
+// metrics pushed by Prometheus using the remote write protocol instead of
+// periodically querying Prometheus. Series are stored if they have the
+// specified metric name and match all of the matchers.
+type PrometheusRemoteWriteConfig struct {
+	MetricName string                   `json:"metricName,omitempty"`
+	Matchers   []PrometheusLabelMatcher `json:"matchers,omitempty"`
+}
+
+type PrometheusLabelMatchType string
+
+const (
+	PrometheusLabelMatchEqual     PrometheusLabelMatchType = "="
+	PrometheusLabelMatchNotEqual  PrometheusLabelMatchType = "!="
+	PrometheusLabelMatchRegexp    PrometheusLabelMatchType = "=~"
+	PrometheusLabelMatchNotRegexp PrometheusLabelMatchType = "!~"
+)
+
+// PrometheusLabelMatcher matches series by the value of a label, the same as
+// label matchers in a PromQL selector. Type defaults to "=".
+type PrometheusLabelMatcher struct {
+	Name  string                   `json:"name"`
+	Value string                   `json:"value"`
+	Type  PrometheusLabelMatchType `json:"type,omitempty"`
 }
 
 type ReportDataSourceStatus struct {
 	TableName                    string                        `json:"tableName,omitempty"`
 	PrometheusMetricImportStatus *PrometheusMetricImportStatus `json:"prometheusMetricImportStatus,omitempty"`
+	// Rollups are the tables the ReportDataSource's metrics are aggregated
+	// into.
+	Rollups []PrometheusMetricsRollupStatus `json:"rollups,omitempty"`
+}
+
+// PrometheusMetricsRollupStatus records the progress of aggregating a
+// ReportDataSource's metrics into a rollup table.
+type PrometheusMetricsRollupStatus struct {
+	Granularity PrometheusMetricsRollupGranularity `json:"granularity"`
+	TableName   string                             `json:"tableName"`
+	// RolledUpTo is the end of the newest period aggregated into the
+	// table. Periods are only aggregated once all of their metrics have
+	// been imported.
+	RolledUpTo *meta.Time `json:"rolledUpTo,omitempty"`
 }
 
 type PrometheusMetricImportStatus struct {
@@ -70,6 +169,8 @@ type PrometheusMetricImportStatus struct {
 	// ImportDataStartTime is the start of the time first time range queried.
 	ImportDataStartTime *meta.Time `json:"importDataStartTime,omitempty"`
 	// ImportDataEndTime is the end of the time last time range queried.
+	// It's updated as each time range is imported, and is where importing
+	// resumes from after the reporting-operator restarts.
 	ImportDataEndTime *meta.Time `json:"importDataEndTime,omitempty"`
 
 	// EarliestImportedMetricTime is the timestamp for the earliest metric
@@ -78,4 +179,27 @@ type PrometheusMetricImportStatus struct {
 	// NewestImportedMetricTime is the timestamp for the newest metric
 	// imported for this ReportDataSource.
 	NewestImportedMetricTime *meta.Time `json:"newestImportedMetricTime,omitempty"`
+
+	// LastGapCheckTime is the time the table was last checked for gaps.
+	LastGapCheckTime *meta.Time `json:"lastGapCheckTime,omitempty"`
+	// GapsCheckedUpTo is the NewestImportedMetricTime as of
+	// LastGapCheckTime. The next check only scans the metrics imported
+	// after it, along with the Gaps which can still be backfilled.
+	GapsCheckedUpTo *meta.Time `json:"gapsCheckedUpTo,omitempty"`
+	// Gaps are the time ranges between EarliestImportedMetricTime and
+	// NewestImportedMetricTime which have no metrics in the table, as of
+	// LastGapCheckTime.
+	Gaps []PrometheusMetricImportGap `json:"gaps,omitempty"`
+}
+
+// PrometheusMetricImportGap is a range of missing metrics in a ReportDataSource
+// table. Start and End are the timestamps of the first and last missing
+// metric, at the ReportDataSource's stepSize resolution.
+type PrometheusMetricImportGap struct {
+	Start meta.Time `json:"start"`
+	End   meta.Time `json:"end"`
+	// BackfillAttempts is the number of times the gap was re-imported
+	// without any metrics being found. Gaps are no longer re-imported once
+	// this reaches a limit, since Prometheus likely has no data for them.
+	BackfillAttempts int `json:"backfillAttempts,omitempty"`
 }
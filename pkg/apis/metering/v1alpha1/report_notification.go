@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ReportNotificationList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []*ReportNotification `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ReportNotification sends a CloudEvent to an HTTP endpoint each time a
+// Report in its namespace finishes running for a reporting period.
+type ReportNotification struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReportNotificationSpec   `json:"spec"`
+	Status ReportNotificationStatus `json:"status"`
+}
+
+type ReportNotificationSpec struct {
+	// URL is the endpoint events are sent to in POST requests.
+	URL string `json:"url"`
+
+	// ReportSelector selects the Reports in the ReportNotification's
+	// namespace events are sent for. If unset, events are sent for every
+	// Report in the namespace.
+	ReportSelector *meta.LabelSelector `json:"reportSelector,omitempty"`
+
+	// Outcomes are the outcomes of report runs events are sent for. If
+	// unset, events are sent for both Succeeded and Failed runs.
+	Outcomes []ReportRunOutcome `json:"outcomes,omitempty"`
+
+	// SigningSecretName is the name of a Secret in the ReportNotification's
+	// namespace containing a signing-key. If set, the HMAC-SHA256 of each
+	// request body using the key is sent in the X-Metering-Signature header.
+	SigningSecretName string `json:"signingSecretName,omitempty"`
+
+	// MaxAttempts is how many times sending an event is attempted before
+	// it's dropped. Defaults to 5.
+	MaxAttempts *int32 `json:"maxAttempts,omitempty"`
+
+	// ResultsBaseURL is the URL of the reporting API as reachable by the
+	// receiver of events, such as the reporting-operator's Route. If set,
+	// events contain a link to the results of the reporting period.
+	ResultsBaseURL string `json:"resultsBaseURL,omitempty"`
+}
+
+type ReportNotificationStatus struct {
+	// LastDelivery is the most recently sent, or dropped, event.
+	LastDelivery *ReportNotificationDelivery `json:"lastDelivery,omitempty"`
+}
+
+// ReportNotificationDelivery records the attempts to send an event.
+type ReportNotificationDelivery struct {
+	// EventID is the id of the CloudEvent.
+	EventID string `json:"eventID"`
+	// Report is the name of the Report the event is for.
+	Report string `json:"report"`
+	// Time is when the last attempt finished.
+	Time meta.Time `json:"time"`
+	// Attempts is how many times sending the event was attempted.
+	Attempts int32 `json:"attempts"`
+	// Outcome is whether the event was sent, or dropped after failing
+	// MaxAttempts times.
+	Outcome ReportRunOutcome `json:"outcome"`
+	// Error contains the error of the last attempt if it Failed.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ReportRerunList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []*ReportRerun `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ReportRerun struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReportRerunSpec   `json:"spec"`
+	Status ReportRerunStatus `json:"status"`
+}
+
+type ReportRerunSpec struct {
+	// ReportName is the name of the Report in the same namespace to re-run.
+	ReportName string `json:"reportName"`
+
+	// ReportingStart is the beginning of the time range to re-run. The
+	// Report's schedule is used to divide the time range between
+	// ReportingStart and ReportingEnd into reporting periods, so it must be
+	// the start of one of the Report's reporting periods.
+	ReportingStart meta.Time `json:"reportingStart"`
+
+	// ReportingEnd is the end of the time range to re-run. It must be the
+	// end of one of the Report's reporting periods.
+	ReportingEnd meta.Time `json:"reportingEnd"`
+}
+
+type ReportRerunPhase string
+
+const (
+	ReportRerunPhasePending   ReportRerunPhase = "Pending"
+	ReportRerunPhaseRunning   ReportRerunPhase = "Running"
+	ReportRerunPhaseSucceeded ReportRerunPhase = "Succeeded"
+	ReportRerunPhaseFailed    ReportRerunPhase = "Failed"
+)
+
+type ReportRerunStatus struct {
+	// Phase is the current state of the re-run: Pending, Running,
+	// Succeeded, or Failed.
+	Phase ReportRerunPhase `json:"phase,omitempty"`
+
+	// Message is a human readable description of the current phase.
+	Message string `json:"message,omitempty"`
+
+	// TotalPeriods is the number of reporting periods being re-run.
+	TotalPeriods int `json:"totalPeriods,omitempty"`
+
+	// CompletedPeriods is the number of reporting periods that have been
+	// re-run so far.
+	CompletedPeriods int `json:"completedPeriods,omitempty"`
+
+	// LastCompletedPeriodEnd is the end of the most recent reporting period
+	// that has been re-run.
+	LastCompletedPeriodEnd *meta.Time `json:"lastCompletedPeriodEnd,omitempty"`
+
+	// StartTime is when the re-run started processing.
+	StartTime *meta.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the re-run finished.
+	CompletionTime *meta.Time `json:"completionTime,omitempty"`
+}
@@ -43,6 +43,23 @@ const (
 	// GenerateReportFailedReason is set when a Report is not running because
 	// it previously failed when generating results previously.
 	GenerateReportFailedReason = "GenerateReportFailed"
+
+	// TableSchemaMismatchReason is set when a Report is not running because
+	// the columns of it's ReportGenerationQuery changed in a way it's
+	// spec.tableSchemaChangePolicy can't handle.
+	TableSchemaMismatchReason = "TableSchemaMismatch"
+
+	// ColumnsAddedReason is set when columns were added to the Report's
+	// table to match it's ReportGenerationQuery.
+	ColumnsAddedReason = "ColumnsAdded"
+
+	// TableRecreatedReason is set when the Report's table was recreated to
+	// match it's ReportGenerationQuery.
+	TableRecreatedReason = "TableRecreated"
+
+	// TableSchemaMatchesReason is set when the Report's table matches it's
+	// ReportGenerationQuery again after a mismatch.
+	TableSchemaMatchesReason = "TableSchemaMatches"
 )
 
 // NewReportCondition creates a new report condition.
@@ -99,3 +116,30 @@ func filterOutCondition(conditions []v1alpha1.ReportCondition, condType v1alpha1
 	}
 	return newConditions
 }
+
+// AddReportRun appends the run to the report's run history, removing the
+// oldest runs to keep at most limit runs. A limit less than or equal to zero
+// disables recording runs.
+func AddReportRun(status *v1alpha1.ReportStatus, run v1alpha1.ReportRun, limit int) {
+	if limit <= 0 {
+		status.Runs = nil
+		return
+	}
+	runs := append(status.Runs, run)
+	if len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	status.Runs = runs
+}
+
+// SetReportExportStatus records the status of the export with the same name,
+// replacing its previous status.
+func SetReportExportStatus(status *v1alpha1.ReportStatus, exportStatus v1alpha1.ReportExportStatus) {
+	for i := range status.Exports {
+		if status.Exports[i].Name == exportStatus.Name {
+			status.Exports[i] = exportStatus
+			return
+		}
+	}
+	status.Exports = append(status.Exports, exportStatus)
+}
@@ -50,6 +50,22 @@ func (in *GenQueryView) DeepCopy() *GenQueryView {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPReportExportTarget) DeepCopyInto(out *HTTPReportExportTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPReportExportTarget.
+func (in *HTTPReportExportTarget) DeepCopy() *HTTPReportExportTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPReportExportTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HiveStorage) DeepCopyInto(out *HiveStorage) {
 	*out = *in
@@ -67,6 +83,22 @@ func (in *HiveStorage) DeepCopy() *HiveStorage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalReportExportTarget) DeepCopyInto(out *LocalReportExportTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalReportExportTarget.
+func (in *LocalReportExportTarget) DeepCopy() *LocalReportExportTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalReportExportTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrestoTable) DeepCopyInto(out *PrestoTable) {
 	*out = *in
@@ -172,6 +204,40 @@ func (in *PrometheusConnectionConfig) DeepCopy() *PrometheusConnectionConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusLabelMatcher) DeepCopyInto(out *PrometheusLabelMatcher) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusLabelMatcher.
+func (in *PrometheusLabelMatcher) DeepCopy() *PrometheusLabelMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusLabelMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusMetricImportGap) DeepCopyInto(out *PrometheusMetricImportGap) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusMetricImportGap.
+func (in *PrometheusMetricImportGap) DeepCopy() *PrometheusMetricImportGap {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusMetricImportGap)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusMetricImportStatus) DeepCopyInto(out *PrometheusMetricImportStatus) {
 	*out = *in
@@ -195,6 +261,21 @@ func (in *PrometheusMetricImportStatus) DeepCopyInto(out *PrometheusMetricImport
 		in, out := &in.NewestImportedMetricTime, &out.NewestImportedMetricTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastGapCheckTime != nil {
+		in, out := &in.LastGapCheckTime, &out.LastGapCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.GapsCheckedUpTo != nil {
+		in, out := &in.GapsCheckedUpTo, &out.GapsCheckedUpTo
+		*out = (*in).DeepCopy()
+	}
+	if in.Gaps != nil {
+		in, out := &in.Gaps, &out.Gaps
+		*out = make([]PrometheusMetricImportGap, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -226,6 +307,23 @@ func (in *PrometheusMetricsDataSource) DeepCopyInto(out *PrometheusMetricsDataSo
 		*out = new(PrometheusConnectionConfig)
 		**out = **in
 	}
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = new(PrometheusRemoteWriteConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RelabelConfigs != nil {
+		in, out := &in.RelabelConfigs, &out.RelabelConfigs
+		*out = make([]PrometheusRelabelConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollups != nil {
+		in, out := &in.Rollups, &out.Rollups
+		*out = new(PrometheusMetricsRollups)
+		**out = **in
+	}
 	return
 }
 
@@ -239,6 +337,42 @@ func (in *PrometheusMetricsDataSource) DeepCopy() *PrometheusMetricsDataSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusMetricsRollupStatus) DeepCopyInto(out *PrometheusMetricsRollupStatus) {
+	*out = *in
+	if in.RolledUpTo != nil {
+		in, out := &in.RolledUpTo, &out.RolledUpTo
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusMetricsRollupStatus.
+func (in *PrometheusMetricsRollupStatus) DeepCopy() *PrometheusMetricsRollupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusMetricsRollupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusMetricsRollups) DeepCopyInto(out *PrometheusMetricsRollups) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusMetricsRollups.
+func (in *PrometheusMetricsRollups) DeepCopy() *PrometheusMetricsRollups {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusMetricsRollups)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusQueryConfig) DeepCopyInto(out *PrometheusQueryConfig) {
 	*out = *in
@@ -270,6 +404,198 @@ func (in *PrometheusQueryConfig) DeepCopy() *PrometheusQueryConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRelabelConfig) DeepCopyInto(out *PrometheusRelabelConfig) {
+	*out = *in
+	if in.SourceLabels != nil {
+		in, out := &in.SourceLabels, &out.SourceLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Separator != nil {
+		in, out := &in.Separator, &out.Separator
+		*out = new(string)
+		**out = **in
+	}
+	if in.Replacement != nil {
+		in, out := &in.Replacement, &out.Replacement
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRelabelConfig.
+func (in *PrometheusRelabelConfig) DeepCopy() *PrometheusRelabelConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRelabelConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRemoteWriteConfig) DeepCopyInto(out *PrometheusRemoteWriteConfig) {
+	*out = *in
+	if in.Matchers != nil {
+		in, out := &in.Matchers, &out.Matchers
+		*out = make([]PrometheusLabelMatcher, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRemoteWriteConfig.
+func (in *PrometheusRemoteWriteConfig) DeepCopy() *PrometheusRemoteWriteConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRemoteWriteConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rate) DeepCopyInto(out *Rate) {
+	*out = *in
+	if in.NodeLabels != nil {
+		in, out := &in.NodeLabels, &out.NodeLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EffectiveFrom != nil {
+		in, out := &in.EffectiveFrom, &out.EffectiveFrom
+		*out = (*in).DeepCopy()
+	}
+	if in.EffectiveTo != nil {
+		in, out := &in.EffectiveTo, &out.EffectiveTo
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rate.
+func (in *Rate) DeepCopy() *Rate {
+	if in == nil {
+		return nil
+	}
+	out := new(Rate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateCard) DeepCopyInto(out *RateCard) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateCard.
+func (in *RateCard) DeepCopy() *RateCard {
+	if in == nil {
+		return nil
+	}
+	out := new(RateCard)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RateCard) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateCardList) DeepCopyInto(out *RateCardList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*RateCard, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(RateCard)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateCardList.
+func (in *RateCardList) DeepCopy() *RateCardList {
+	if in == nil {
+		return nil
+	}
+	out := new(RateCardList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RateCardList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateCardSpec) DeepCopyInto(out *RateCardSpec) {
+	*out = *in
+	if in.Rates != nil {
+		in, out := &in.Rates, &out.Rates
+		*out = make([]Rate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageLocationRef)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateCardSpec.
+func (in *RateCardSpec) DeepCopy() *RateCardSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RateCardSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateCardStatus) DeepCopyInto(out *RateCardStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateCardStatus.
+func (in *RateCardStatus) DeepCopy() *RateCardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RateCardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Report) DeepCopyInto(out *Report) {
 	*out = *in
@@ -394,6 +720,11 @@ func (in *ReportDataSourceSpec) DeepCopyInto(out *ReportDataSourceSpec) {
 		*out = new(AWSBillingDataSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -415,6 +746,13 @@ func (in *ReportDataSourceStatus) DeepCopyInto(out *ReportDataSourceStatus) {
 		*out = new(PrometheusMetricImportStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Rollups != nil {
+		in, out := &in.Rollups, &out.Rollups
+		*out = make([]PrometheusMetricsRollupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -428,6 +766,56 @@ func (in *ReportDataSourceStatus) DeepCopy() *ReportDataSourceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportExport) DeepCopyInto(out *ReportExport) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3ReportExportTarget)
+		**out = **in
+	}
+	if in.Local != nil {
+		in, out := &in.Local, &out.Local
+		*out = new(LocalReportExportTarget)
+		**out = **in
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPReportExportTarget)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportExport.
+func (in *ReportExport) DeepCopy() *ReportExport {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportExportStatus) DeepCopyInto(out *ReportExportStatus) {
+	*out = *in
+	in.PeriodStart.DeepCopyInto(&out.PeriodStart)
+	in.PeriodEnd.DeepCopyInto(&out.PeriodEnd)
+	in.ExportTime.DeepCopyInto(&out.ExportTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportExportStatus.
+func (in *ReportExportStatus) DeepCopy() *ReportExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReportGenerationQuery) DeepCopyInto(out *ReportGenerationQuery) {
 	*out = *in
@@ -672,6 +1060,140 @@ func (in *ReportList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportNotification) DeepCopyInto(out *ReportNotification) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportNotification.
+func (in *ReportNotification) DeepCopy() *ReportNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReportNotification) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportNotificationDelivery) DeepCopyInto(out *ReportNotificationDelivery) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportNotificationDelivery.
+func (in *ReportNotificationDelivery) DeepCopy() *ReportNotificationDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportNotificationDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportNotificationList) DeepCopyInto(out *ReportNotificationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*ReportNotification, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ReportNotification)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportNotificationList.
+func (in *ReportNotificationList) DeepCopy() *ReportNotificationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportNotificationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReportNotificationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportNotificationSpec) DeepCopyInto(out *ReportNotificationSpec) {
+	*out = *in
+	if in.ReportSelector != nil {
+		in, out := &in.ReportSelector, &out.ReportSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Outcomes != nil {
+		in, out := &in.Outcomes, &out.Outcomes
+		*out = make([]ReportRunOutcome, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAttempts != nil {
+		in, out := &in.MaxAttempts, &out.MaxAttempts
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportNotificationSpec.
+func (in *ReportNotificationSpec) DeepCopy() *ReportNotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportNotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportNotificationStatus) DeepCopyInto(out *ReportNotificationStatus) {
+	*out = *in
+	if in.LastDelivery != nil {
+		in, out := &in.LastDelivery, &out.LastDelivery
+		*out = new(ReportNotificationDelivery)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportNotificationStatus.
+func (in *ReportNotificationStatus) DeepCopy() *ReportNotificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportNotificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReportPrometheusQuery) DeepCopyInto(out *ReportPrometheusQuery) {
 	*out = *in
@@ -752,6 +1274,138 @@ func (in *ReportPrometheusQuerySpec) DeepCopy() *ReportPrometheusQuerySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRerun) DeepCopyInto(out *ReportRerun) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRerun.
+func (in *ReportRerun) DeepCopy() *ReportRerun {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRerun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReportRerun) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRerunList) DeepCopyInto(out *ReportRerunList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]*ReportRerun, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(ReportRerun)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRerunList.
+func (in *ReportRerunList) DeepCopy() *ReportRerunList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRerunList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReportRerunList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRerunSpec) DeepCopyInto(out *ReportRerunSpec) {
+	*out = *in
+	in.ReportingStart.DeepCopyInto(&out.ReportingStart)
+	in.ReportingEnd.DeepCopyInto(&out.ReportingEnd)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRerunSpec.
+func (in *ReportRerunSpec) DeepCopy() *ReportRerunSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRerunSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRerunStatus) DeepCopyInto(out *ReportRerunStatus) {
+	*out = *in
+	if in.LastCompletedPeriodEnd != nil {
+		in, out := &in.LastCompletedPeriodEnd, &out.LastCompletedPeriodEnd
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRerunStatus.
+func (in *ReportRerunStatus) DeepCopy() *ReportRerunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRerunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRun) DeepCopyInto(out *ReportRun) {
+	*out = *in
+	in.PeriodStart.DeepCopyInto(&out.PeriodStart)
+	in.PeriodEnd.DeepCopyInto(&out.PeriodEnd)
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.FinishTime.DeepCopyInto(&out.FinishTime)
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRun.
+func (in *ReportRun) DeepCopy() *ReportRun {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReportSchedule) DeepCopyInto(out *ReportSchedule) {
 	*out = *in
@@ -780,6 +1434,11 @@ func (in *ReportSchedule) DeepCopyInto(out *ReportSchedule) {
 		*out = new(ReportScheduleMonthly)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -911,6 +1570,18 @@ func (in *ReportSpec) DeepCopyInto(out *ReportSpec) {
 		*out = new(StorageLocationRef)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exports != nil {
+		in, out := &in.Exports, &out.Exports
+		*out = make([]ReportExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -942,6 +1613,20 @@ func (in *ReportStatus) DeepCopyInto(out *ReportStatus) {
 		in, out := &in.NextReportTime, &out.NextReportTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Runs != nil {
+		in, out := &in.Runs, &out.Runs
+		*out = make([]ReportRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Exports != nil {
+		in, out := &in.Exports, &out.Exports
+		*out = make([]ReportExportStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -955,6 +1640,32 @@ func (in *ReportStatus) DeepCopy() *ReportStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionPolicy) DeepCopyInto(out *RetentionPolicy) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Periods != nil {
+		in, out := &in.Periods, &out.Periods
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionPolicy.
+func (in *RetentionPolicy) DeepCopy() *RetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3Bucket) DeepCopyInto(out *S3Bucket) {
 	*out = *in
@@ -971,6 +1682,22 @@ func (in *S3Bucket) DeepCopy() *S3Bucket {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3ReportExportTarget) DeepCopyInto(out *S3ReportExportTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3ReportExportTarget.
+func (in *S3ReportExportTarget) DeepCopy() *S3ReportExportTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(S3ReportExportTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageLocation) DeepCopyInto(out *StorageLocation) {
 	*out = *in
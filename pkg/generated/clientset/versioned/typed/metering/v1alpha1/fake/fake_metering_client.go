@@ -16,6 +16,10 @@ func (c *FakeMeteringV1alpha1) PrestoTables(namespace string) v1alpha1.PrestoTab
 	return &FakePrestoTables{c, namespace}
 }
 
+func (c *FakeMeteringV1alpha1) RateCards(namespace string) v1alpha1.RateCardInterface {
+	return &FakeRateCards{c, namespace}
+}
+
 func (c *FakeMeteringV1alpha1) Reports(namespace string) v1alpha1.ReportInterface {
 	return &FakeReports{c, namespace}
 }
@@ -28,10 +32,18 @@ func (c *FakeMeteringV1alpha1) ReportGenerationQueries(namespace string) v1alpha
 	return &FakeReportGenerationQueries{c, namespace}
 }
 
+func (c *FakeMeteringV1alpha1) ReportNotifications(namespace string) v1alpha1.ReportNotificationInterface {
+	return &FakeReportNotifications{c, namespace}
+}
+
 func (c *FakeMeteringV1alpha1) ReportPrometheusQueries(namespace string) v1alpha1.ReportPrometheusQueryInterface {
 	return &FakeReportPrometheusQueries{c, namespace}
 }
 
+func (c *FakeMeteringV1alpha1) ReportReruns(namespace string) v1alpha1.ReportRerunInterface {
+	return &FakeReportReruns{c, namespace}
+}
+
 func (c *FakeMeteringV1alpha1) StorageLocations(namespace string) v1alpha1.StorageLocationInterface {
 	return &FakeStorageLocations{c, namespace}
 }
@@ -0,0 +1,124 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeRateCards implements RateCardInterface
+type FakeRateCards struct {
+	Fake *FakeMeteringV1alpha1
+	ns   string
+}
+
+var ratecardsResource = schema.GroupVersionResource{Group: "metering.openshift.io", Version: "v1alpha1", Resource: "ratecards"}
+
+var ratecardsKind = schema.GroupVersionKind{Group: "metering.openshift.io", Version: "v1alpha1", Kind: "RateCard"}
+
+// Get takes name of the rateCard, and returns the corresponding rateCard object, and an error if there is any.
+func (c *FakeRateCards) Get(name string, options v1.GetOptions) (result *v1alpha1.RateCard, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(ratecardsResource, c.ns, name), &v1alpha1.RateCard{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RateCard), err
+}
+
+// List takes label and field selectors, and returns the list of RateCards that match those selectors.
+func (c *FakeRateCards) List(opts v1.ListOptions) (result *v1alpha1.RateCardList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(ratecardsResource, ratecardsKind, c.ns, opts), &v1alpha1.RateCardList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.RateCardList{ListMeta: obj.(*v1alpha1.RateCardList).ListMeta}
+	for _, item := range obj.(*v1alpha1.RateCardList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested rateCards.
+func (c *FakeRateCards) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(ratecardsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a rateCard and creates it.  Returns the server's representation of the rateCard, and an error, if there is any.
+func (c *FakeRateCards) Create(rateCard *v1alpha1.RateCard) (result *v1alpha1.RateCard, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(ratecardsResource, c.ns, rateCard), &v1alpha1.RateCard{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RateCard), err
+}
+
+// Update takes the representation of a rateCard and updates it. Returns the server's representation of the rateCard, and an error, if there is any.
+func (c *FakeRateCards) Update(rateCard *v1alpha1.RateCard) (result *v1alpha1.RateCard, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(ratecardsResource, c.ns, rateCard), &v1alpha1.RateCard{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RateCard), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeRateCards) UpdateStatus(rateCard *v1alpha1.RateCard) (*v1alpha1.RateCard, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(ratecardsResource, "status", c.ns, rateCard), &v1alpha1.RateCard{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RateCard), err
+}
+
+// Delete takes name of the rateCard and deletes it. Returns an error if one occurs.
+func (c *FakeRateCards) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(ratecardsResource, c.ns, name), &v1alpha1.RateCard{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeRateCards) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(ratecardsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.RateCardList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched rateCard.
+func (c *FakeRateCards) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RateCard, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(ratecardsResource, c.ns, name, pt, data, subresources...), &v1alpha1.RateCard{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RateCard), err
+}
@@ -0,0 +1,124 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeReportNotifications implements ReportNotificationInterface
+type FakeReportNotifications struct {
+	Fake *FakeMeteringV1alpha1
+	ns   string
+}
+
+var reportnotificationsResource = schema.GroupVersionResource{Group: "metering.openshift.io", Version: "v1alpha1", Resource: "reportnotifications"}
+
+var reportnotificationsKind = schema.GroupVersionKind{Group: "metering.openshift.io", Version: "v1alpha1", Kind: "ReportNotification"}
+
+// Get takes name of the reportNotification, and returns the corresponding reportNotification object, and an error if there is any.
+func (c *FakeReportNotifications) Get(name string, options v1.GetOptions) (result *v1alpha1.ReportNotification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(reportnotificationsResource, c.ns, name), &v1alpha1.ReportNotification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportNotification), err
+}
+
+// List takes label and field selectors, and returns the list of ReportNotifications that match those selectors.
+func (c *FakeReportNotifications) List(opts v1.ListOptions) (result *v1alpha1.ReportNotificationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(reportnotificationsResource, reportnotificationsKind, c.ns, opts), &v1alpha1.ReportNotificationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.ReportNotificationList{ListMeta: obj.(*v1alpha1.ReportNotificationList).ListMeta}
+	for _, item := range obj.(*v1alpha1.ReportNotificationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested reportNotifications.
+func (c *FakeReportNotifications) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(reportnotificationsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a reportNotification and creates it.  Returns the server's representation of the reportNotification, and an error, if there is any.
+func (c *FakeReportNotifications) Create(reportNotification *v1alpha1.ReportNotification) (result *v1alpha1.ReportNotification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(reportnotificationsResource, c.ns, reportNotification), &v1alpha1.ReportNotification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportNotification), err
+}
+
+// Update takes the representation of a reportNotification and updates it. Returns the server's representation of the reportNotification, and an error, if there is any.
+func (c *FakeReportNotifications) Update(reportNotification *v1alpha1.ReportNotification) (result *v1alpha1.ReportNotification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(reportnotificationsResource, c.ns, reportNotification), &v1alpha1.ReportNotification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportNotification), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeReportNotifications) UpdateStatus(reportNotification *v1alpha1.ReportNotification) (*v1alpha1.ReportNotification, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(reportnotificationsResource, "status", c.ns, reportNotification), &v1alpha1.ReportNotification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportNotification), err
+}
+
+// Delete takes name of the reportNotification and deletes it. Returns an error if one occurs.
+func (c *FakeReportNotifications) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(reportnotificationsResource, c.ns, name), &v1alpha1.ReportNotification{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeReportNotifications) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(reportnotificationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.ReportNotificationList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched reportNotification.
+func (c *FakeReportNotifications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportNotification, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(reportnotificationsResource, c.ns, name, pt, data, subresources...), &v1alpha1.ReportNotification{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportNotification), err
+}
@@ -0,0 +1,124 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeReportReruns implements ReportRerunInterface
+type FakeReportReruns struct {
+	Fake *FakeMeteringV1alpha1
+	ns   string
+}
+
+var reportrerunsResource = schema.GroupVersionResource{Group: "metering.openshift.io", Version: "v1alpha1", Resource: "reportreruns"}
+
+var reportrerunsKind = schema.GroupVersionKind{Group: "metering.openshift.io", Version: "v1alpha1", Kind: "ReportRerun"}
+
+// Get takes name of the reportRerun, and returns the corresponding reportRerun object, and an error if there is any.
+func (c *FakeReportReruns) Get(name string, options v1.GetOptions) (result *v1alpha1.ReportRerun, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(reportrerunsResource, c.ns, name), &v1alpha1.ReportRerun{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportRerun), err
+}
+
+// List takes label and field selectors, and returns the list of ReportReruns that match those selectors.
+func (c *FakeReportReruns) List(opts v1.ListOptions) (result *v1alpha1.ReportRerunList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(reportrerunsResource, reportrerunsKind, c.ns, opts), &v1alpha1.ReportRerunList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.ReportRerunList{ListMeta: obj.(*v1alpha1.ReportRerunList).ListMeta}
+	for _, item := range obj.(*v1alpha1.ReportRerunList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested reportReruns.
+func (c *FakeReportReruns) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(reportrerunsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a reportRerun and creates it.  Returns the server's representation of the reportRerun, and an error, if there is any.
+func (c *FakeReportReruns) Create(reportRerun *v1alpha1.ReportRerun) (result *v1alpha1.ReportRerun, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(reportrerunsResource, c.ns, reportRerun), &v1alpha1.ReportRerun{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportRerun), err
+}
+
+// Update takes the representation of a reportRerun and updates it. Returns the server's representation of the reportRerun, and an error, if there is any.
+func (c *FakeReportReruns) Update(reportRerun *v1alpha1.ReportRerun) (result *v1alpha1.ReportRerun, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(reportrerunsResource, c.ns, reportRerun), &v1alpha1.ReportRerun{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportRerun), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeReportReruns) UpdateStatus(reportRerun *v1alpha1.ReportRerun) (*v1alpha1.ReportRerun, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(reportrerunsResource, "status", c.ns, reportRerun), &v1alpha1.ReportRerun{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportRerun), err
+}
+
+// Delete takes name of the reportRerun and deletes it. Returns an error if one occurs.
+func (c *FakeReportReruns) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(reportrerunsResource, c.ns, name), &v1alpha1.ReportRerun{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeReportReruns) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(reportrerunsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.ReportRerunList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched reportRerun.
+func (c *FakeReportReruns) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportRerun, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(reportrerunsResource, c.ns, name, pt, data, subresources...), &v1alpha1.ReportRerun{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ReportRerun), err
+}
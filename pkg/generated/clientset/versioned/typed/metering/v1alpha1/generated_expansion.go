@@ -4,12 +4,18 @@ package v1alpha1
 
 type PrestoTableExpansion interface{}
 
+type RateCardExpansion interface{}
+
 type ReportExpansion interface{}
 
 type ReportDataSourceExpansion interface{}
 
 type ReportGenerationQueryExpansion interface{}
 
+type ReportNotificationExpansion interface{}
+
 type ReportPrometheusQueryExpansion interface{}
 
+type ReportRerunExpansion interface{}
+
 type StorageLocationExpansion interface{}
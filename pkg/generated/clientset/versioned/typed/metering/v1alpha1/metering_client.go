@@ -12,10 +12,13 @@ import (
 type MeteringV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	PrestoTablesGetter
+	RateCardsGetter
 	ReportsGetter
 	ReportDataSourcesGetter
 	ReportGenerationQueriesGetter
+	ReportNotificationsGetter
 	ReportPrometheusQueriesGetter
+	ReportRerunsGetter
 	StorageLocationsGetter
 }
 
@@ -28,6 +31,10 @@ func (c *MeteringV1alpha1Client) PrestoTables(namespace string) PrestoTableInter
 	return newPrestoTables(c, namespace)
 }
 
+func (c *MeteringV1alpha1Client) RateCards(namespace string) RateCardInterface {
+	return newRateCards(c, namespace)
+}
+
 func (c *MeteringV1alpha1Client) Reports(namespace string) ReportInterface {
 	return newReports(c, namespace)
 }
@@ -40,10 +47,18 @@ func (c *MeteringV1alpha1Client) ReportGenerationQueries(namespace string) Repor
 	return newReportGenerationQueries(c, namespace)
 }
 
+func (c *MeteringV1alpha1Client) ReportNotifications(namespace string) ReportNotificationInterface {
+	return newReportNotifications(c, namespace)
+}
+
 func (c *MeteringV1alpha1Client) ReportPrometheusQueries(namespace string) ReportPrometheusQueryInterface {
 	return newReportPrometheusQueries(c, namespace)
 }
 
+func (c *MeteringV1alpha1Client) ReportReruns(namespace string) ReportRerunInterface {
+	return newReportReruns(c, namespace)
+}
+
 func (c *MeteringV1alpha1Client) StorageLocations(namespace string) StorageLocationInterface {
 	return newStorageLocations(c, namespace)
 }
@@ -0,0 +1,175 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	scheme "github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// RateCardsGetter has a method to return a RateCardInterface.
+// A group's client should implement this interface.
+type RateCardsGetter interface {
+	RateCards(namespace string) RateCardInterface
+}
+
+// RateCardInterface has methods to work with RateCard resources.
+type RateCardInterface interface {
+	Create(*v1alpha1.RateCard) (*v1alpha1.RateCard, error)
+	Update(*v1alpha1.RateCard) (*v1alpha1.RateCard, error)
+	UpdateStatus(*v1alpha1.RateCard) (*v1alpha1.RateCard, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.RateCard, error)
+	List(opts v1.ListOptions) (*v1alpha1.RateCardList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RateCard, err error)
+	RateCardExpansion
+}
+
+// rateCards implements RateCardInterface
+type rateCards struct {
+	client rest.Interface
+	ns     string
+}
+
+// newRateCards returns a RateCards
+func newRateCards(c *MeteringV1alpha1Client, namespace string) *rateCards {
+	return &rateCards{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the rateCard, and returns the corresponding rateCard object, and an error if there is any.
+func (c *rateCards) Get(name string, options v1.GetOptions) (result *v1alpha1.RateCard, err error) {
+	result = &v1alpha1.RateCard{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("ratecards").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of RateCards that match those selectors.
+func (c *rateCards) List(opts v1.ListOptions) (result *v1alpha1.RateCardList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.RateCardList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("ratecards").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested rateCards.
+func (c *rateCards) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("ratecards").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a rateCard and creates it.  Returns the server's representation of the rateCard, and an error, if there is any.
+func (c *rateCards) Create(rateCard *v1alpha1.RateCard) (result *v1alpha1.RateCard, err error) {
+	result = &v1alpha1.RateCard{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("ratecards").
+		Body(rateCard).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a rateCard and updates it. Returns the server's representation of the rateCard, and an error, if there is any.
+func (c *rateCards) Update(rateCard *v1alpha1.RateCard) (result *v1alpha1.RateCard, err error) {
+	result = &v1alpha1.RateCard{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("ratecards").
+		Name(rateCard.Name).
+		Body(rateCard).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *rateCards) UpdateStatus(rateCard *v1alpha1.RateCard) (result *v1alpha1.RateCard, err error) {
+	result = &v1alpha1.RateCard{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("ratecards").
+		Name(rateCard.Name).
+		SubResource("status").
+		Body(rateCard).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the rateCard and deletes it. Returns an error if one occurs.
+func (c *rateCards) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("ratecards").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *rateCards) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("ratecards").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched rateCard.
+func (c *rateCards) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RateCard, err error) {
+	result = &v1alpha1.RateCard{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("ratecards").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
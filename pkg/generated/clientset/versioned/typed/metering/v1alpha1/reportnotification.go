@@ -0,0 +1,175 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	scheme "github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ReportNotificationsGetter has a method to return a ReportNotificationInterface.
+// A group's client should implement this interface.
+type ReportNotificationsGetter interface {
+	ReportNotifications(namespace string) ReportNotificationInterface
+}
+
+// ReportNotificationInterface has methods to work with ReportNotification resources.
+type ReportNotificationInterface interface {
+	Create(*v1alpha1.ReportNotification) (*v1alpha1.ReportNotification, error)
+	Update(*v1alpha1.ReportNotification) (*v1alpha1.ReportNotification, error)
+	UpdateStatus(*v1alpha1.ReportNotification) (*v1alpha1.ReportNotification, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.ReportNotification, error)
+	List(opts v1.ListOptions) (*v1alpha1.ReportNotificationList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportNotification, err error)
+	ReportNotificationExpansion
+}
+
+// reportNotifications implements ReportNotificationInterface
+type reportNotifications struct {
+	client rest.Interface
+	ns     string
+}
+
+// newReportNotifications returns a ReportNotifications
+func newReportNotifications(c *MeteringV1alpha1Client, namespace string) *reportNotifications {
+	return &reportNotifications{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the reportNotification, and returns the corresponding reportNotification object, and an error if there is any.
+func (c *reportNotifications) Get(name string, options v1.GetOptions) (result *v1alpha1.ReportNotification, err error) {
+	result = &v1alpha1.ReportNotification{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ReportNotifications that match those selectors.
+func (c *reportNotifications) List(opts v1.ListOptions) (result *v1alpha1.ReportNotificationList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.ReportNotificationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested reportNotifications.
+func (c *reportNotifications) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a reportNotification and creates it.  Returns the server's representation of the reportNotification, and an error, if there is any.
+func (c *reportNotifications) Create(reportNotification *v1alpha1.ReportNotification) (result *v1alpha1.ReportNotification, err error) {
+	result = &v1alpha1.ReportNotification{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		Body(reportNotification).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a reportNotification and updates it. Returns the server's representation of the reportNotification, and an error, if there is any.
+func (c *reportNotifications) Update(reportNotification *v1alpha1.ReportNotification) (result *v1alpha1.ReportNotification, err error) {
+	result = &v1alpha1.ReportNotification{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		Name(reportNotification.Name).
+		Body(reportNotification).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *reportNotifications) UpdateStatus(reportNotification *v1alpha1.ReportNotification) (result *v1alpha1.ReportNotification, err error) {
+	result = &v1alpha1.ReportNotification{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		Name(reportNotification.Name).
+		SubResource("status").
+		Body(reportNotification).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the reportNotification and deletes it. Returns an error if one occurs.
+func (c *reportNotifications) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *reportNotifications) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched reportNotification.
+func (c *reportNotifications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportNotification, err error) {
+	result = &v1alpha1.ReportNotification{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("reportnotifications").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
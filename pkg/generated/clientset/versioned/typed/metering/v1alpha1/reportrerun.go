@@ -0,0 +1,175 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	scheme "github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ReportRerunsGetter has a method to return a ReportRerunInterface.
+// A group's client should implement this interface.
+type ReportRerunsGetter interface {
+	ReportReruns(namespace string) ReportRerunInterface
+}
+
+// ReportRerunInterface has methods to work with ReportRerun resources.
+type ReportRerunInterface interface {
+	Create(*v1alpha1.ReportRerun) (*v1alpha1.ReportRerun, error)
+	Update(*v1alpha1.ReportRerun) (*v1alpha1.ReportRerun, error)
+	UpdateStatus(*v1alpha1.ReportRerun) (*v1alpha1.ReportRerun, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.ReportRerun, error)
+	List(opts v1.ListOptions) (*v1alpha1.ReportRerunList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportRerun, err error)
+	ReportRerunExpansion
+}
+
+// reportReruns implements ReportRerunInterface
+type reportReruns struct {
+	client rest.Interface
+	ns     string
+}
+
+// newReportReruns returns a ReportReruns
+func newReportReruns(c *MeteringV1alpha1Client, namespace string) *reportReruns {
+	return &reportReruns{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the reportRerun, and returns the corresponding reportRerun object, and an error if there is any.
+func (c *reportReruns) Get(name string, options v1.GetOptions) (result *v1alpha1.ReportRerun, err error) {
+	result = &v1alpha1.ReportRerun{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ReportReruns that match those selectors.
+func (c *reportReruns) List(opts v1.ListOptions) (result *v1alpha1.ReportRerunList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.ReportRerunList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested reportReruns.
+func (c *reportReruns) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a reportRerun and creates it.  Returns the server's representation of the reportRerun, and an error, if there is any.
+func (c *reportReruns) Create(reportRerun *v1alpha1.ReportRerun) (result *v1alpha1.ReportRerun, err error) {
+	result = &v1alpha1.ReportRerun{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		Body(reportRerun).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a reportRerun and updates it. Returns the server's representation of the reportRerun, and an error, if there is any.
+func (c *reportReruns) Update(reportRerun *v1alpha1.ReportRerun) (result *v1alpha1.ReportRerun, err error) {
+	result = &v1alpha1.ReportRerun{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		Name(reportRerun.Name).
+		Body(reportRerun).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *reportReruns) UpdateStatus(reportRerun *v1alpha1.ReportRerun) (result *v1alpha1.ReportRerun, err error) {
+	result = &v1alpha1.ReportRerun{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		Name(reportRerun.Name).
+		SubResource("status").
+		Body(reportRerun).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the reportRerun and deletes it. Returns an error if one occurs.
+func (c *reportReruns) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *reportReruns) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("reportreruns").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched reportRerun.
+func (c *reportReruns) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.ReportRerun, err error) {
+	result = &v1alpha1.ReportRerun{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("reportreruns").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -10,14 +10,20 @@ import (
 type Interface interface {
 	// PrestoTables returns a PrestoTableInformer.
 	PrestoTables() PrestoTableInformer
+	// RateCards returns a RateCardInformer.
+	RateCards() RateCardInformer
 	// Reports returns a ReportInformer.
 	Reports() ReportInformer
 	// ReportDataSources returns a ReportDataSourceInformer.
 	ReportDataSources() ReportDataSourceInformer
 	// ReportGenerationQueries returns a ReportGenerationQueryInformer.
 	ReportGenerationQueries() ReportGenerationQueryInformer
+	// ReportNotifications returns a ReportNotificationInformer.
+	ReportNotifications() ReportNotificationInformer
 	// ReportPrometheusQueries returns a ReportPrometheusQueryInformer.
 	ReportPrometheusQueries() ReportPrometheusQueryInformer
+	// ReportReruns returns a ReportRerunInformer.
+	ReportReruns() ReportRerunInformer
 	// StorageLocations returns a StorageLocationInformer.
 	StorageLocations() StorageLocationInformer
 }
@@ -38,6 +44,11 @@ func (v *version) PrestoTables() PrestoTableInformer {
 	return &prestoTableInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// RateCards returns a RateCardInformer.
+func (v *version) RateCards() RateCardInformer {
+	return &rateCardInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Reports returns a ReportInformer.
 func (v *version) Reports() ReportInformer {
 	return &reportInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -53,11 +64,21 @@ func (v *version) ReportGenerationQueries() ReportGenerationQueryInformer {
 	return &reportGenerationQueryInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// ReportNotifications returns a ReportNotificationInformer.
+func (v *version) ReportNotifications() ReportNotificationInformer {
+	return &reportNotificationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // ReportPrometheusQueries returns a ReportPrometheusQueryInformer.
 func (v *version) ReportPrometheusQueries() ReportPrometheusQueryInformer {
 	return &reportPrometheusQueryInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// ReportReruns returns a ReportRerunInformer.
+func (v *version) ReportReruns() ReportRerunInformer {
+	return &reportRerunInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // StorageLocations returns a StorageLocationInformer.
 func (v *version) StorageLocations() StorageLocationInformer {
 	return &storageLocationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,73 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	time "time"
+
+	meteringv1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	versioned "github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/operator-framework/operator-metering/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/generated/listers/metering/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ReportNotificationInformer provides access to a shared informer and lister for
+// ReportNotifications.
+type ReportNotificationInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.ReportNotificationLister
+}
+
+type reportNotificationInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewReportNotificationInformer constructs a new informer for ReportNotification type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewReportNotificationInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredReportNotificationInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredReportNotificationInformer constructs a new informer for ReportNotification type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredReportNotificationInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MeteringV1alpha1().ReportNotifications(namespace).List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MeteringV1alpha1().ReportNotifications(namespace).Watch(options)
+			},
+		},
+		&meteringv1alpha1.ReportNotification{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *reportNotificationInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredReportNotificationInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *reportNotificationInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&meteringv1alpha1.ReportNotification{}, f.defaultInformer)
+}
+
+func (f *reportNotificationInformer) Lister() v1alpha1.ReportNotificationLister {
+	return v1alpha1.NewReportNotificationLister(f.Informer().GetIndexer())
+}
@@ -10,6 +10,14 @@ type PrestoTableListerExpansion interface{}
 // PrestoTableNamespaceLister.
 type PrestoTableNamespaceListerExpansion interface{}
 
+// RateCardListerExpansion allows custom methods to be added to
+// RateCardLister.
+type RateCardListerExpansion interface{}
+
+// RateCardNamespaceListerExpansion allows custom methods to be added to
+// RateCardNamespaceLister.
+type RateCardNamespaceListerExpansion interface{}
+
 // ReportListerExpansion allows custom methods to be added to
 // ReportLister.
 type ReportListerExpansion interface{}
@@ -34,6 +42,14 @@ type ReportGenerationQueryListerExpansion interface{}
 // ReportGenerationQueryNamespaceLister.
 type ReportGenerationQueryNamespaceListerExpansion interface{}
 
+// ReportNotificationListerExpansion allows custom methods to be added to
+// ReportNotificationLister.
+type ReportNotificationListerExpansion interface{}
+
+// ReportNotificationNamespaceListerExpansion allows custom methods to be added to
+// ReportNotificationNamespaceLister.
+type ReportNotificationNamespaceListerExpansion interface{}
+
 // ReportPrometheusQueryListerExpansion allows custom methods to be added to
 // ReportPrometheusQueryLister.
 type ReportPrometheusQueryListerExpansion interface{}
@@ -42,6 +58,14 @@ type ReportPrometheusQueryListerExpansion interface{}
 // ReportPrometheusQueryNamespaceLister.
 type ReportPrometheusQueryNamespaceListerExpansion interface{}
 
+// ReportRerunListerExpansion allows custom methods to be added to
+// ReportRerunLister.
+type ReportRerunListerExpansion interface{}
+
+// ReportRerunNamespaceListerExpansion allows custom methods to be added to
+// ReportRerunNamespaceLister.
+type ReportRerunNamespaceListerExpansion interface{}
+
 // StorageLocationListerExpansion allows custom methods to be added to
 // StorageLocationLister.
 type StorageLocationListerExpansion interface{}
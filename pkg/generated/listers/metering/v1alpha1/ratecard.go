@@ -0,0 +1,78 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RateCardLister helps list RateCards.
+type RateCardLister interface {
+	// List lists all RateCards in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.RateCard, err error)
+	// RateCards returns an object that can list and get RateCards.
+	RateCards(namespace string) RateCardNamespaceLister
+	RateCardListerExpansion
+}
+
+// rateCardLister implements the RateCardLister interface.
+type rateCardLister struct {
+	indexer cache.Indexer
+}
+
+// NewRateCardLister returns a new RateCardLister.
+func NewRateCardLister(indexer cache.Indexer) RateCardLister {
+	return &rateCardLister{indexer: indexer}
+}
+
+// List lists all RateCards in the indexer.
+func (s *rateCardLister) List(selector labels.Selector) (ret []*v1alpha1.RateCard, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.RateCard))
+	})
+	return ret, err
+}
+
+// RateCards returns an object that can list and get RateCards.
+func (s *rateCardLister) RateCards(namespace string) RateCardNamespaceLister {
+	return rateCardNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// RateCardNamespaceLister helps list and get RateCards.
+type RateCardNamespaceLister interface {
+	// List lists all RateCards in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.RateCard, err error)
+	// Get retrieves the RateCard from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.RateCard, error)
+	RateCardNamespaceListerExpansion
+}
+
+// rateCardNamespaceLister implements the RateCardNamespaceLister
+// interface.
+type rateCardNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all RateCards in the indexer for a given namespace.
+func (s rateCardNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.RateCard, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.RateCard))
+	})
+	return ret, err
+}
+
+// Get retrieves the RateCard from the indexer for a given namespace and name.
+func (s rateCardNamespaceLister) Get(name string) (*v1alpha1.RateCard, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("ratecard"), name)
+	}
+	return obj.(*v1alpha1.RateCard), nil
+}
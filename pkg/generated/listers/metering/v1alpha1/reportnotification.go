@@ -0,0 +1,78 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ReportNotificationLister helps list ReportNotifications.
+type ReportNotificationLister interface {
+	// List lists all ReportNotifications in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.ReportNotification, err error)
+	// ReportNotifications returns an object that can list and get ReportNotifications.
+	ReportNotifications(namespace string) ReportNotificationNamespaceLister
+	ReportNotificationListerExpansion
+}
+
+// reportNotificationLister implements the ReportNotificationLister interface.
+type reportNotificationLister struct {
+	indexer cache.Indexer
+}
+
+// NewReportNotificationLister returns a new ReportNotificationLister.
+func NewReportNotificationLister(indexer cache.Indexer) ReportNotificationLister {
+	return &reportNotificationLister{indexer: indexer}
+}
+
+// List lists all ReportNotifications in the indexer.
+func (s *reportNotificationLister) List(selector labels.Selector) (ret []*v1alpha1.ReportNotification, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.ReportNotification))
+	})
+	return ret, err
+}
+
+// ReportNotifications returns an object that can list and get ReportNotifications.
+func (s *reportNotificationLister) ReportNotifications(namespace string) ReportNotificationNamespaceLister {
+	return reportNotificationNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// ReportNotificationNamespaceLister helps list and get ReportNotifications.
+type ReportNotificationNamespaceLister interface {
+	// List lists all ReportNotifications in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.ReportNotification, err error)
+	// Get retrieves the ReportNotification from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.ReportNotification, error)
+	ReportNotificationNamespaceListerExpansion
+}
+
+// reportNotificationNamespaceLister implements the ReportNotificationNamespaceLister
+// interface.
+type reportNotificationNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all ReportNotifications in the indexer for a given namespace.
+func (s reportNotificationNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.ReportNotification, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.ReportNotification))
+	})
+	return ret, err
+}
+
+// Get retrieves the ReportNotification from the indexer for a given namespace and name.
+func (s reportNotificationNamespaceLister) Get(name string) (*v1alpha1.ReportNotification, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("reportnotification"), name)
+	}
+	return obj.(*v1alpha1.ReportNotification), nil
+}
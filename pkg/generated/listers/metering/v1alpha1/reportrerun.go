@@ -0,0 +1,78 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ReportRerunLister helps list ReportReruns.
+type ReportRerunLister interface {
+	// List lists all ReportReruns in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.ReportRerun, err error)
+	// ReportReruns returns an object that can list and get ReportReruns.
+	ReportReruns(namespace string) ReportRerunNamespaceLister
+	ReportRerunListerExpansion
+}
+
+// reportRerunLister implements the ReportRerunLister interface.
+type reportRerunLister struct {
+	indexer cache.Indexer
+}
+
+// NewReportRerunLister returns a new ReportRerunLister.
+func NewReportRerunLister(indexer cache.Indexer) ReportRerunLister {
+	return &reportRerunLister{indexer: indexer}
+}
+
+// List lists all ReportReruns in the indexer.
+func (s *reportRerunLister) List(selector labels.Selector) (ret []*v1alpha1.ReportRerun, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.ReportRerun))
+	})
+	return ret, err
+}
+
+// ReportReruns returns an object that can list and get ReportReruns.
+func (s *reportRerunLister) ReportReruns(namespace string) ReportRerunNamespaceLister {
+	return reportRerunNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// ReportRerunNamespaceLister helps list and get ReportReruns.
+type ReportRerunNamespaceLister interface {
+	// List lists all ReportReruns in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.ReportRerun, err error)
+	// Get retrieves the ReportRerun from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.ReportRerun, error)
+	ReportRerunNamespaceListerExpansion
+}
+
+// reportRerunNamespaceLister implements the ReportRerunNamespaceLister
+// interface.
+type reportRerunNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all ReportReruns in the indexer for a given namespace.
+func (s reportRerunNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.ReportRerun, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.ReportRerun))
+	})
+	return ret, err
+}
+
+// Get retrieves the ReportRerun from the indexer for a given namespace and name.
+func (s reportRerunNamespaceLister) Get(name string) (*v1alpha1.ReportRerun, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("reportrerun"), name)
+	}
+	return obj.(*v1alpha1.ReportRerun), nil
+}
@@ -2,6 +2,8 @@ package hive
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,53 @@ func generateDropTableSQL(name string, ignoreNotExists, purge bool) string {
 	return fmt.Sprintf("DROP TABLE %s %s %s", ifExists, name, purgeStr)
 }
 
+func generateDropPartitionSQL(tableName string, partitionSpec map[string]string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP IF EXISTS PARTITION (%s) PURGE", tableName, generatePartitionSpecSQL(partitionSpec))
+}
+
+func generateAddPartitionSQL(tableName string, partitionSpec map[string]string, location string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD IF NOT EXISTS PARTITION (%s) LOCATION '%s'", tableName, generatePartitionSpecSQL(partitionSpec), location)
+}
+
+func generateSetPartitionLocationSQL(tableName string, partitionSpec map[string]string, location string) string {
+	return fmt.Sprintf("ALTER TABLE %s PARTITION (%s) SET LOCATION '%s'", tableName, generatePartitionSpecSQL(partitionSpec), location)
+}
+
+func generateSetLocationSQL(tableName, location string) string {
+	return fmt.Sprintf("ALTER TABLE %s SET LOCATION '%s'", tableName, location)
+}
+
+func generateLoadDataSQL(tableName, location string) string {
+	return fmt.Sprintf("LOAD DATA INPATH '%s' INTO TABLE %s", location, tableName)
+}
+
+func generateSetExternalSQL(tableName string, external bool) string {
+	return fmt.Sprintf("ALTER TABLE %s SET TBLPROPERTIES ('EXTERNAL'='%s')", tableName, strings.ToUpper(strconv.FormatBool(external)))
+}
+
+// generatePartitionSpecSQL returns the partition columns and values of
+// partitionSpec, sorted by column name.
+func generatePartitionSpecSQL(partitionSpec map[string]string) string {
+	keys := make([]string, 0, len(partitionSpec))
+	for key := range partitionSpec {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = fmt.Sprintf("`%s`='%s'", key, partitionSpec[key])
+	}
+	return strings.Join(values, ",")
+}
+
+func generateAddColumnsSQL(tableName string, columns []Column, cascade bool) string {
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMNS (%s)", tableName, generateColumnListSQL(columns))
+	if cascade {
+		query += " CASCADE"
+	}
+	return query
+}
+
 // generateCreateTableSQL returns a query for a CREATE statement which instantiates a new external Hive table.
 // If is external is set, an external Hive table will be used.
 func generateCreateTableSQL(params TableParameters, properties TableProperties) string {
@@ -39,6 +39,59 @@ func ExecuteDropTable(queryer db.Queryer, tableName string, ignoreNotExists bool
 	return err
 }
 
+// ExecuteDropPartition drops the partition of tableName matching
+// partitionSpec, deleting its data.
+func ExecuteDropPartition(queryer db.Queryer, tableName string, partitionSpec map[string]string) error {
+	query := generateDropPartitionSQL(tableName, partitionSpec)
+	_, err := queryer.Query(query)
+	return err
+}
+
+// ExecuteAddColumns adds columns to the end of tableName's columns. If
+// cascade is true, the columns are also added to the existing partitions of
+// tableName.
+func ExecuteAddColumns(queryer db.Queryer, tableName string, columns []Column, cascade bool) error {
+	query := generateAddColumnsSQL(tableName, columns, cascade)
+	_, err := queryer.Query(query)
+	return err
+}
+
+// ExecuteSetLocation changes the location of tableName, so it reads the
+// files in location instead. The files in the previous location are kept.
+func ExecuteSetLocation(queryer db.Queryer, tableName, location string) error {
+	query := generateSetLocationSQL(tableName, location)
+	_, err := queryer.Query(query)
+	return err
+}
+
+// ExecuteSetPartitionLocation changes the location of the partition of
+// tableName matching partitionSpec, adding the partition if it doesn't
+// exist. The files in the previous location are kept.
+func ExecuteSetPartitionLocation(queryer db.Queryer, tableName string, partitionSpec map[string]string, location string) error {
+	_, err := queryer.Query(generateAddPartitionSQL(tableName, partitionSpec, location))
+	if err != nil {
+		return err
+	}
+	_, err = queryer.Query(generateSetPartitionLocationSQL(tableName, partitionSpec, location))
+	return err
+}
+
+// ExecuteSetExternal changes whether tableName is an external table. The
+// files of external tables are kept when they're dropped.
+func ExecuteSetExternal(queryer db.Queryer, tableName string, external bool) error {
+	query := generateSetExternalSQL(tableName, external)
+	_, err := queryer.Query(query)
+	return err
+}
+
+// ExecuteLoadData moves the files in location into the location of
+// tableName, adding their rows to tableName.
+func ExecuteLoadData(queryer db.Queryer, tableName, location string) error {
+	query := generateLoadDataSQL(tableName, location)
+	_, err := queryer.Query(query)
+	return err
+}
+
 // s3Location returns the HDFS path based on an S3 bucket and prefix.
 func S3Location(bucket, prefix string) (string, error) {
 	bucket = path.Join(bucket, prefix)
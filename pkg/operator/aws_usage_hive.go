@@ -51,5 +51,7 @@ func (op *Reporting) createAWSUsageTable(logger logrus.FieldLogger, dataSource *
 		SerdeRowProperties: reportingutil.AWSUsageHiveSerdeProps,
 		External:           true,
 	}
-	return op.createTableAndCR(logger, dataSource, cbTypes.SchemeGroupVersion.WithKind("ReportDataSource"), params, properties)
+	gvk := cbTypes.SchemeGroupVersion.WithKind("ReportDataSource")
+	resourceName := reportingutil.PrestoTableResourceNameFromKind(gvk.Kind, dataSource.Namespace, dataSource.Name)
+	return op.createTableAndCR(logger, dataSource, gvk, resourceName, params, properties)
 }
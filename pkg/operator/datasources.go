@@ -3,11 +3,14 @@ package operator
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -143,6 +146,10 @@ func (op *Reporting) handlePrometheusMetricsDataSource(logger log.FieldLogger, d
 		return nil
 	}
 
+	if dataSource.Spec.Promsum.RemoteWrite != nil {
+		return op.handleRemoteWriteDataSource(logger, dataSource)
+	}
+
 	if op.cfg.DisablePromsum {
 		logger.Infof("Periodic Prometheus ReportDataSource importing disabled")
 		return nil
@@ -161,7 +168,12 @@ func (op *Reporting) handlePrometheusMetricsDataSource(logger log.FieldLogger, d
 		"tableName":        dataSource.Status.TableName,
 	})
 
-	importerCfg := op.newPromImporterCfg(dataSource, reportPromQuery)
+	importerCfg, err := op.newPromImporterCfg(dataSource, reportPromQuery)
+	if err != nil {
+		// retrying won't help until the ReportDataSource is updated
+		logger.WithError(err).Errorf("invalid ReportDataSource %s", dataSource.Name)
+		return nil
+	}
 
 	// wrap in a closure to handle lock and unlock of the mutex
 	importer, err := func() (*prestostore.PrometheusImporter, error) {
@@ -185,6 +197,11 @@ func (op *Reporting) handlePrometheusMetricsDataSource(logger log.FieldLogger, d
 		return err
 	}
 
+	dataSource, err = op.createPrometheusMetricsDataSourceRollupTables(dataSourceLogger, dataSource)
+	if err != nil {
+		return err
+	}
+
 	if dataSource.Status.PrometheusMetricImportStatus == nil {
 		dataSource.Status.PrometheusMetricImportStatus = &cbTypes.PrometheusMetricImportStatus{}
 	}
@@ -193,8 +210,10 @@ func (op *Reporting) handlePrometheusMetricsDataSource(logger log.FieldLogger, d
 	dataSource.Status.PrometheusMetricImportStatus.LastImportTime = &metav1.Time{op.clock.Now().UTC()}
 
 	// run the import
-	results, err := importer.ImportFromLastTimestamp(context.Background(), allowIncompleteChunks)
+	checkpointer := &reportDataSourceImportCheckpointer{op: op, dataSource: dataSource}
+	results, err := importer.ImportFromLastTimestamp(context.Background(), allowIncompleteChunks, checkpointer)
 	if err != nil {
+		op.eventRecorder.Eventf(dataSource, v1.EventTypeWarning, importFailedEventReason, "Failed to import Prometheus metrics: %v", err)
 		return fmt.Errorf("ImportFromLastTimestamp errored: %v", err)
 	}
 
@@ -270,12 +289,74 @@ func (op *Reporting) handlePrometheusMetricsDataSource(logger log.FieldLogger, d
 		}
 	}
 
+	prevGaps := dataSource.Status.PrometheusMetricImportStatus.Gaps
+	if op.checkPrometheusMetricsDataSourceGaps(dataSourceLogger, dataSource, importer, importerCfg.StepSize) {
+		gapsChanged := !reflect.DeepEqual(prevGaps, dataSource.Status.PrometheusMetricImportStatus.Gaps)
+		dataSource, err = op.meteringClient.MeteringV1alpha1().ReportDataSources(dataSource.Namespace).Update(dataSource)
+		if err != nil {
+			return fmt.Errorf("unable to update ReportDataSource PrometheusMetricImportStatus gaps: %v", err)
+		}
+		// Reports requiring complete data may be waiting for gaps to be
+		// backfilled.
+		if gapsChanged {
+			if err := op.queueDependentReportsForDataSource(dataSource); err != nil {
+				logger.WithError(err).Errorf("error queuing Report dependents of ReportDataSource %s", dataSource.Name)
+			}
+		}
+	}
+
+	if op.updatePrometheusMetricsDataSourceRollups(dataSourceLogger, dataSource) {
+		dataSource, err = op.meteringClient.MeteringV1alpha1().ReportDataSources(dataSource.Namespace).Update(dataSource)
+		if err != nil {
+			return fmt.Errorf("unable to update ReportDataSource %s rollups: %v", dataSource.Name, err)
+		}
+	}
+
 	nextImport := op.clock.Now().Add(importDelay).UTC()
 	logger.Infof("queuing Prometheus ReportDataSource %s to import data again in %s at %s", dataSource.Name, importDelay, nextImport)
 	op.enqueueReportDataSourceAfter(dataSource, importDelay)
 	return nil
 }
 
+// reportDataSourceImportCheckpointer stores the checkpoints of a
+// PrometheusImporter in the ImportDataStartTime and ImportDataEndTime of a
+// ReportDataSource's PrometheusMetricImportStatus. The ReportDataSource is
+// replaced with the updated copy after each checkpoint, so it can be updated
+// again afterwards without conflicts. Checkpoints only change the status, so
+// the validating webhook allows them even if the spec has become invalid.
+type reportDataSourceImportCheckpointer struct {
+	op         *Reporting
+	dataSource *cbTypes.ReportDataSource
+}
+
+func (c *reportDataSourceImportCheckpointer) GetImportCheckpoint() (*time.Time, error) {
+	importStatus := c.dataSource.Status.PrometheusMetricImportStatus
+	if importStatus == nil || importStatus.ImportDataEndTime == nil {
+		return nil, nil
+	}
+	checkpoint := importStatus.ImportDataEndTime.Time
+	return &checkpoint, nil
+}
+
+func (c *reportDataSourceImportCheckpointer) StoreImportCheckpoint(timeRange prom.Range) error {
+	if c.dataSource.Status.PrometheusMetricImportStatus == nil {
+		c.dataSource.Status.PrometheusMetricImportStatus = &cbTypes.PrometheusMetricImportStatus{}
+	}
+	importStatus := c.dataSource.Status.PrometheusMetricImportStatus
+	if importStatus.ImportDataStartTime == nil || timeRange.Start.Before(importStatus.ImportDataStartTime.Time) {
+		importStatus.ImportDataStartTime = &metav1.Time{Time: timeRange.Start}
+	}
+	if importStatus.ImportDataEndTime == nil || importStatus.ImportDataEndTime.Time.Before(timeRange.End) {
+		importStatus.ImportDataEndTime = &metav1.Time{Time: timeRange.End}
+	}
+	dataSource, err := c.op.meteringClient.MeteringV1alpha1().ReportDataSources(c.dataSource.Namespace).Update(c.dataSource)
+	if err != nil {
+		return fmt.Errorf("unable to update ReportDataSource %s PrometheusMetricImportStatus: %v", c.dataSource.Name, err)
+	}
+	*c.dataSource = *dataSource
+	return nil
+}
+
 func (op *Reporting) handleAWSBillingDataSource(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource) error {
 	source := dataSource.Spec.AWSBilling.Source
 	if source == nil {
@@ -292,6 +373,7 @@ func (op *Reporting) handleAWSBillingDataSource(logger log.FieldLogger, dataSour
 
 	manifests, err := manifestRetriever.RetrieveManifests()
 	if err != nil {
+		op.eventRecorder.Eventf(dataSource, v1.EventTypeWarning, importFailedEventReason, "Failed to retrieve AWS billing report manifests from s3://%s/%s: %v", source.Bucket, source.Prefix, err)
 		return err
 	}
 
@@ -330,11 +412,16 @@ func (op *Reporting) handleAWSBillingDataSource(logger log.FieldLogger, dataSour
 		}
 	}
 
-	err = op.updateAWSBillingPartitions(logger, gauge, source, prestoTable, manifests)
+	changes, err := op.updateAWSBillingPartitions(logger, gauge, source, prestoTable, manifests)
 	if err != nil {
+		op.eventRecorder.Eventf(dataSource, v1.EventTypeWarning, partitionsUpdateFailedEventReason, "Failed to update the partitions of table %s: %v", dataSource.Status.TableName, err)
 		return fmt.Errorf("error updating AWS billing partitions for ReportDataSource %s: %v", dataSource.Name, err)
 	}
 
+	if len(changes.toAddPartitions) != 0 || len(changes.toRemovePartitions) != 0 || len(changes.toUpdatePartitions) != 0 {
+		op.eventRecorder.Eventf(dataSource, v1.EventTypeNormal, partitionsUpdatedEventReason, "Updated the partitions of table %s: %d added, %d removed, %d updated", dataSource.Status.TableName, len(changes.toAddPartitions), len(changes.toRemovePartitions), len(changes.toUpdatePartitions))
+	}
+
 	nextUpdate := op.clock.Now().Add(partitionUpdateInterval).UTC()
 
 	logger.Infof("queuing AWSBilling ReportDataSource %s to update partitions again in %s at %s", dataSource.Name, partitionUpdateInterval, nextUpdate)
@@ -349,12 +436,14 @@ func (op *Reporting) handleAWSBillingDataSource(logger log.FieldLogger, dataSour
 	return nil
 }
 
-func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitionsGauge prometheus.Gauge, source *cbTypes.S3Bucket, prestoTable *cbTypes.PrestoTable, manifests []*aws.Manifest) error {
+// updateAWSBillingPartitions updates the partitions of prestoTable to match
+// the AWS billing report manifests, returning the partitions which changed.
+func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitionsGauge prometheus.Gauge, source *cbTypes.S3Bucket, prestoTable *cbTypes.PrestoTable, manifests []*aws.Manifest) (partitionChanges, error) {
 	logger.Infof("updating partitions for presto table %s", prestoTable.Name)
 	// Fetch the billing manifests
 	if len(manifests) == 0 {
 		logger.Warnf("PrestoTable %q has no report manifests in its bucket, the first report has likely not been generated yet", prestoTable.Name)
-		return nil
+		return partitionChanges{}, nil
 	}
 
 	// Compare the manifests list and existing partitions, deleting stale
@@ -362,7 +451,7 @@ func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitio
 	currentPartitions := prestoTable.Status.Partitions
 	desiredPartitions, err := getDesiredPartitions(source.Bucket, manifests)
 	if err != nil {
-		return err
+		return partitionChanges{}, err
 	}
 
 	changes := getPartitionChanges(currentPartitions, desiredPartitions)
@@ -407,7 +496,7 @@ func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitio
 		err = op.awsTablePartitionManager.DropPartition(tableName, start, end)
 		if err != nil {
 			logger.WithError(err).Errorf("failed to drop partition in table %s for range %s-%s", tableName, start, end)
-			return err
+			return partitionChanges{}, err
 		}
 		logger.Debugf("partition successfully deleted from presto table %q with range %s-%s", tableName, start, end)
 	}
@@ -420,7 +509,7 @@ func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitio
 		err = op.awsTablePartitionManager.AddPartition(tableName, start, end, p.Location)
 		if err != nil {
 			logger.WithError(err).Errorf("failed to add partition in table %s for range %s-%s at location %s", prestoTable.Status.Parameters.Name, p.PartitionSpec["start"], p.PartitionSpec["end"], p.Location)
-			return err
+			return partitionChanges{}, err
 		}
 		logger.Debugf("partition successfully added to presto table %q with range %s-%s", tableName, start, end)
 	}
@@ -433,11 +522,11 @@ func (op *Reporting) updateAWSBillingPartitions(logger log.FieldLogger, partitio
 	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
 	if err != nil {
 		logger.WithError(err).Errorf("failed to update PrestoTable CR partitions for %q", prestoTable.Name)
-		return err
+		return partitionChanges{}, err
 	}
 
 	logger.Infof("finished updating partitions for prestoTable %q", prestoTable.Name)
-	return nil
+	return changes, nil
 }
 
 func getDesiredPartitions(bucket string, manifests []*aws.Manifest) ([]cbTypes.TablePartition, error) {
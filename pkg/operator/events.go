@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+
+	meteringscheme "github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/scheme"
+)
+
+// Reasons of the Events recorded for Reports, ReportDataSources,
+// ReportGenerationQueries and PrestoTables.
+const (
+	reportScheduledEventReason             = "ReportScheduled"
+	reportStartedEventReason               = "ReportStarted"
+	reportSucceededEventReason             = "ReportSucceeded"
+	reportFailedEventReason                = "ReportFailed"
+	reportWaitingOnDependenciesEventReason = "ReportWaitingOnDependencies"
+	invalidReportEventReason               = "InvalidReport"
+
+	importFailedEventReason           = "ImportFailed"
+	partitionsUpdatedEventReason      = "PartitionsUpdated"
+	partitionsUpdateFailedEventReason = "PartitionsUpdateFailed"
+
+	tableCreateFailedEventReason = "TableCreateFailed"
+	tableDroppedEventReason      = "TableDropped"
+	tableDropFailedEventReason   = "TableDropFailed"
+
+	tableSchemaUpdatedEventReason  = "TableSchemaUpdated"
+	tableSchemaMismatchEventReason = "TableSchemaMismatch"
+
+	viewDroppedEventReason    = "ViewDropped"
+	viewDropFailedEventReason = "ViewDropFailed"
+
+	orphanedTableFoundEventReason   = "OrphanedTableFound"
+	orphanedTableDroppedEventReason = "OrphanedTableDropped"
+)
+
+const (
+	// eventRateLimitInterval is how long identical Events for the same
+	// object are dropped for after one is recorded. Resources which fail to
+	// sync are retried far more often than this, and the Event from the
+	// first failure is enough to explain the rest.
+	eventRateLimitInterval = 5 * time.Minute
+
+	// eventRateLimitCacheSize is the number of recently recorded Events
+	// remembered for rate limiting.
+	eventRateLimitCacheSize = 4096
+)
+
+// eventScheme contains the types Events can be recorded for, so references to
+// them can be created without the objects having their TypeMeta set.
+var eventScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(eventScheme))
+	utilruntime.Must(meteringscheme.AddToScheme(eventScheme))
+}
+
+// rateLimitedEventRecorder is an EventRecorder which drops Events identical to
+// one recorded for the same object within the last interval. This keeps
+// requeued resources from emitting an Event each time they're retried.
+type rateLimitedEventRecorder struct {
+	record.EventRecorder
+	clock    clock.Clock
+	interval time.Duration
+
+	mu     sync.Mutex
+	recent *lru.Cache
+}
+
+func newRateLimitedEventRecorder(recorder record.EventRecorder, clock clock.Clock, interval time.Duration) *rateLimitedEventRecorder {
+	return &rateLimitedEventRecorder{
+		EventRecorder: recorder,
+		clock:         clock,
+		interval:      interval,
+		recent:        lru.New(eventRateLimitCacheSize),
+	}
+}
+
+func (r *rateLimitedEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *rateLimitedEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// allow returns true if the Event hasn't been recorded for object within the
+// last interval, and remembers that it's being recorded now.
+func (r *rateLimitedEventRecorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return true
+	}
+	key := strings.Join([]string{string(accessor.GetUID()), accessor.GetNamespace(), accessor.GetName(), eventtype, reason, message}, "\x00")
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.recent.Get(key); ok && now.Sub(last.(time.Time)) < r.interval {
+		return false
+	}
+	r.recent.Add(key, now)
+	return true
+}
@@ -0,0 +1,60 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+)
+
+func TestRateLimitedEventRecorder(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(10)
+	fakeClock := clock.NewFakeClock(time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC))
+	recorder := newRateLimitedEventRecorder(fakeRecorder, fakeClock, time.Minute)
+
+	report := &cbTypes.Report{ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "default", UID: "1"}}
+	otherReport := &cbTypes.Report{ObjectMeta: metav1.ObjectMeta{Name: "other-report", Namespace: "default", UID: "2"}}
+
+	recorder.Eventf(report, v1.EventTypeWarning, reportFailedEventReason, "Failed: %s", "timeout")
+	// identical events for the same object are dropped within the interval
+	recorder.Eventf(report, v1.EventTypeWarning, reportFailedEventReason, "Failed: %s", "timeout")
+	// but not events with a different message, or for other objects
+	recorder.Eventf(report, v1.EventTypeWarning, reportFailedEventReason, "Failed: %s", "out of memory")
+	recorder.Eventf(otherReport, v1.EventTypeWarning, reportFailedEventReason, "Failed: %s", "timeout")
+	fakeClock.Step(time.Minute)
+	recorder.Eventf(report, v1.EventTypeWarning, reportFailedEventReason, "Failed: %s", "timeout")
+	close(fakeRecorder.Events)
+
+	var events []string
+	for event := range fakeRecorder.Events {
+		events = append(events, event)
+	}
+	assert.Equal(t, []string{
+		"Warning ReportFailed Failed: timeout",
+		"Warning ReportFailed Failed: out of memory",
+		"Warning ReportFailed Failed: timeout",
+		"Warning ReportFailed Failed: timeout",
+	}, events)
+}
+
+func TestEventScheme(t *testing.T) {
+	// objects from listers have no TypeMeta, so the scheme must know the
+	// metering types to reference them in Events.
+	report := &cbTypes.Report{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-report",
+		Namespace: "default",
+		SelfLink:  "/apis/metering.openshift.io/v1alpha1/namespaces/default/reports/test-report",
+	}}
+	ref, err := reference.GetReference(eventScheme, report)
+	require.NoError(t, err)
+	assert.Equal(t, "Report", ref.Kind)
+	assert.Equal(t, "metering.openshift.io/v1alpha1", ref.APIVersion)
+}
@@ -0,0 +1,232 @@
+package operator
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+)
+
+const (
+	// maxGapBackfillAttempts is the number of times a gap is re-imported
+	// without finding any metrics before it's no longer re-imported.
+	maxGapBackfillAttempts = 3
+)
+
+var (
+	prometheusReportDatasourceGapLabels = []string{
+		"reportdatasource",
+		"namespace",
+		"table_name",
+	}
+
+	prometheusReportDatasourceGapsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_gaps",
+			Help:      "Number of gaps found in the table of a Prometheus ReportDataSource during the last gap check.",
+		},
+		prometheusReportDatasourceGapLabels,
+	)
+
+	prometheusReportDatasourceGapBackfillsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_gap_backfills_total",
+			Help:      "Number of attempts to re-import gaps in the table of a Prometheus ReportDataSource.",
+		},
+		prometheusReportDatasourceGapLabels,
+	)
+
+	prometheusReportDatasourceFailedGapBackfillsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_failed_gap_backfills_total",
+			Help:      "Number of failed attempts to re-import gaps in the table of a Prometheus ReportDataSource.",
+		},
+		prometheusReportDatasourceGapLabels,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(prometheusReportDatasourceGapsGauge)
+	prometheus.MustRegister(prometheusReportDatasourceGapBackfillsCounter)
+	prometheus.MustRegister(prometheusReportDatasourceFailedGapBackfillsCounter)
+}
+
+// checkPrometheusMetricsDataSourceGaps checks the table of a Prometheus
+// ReportDataSource for gaps if it hasn't been checked within the gap check
+// interval, records them in the ReportDataSource's
+// PrometheusMetricImportStatus, and re-imports the ones within Prometheus'
+// retention. Returns true if the status was changed and needs to be updated.
+func (op *Reporting) checkPrometheusMetricsDataSourceGaps(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource, importer *prestostore.PrometheusImporter, stepSize time.Duration) bool {
+	importStatus := dataSource.Status.PrometheusMetricImportStatus
+	if op.cfg.PrometheusDataSourceGapCheckInterval <= 0 || importStatus == nil || importStatus.EarliestImportedMetricTime == nil || importStatus.NewestImportedMetricTime == nil {
+		return false
+	}
+	now := op.clock.Now().UTC()
+	if importStatus.LastGapCheckTime != nil && now.Sub(importStatus.LastGapCheckTime.Time) < op.cfg.PrometheusDataSourceGapCheckInterval {
+		return false
+	}
+
+	tableName := dataSource.Status.TableName
+	promLabels := prometheus.Labels{
+		"reportdatasource": dataSource.Name,
+		"namespace":        dataSource.Namespace,
+		"table_name":       tableName,
+	}
+
+	// Prometheus doesn't have data older than its retention, so only the
+	// part of each gap within the retention can be backfilled.
+	retentionStart := now.Add(-op.cfg.PrometheusDataSourceRetention)
+
+	checkRanges, uncheckedGaps := prometheusMetricImportGapCheckRanges(importStatus, retentionStart, stepSize)
+	var detectedGaps []prom.Range
+	for _, r := range checkRanges {
+		logger.Debugf("checking table %s for gaps between %s and %s", tableName, r.Start, r.End)
+		rangeGaps, err := op.prometheusMetricsRepo.GetTimestampGapsForTable(tableName, r.Start, r.End, stepSize)
+		if err != nil {
+			logger.WithError(err).Errorf("unable to check table %s for gaps", tableName)
+			return false
+		}
+		detectedGaps = append(detectedGaps, rangeGaps...)
+	}
+	gaps := append(mergePrometheusMetricImportGaps(importStatus.Gaps, detectedGaps), uncheckedGaps...)
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].Start.Before(&gaps[j].Start)
+	})
+	prometheusReportDatasourceGapsGauge.With(promLabels).Set(float64(len(gaps)))
+	if len(gaps) != 0 {
+		logger.Warnf("found %d gaps in table %s", len(gaps), tableName)
+	}
+
+	backfilled := false
+	for i := range gaps {
+		gap := &gaps[i]
+		if gap.BackfillAttempts >= maxGapBackfillAttempts || gap.End.Time.Before(retentionStart) {
+			continue
+		}
+		start := gap.Start.Time
+		if start.Before(retentionStart) {
+			// start from the first missing timestamp within the retention
+			steps := (retentionStart.Sub(start) + stepSize - 1) / stepSize
+			start = start.Add(steps * stepSize)
+		}
+
+		logger.Infof("backfilling gap in table %s from %s to %s", tableName, start, gap.End.Time)
+		prometheusReportDatasourceGapBackfillsCounter.With(promLabels).Inc()
+		results, err := importer.ImportFromTimeRange(context.Background(), start, gap.End.Time, true)
+		if err != nil {
+			prometheusReportDatasourceFailedGapBackfillsCounter.With(promLabels).Inc()
+			logger.WithError(err).Errorf("unable to backfill gap in table %s from %s to %s", tableName, start, gap.End.Time)
+			gap.BackfillAttempts++
+			continue
+		}
+		if len(results.Metrics) == 0 {
+			logger.Warnf("no metrics found when backfilling gap in table %s from %s to %s", tableName, start, gap.End.Time)
+			gap.BackfillAttempts++
+			continue
+		}
+		backfilled = true
+		op.resetPrometheusMetricsDataSourceRollups(logger, dataSource, start)
+	}
+
+	importStatus.Gaps = gaps
+	importStatus.GapsCheckedUpTo = &metav1.Time{Time: importStatus.NewestImportedMetricTime.Time}
+	// Leave the LastGapCheckTime unchanged if metrics were backfilled, so
+	// the gaps are checked again after the next import to find out what's
+	// still missing, since large gaps take multiple imports to fill.
+	if !backfilled {
+		importStatus.LastGapCheckTime = &metav1.Time{Time: now}
+	}
+	return true
+}
+
+// prometheusMetricImportGapCheckRanges returns the time ranges of a table to
+// check for gaps: the metrics imported since GapsCheckedUpTo, and each of
+// the recorded gaps which can still be backfilled, since backfilling may
+// have filled them. Each range starts and ends with a timestamp known to be
+// in the table, so gaps at its edges are found. The recorded gaps which
+// aren't checked again are also returned, except the ones older than the
+// earliest metric in the table, which has been removed by its retention.
+// The whole table is checked if it hasn't been checked before.
+func prometheusMetricImportGapCheckRanges(importStatus *cbTypes.PrometheusMetricImportStatus, retentionStart time.Time, stepSize time.Duration) ([]prom.Range, []cbTypes.PrometheusMetricImportGap) {
+	earliest := importStatus.EarliestImportedMetricTime.Time
+	newest := importStatus.NewestImportedMetricTime.Time
+	checkedUpTo := importStatus.GapsCheckedUpTo
+	if checkedUpTo == nil || !checkedUpTo.Time.After(earliest) || checkedUpTo.Time.After(newest) {
+		return []prom.Range{{Start: earliest, End: newest, Step: stepSize}}, nil
+	}
+
+	var ranges []prom.Range
+	var unchecked []cbTypes.PrometheusMetricImportGap
+	for _, gap := range importStatus.Gaps {
+		if gap.End.Time.Before(earliest) {
+			continue
+		}
+		if gap.BackfillAttempts >= maxGapBackfillAttempts || gap.End.Time.Before(retentionStart) {
+			unchecked = append(unchecked, gap)
+			continue
+		}
+		start := gap.Start.Time.Add(-stepSize)
+		if start.Before(earliest) {
+			start = earliest
+		}
+		end := gap.End.Time.Add(stepSize)
+		if end.After(newest) {
+			end = newest
+		}
+		ranges = append(ranges, prom.Range{Start: start, End: end, Step: stepSize})
+	}
+
+	if checkedUpTo.Time.Before(newest) {
+		ranges = append(ranges, prom.Range{Start: checkedUpTo.Time, End: newest, Step: stepSize})
+	}
+	return ranges, unchecked
+}
+
+// mergePrometheusMetricImportGaps converts the gaps detected in a table into
+// PrometheusMetricImportGaps, keeping the BackfillAttempts of the previously
+// recorded gaps they overlap.
+func mergePrometheusMetricImportGaps(previous []cbTypes.PrometheusMetricImportGap, detected []prom.Range) []cbTypes.PrometheusMetricImportGap {
+	if len(detected) == 0 {
+		return nil
+	}
+	gaps := make([]cbTypes.PrometheusMetricImportGap, len(detected))
+	for i, r := range detected {
+		gap := cbTypes.PrometheusMetricImportGap{
+			Start: metav1.Time{Time: r.Start.UTC()},
+			End:   metav1.Time{Time: r.End.UTC()},
+		}
+		for _, prev := range prometheusMetricImportGapsOverlapping(previous, r.Start, r.End) {
+			if prev.BackfillAttempts > gap.BackfillAttempts {
+				gap.BackfillAttempts = prev.BackfillAttempts
+			}
+		}
+		gaps[i] = gap
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].Start.Before(&gaps[j].Start)
+	})
+	return gaps
+}
+
+// prometheusMetricImportGapsOverlapping returns the gaps which have missing
+// metrics between start and end, inclusively.
+func prometheusMetricImportGapsOverlapping(gaps []cbTypes.PrometheusMetricImportGap, start, end time.Time) []cbTypes.PrometheusMetricImportGap {
+	var overlapping []cbTypes.PrometheusMetricImportGap
+	for _, gap := range gaps {
+		if gap.Start.Time.After(end) || gap.End.Time.Before(start) {
+			continue
+		}
+		overlapping = append(overlapping, gap)
+	}
+	return overlapping
+}
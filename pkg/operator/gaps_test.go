@@ -0,0 +1,168 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+)
+
+func TestMergePrometheusMetricImportGaps(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newGap := func(start, end time.Duration, attempts int) cbTypes.PrometheusMetricImportGap {
+		return cbTypes.PrometheusMetricImportGap{
+			Start:            metav1.Time{Time: janOne.Add(start)},
+			End:              metav1.Time{Time: janOne.Add(end)},
+			BackfillAttempts: attempts,
+		}
+	}
+	newRange := func(start, end time.Duration) prom.Range {
+		return prom.Range{Start: janOne.Add(start), End: janOne.Add(end), Step: time.Minute}
+	}
+
+	tests := map[string]struct {
+		previous []cbTypes.PrometheusMetricImportGap
+		detected []prom.Range
+		expected []cbTypes.PrometheusMetricImportGap
+	}{
+		"no gaps detected": {
+			previous: []cbTypes.PrometheusMetricImportGap{newGap(time.Hour, 2*time.Hour, 1)},
+			detected: nil,
+			expected: nil,
+		},
+		"new gaps are sorted": {
+			detected: []prom.Range{newRange(3*time.Hour, 4*time.Hour), newRange(time.Hour, 2*time.Hour)},
+			expected: []cbTypes.PrometheusMetricImportGap{newGap(time.Hour, 2*time.Hour, 0), newGap(3*time.Hour, 4*time.Hour, 0)},
+		},
+		"partially backfilled gaps keep their attempts": {
+			previous: []cbTypes.PrometheusMetricImportGap{newGap(time.Hour, 2*time.Hour, 1), newGap(3*time.Hour, 4*time.Hour, 2)},
+			detected: []prom.Range{newRange(90*time.Minute, 2*time.Hour), newRange(5*time.Hour, 6*time.Hour)},
+			expected: []cbTypes.PrometheusMetricImportGap{newGap(90*time.Minute, 2*time.Hour, 1), newGap(5*time.Hour, 6*time.Hour, 0)},
+		},
+		"gaps overlapping multiple previous gaps keep the most attempts": {
+			previous: []cbTypes.PrometheusMetricImportGap{newGap(time.Hour, 2*time.Hour, 1), newGap(3*time.Hour, 4*time.Hour, 2)},
+			detected: []prom.Range{newRange(time.Hour, 4*time.Hour)},
+			expected: []cbTypes.PrometheusMetricImportGap{newGap(time.Hour, 4*time.Hour, 2)},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, mergePrometheusMetricImportGaps(test.previous, test.detected))
+		})
+	}
+}
+
+func TestPrometheusMetricImportGapsOverlapping(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	gaps := []cbTypes.PrometheusMetricImportGap{
+		{Start: metav1.Time{Time: janOne.Add(time.Hour)}, End: metav1.Time{Time: janOne.Add(2 * time.Hour)}},
+		{Start: metav1.Time{Time: janOne.Add(5 * time.Hour)}, End: metav1.Time{Time: janOne.Add(5 * time.Hour)}},
+	}
+
+	assert.Empty(t, prometheusMetricImportGapsOverlapping(gaps, janOne, janOne.Add(time.Hour-time.Minute)), "expected no gaps before the first gap")
+	assert.Equal(t, gaps[:1], prometheusMetricImportGapsOverlapping(gaps, janOne, janOne.Add(time.Hour)), "expected the gap starting at the end of the range")
+	assert.Equal(t, gaps[:1], prometheusMetricImportGapsOverlapping(gaps, janOne.Add(90*time.Minute), janOne.Add(3*time.Hour)), "expected the gap ending within the range")
+	assert.Empty(t, prometheusMetricImportGapsOverlapping(gaps, janOne.Add(2*time.Hour+time.Minute), janOne.Add(5*time.Hour-time.Minute)), "expected no gaps between the gaps")
+	assert.Equal(t, gaps, prometheusMetricImportGapsOverlapping(gaps, janOne, janOne.Add(24*time.Hour)), "expected all gaps within the range")
+}
+
+func TestPrometheusMetricImportGapCheckRanges(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newGap := func(start, end time.Duration, attempts int) cbTypes.PrometheusMetricImportGap {
+		return cbTypes.PrometheusMetricImportGap{
+			Start:            metav1.Time{Time: janOne.Add(start)},
+			End:              metav1.Time{Time: janOne.Add(end)},
+			BackfillAttempts: attempts,
+		}
+	}
+	newRange := func(start, end time.Duration) prom.Range {
+		return prom.Range{Start: janOne.Add(start), End: janOne.Add(end), Step: time.Minute}
+	}
+	timePtr := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: janOne.Add(d)} }
+	retentionStart := janOne.Add(2 * time.Hour)
+
+	tests := map[string]struct {
+		earliest          time.Duration
+		newest            time.Duration
+		checkedUpTo       *metav1.Time
+		gaps              []cbTypes.PrometheusMetricImportGap
+		expectedRanges    []prom.Range
+		expectedUnchecked []cbTypes.PrometheusMetricImportGap
+	}{
+		"never checked checks the whole table": {
+			earliest:       0,
+			newest:         10 * time.Hour,
+			gaps:           []cbTypes.PrometheusMetricImportGap{newGap(3*time.Hour, 4*time.Hour, 0)},
+			expectedRanges: []prom.Range{newRange(0, 10*time.Hour)},
+		},
+		"checked up to after the newest metric checks the whole table": {
+			earliest:       0,
+			newest:         10 * time.Hour,
+			checkedUpTo:    timePtr(12 * time.Hour),
+			expectedRanges: []prom.Range{newRange(0, 10*time.Hour)},
+		},
+		"only metrics imported since the last check are checked": {
+			earliest:       0,
+			newest:         10 * time.Hour,
+			checkedUpTo:    timePtr(9 * time.Hour),
+			expectedRanges: []prom.Range{newRange(9*time.Hour, 10*time.Hour)},
+		},
+		"nothing imported since the last check": {
+			earliest:    0,
+			newest:      10 * time.Hour,
+			checkedUpTo: timePtr(10 * time.Hour),
+		},
+		"gaps which can be backfilled are checked again": {
+			earliest:    0,
+			newest:      10 * time.Hour,
+			checkedUpTo: timePtr(9 * time.Hour),
+			gaps: []cbTypes.PrometheusMetricImportGap{
+				newGap(time.Minute, time.Hour, 0),
+				newGap(3*time.Hour, 4*time.Hour, 1),
+				newGap(5*time.Hour, 6*time.Hour, maxGapBackfillAttempts),
+			},
+			expectedRanges: []prom.Range{
+				newRange(3*time.Hour-time.Minute, 4*time.Hour+time.Minute),
+				newRange(9*time.Hour, 10*time.Hour),
+			},
+			expectedUnchecked: []cbTypes.PrometheusMetricImportGap{
+				newGap(time.Minute, time.Hour, 0),
+				newGap(5*time.Hour, 6*time.Hour, maxGapBackfillAttempts),
+			},
+		},
+		"gaps older than the earliest metric are dropped": {
+			earliest:    3 * time.Hour,
+			newest:      10 * time.Hour,
+			checkedUpTo: timePtr(9 * time.Hour),
+			gaps: []cbTypes.PrometheusMetricImportGap{
+				newGap(time.Hour, 2*time.Hour, 0),
+				newGap(2*time.Hour, 4*time.Hour, 0),
+			},
+			expectedRanges: []prom.Range{
+				newRange(3*time.Hour, 4*time.Hour+time.Minute),
+				newRange(9*time.Hour, 10*time.Hour),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			importStatus := &cbTypes.PrometheusMetricImportStatus{
+				EarliestImportedMetricTime: timePtr(test.earliest),
+				NewestImportedMetricTime:   timePtr(test.newest),
+				GapsCheckedUpTo:            test.checkedUpTo,
+				Gaps:                       test.gaps,
+			}
+			ranges, unchecked := prometheusMetricImportGapCheckRanges(importStatus, retentionStart, time.Minute)
+			assert.Equal(t, test.expectedRanges, ranges)
+			assert.Equal(t, test.expectedUnchecked, unchecked)
+		})
+	}
+}
@@ -6,15 +6,26 @@ import (
 	"path"
 
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	"github.com/operator-framework/operator-metering/pkg/hive"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
 )
 
 func (op *Reporting) createTableForStorage(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, storage *cbTypes.StorageLocationRef, tableName string, columns, partitions []hive.Column) error {
+	resourceName := reportingutil.PrestoTableResourceNameFromKind(gvk.Kind, obj.GetNamespace(), obj.GetName())
+	return op.createTableForStorageWithResourceName(logger, obj, gvk, storage, resourceName, tableName, columns, partitions)
+}
+
+// createTableForStorageWithResourceName is like createTableForStorage, but
+// allows objects with multiple tables to give each PrestoTable resource a
+// different name.
+func (op *Reporting) createTableForStorageWithResourceName(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, storage *cbTypes.StorageLocationRef, resourceName, tableName string, columns, partitions []hive.Column) error {
 	tableProperties, err := op.getHiveTableProperties(logger, storage, gvk.Kind, obj.GetNamespace())
 	if err != nil {
 		return fmt.Errorf("storage incorrectly configured for %s %s, err: %v", gvk, obj.GetName(), err)
@@ -25,7 +36,7 @@ func (op *Reporting) createTableForStorage(logger log.FieldLogger, obj metav1.Ob
 		Partitions:   partitions,
 		IgnoreExists: true,
 	}
-	return op.createTableWith(logger, obj, gvk, tableParams, *tableProperties)
+	return op.createTableWith(logger, obj, gvk, resourceName, tableParams, *tableProperties)
 }
 
 func (op *Reporting) createTableForStorageNoCR(logger log.FieldLogger, storage *cbTypes.StorageLocationRef, tableName, namespace string, columns []hive.Column) error {
@@ -45,20 +56,23 @@ func (op *Reporting) createTableForStorageNoCR(logger log.FieldLogger, storage *
 	return op.createTable(logger, tableParams, newTableProperties)
 }
 
-func (op *Reporting) createTableWith(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, params hive.TableParameters, properties hive.TableProperties) error {
+func (op *Reporting) createTableWith(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, resourceName string, params hive.TableParameters, properties hive.TableProperties) error {
 	newTableProperties, err := addTableNameToLocation(properties, params.Name)
 	if err != nil {
 		return err
 	}
-	return op.createTableAndCR(logger, obj, gvk, params, newTableProperties)
+	return op.createTableAndCR(logger, obj, gvk, resourceName, params, newTableProperties)
 }
 
-func (op *Reporting) createTableAndCR(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, params hive.TableParameters, properties hive.TableProperties) error {
+func (op *Reporting) createTableAndCR(logger log.FieldLogger, obj metav1.Object, gvk schema.GroupVersionKind, resourceName string, params hive.TableParameters, properties hive.TableProperties) error {
 	err := op.createTable(logger, params, properties)
 	if err != nil {
+		if runtimeObj, ok := obj.(runtime.Object); ok {
+			op.eventRecorder.Eventf(runtimeObj, v1.EventTypeWarning, tableCreateFailedEventReason, "Failed to create table %s: %v", params.Name, err)
+		}
 		return err
 	}
-	err = op.createPrestoTableCR(obj, gvk, params, properties, nil)
+	err = op.createPrestoTableCR(obj, gvk, resourceName, params, properties, nil)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			logger.Infof("presto table resource already exists")
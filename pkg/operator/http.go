@@ -1,6 +1,7 @@
 package operator
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
 	"encoding/json"
@@ -21,13 +22,18 @@ import (
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 
 	api "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	cbutil "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1/util"
 	listers "github.com/operator-framework/operator-metering/pkg/generated/listers/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/hive"
 	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
 	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/parquet"
 	"github.com/operator-framework/operator-metering/pkg/presto"
+	"github.com/operator-framework/operator-metering/pkg/promremote"
 	"github.com/operator-framework/operator-metering/pkg/util/chiprometheus"
 	"github.com/operator-framework/operator-metering/pkg/util/orderedmap"
 )
@@ -37,21 +43,28 @@ var prometheusMiddleware = chiprometheus.NewMiddleware("reporting-operator")
 
 const (
 	APIV1ReportsGetEndpoint    = "/api/v1/reports/get"
+	APIV1ReportsRunEndpoint    = "/api/v1/reports/run"
 	APIV2ReportsEndpointPrefix = "/api/v2/reports"
+
+	APIV1PrometheusRemoteWriteEndpoint = "/api/v1/datasources/prometheus/write"
 )
 
 type server struct {
 	logger log.FieldLogger
 
-	rand          *rand.Rand
-	collectorFunc prometheusImporterFunc
+	rand             *rand.Rand
+	collectorFunc    prometheusImporterFunc
+	remoteWriteStore prometheusRemoteWriteStoreFunc
 
 	prometheusMetricsRepo prestostore.PrometheusMetricsRepo
 	reportResultsGetter   prestostore.ReportResultsGetter
+	reportQueryRunner     prestostore.ReportQueryRunner
 
 	reportLister                 listers.ReportLister
 	reportGenerationQuerieLister listers.ReportGenerationQueryLister
+	reportDataSourceLister       listers.ReportDataSourceLister
 	prestoTableLister            listers.PrestoTableLister
+	rateCardLister               listers.RateCardLister
 }
 
 type requestLogger struct {
@@ -67,10 +80,14 @@ func newRouter(
 	rand *rand.Rand,
 	prometheusMetricsRepo prestostore.PrometheusMetricsRepo,
 	reportResultsGetter prestostore.ReportResultsGetter,
+	reportQueryRunner prestostore.ReportQueryRunner,
 	collectorFunc prometheusImporterFunc,
+	remoteWriteStore prometheusRemoteWriteStoreFunc,
 	reportLister listers.ReportLister,
 	reportGenerationQuerieLister listers.ReportGenerationQueryLister,
+	reportDataSourceLister listers.ReportDataSourceLister,
 	prestoTableLister listers.PrestoTableLister,
+	rateCardLister listers.RateCardLister,
 ) chi.Router {
 	router := chi.NewRouter()
 	logger = logger.WithField("component", "api")
@@ -82,20 +99,29 @@ func newRouter(
 		logger:                       logger,
 		rand:                         rand,
 		collectorFunc:                collectorFunc,
+		remoteWriteStore:             remoteWriteStore,
 		prometheusMetricsRepo:        prometheusMetricsRepo,
 		reportResultsGetter:          reportResultsGetter,
+		reportQueryRunner:            reportQueryRunner,
 		reportLister:                 reportLister,
 		reportGenerationQuerieLister: reportGenerationQuerieLister,
+		reportDataSourceLister:       reportDataSourceLister,
 		prestoTableLister:            prestoTableLister,
+		rateCardLister:               rateCardLister,
 	}
 
 	router.HandleFunc(APIV2ReportsEndpointPrefix+"/{namespace}/{name}/full", srv.getReportV2FullHandler)
 	router.HandleFunc(APIV2ReportsEndpointPrefix+"/{namespace}/{name}/table", srv.getReportV2TableHandler)
+	router.HandleFunc(APIV2ReportsEndpointPrefix+"/{namespace}/{name}/runs", srv.getReportV2RunsHandler)
+	router.HandleFunc(APIV2ReportsEndpointPrefix+"/{namespace}/{name}/periods", srv.getReportV2PeriodsHandler)
 	router.HandleFunc(APIV1ReportsGetEndpoint, srv.getReportV1Handler)
+	router.HandleFunc(APIV1ReportsRunEndpoint, srv.runReportHandler)
 	router.HandleFunc("/api/v1/datasources/prometheus/collect/{namespace}", srv.collectPromsumDataHandler)
 	router.HandleFunc("/api/v1/datasources/prometheus/collect/{namespace}/{datasourceName}", srv.collectPromsumDataHandler)
 	router.HandleFunc("/api/v1/datasources/prometheus/store/{namespace}/{datasourceName}", srv.storePromsumDataHandler)
 	router.HandleFunc("/api/v1/datasources/prometheus/fetch/{namespace}/{datasourceName}", srv.fetchPromsumDataHandler)
+	router.Post(APIV1PrometheusRemoteWriteEndpoint, srv.remoteWriteHandler)
+	router.Post(APIV1PrometheusRemoteWriteEndpoint+"/{namespace}", srv.remoteWriteHandler)
 
 	return router
 }
@@ -117,10 +143,10 @@ func (srv *server) validateGetReportReq(logger log.FieldLogger, requiredQueryPar
 	}
 	format := r.Form["format"][0]
 	switch format {
-	case "json", "csv", "tab", "tabular":
+	case "json", "ndjson", "csv", "tab", "tabular", "parquet":
 		return true
 	}
-	writeErrorResponse(logger, w, r, http.StatusBadRequest, "format must be one of: csv, json or tabular")
+	writeErrorResponse(logger, w, r, http.StatusBadRequest, "format must be one of: csv, json, ndjson, parquet or tabular")
 	return false
 }
 
@@ -160,6 +186,103 @@ func (srv *server) getReportV2TableHandler(w http.ResponseWriter, r *http.Reques
 	srv.getReport(logger, name, namespace, r.Form["format"][0], true, false, w, r)
 }
 
+type GetReportRunsResponse struct {
+	Runs []api.ReportRun `json:"runs"`
+}
+
+func (srv *server) getReportV2RunsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := newRequestLogger(srv.logger, r, srv.rand)
+	name := chi.URLParam(r, "name")
+	namespace := chi.URLParam(r, "namespace")
+	if r.Method != "GET" {
+		writeErrorResponse(logger, w, r, http.StatusNotFound, "Not found")
+		return
+	}
+	if name == "" {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "the following fields are missing or empty: name")
+		return
+	}
+
+	report, err := srv.reportLister.Reports(namespace).Get(name)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if k8serrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		logger.WithError(err).Errorf("error getting report: %v", err)
+		writeErrorResponse(logger, w, r, code, "error getting report: %v", err)
+		return
+	}
+
+	runs := report.Status.Runs
+	if runs == nil {
+		runs = []api.ReportRun{}
+	}
+	writeResponseAsJSON(logger, w, http.StatusOK, GetReportRunsResponse{Runs: runs})
+}
+
+type GetReportPeriodsResponse struct {
+	Periods []ReportPeriod `json:"periods"`
+}
+
+// ReportPeriod is a reporting period a report has results for.
+type ReportPeriod struct {
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+func (srv *server) getReportV2PeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := newRequestLogger(srv.logger, r, srv.rand)
+	name := chi.URLParam(r, "name")
+	namespace := chi.URLParam(r, "namespace")
+	if r.Method != "GET" {
+		writeErrorResponse(logger, w, r, http.StatusNotFound, "Not found")
+		return
+	}
+	if name == "" {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "the following fields are missing or empty: name")
+		return
+	}
+
+	report, err := srv.reportLister.Reports(namespace).Get(name)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if k8serrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		logger.WithError(err).Errorf("error getting report: %v", err)
+		writeErrorResponse(logger, w, r, code, "error getting report: %v", err)
+		return
+	}
+
+	reportQuery, err := srv.reportGenerationQuerieLister.ReportGenerationQueries(report.Namespace).Get(report.Spec.GenerationQueryName)
+	if err != nil {
+		logger.WithError(err).Errorf("error getting report: %v", err)
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "error getting report: %v", err)
+		return
+	}
+	if !reportingutil.HasPeriodColumns(reportQuery) {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "ReportGenerationQuery %s has no %s and %s timestamp columns to determine the reporting periods of the report's results", reportQuery.Name, reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName)
+		return
+	}
+	if report.Status.TableName == "" {
+		writeErrorResponse(logger, w, r, http.StatusAccepted, "Report is not processed yet")
+		return
+	}
+
+	periods, err := srv.reportResultsGetter.GetReportResultsPeriods(report.Status.TableName)
+	if err != nil {
+		logger.WithError(err).Errorf("failed to perform presto query")
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "failed to perform presto query (see operator logs for more details): %v", err)
+		return
+	}
+	resp := GetReportPeriodsResponse{Periods: make([]ReportPeriod, len(periods))}
+	for i, period := range periods {
+		resp.Periods[i] = ReportPeriod{PeriodStart: period.PeriodStart, PeriodEnd: period.PeriodEnd}
+	}
+	writeResponseAsJSON(logger, w, http.StatusOK, resp)
+}
+
 func checkForFields(fields []string, vals url.Values) error {
 	var missingFields []string
 	for _, f := range fields {
@@ -233,94 +356,504 @@ func (srv *server) getReport(logger log.FieldLogger, name, namespace, format str
 		logger.Debugf("mismatched columns, PrestoTable columns: %v, ReportGenerationQuery columns: %v", prestoColumns, queryPrestoColumns)
 	}
 
+	columns := reportQuery.Spec.Columns
+	var addCosts func(presto.Row)
+	if rateCardName := r.FormValue("rateCard"); useNewFormat && rateCardName != "" {
+		rateCard, err := srv.rateCardLister.RateCards(report.Namespace).Get(rateCardName)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if k8serrors.IsNotFound(err) {
+				code = http.StatusNotFound
+			}
+			logger.WithError(err).Errorf("error getting RateCard: %v", err)
+			writeErrorResponse(logger, w, r, code, "error getting RateCard: %v", err)
+			return
+		}
+		// rows without a period_start are priced using the rates in
+		// effect when the report last ran
+		defaultTime := time.Now().UTC()
+		if report.Status.LastReportTime != nil {
+			defaultTime = report.Status.LastReportTime.Time
+		}
+		columns, addCosts, err = addRateCardCostColumns(rateCard, columns, defaultTime)
+		if err != nil {
+			logger.WithError(err).Errorf("error calculating costs: %v", err)
+			writeErrorResponse(logger, w, r, http.StatusBadRequest, "error calculating costs: %v", err)
+			return
+		}
+	}
+
+	queryColumns := prestoColumns
+	var resultsQuery prestostore.ReportResultsQuery
+	var params *reportResultsQueryParams
+	if useNewFormat {
+		var reportTime time.Time
+		if report.Status.LastReportTime != nil {
+			reportTime = report.Status.LastReportTime.Time
+		}
+		params, err = parseReportResultsQueryParams(r.Form, columns, reportQuery.Spec.Columns, prestoColumns, reportTime, report.Status.TablePartitionedByPeriod)
+		if err != nil {
+			code := http.StatusBadRequest
+			if err == errReportResultsChanged {
+				code = http.StatusGone
+			}
+			writeErrorResponse(logger, w, r, code, "%v", err)
+			return
+		}
+		columns = params.Columns
+		resultsQuery = params.Query
+		// costs are calculated using the namespace and period of each row,
+		// so every column is needed when using a RateCard
+		if addCosts == nil {
+			queryColumns = params.TableColumns
+		}
+		// get an extra row to find out if there's another page
+		if resultsQuery.Limit > 0 {
+			resultsQuery.Limit++
+		}
+	}
+
 	tableName := reportingutil.ReportTableName(namespace, name)
-	results, err := srv.reportResultsGetter.GetReportResults(tableName, prestoColumns)
+	results, err := srv.reportResultsGetter.GetReportResultsIterator(tableName, queryColumns, resultsQuery)
 	if err != nil {
 		logger.WithError(err).Errorf("failed to perform presto query")
 		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "failed to perform presto query (see operator logs for more details): %v", err)
 		return
 	}
+	defer results.Close()
 
-	if len(results) > 0 && len(prestoTable.Status.Parameters.Columns) != len(results[0]) {
-		logger.Errorf("report results schema doesn't match expected schema, got %d columns, expected %d", len(results[0]), len(prestoTable.Status.Parameters.Columns))
-		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "report results schema doesn't match expected schema")
+	expectedColumns := len(queryColumns)
+	results = newRowTransformIterator(results, func(row presto.Row) error {
+		if len(row) != expectedColumns {
+			return fmt.Errorf("report results schema doesn't match expected schema, got %d columns, expected %d", len(row), expectedColumns)
+		}
+		return nil
+	})
+
+	if !useNewFormat {
+		writeResultsResponseV1(logger, format, reportQuery.Name, columns, results, w, r)
 		return
 	}
 
-	if useNewFormat {
-		writeResultsResponseV2(logger, full, format, reportQuery.Name, reportQuery.Spec.Columns, results, w, r)
-	} else {
-		writeResultsResponseV1(logger, format, reportQuery.Name, reportQuery.Spec.Columns, results, w, r)
+	if addCosts != nil {
+		selected := make(map[string]bool)
+		for _, column := range columns {
+			selected[column.Name] = true
+		}
+		results = newRowTransformIterator(results, func(row presto.Row) error {
+			addCosts(row)
+			for key := range row {
+				if !selected[key] {
+					delete(row, key)
+				}
+			}
+			return nil
+		})
+	}
+
+	var continueToken string
+	if params.Query.Limit > 0 {
+		// pages are small enough to read into memory, and need to be read
+		// before writing the response to know if there's a next page.
+		var page []presto.Row
+		for results.Next() {
+			page = append(page, results.Row())
+		}
+		if err := results.Err(); err != nil {
+			logger.WithError(err).Errorf("failed to perform presto query")
+			writeErrorResponse(logger, w, r, http.StatusInternalServerError, "failed to perform presto query (see operator logs for more details): %v", err)
+			return
+		}
+		if int64(len(page)) > params.Query.Limit {
+			page = page[:params.Query.Limit]
+			continueToken, err = params.ContinueToken()
+			if err != nil {
+				logger.WithError(err).Errorf("error creating continue token: %v", err)
+				writeErrorResponse(logger, w, r, http.StatusInternalServerError, "error creating continue token: %v", err)
+				return
+			}
+			w.Header().Set(reportResultsContinueHeader, continueToken)
+		}
+		results = presto.NewRowSliceIterator(page)
 	}
+	writeResultsResponseV2(logger, full, format, reportQuery.Name, columns, continueToken, results, w, r)
 }
 
-func writeResultsResponseAsCSV(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s.csv", name))
-	err := writeResultsAsCSV(columns, results, w, ',')
-	if err != nil {
-		writeErrorResponse(logger, w, r, http.StatusInternalServerError, err.Error())
+// resultsFlushInterval is the number of rows written to a streamed results
+// response between each flush to the client.
+const resultsFlushInterval = 1000
+
+// resultsEncoder encodes report results one row at a time.
+type resultsEncoder interface {
+	// WriteRow encodes a row.
+	WriteRow(row presto.Row) error
+	// Flush writes any buffered rows to the underlying writer.
+	Flush() error
+	// Close finishes encoding and writes any buffered data to the
+	// underlying writer.
+	Close() error
+}
+
+// streamResultsResponse writes results to w using enc, flushing the response
+// every resultsFlushInterval rows so it's sent to the client using chunked
+// transfer encoding while the results are still being read.
+func streamResultsResponse(logger log.FieldLogger, contentType, filename string, enc resultsEncoder, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
+	// read the first row before writing anything, so that a failing query
+	// can still be reported with an error status code.
+	hasRow := results.Next()
+	if err := results.Err(); err != nil {
+		logger.WithError(err).Errorf("failed to read report results")
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "failed to read report results (see operator logs for more details): %v", err)
 		return
 	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s", filename))
 	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	rowCount := 0
+	for ; hasRow; hasRow = results.Next() {
+		if err := enc.WriteRow(results.Row()); err != nil {
+			abortResultsResponse(logger, err)
+		}
+		rowCount++
+		if rowCount%resultsFlushInterval == 0 {
+			if err := enc.Flush(); err != nil {
+				abortResultsResponse(logger, err)
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := results.Err(); err != nil {
+		abortResultsResponse(logger, err)
+	}
+	if err := enc.Close(); err != nil {
+		abortResultsResponse(logger, err)
+	}
+}
+
+// abortResultsResponse is used when streaming results fails after the
+// response status has been sent. Aborting the handler closes the connection
+// without terminating the chunked response, which is the only way left to
+// tell the client the response is incomplete.
+func abortResultsResponse(logger log.FieldLogger, err error) {
+	logger.WithError(err).Errorf("failed to stream report results, aborting response")
+	panic(http.ErrAbortHandler)
+}
+
+// rowTransformIterator applies transform to each row of a RowIterator as
+// it's read, stopping iteration with transform's error if it fails.
+type rowTransformIterator struct {
+	presto.RowIterator
+	transform func(presto.Row) error
+	row       presto.Row
+	err       error
+}
+
+func newRowTransformIterator(rows presto.RowIterator, transform func(presto.Row) error) *rowTransformIterator {
+	return &rowTransformIterator{RowIterator: rows, transform: transform}
 }
 
-func writeResultsAsCSV(columns []api.ReportGenerationQueryColumn, results []presto.Row, w io.Writer, delimiter rune) error {
+func (it *rowTransformIterator) Next() bool {
+	if it.err != nil || !it.RowIterator.Next() {
+		return false
+	}
+	row := it.RowIterator.Row()
+	if err := it.transform(row); err != nil {
+		it.err = err
+		return false
+	}
+	it.row = row
+	return true
+}
+
+func (it *rowTransformIterator) Row() presto.Row {
+	return it.row
+}
+
+func (it *rowTransformIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.RowIterator.Err()
+}
+
+// csvResultsEncoder writes results as CSV, with a header containing the
+// column names before the first row.
+type csvResultsEncoder struct {
+	csvWriter     *csv.Writer
+	columns       []api.ReportGenerationQueryColumn
+	headerWritten bool
+}
+
+func newCSVResultsEncoder(columns []api.ReportGenerationQueryColumn, w io.Writer, delimiter rune) *csvResultsEncoder {
 	csvWriter := csv.NewWriter(w)
 	csvWriter.Comma = delimiter
+	return &csvResultsEncoder{csvWriter: csvWriter, columns: columns}
+}
 
-	// Write headers
-	var keys []string
-	if len(results) >= 1 {
-		for _, column := range columns {
+func (enc *csvResultsEncoder) WriteRow(row presto.Row) error {
+	if !enc.headerWritten {
+		var keys []string
+		for _, column := range enc.columns {
 			keys = append(keys, column.Name)
 		}
-		err := csvWriter.Write(keys)
-		if err != nil {
+		if err := enc.csvWriter.Write(keys); err != nil {
 			return err
 		}
+		enc.headerWritten = true
+	}
+
+	vals := make([]string, len(enc.columns))
+	for i, column := range enc.columns {
+		val, ok := row[column.Name]
+		if !ok {
+			return fmt.Errorf("report results schema doesn't match expected schema, unexpected key: %q", column.Name)
+		}
+		switch v := val.(type) {
+		case string:
+			vals[i] = v
+		case []byte:
+			vals[i] = string(v)
+		case uint, uint8, uint16, uint32, uint64, int, int8, int16, int32, int64:
+			vals[i] = fmt.Sprintf("%d", v)
+		case float32, float64, complex64, complex128:
+			vals[i] = fmt.Sprintf("%f", v)
+		case bool:
+			vals[i] = fmt.Sprintf("%t", v)
+		case time.Time:
+			vals[i] = v.String()
+		case nil:
+			vals[i] = ""
+		default:
+			return fmt.Errorf("error marshalling csv: unknown type %t for value %v", val, val)
+		}
+	}
+	return enc.csvWriter.Write(vals)
+}
+
+func (enc *csvResultsEncoder) Flush() error {
+	enc.csvWriter.Flush()
+	return enc.csvWriter.Error()
+}
+
+func (enc *csvResultsEncoder) Close() error {
+	return enc.Flush()
+}
+
+// tabularResultsEncoder writes results as tab separated values with aligned
+// columns. Columns are aligned within the rows written between flushes, as
+// aligning them across all results would require buffering every row.
+type tabularResultsEncoder struct {
+	*csvResultsEncoder
+	tabWriter *tabwriter.Writer
+}
+
+func newTabularResultsEncoder(columns []api.ReportGenerationQueryColumn, w io.Writer, padding int) *tabularResultsEncoder {
+	tabWriter := tabwriter.NewWriter(w, 0, 8, padding, '\t', 0)
+	return &tabularResultsEncoder{
+		csvResultsEncoder: newCSVResultsEncoder(columns, tabWriter, '\t'),
+		tabWriter:         tabWriter,
+	}
+}
+
+func (enc *tabularResultsEncoder) Flush() error {
+	if err := enc.csvResultsEncoder.Flush(); err != nil {
+		return err
+	}
+	return enc.tabWriter.Flush()
+}
+
+func (enc *tabularResultsEncoder) Close() error {
+	return enc.Flush()
+}
+
+// jsonArrayResultsEncoder writes results as a JSON array, converting each row
+// to a JSON value using convert. prefix and suffix are written before and
+// after the array.
+type jsonArrayResultsEncoder struct {
+	w              *bufio.Writer
+	prefix, suffix string
+	convert        func(presto.Row) (interface{}, error)
+	rowCount       int
+}
+
+func newJSONArrayResultsEncoder(w io.Writer, prefix, suffix string, convert func(presto.Row) (interface{}, error)) *jsonArrayResultsEncoder {
+	return &jsonArrayResultsEncoder{w: bufio.NewWriter(w), prefix: prefix, suffix: suffix, convert: convert}
+}
+
+func (enc *jsonArrayResultsEncoder) WriteRow(row presto.Row) error {
+	sep := ","
+	if enc.rowCount == 0 {
+		sep = enc.prefix + "["
+	}
+	enc.rowCount++
+	val, err := enc.convert(row)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	if _, err := enc.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = enc.w.Write(b)
+	return err
+}
+
+func (enc *jsonArrayResultsEncoder) Flush() error {
+	return enc.w.Flush()
+}
+
+func (enc *jsonArrayResultsEncoder) Close() error {
+	end := "]" + enc.suffix
+	if enc.rowCount == 0 {
+		end = enc.prefix + "[]" + enc.suffix
+	}
+	if _, err := enc.w.WriteString(end); err != nil {
+		return err
+	}
+	return enc.w.Flush()
+}
+
+// ndjsonResultsEncoder writes results as newline delimited JSON, with each
+// row written as a JSON object on its own line.
+type ndjsonResultsEncoder struct {
+	w *bufio.Writer
+}
+
+func newNDJSONResultsEncoder(w io.Writer) *ndjsonResultsEncoder {
+	return &ndjsonResultsEncoder{w: bufio.NewWriter(w)}
+}
+
+func (enc *ndjsonResultsEncoder) WriteRow(row presto.Row) error {
+	result, err := orderedmap.NewFromMap(row)
+	if err != nil {
+		return fmt.Errorf("error converting results: %v", err)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
 	}
+	if _, err := enc.w.Write(b); err != nil {
+		return err
+	}
+	return enc.w.WriteByte('\n')
+}
+
+func (enc *ndjsonResultsEncoder) Flush() error {
+	return enc.w.Flush()
+}
+
+func (enc *ndjsonResultsEncoder) Close() error {
+	return enc.w.Flush()
+}
+
+// parquetRowGroupSize is the number of rows written to each row group of a
+// Parquet results response.
+const parquetRowGroupSize = 10000
+
+// parquetResultsEncoder writes results as a Parquet file. Rows are buffered
+// until there's enough of them to write a row group, and the file's footer is
+// written when it's closed.
+type parquetResultsEncoder struct {
+	w      *bufio.Writer
+	writer *parquet.Writer
+}
+
+func newParquetResultsEncoder(columns []api.ReportGenerationQueryColumn, w io.Writer) (*parquetResultsEncoder, error) {
+	parquetCols, err := parquetColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+	bufWriter := bufio.NewWriter(w)
+	writer, err := parquet.NewWriter(bufWriter, parquetCols)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetResultsEncoder{w: bufWriter, writer: writer}, nil
+}
+
+func (enc *parquetResultsEncoder) WriteRow(row presto.Row) error {
+	return enc.writer.Write(row)
+}
+
+func (enc *parquetResultsEncoder) Flush() error {
+	if enc.writer.BufferedRows() < parquetRowGroupSize {
+		return nil
+	}
+	if err := enc.writer.Flush(); err != nil {
+		return err
+	}
+	return enc.w.Flush()
+}
 
-	// Write the rest
-	for _, row := range results {
-		vals := make([]string, len(keys))
-		for i, key := range keys {
-			val, ok := row[key]
-			if !ok {
-				return fmt.Errorf("report results schema doesn't match expected schema, unexpected key: %q", key)
+func (enc *parquetResultsEncoder) Close() error {
+	if err := enc.writer.Close(); err != nil {
+		return err
+	}
+	return enc.w.Flush()
+}
+
+// parquetColumns converts the columns of a ReportGenerationQuery into the
+// columns of a Parquet file using the types Presto returns for each column.
+func parquetColumns(columns []api.ReportGenerationQueryColumn) ([]parquet.Column, error) {
+	parquetCols := make([]parquet.Column, len(columns))
+	for i, column := range columns {
+		prestoCol, err := reportingutil.HiveColumnToPrestoColumn(hive.Column{Name: column.Name, Type: column.Type})
+		if err != nil {
+			return nil, err
+		}
+		parquetCols[i].Name = column.Name
+		if strings.HasPrefix(prestoCol.Type, "map(") {
+			mapComponents := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(prestoCol.Type, "map("), ")"), ",", 2)
+			keyType, err := prestoTypeToParquetType(mapComponents[0])
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", column.Name, err)
 			}
-			switch v := val.(type) {
-			case string:
-				vals[i] = v
-			case []byte:
-				vals[i] = string(v)
-			case uint, uint8, uint16, uint32, uint64, int, int8, int16, int32, int64:
-				vals[i] = fmt.Sprintf("%d", v)
-			case float32, float64, complex64, complex128:
-				vals[i] = fmt.Sprintf("%f", v)
-			case bool:
-				vals[i] = fmt.Sprintf("%t", v)
-			case time.Time:
-				vals[i] = v.String()
-			case nil:
-				vals[i] = ""
-			default:
-				return fmt.Errorf("error marshalling csv: unknown type %t for value %v", val, val)
+			valueType, err := prestoTypeToParquetType(mapComponents[1])
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", column.Name, err)
 			}
+			parquetCols[i].Type = parquet.Map
+			parquetCols[i].KeyType = keyType
+			parquetCols[i].ValueType = valueType
+			continue
 		}
-		err := csvWriter.Write(vals)
+		parquetCols[i].Type, err = prestoTypeToParquetType(prestoCol.Type)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("column %q: %v", column.Name, err)
 		}
 	}
+	return parquetCols, nil
+}
+
+func prestoTypeToParquetType(colType string) (parquet.Type, error) {
+	switch colType {
+	case "BIGINT":
+		return parquet.Int64, nil
+	case "DOUBLE":
+		return parquet.Double, nil
+	case "VARCHAR":
+		return parquet.String, nil
+	case "TIMESTAMP":
+		return parquet.Timestamp, nil
+	case "BOOLEAN":
+		return parquet.Boolean, nil
+	}
+	return 0, fmt.Errorf("unsupported type %q", colType)
+}
 
-	csvWriter.Flush()
-	return csvWriter.Error()
+func writeResultsResponseAsCSV(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
+	streamResultsResponse(logger, "text/csv", name+".csv", newCSVResultsEncoder(columns, w, ','), results, w, r)
 }
 
-func writeResultsResponseAsTabular(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/tab-separated-values")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s.tsv", name))
+func writeResultsResponseAsTabular(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
 	var padding int = 2
 	paddingStr := r.FormValue("padding")
 	if paddingStr != "" {
@@ -331,38 +864,41 @@ func writeResultsResponseAsTabular(logger log.FieldLogger, name string, columns
 			return
 		}
 	}
-	tabWriter := tabwriter.NewWriter(w, 0, 8, padding, '\t', 0)
-	err := writeResultsAsCSV(columns, results, tabWriter, '\t')
-	if err != nil {
-		writeErrorResponse(logger, w, r, http.StatusInternalServerError, err.Error())
-		return
-	}
-	err = tabWriter.Flush()
-	if err != nil {
-		writeErrorResponse(logger, w, r, http.StatusInternalServerError, err.Error())
-		return
-	}
-	w.WriteHeader(http.StatusOK)
+	streamResultsResponse(logger, "text/tab-separated-values", name+".tsv", newTabularResultsEncoder(columns, w, padding), results, w, r)
 }
 
-func writeResultsResponseAsJSON(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s.json", name))
-	newResults := make([]*orderedmap.OrderedMap, len(results))
-	for i, item := range results {
-		var err error
-		newResults[i], err = orderedmap.NewFromMap(item)
+func writeResultsResponseAsJSON(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
+	enc := newJSONArrayResultsEncoder(w, "", "", func(row presto.Row) (interface{}, error) {
+		result, err := orderedmap.NewFromMap(row)
 		if err != nil {
-			writeErrorResponse(logger, w, r, http.StatusInternalServerError, "error converting results: %v", err)
-			return
+			return nil, fmt.Errorf("error converting results: %v", err)
 		}
+		return result, nil
+	})
+	streamResultsResponse(logger, "application/json", name+".json", enc, results, w, r)
+}
+
+func writeResultsResponseAsNDJSON(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
+	streamResultsResponse(logger, "application/x-ndjson", name+".ndjson", newNDJSONResultsEncoder(w), results, w, r)
+}
+
+func writeResultsResponseAsParquet(logger log.FieldLogger, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
+	enc, err := newParquetResultsEncoder(columns, w)
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "report results cannot be written as parquet: %v", err)
+		return
 	}
-	writeResponseAsJSON(logger, w, http.StatusOK, newResults)
+	streamResultsResponse(logger, "application/vnd.apache.parquet", name+".parquet", enc, results, w, r)
 }
 
-func writeResultsResponse(logger log.FieldLogger, format, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
+func writeResultsResponse(logger log.FieldLogger, format, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
 	switch format {
 	case "json":
 		writeResultsResponseAsJSON(logger, name, columns, results, w, r)
+	case "ndjson":
+		writeResultsResponseAsNDJSON(logger, name, columns, results, w, r)
+	case "parquet":
+		writeResultsResponseAsParquet(logger, name, columns, results, w, r)
 	case "csv":
 		writeResultsResponseAsCSV(logger, name, columns, results, w, r)
 	case "tab", "tabular":
@@ -372,6 +908,9 @@ func writeResultsResponse(logger log.FieldLogger, format, name string, columns [
 
 type GetReportResults struct {
 	Results []ReportResultEntry `json:"results"`
+	// Continue is set when there are more results than the requested limit,
+	// and is used as the continue query parameter to get the next page.
+	Continue string `json:"continue,omitempty"`
 }
 
 type ReportResultEntry struct {
@@ -385,30 +924,22 @@ type ReportResultValues struct {
 	Unit        string      `json:"unit,omitempty"`
 }
 
-// convertsToGetReportResults converts Rows returned from `presto.ExecuteSelect` into a GetReportResults
-func convertsToGetReportResults(input []presto.Row, columns []api.ReportGenerationQueryColumn) GetReportResults {
-	results := GetReportResults{}
-	columnsMap := make(map[string]api.ReportGenerationQueryColumn)
-	for _, column := range columns {
-		columnsMap[column.Name] = column
-	}
-	for _, row := range input {
-		var valSlice ReportResultEntry
-		for columnName, columnValue := range row {
-			resultsValue := ReportResultValues{
-				Name:        columnName,
-				Value:       columnValue,
-				TableHidden: columnsMap[columnName].TableHidden,
-				Unit:        columnsMap[columnName].Unit,
-			}
-			valSlice.Values = append(valSlice.Values, resultsValue)
+// convertToReportResultEntry converts a Row returned from `presto.ExecuteSelect` into a ReportResultEntry
+func convertToReportResultEntry(row presto.Row, columnsMap map[string]api.ReportGenerationQueryColumn) ReportResultEntry {
+	var valSlice ReportResultEntry
+	for columnName, columnValue := range row {
+		resultsValue := ReportResultValues{
+			Name:        columnName,
+			Value:       columnValue,
+			TableHidden: columnsMap[columnName].TableHidden,
+			Unit:        columnsMap[columnName].Unit,
 		}
-		results.Results = append(results.Results, valSlice)
+		valSlice.Values = append(valSlice.Values, resultsValue)
 	}
-	return results
+	return valSlice
 }
 
-func writeResultsResponseV1(logger log.FieldLogger, format string, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
+func writeResultsResponseV1(logger log.FieldLogger, format string, name string, columns []api.ReportGenerationQueryColumn, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
 	columnsMap := make(map[string]api.ReportGenerationQueryColumn)
 	var filteredColumns []api.ReportGenerationQueryColumn
 
@@ -424,18 +955,21 @@ func writeResultsResponseV1(logger log.FieldLogger, format string, name string,
 	}
 
 	// filter rows
-	for _, row := range results {
+	results = newRowTransformIterator(results, func(row presto.Row) error {
 		for _, column := range columnsMap {
 			if columnsMap[column.Name].TableHidden {
 				delete(row, columnsMap[column.Name].Name)
 			}
 		}
-	}
+		return nil
+	})
 
 	writeResultsResponse(logger, format, name, filteredColumns, results, w, r)
 }
 
-func writeResultsResponseV2(logger log.FieldLogger, full bool, format string, name string, columns []api.ReportGenerationQueryColumn, results []presto.Row, w http.ResponseWriter, r *http.Request) {
+// writeResultsResponseV2 writes results to the client. If continueToken is
+// set, it's included in JSON responses to get the next page of results.
+func writeResultsResponseV2(logger log.FieldLogger, full bool, format string, name string, columns []api.ReportGenerationQueryColumn, continueToken string, results presto.RowIterator, w http.ResponseWriter, r *http.Request) {
 	format = strings.ToLower(format)
 	isTableFormat := format == "csv" || format == "tab" || format == "tabular"
 	columnsMap := make(map[string]api.ReportGenerationQueryColumn)
@@ -458,27 +992,136 @@ func writeResultsResponseV2(logger log.FieldLogger, full bool, format string, na
 	}
 
 	// filter the rows
-	for _, row := range results {
+	results = newRowTransformIterator(results, func(row presto.Row) error {
 		for _, column := range columnsMap {
 			tableHidden := columnsMap[column.Name].TableHidden
 			if tableHidden && (isTableFormat || !full) {
 				delete(row, columnsMap[column.Name].Name)
 			}
 		}
-	}
+		return nil
+	})
 
 	if format == "json" {
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s.json", name))
-		writeResponseAsJSON(logger, w, http.StatusOK, convertsToGetReportResults(results, filteredColumns))
+		filteredColumnsMap := make(map[string]api.ReportGenerationQueryColumn)
+		for _, column := range filteredColumns {
+			filteredColumnsMap[column.Name] = column
+		}
+		suffix := "}"
+		if continueToken != "" {
+			suffix = fmt.Sprintf(`,"continue":%q}`, continueToken)
+		}
+		enc := newJSONArrayResultsEncoder(w, `{"results":`, suffix, func(row presto.Row) (interface{}, error) {
+			return convertToReportResultEntry(row, filteredColumnsMap), nil
+		})
+		streamResultsResponse(logger, "application/json", name+".json", enc, results, w, r)
 		return
 	}
 
 	writeResultsResponse(logger, format, name, filteredColumns, results, w, r)
 }
 
-func (srv *server) runReport(logger log.FieldLogger, query, start, end string, w http.ResponseWriter) {
-	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte("method not yet implemented"))
+func (srv *server) runReportHandler(w http.ResponseWriter, r *http.Request) {
+	logger := newRequestLogger(srv.logger, r, srv.rand)
+	if !srv.validateGetReportReq(logger, []string{"query", "namespace", "start", "end", "format"}, w, r) {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, r.Form["start"][0])
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "invalid start time parameter: %v", err)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, r.Form["end"][0])
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "invalid end time parameter: %v", err)
+		return
+	}
+	if !end.After(start) {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "end time must be after start time")
+		return
+	}
+
+	// inputs are optional, and are specified as a JSON list in the same
+	// form as a Report's spec.inputs
+	var inputs api.ReportGenerationQueryInputValues
+	if inputsParam := r.Form.Get("inputs"); inputsParam != "" {
+		err = json.Unmarshal([]byte(inputsParam), &inputs)
+		if err != nil {
+			writeErrorResponse(logger, w, r, http.StatusBadRequest, "invalid inputs parameter, must be a JSON list of name/value objects: %v", err)
+			return
+		}
+	}
+
+	srv.runReport(logger, r.Form["query"][0], r.Form["namespace"][0], r.Form["format"][0], start.UTC(), end.UTC(), inputs, w, r)
+}
+
+// runReport renders the ReportGenerationQuery for the given time range and
+// inputs, executes it, and writes the results back to the client without
+// creating a Report or a table to store the results in.
+func (srv *server) runReport(logger log.FieldLogger, queryName, namespace, format string, start, end time.Time, inputs []api.ReportGenerationQueryInputValue, w http.ResponseWriter, r *http.Request) {
+	logger = logger.WithFields(log.Fields{
+		"reportGenerationQuery": queryName,
+		"namespace":             namespace,
+	})
+
+	genQuery, err := srv.reportGenerationQuerieLister.ReportGenerationQueries(namespace).Get(queryName)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if k8serrors.IsNotFound(err) {
+			code = http.StatusNotFound
+		}
+		logger.WithError(err).Errorf("error getting ReportGenerationQuery: %v", err)
+		writeErrorResponse(logger, w, r, code, "error getting ReportGenerationQuery: %v", err)
+		return
+	}
+
+	queryDependencies, err := reporting.GetAndValidateGenerationQueryDependencies(
+		reporting.NewReportGenerationQueryListerGetter(srv.reportGenerationQuerieLister),
+		reporting.NewReportDataSourceListerGetter(srv.reportDataSourceLister),
+		reporting.NewReportListerGetter(srv.reportLister),
+		genQuery,
+		nil,
+	)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if k8serrors.IsNotFound(err) || reporting.IsUninitializedDependencyError(err) || reporting.IsInvalidDependencyError(err) {
+			code = http.StatusBadRequest
+		}
+		writeErrorResponse(logger, w, r, code, "failed to validate ReportGenerationQuery dependencies %s: %v", genQuery.Name, err)
+		return
+	}
+
+	reportQueryInputs, err := reporting.ValidateReportGenerationQueryInputs(genQuery, inputs)
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	tmplCtx := &reporting.ReportQueryTemplateContext{
+		DynamicDependentQueries: queryDependencies.DynamicReportGenerationQueries,
+		ReportDataSources:       queryDependencies.ReportDataSources,
+		Report: &reporting.ReportTemplateInfo{
+			ReportingStart: &start,
+			ReportingEnd:   &end,
+			Inputs:         reportQueryInputs,
+		},
+	}
+	query, err := reporting.RenderQuery(genQuery.Spec.Query, namespace, tmplCtx)
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "unable to render ReportGenerationQuery %s: %v", genQuery.Name, err)
+		return
+	}
+
+	logger.Debugf("running ReportGenerationQuery %s for period %s to %s", genQuery.Name, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	results, err := srv.reportQueryRunner.RunReportQuery(query)
+	if err != nil {
+		logger.WithError(err).Errorf("failed to perform presto query")
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "failed to perform presto query (see operator logs for more details): %v", err)
+		return
+	}
+
+	writeResultsResponseV2(logger, true, format, genQuery.Name, genQuery.Spec.Columns, "", presto.NewRowSliceIterator(results), w, r)
 }
 
 type CollectPromsumDataRequest struct {
@@ -565,6 +1208,79 @@ func (srv *server) storePromsumDataHandler(w http.ResponseWriter, r *http.Reques
 	writeResponseAsJSON(logger, w, http.StatusOK, struct{}{})
 }
 
+// remoteWriteHandler receives Prometheus remote write requests, and stores
+// each series into the tables of the ReportDataSources it matches. If a
+// namespace is specified, only ReportDataSources in that namespace are
+// matched.
+func (srv *server) remoteWriteHandler(w http.ResponseWriter, r *http.Request) {
+	logger := newRequestLogger(srv.logger, r, srv.rand)
+	namespace := chi.URLParam(r, "namespace")
+
+	req, err := promremote.DecodeWriteRequest(r.Body, promremote.DefaultMaxRequestSize)
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusBadRequest, "unable to decode remote write request: %v", err)
+		return
+	}
+
+	var dataSources []*api.ReportDataSource
+	if namespace != "" {
+		dataSources, err = srv.reportDataSourceLister.ReportDataSources(namespace).List(labels.Everything())
+	} else {
+		dataSources, err = srv.reportDataSourceLister.List(labels.Everything())
+	}
+	if err != nil {
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "unable to list ReportDataSources: %v", err)
+		return
+	}
+
+	var matchers []*remoteWriteMatcher
+	for _, dataSource := range dataSources {
+		if dataSource.Spec.Promsum == nil || dataSource.Spec.Promsum.RemoteWrite == nil || dataSource.Status.TableName == "" {
+			continue
+		}
+		matcher, err := newRemoteWriteMatcher(dataSource)
+		if err != nil {
+			logger.WithError(err).Warnf("skipping ReportDataSource %s/%s with invalid remoteWrite configuration", dataSource.Namespace, dataSource.Name)
+			continue
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	matchedSeries := make([][]*promremote.TimeSeries, len(matchers))
+	for _, series := range req.Timeseries {
+		seriesLabels := series.LabelsMap()
+		for i, matcher := range matchers {
+			if matcher.matches(seriesLabels) {
+				matchedSeries[i] = append(matchedSeries[i], series)
+			}
+		}
+	}
+
+	// Every ReportDataSource is stored even if storing another fails, since
+	// Prometheus retries requests which fail with a 5xx status code, and the
+	// steps already stored are skipped when the request is retried.
+	var failed []string
+	for i, matcher := range matchers {
+		if len(matchedSeries[i]) == 0 {
+			continue
+		}
+		dataSource := matcher.dataSource
+		numSamples, err := srv.remoteWriteStore(r.Context(), dataSource, matchedSeries[i])
+		if err != nil {
+			logger.WithError(err).Errorf("unable to store metrics for ReportDataSource %s/%s", dataSource.Namespace, dataSource.Name)
+			failed = append(failed, fmt.Sprintf("%s/%s: %v", dataSource.Namespace, dataSource.Name, err))
+			continue
+		}
+		logger.Debugf("stored %d samples from %d series for ReportDataSource %s/%s", numSamples, len(matchedSeries[i]), dataSource.Namespace, dataSource.Name)
+	}
+	if len(failed) != 0 {
+		writeErrorResponse(logger, w, r, http.StatusInternalServerError, "unable to store metrics for ReportDataSources: %s", strings.Join(failed, ", "))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (srv *server) fetchPromsumDataHandler(w http.ResponseWriter, r *http.Request) {
 	logger := newRequestLogger(srv.logger, r, srv.rand)
 
@@ -1,6 +1,7 @@
 package operator
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -15,9 +16,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
@@ -25,6 +30,7 @@ import (
 	"github.com/operator-framework/operator-metering/pkg/hive"
 	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
 	"github.com/operator-framework/operator-metering/pkg/presto"
+	"github.com/operator-framework/operator-metering/pkg/promremote"
 	"github.com/operator-framework/operator-metering/test/testhelpers"
 )
 
@@ -34,6 +40,9 @@ var (
 	noopPrometheusImporterFunc = func(ctx context.Context, namespace, dsName string, start, end time.Time) ([]*prometheusImportResults, error) {
 		return nil, nil
 	}
+	noopPrometheusRemoteWriteStoreFunc = func(ctx context.Context, dataSource *v1alpha1.ReportDataSource, series []*promremote.TimeSeries) (int, error) {
+		return 0, nil
+	}
 	testLogger = logrus.New()
 )
 
@@ -42,14 +51,15 @@ func apiReportV2URLFull(namespace, reportName string) string {
 	return path.Join(APIV2ReportsEndpointPrefix, namespace, reportName, "full")
 }
 
-//for v2 endpoints TableHidden
+// for v2 endpoints TableHidden
 func apiReportV2URLTable(namespace, reportName string) string {
 	return path.Join(APIV2ReportsEndpointPrefix, namespace, reportName, "table")
 }
 
 type fakePrometheusMetricsRepo struct {
-	metrics map[string][]*prestostore.PrometheusMetric
-	err     error
+	metrics    map[string][]*prestostore.PrometheusMetric
+	partitions map[string][]string
+	err        error
 }
 
 func (f *fakePrometheusMetricsRepo) StorePrometheusMetrics(ctx context.Context, tableName string, metrics []*prestostore.PrometheusMetric) error {
@@ -82,8 +92,21 @@ func (f *fakePrometheusMetricsRepo) GetLastTimestampForTable(tableName string) (
 	return nil, fmt.Errorf("table %s not found", tableName)
 }
 
+func (f *fakePrometheusMetricsRepo) GetTimestampGapsForTable(tableName string, start, end time.Time, stepSize time.Duration) ([]prom.Range, error) {
+	return nil, nil
+}
+
+func (f *fakePrometheusMetricsRepo) GetTimestampPartitionsForTable(tableName string) ([]string, error) {
+	return f.partitions[tableName], nil
+}
+
+func (f *fakePrometheusMetricsRepo) StorePrometheusMetricsRollup(tableName, sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) error {
+	return nil
+}
+
 type fakeReportResultsGetter struct {
 	results []presto.Row
+	periods []prestostore.ReportResultsPeriod
 	err     error
 }
 
@@ -91,6 +114,28 @@ func (f *fakeReportResultsGetter) GetReportResults(tableName string, columns []p
 	return f.results, f.err
 }
 
+func (f *fakeReportResultsGetter) GetReportResultsIterator(tableName string, columns []presto.Column, query prestostore.ReportResultsQuery) (presto.RowIterator, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return presto.NewRowSliceIterator(f.results), nil
+}
+
+func (f *fakeReportResultsGetter) GetReportResultsPeriods(tableName string) ([]prestostore.ReportResultsPeriod, error) {
+	return f.periods, f.err
+}
+
+type fakeReportQueryRunner struct {
+	queries []string
+	results []presto.Row
+	err     error
+}
+
+func (f *fakeReportQueryRunner) RunReportQuery(query string) ([]presto.Row, error) {
+	f.queries = append(f.queries, query)
+	return f.results, f.err
+}
+
 func TestAPIV1ReportsGet(t *testing.T) {
 	const namespace = "default"
 	const testReportName = "test-report"
@@ -286,6 +331,7 @@ func TestAPIV1ReportsGet(t *testing.T) {
 
 			reportLister := listers.NewReportLister(reportIndexer)
 			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer)
+			reportDataSourceLister := listers.NewReportDataSourceLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
 			prestoTableLister := listers.NewPrestoTableLister(prestoTableIndexer)
 
 			// add our test report if one is specified
@@ -301,8 +347,8 @@ func TestAPIV1ReportsGet(t *testing.T) {
 			}
 
 			// setup a test server suitable for making API calls against
-			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, noopPrometheusImporterFunc,
-				reportLister, reportGenerationQueryLister, prestoTableLister,
+			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
 			)
 			server := httptest.NewServer(router)
 			defer server.Close()
@@ -527,7 +573,7 @@ func TestAPIV2ReportsFull(t *testing.T) {
 			report:                testhelpers.NewReport(testReportName, namespace, testQueryName, reportStart, reportEnd, v1alpha1.ReportStatus{}),
 			apiPath:               apiReportV2URLFull(namespace, testReportName) + "?format=doesntexist",
 			expectedStatusCode:    http.StatusBadRequest,
-			expectedAPIError:      "format must be one of: csv, json or tabular",
+			expectedAPIError:      "format must be one of: csv, json, ndjson, parquet or tabular",
 			reportResultsGetter:   &fakeReportResultsGetter{},
 			prometheusMetricsRepo: &fakePrometheusMetricsRepo{},
 		},
@@ -586,6 +632,7 @@ func TestAPIV2ReportsFull(t *testing.T) {
 
 			reportLister := listers.NewReportLister(reportIndexer)
 			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer)
+			reportDataSourceLister := listers.NewReportDataSourceLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
 			prestoTableLister := listers.NewPrestoTableLister(prestoTableIndexer)
 
 			// add our test report if one is specified
@@ -601,8 +648,8 @@ func TestAPIV2ReportsFull(t *testing.T) {
 			}
 
 			// setup a test server suitable for making API calls against
-			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, noopPrometheusImporterFunc,
-				reportLister, reportGenerationQueryLister, prestoTableLister,
+			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
 			)
 			server := httptest.NewServer(router)
 			defer server.Close()
@@ -661,6 +708,7 @@ func TestAPIV2ReportsTable(t *testing.T) {
 		expectedStatusCode int
 		expectedAPIError   string
 		expectedResults    *GetReportResults
+		expectedContinue   bool
 
 		prometheusMetricsRepo prestostore.PrometheusMetricsRepo
 		reportResultsGetter   prestostore.ReportResultsGetter
@@ -811,7 +859,7 @@ func TestAPIV2ReportsTable(t *testing.T) {
 			report:                testhelpers.NewReport(testReportName, namespace, testQueryName, reportStart, reportEnd, v1alpha1.ReportStatus{}),
 			apiPath:               apiReportV2URLTable(namespace, testReportName) + "?format=doesntexist",
 			expectedStatusCode:    http.StatusBadRequest,
-			expectedAPIError:      "format must be one of: csv, json or tabular",
+			expectedAPIError:      "format must be one of: csv, json, ndjson, parquet or tabular",
 			reportResultsGetter:   &fakeReportResultsGetter{},
 			prometheusMetricsRepo: &fakePrometheusMetricsRepo{},
 		},
@@ -854,6 +902,85 @@ func TestAPIV2ReportsTable(t *testing.T) {
 			expectedStatusCode:    http.StatusInternalServerError,
 			expectedAPIError:      "results schema doesn't match expected schema",
 		},
+		"paginated-results": {
+			reportName: testReportName,
+			apiPath:    apiReportV2URLTable(namespace, testReportName) + testFormat + "&filter=namespace:eq:default&orderBy=-foo&limit=1",
+			report:     testhelpers.NewReport(testReportName, namespace, testQueryName, reportStart, reportEnd, v1alpha1.ReportStatus{}),
+			query: testhelpers.NewReportGenerationQuery(testQueryName, namespace, []v1alpha1.ReportGenerationQueryColumn{
+				{
+					Name: "namespace",
+					Type: "string",
+				},
+				{
+					Name: "foo",
+					Type: "double",
+				},
+			}),
+			prestoTable: testhelpers.NewPrestoTable(testReportName, namespace, []hive.Column{
+				{
+					Name: "namespace",
+					Type: "string",
+				},
+				{
+					Name: "foo",
+					Type: "double",
+				},
+			}),
+			reportResultsGetter: &fakeReportResultsGetter{
+				results: []presto.Row{
+					{
+						"namespace": "default",
+						"foo":       2.5,
+					},
+					{
+						"namespace": "default",
+						"foo":       1.5,
+					},
+				},
+			},
+			prometheusMetricsRepo: &fakePrometheusMetricsRepo{},
+			expectedStatusCode:    http.StatusOK,
+			expectedResults: &GetReportResults{
+				Results: []ReportResultEntry{
+					{
+						Values: []ReportResultValues{
+							{Name: "namespace", Value: "default"},
+							{Name: "foo", Value: 2.5},
+						},
+					},
+				},
+			},
+			expectedContinue: true,
+		},
+		"invalid-filter": {
+			reportName: testReportName,
+			apiPath:    apiReportV2URLTable(namespace, testReportName) + testFormat + "&filter=foo:eq:not-a-number",
+			report:     testhelpers.NewReport(testReportName, namespace, testQueryName, reportStart, reportEnd, v1alpha1.ReportStatus{}),
+			query: testhelpers.NewReportGenerationQuery(testQueryName, namespace, []v1alpha1.ReportGenerationQueryColumn{
+				{
+					Name: "namespace",
+					Type: "string",
+				},
+				{
+					Name: "foo",
+					Type: "double",
+				},
+			}),
+			prestoTable: testhelpers.NewPrestoTable(testReportName, namespace, []hive.Column{
+				{
+					Name: "namespace",
+					Type: "string",
+				},
+				{
+					Name: "foo",
+					Type: "double",
+				},
+			}),
+			reportResultsGetter:   &fakeReportResultsGetter{},
+			prometheusMetricsRepo: &fakePrometheusMetricsRepo{},
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedAPIError:      `"not-a-number" is not a valid double`,
+		},
 	}
 
 	for testName, tt := range tests {
@@ -870,6 +997,7 @@ func TestAPIV2ReportsTable(t *testing.T) {
 
 			reportLister := listers.NewReportLister(reportIndexer)
 			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer)
+			reportDataSourceLister := listers.NewReportDataSourceLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
 			prestoTableLister := listers.NewPrestoTableLister(prestoTableIndexer)
 
 			// add our test report if one is specified
@@ -885,8 +1013,8 @@ func TestAPIV2ReportsTable(t *testing.T) {
 			}
 
 			// setup a test server suitable for making API calls against
-			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, noopPrometheusImporterFunc,
-				reportLister, reportGenerationQueryLister, prestoTableLister,
+			router := newRouter(testLogger, testRand, tt.prometheusMetricsRepo, tt.reportResultsGetter, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
 			)
 			server := httptest.NewServer(router)
 			defer server.Close()
@@ -918,7 +1046,715 @@ func TestAPIV2ReportsTable(t *testing.T) {
 				assert.NoError(t, err, "expected unmarshal to not error")
 				// TODO(chance): check more than the results length matching
 				assert.Len(t, results.Results, len(tt.expectedResults.Results), "expected API results length to match expected results length")
+				assert.Equal(t, tt.expectedContinue, results.Continue != "", "expected a continue token only when there are more results")
+				assert.Equal(t, results.Continue, resp.Header.Get(reportResultsContinueHeader), "expected the continue token header to match the continue token in the body")
 			}
 		})
 	}
 }
+
+func TestAPIV1ReportsRun(t *testing.T) {
+	const namespace = "default"
+	const testQueryName = "test-query"
+	const testDataSourceName = "test-datasource"
+
+	columns := []v1alpha1.ReportGenerationQueryColumn{
+		{
+			Name: "timestamp",
+			Type: "timestamp",
+		},
+		{
+			Name: "foo",
+			Type: "double",
+		},
+	}
+	testQuery := testhelpers.NewReportGenerationQuery(testQueryName, namespace, columns)
+	testQuery.Spec.Query = `SELECT * FROM {| dataSourceTableName "test-datasource" |} WHERE "timestamp" >= timestamp '{| .Report.ReportingStart | prestoTimestamp |}' AND "timestamp" < timestamp '{| .Report.ReportingEnd | prestoTimestamp |}'{| if .Report.Inputs.Foo |} AND foo = {| .Report.Inputs.Foo |}{| end |}`
+	testQuery.Spec.DataSources = []string{testDataSourceName}
+	testQuery.Spec.Inputs = []v1alpha1.ReportGenerationQueryInputDefinition{
+		{
+			Name: "Foo",
+			Type: "integer",
+		},
+	}
+
+	testDataSource := testhelpers.NewReportDataSource(testDataSourceName, namespace)
+	testDataSource.Status.TableName = "datasource_default_test_datasource"
+	uninitializedDataSource := testhelpers.NewReportDataSource(testDataSourceName, namespace)
+
+	tests := map[string]struct {
+		params url.Values
+
+		query      *v1alpha1.ReportGenerationQuery
+		dataSource *v1alpha1.ReportDataSource
+
+		queryRunner *fakeReportQueryRunner
+
+		expectedStatusCode int
+		expectedAPIError   string
+		expectedQuery      string
+		expectedResults    []presto.Row
+	}{
+		"query-runs-successfully": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+			},
+			query:      testQuery,
+			dataSource: testDataSource,
+			queryRunner: &fakeReportQueryRunner{
+				results: []presto.Row{
+					{"timestamp": "2019-01-01 00:00:00.000", "foo": 1.0},
+					{"timestamp": "2019-01-01 00:00:00.000", "foo": 2.0},
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedQuery:      `SELECT * FROM datasource_default_test_datasource WHERE "timestamp" >= timestamp '2019-01-01 00:00:00.000' AND "timestamp" < timestamp '2019-01-02 00:00:00.000'`,
+			expectedResults: []presto.Row{
+				{"timestamp": "2019-01-01 00:00:00.000", "foo": 1.0},
+				{"timestamp": "2019-01-01 00:00:00.000", "foo": 2.0},
+			},
+		},
+		"query-runs-with-inputs": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+				"inputs":    []string{`[{"name":"Foo","value":5}]`},
+			},
+			query:              testQuery,
+			dataSource:         testDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusOK,
+			expectedQuery:      `SELECT * FROM datasource_default_test_datasource WHERE "timestamp" >= timestamp '2019-01-01 00:00:00.000' AND "timestamp" < timestamp '2019-01-02 00:00:00.000' AND foo = 5`,
+		},
+		"query-does-not-exist": {
+			params: url.Values{
+				"query":     []string{"does-not-exist"},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+			},
+			query:              testQuery,
+			dataSource:         testDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedAPIError:   "not found",
+		},
+		"missing-params": {
+			params: url.Values{
+				"query":  []string{testQueryName},
+				"format": []string{"json"},
+			},
+			query:              testQuery,
+			dataSource:         testDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedAPIError:   "the following fields are missing or empty: namespace,start,end",
+		},
+		"end-before-start": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-02T00:00:00Z"},
+				"end":       []string{"2019-01-01T00:00:00Z"},
+				"format":    []string{"json"},
+			},
+			query:              testQuery,
+			dataSource:         testDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedAPIError:   "end time must be after start time",
+		},
+		"invalid-inputs": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+				"inputs":    []string{`[{"name":"Foo","value":"not-a-number"}]`},
+			},
+			query:              testQuery,
+			dataSource:         testDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedAPIError:   "is not valid a integer",
+		},
+		"uninitialized-datasource": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+			},
+			query:              testQuery,
+			dataSource:         uninitializedDataSource,
+			queryRunner:        &fakeReportQueryRunner{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedAPIError:   "uninitialized ReportDataSource dependencies: test-datasource",
+		},
+		"presto-error": {
+			params: url.Values{
+				"query":     []string{testQueryName},
+				"namespace": []string{namespace},
+				"start":     []string{"2019-01-01T00:00:00Z"},
+				"end":       []string{"2019-01-02T00:00:00Z"},
+				"format":    []string{"json"},
+			},
+			query:      testQuery,
+			dataSource: testDataSource,
+			queryRunner: &fakeReportQueryRunner{
+				err: errors.New("presto is down"),
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedAPIError:   "presto is down",
+		},
+	}
+
+	for testName, tt := range tests {
+		tt := tt
+		testName := testName
+		t.Run(testName, func(t *testing.T) {
+			reportIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportGenerationQueryIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportDataSourceIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			prestoTableIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+
+			reportLister := listers.NewReportLister(reportIndexer)
+			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer)
+			reportDataSourceLister := listers.NewReportDataSourceLister(reportDataSourceIndexer)
+			prestoTableLister := listers.NewPrestoTableLister(prestoTableIndexer)
+
+			if tt.query != nil {
+				reportGenerationQueryIndexer.Add(tt.query)
+			}
+			if tt.dataSource != nil {
+				reportDataSourceIndexer.Add(tt.dataSource)
+			}
+
+			router := newRouter(testLogger, testRand, &fakePrometheusMetricsRepo{}, &fakeReportResultsGetter{}, tt.queryRunner, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+			)
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			endpointURL, err := url.Parse(server.URL + APIV1ReportsRunEndpoint)
+			require.NoError(t, err)
+			endpointURL.RawQuery = tt.params.Encode()
+
+			resp, err := server.Client().Get(endpointURL.String())
+			require.NoError(t, err, "expected making http request to not return error")
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err, "expected read all of resp.Body to succeed")
+
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode, "Expected http status code to match")
+			t.Logf("response body: %s", string(body))
+
+			if tt.expectedAPIError != "" {
+				var errResp errorResponse
+				err = json.Unmarshal(body, &errResp)
+				assert.NoError(t, err, "expected unmarshal to not error")
+				assert.Contains(t, errResp.Error, tt.expectedAPIError, "expected error response to contain expected api error")
+				return
+			}
+
+			require.Len(t, tt.queryRunner.queries, 1, "expected a single query to be executed")
+			assert.Equal(t, tt.expectedQuery, tt.queryRunner.queries[0], "expected rendered query to match")
+
+			var results GetReportResults
+			err = json.Unmarshal(body, &results)
+			assert.NoError(t, err, "expected unmarshal to not error")
+			assert.Len(t, results.Results, len(tt.expectedResults), "expected API results length to match expected results length")
+		})
+	}
+}
+
+func TestAPIV2ReportsRuns(t *testing.T) {
+	const namespace = "default"
+	const testReportName = "test-report"
+	const testQueryName = "test-query"
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.Add(time.Hour)
+
+	testRuns := []v1alpha1.ReportRun{
+		{
+			PeriodStart: meta.Time{Time: periodStart},
+			PeriodEnd:   meta.Time{Time: periodEnd},
+			StartTime:   meta.Time{Time: periodEnd},
+			FinishTime:  meta.Time{Time: periodEnd.Add(time.Minute)},
+			Duration:    meta.Duration{Duration: time.Minute},
+			Outcome:     v1alpha1.ReportRunFailed,
+			Error:       "presto is down",
+		},
+		{
+			PeriodStart: meta.Time{Time: periodStart},
+			PeriodEnd:   meta.Time{Time: periodEnd},
+			StartTime:   meta.Time{Time: periodEnd.Add(2 * time.Minute)},
+			FinishTime:  meta.Time{Time: periodEnd.Add(3 * time.Minute)},
+			Duration:    meta.Duration{Duration: time.Minute},
+			RowCount:    42,
+			Outcome:     v1alpha1.ReportRunSucceeded,
+		},
+	}
+
+	tests := map[string]struct {
+		apiPath string
+		report  *v1alpha1.Report
+
+		expectedStatusCode int
+		expectedAPIError   string
+		expectedRuns       []v1alpha1.ReportRun
+	}{
+		"report-with-runs": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, testReportName, "runs"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{Runs: testRuns}),
+			expectedStatusCode: http.StatusOK,
+			expectedRuns:       testRuns,
+		},
+		"report-without-runs": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, testReportName, "runs"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{}),
+			expectedStatusCode: http.StatusOK,
+			expectedRuns:       []v1alpha1.ReportRun{},
+		},
+		"report-not-found": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, "does-not-exist", "runs"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{}),
+			expectedStatusCode: http.StatusNotFound,
+			expectedAPIError:   "not found",
+		},
+	}
+
+	for testName, tt := range tests {
+		tt := tt
+		testName := testName
+		t.Run(testName, func(t *testing.T) {
+			reportIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportLister := listers.NewReportLister(reportIndexer)
+			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
+			reportDataSourceLister := listers.NewReportDataSourceLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
+			prestoTableLister := listers.NewPrestoTableLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
+
+			if tt.report != nil {
+				reportIndexer.Add(tt.report)
+			}
+
+			router := newRouter(testLogger, testRand, &fakePrometheusMetricsRepo{}, &fakeReportResultsGetter{}, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+			)
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			resp, err := server.Client().Get(server.URL + tt.apiPath)
+			require.NoError(t, err, "expected making http request to not return error")
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err, "expected read all of resp.Body to succeed")
+
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode, "Expected http status code to match")
+			t.Logf("response body: %s", string(body))
+
+			if tt.expectedAPIError != "" {
+				var errResp errorResponse
+				err = json.Unmarshal(body, &errResp)
+				assert.NoError(t, err, "expected unmarshal to not error")
+				assert.Contains(t, errResp.Error, tt.expectedAPIError, "expected error response to contain expected api error")
+				return
+			}
+
+			var runsResp GetReportRunsResponse
+			err = json.Unmarshal(body, &runsResp)
+			require.NoError(t, err, "expected unmarshal to not error")
+			require.Len(t, runsResp.Runs, len(tt.expectedRuns), "expected number of runs to match")
+			for i, run := range runsResp.Runs {
+				assert.Equal(t, tt.expectedRuns[i].Outcome, run.Outcome, "expected run outcome to match")
+				assert.Equal(t, tt.expectedRuns[i].RowCount, run.RowCount, "expected run rowCount to match")
+				assert.Equal(t, tt.expectedRuns[i].Error, run.Error, "expected run error to match")
+				assert.True(t, tt.expectedRuns[i].PeriodStart.Equal(&run.PeriodStart), "expected run periodStart to match")
+			}
+		})
+	}
+}
+
+func TestAPIV2ReportsPeriods(t *testing.T) {
+	const namespace = "default"
+	const testReportName = "test-report"
+	const testQueryName = "test-query"
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testPeriods := []prestostore.ReportResultsPeriod{
+		{PeriodStart: periodStart, PeriodEnd: periodStart.AddDate(0, 1, 0)},
+		{PeriodStart: periodStart.AddDate(0, 1, 0), PeriodEnd: periodStart.AddDate(0, 2, 0)},
+	}
+	periodColumns := []v1alpha1.ReportGenerationQueryColumn{
+		{Name: "period_start", Type: "timestamp"},
+		{Name: "period_end", Type: "timestamp"},
+		{Name: "namespace", Type: "string"},
+	}
+
+	tests := map[string]struct {
+		apiPath string
+		report  *v1alpha1.Report
+		query   *v1alpha1.ReportGenerationQuery
+
+		expectedStatusCode int
+		expectedAPIError   string
+		expectedPeriods    []prestostore.ReportResultsPeriod
+	}{
+		"report-with-periods": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, testReportName, "periods"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{TableName: "report_default_test_report"}),
+			query:              testhelpers.NewReportGenerationQuery(testQueryName, namespace, periodColumns),
+			expectedStatusCode: http.StatusOK,
+			expectedPeriods:    testPeriods,
+		},
+		"report-not-processed": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, testReportName, "periods"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{}),
+			query:              testhelpers.NewReportGenerationQuery(testQueryName, namespace, periodColumns),
+			expectedStatusCode: http.StatusAccepted,
+			expectedAPIError:   "not processed yet",
+		},
+		"query-without-period-columns": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, testReportName, "periods"),
+			report:             testhelpers.NewReport(testReportName, namespace, testQueryName, nil, nil, v1alpha1.ReportStatus{TableName: "report_default_test_report"}),
+			query:              testhelpers.NewReportGenerationQuery(testQueryName, namespace, periodColumns[2:]),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedAPIError:   "has no period_start and period_end timestamp columns",
+		},
+		"report-not-found": {
+			apiPath:            path.Join(APIV2ReportsEndpointPrefix, namespace, "does-not-exist", "periods"),
+			expectedStatusCode: http.StatusNotFound,
+			expectedAPIError:   "not found",
+		},
+	}
+
+	for testName, tt := range tests {
+		tt := tt
+		testName := testName
+		t.Run(testName, func(t *testing.T) {
+			reportIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportGenerationQueryIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportLister := listers.NewReportLister(reportIndexer)
+			reportGenerationQueryLister := listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer)
+			reportDataSourceLister := listers.NewReportDataSourceLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
+			prestoTableLister := listers.NewPrestoTableLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{}))
+
+			if tt.report != nil {
+				reportIndexer.Add(tt.report)
+			}
+			if tt.query != nil {
+				reportGenerationQueryIndexer.Add(tt.query)
+			}
+
+			router := newRouter(testLogger, testRand, &fakePrometheusMetricsRepo{}, &fakeReportResultsGetter{periods: testPeriods}, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, noopPrometheusRemoteWriteStoreFunc,
+				reportLister, reportGenerationQueryLister, reportDataSourceLister, prestoTableLister, listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+			)
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			resp, err := server.Client().Get(server.URL + tt.apiPath)
+			require.NoError(t, err, "expected making http request to not return error")
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err, "expected read all of resp.Body to succeed")
+
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode, "Expected http status code to match")
+			t.Logf("response body: %s", string(body))
+
+			if tt.expectedAPIError != "" {
+				var errResp errorResponse
+				err = json.Unmarshal(body, &errResp)
+				assert.NoError(t, err, "expected unmarshal to not error")
+				assert.Contains(t, errResp.Error, tt.expectedAPIError, "expected error response to contain expected api error")
+				return
+			}
+
+			var periodsResp GetReportPeriodsResponse
+			err = json.Unmarshal(body, &periodsResp)
+			require.NoError(t, err, "expected unmarshal to not error")
+			require.Len(t, periodsResp.Periods, len(tt.expectedPeriods), "expected number of periods to match")
+			for i, period := range periodsResp.Periods {
+				assert.True(t, tt.expectedPeriods[i].PeriodStart.Equal(period.PeriodStart), "expected periodStart to match")
+				assert.True(t, tt.expectedPeriods[i].PeriodEnd.Equal(period.PeriodEnd), "expected periodEnd to match")
+			}
+		})
+	}
+}
+
+// encodeRemoteWriteRequest encodes req the same as Prometheus does.
+func encodeRemoteWriteRequest(t *testing.T, req *promremote.WriteRequest) []byte {
+	data, err := proto.Marshal(req)
+	require.NoError(t, err)
+	return snappy.Encode(nil, data)
+}
+
+type erroringRowIterator struct {
+	presto.RowIterator
+	err error
+}
+
+func (it *erroringRowIterator) Err() error {
+	return it.err
+}
+
+func TestWriteResultsResponseV2Streaming(t *testing.T) {
+	columns := []v1alpha1.ReportGenerationQueryColumn{
+		{Name: "namespace", Type: "varchar"},
+		{Name: "pods", Type: "bigint"},
+		{Name: "hidden", Type: "bigint", TableHidden: true},
+	}
+	rowCount := resultsFlushInterval*2 + 1
+	newResults := func() []presto.Row {
+		results := make([]presto.Row, rowCount)
+		for i := range results {
+			results[i] = presto.Row{"namespace": fmt.Sprintf("ns-%d", i), "pods": int64(i), "hidden": int64(i)}
+		}
+		return results
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		writeResultsResponseV2(testLogger, false, "csv", "test", columns, "", presto.NewRowSliceIterator(newResults()), rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, rec.Flushed, "expected the response to be flushed while streaming")
+		lines := bytes.Split(bytes.TrimSpace(rec.Body.Bytes()), []byte("\n"))
+		require.Len(t, lines, rowCount+1, "expected a header and a line per row")
+		assert.Equal(t, "namespace,pods", string(lines[0]))
+		assert.Equal(t, "ns-0,0", string(lines[1]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		writeResultsResponseV2(testLogger, true, "json", "test", columns, "", presto.NewRowSliceIterator(newResults()), rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var results GetReportResults
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+		require.Len(t, results.Results, rowCount)
+		assert.Len(t, results.Results[0].Values, 3)
+	})
+
+	t.Run("json-empty", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		writeResultsResponseV2(testLogger, true, "json", "test", columns, "", presto.NewRowSliceIterator(nil), rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"results":[]}`, rec.Body.String())
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		writeResultsResponseV2(testLogger, false, "ndjson", "test", columns, "", presto.NewRowSliceIterator(newResults()), rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		lines := bytes.Split(bytes.TrimSpace(rec.Body.Bytes()), []byte("\n"))
+		require.Len(t, lines, rowCount, "expected a line per row")
+		assert.JSONEq(t, `{"namespace":"ns-0","pods":0}`, string(lines[0]))
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		writeResultsResponseV2(testLogger, true, "parquet", "test", columns, "", presto.NewRowSliceIterator(newResults()), rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/vnd.apache.parquet", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment;filename=test.parquet", rec.Header().Get("Content-Disposition"))
+		body := rec.Body.Bytes()
+		require.True(t, len(body) > 8)
+		assert.Equal(t, "PAR1", string(body[:4]), "expected the parquet magic number at the start of the file")
+		assert.Equal(t, "PAR1", string(body[len(body)-4:]), "expected the parquet magic number at the end of the file")
+	})
+
+	t.Run("parquet-unsupported-column", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		arrayColumns := []v1alpha1.ReportGenerationQueryColumn{{Name: "pods", Type: "array<string>"}}
+		writeResultsResponseV2(testLogger, true, "parquet", "test", arrayColumns, "", presto.NewRowSliceIterator(nil), rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("error-before-first-row", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		results := &erroringRowIterator{RowIterator: presto.NewRowSliceIterator(nil), err: errors.New("mock database had an error")}
+		writeResultsResponseV2(testLogger, true, "json", "test", columns, "", results, rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "mock database had an error")
+	})
+
+	t.Run("error-after-first-row", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		results := newRowTransformIterator(presto.NewRowSliceIterator(newResults()), func(row presto.Row) error {
+			if row["pods"].(int64) == resultsFlushInterval {
+				return errors.New("mock database had an error")
+			}
+			return nil
+		})
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			writeResultsResponseV2(testLogger, true, "csv", "test", columns, "", results, rec, req)
+		}, "expected the response to be aborted")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestAPIV1PrometheusRemoteWrite(t *testing.T) {
+	const namespace = "default"
+	newDataSource := func(name, namespace string, remoteWrite *v1alpha1.PrometheusRemoteWriteConfig) *v1alpha1.ReportDataSource {
+		return &v1alpha1.ReportDataSource{
+			ObjectMeta: meta.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1alpha1.ReportDataSourceSpec{
+				Promsum: &v1alpha1.PrometheusMetricsDataSource{RemoteWrite: remoteWrite},
+			},
+			Status: v1alpha1.ReportDataSourceStatus{TableName: "datasource_" + name},
+		}
+	}
+	dataSources := []*v1alpha1.ReportDataSource{
+		newDataSource("cpu-requests", namespace, &v1alpha1.PrometheusRemoteWriteConfig{
+			MetricName: "kube_pod_container_resource_requests_cpu_cores",
+		}),
+		newDataSource("app-cpu-requests", namespace, &v1alpha1.PrometheusRemoteWriteConfig{
+			MetricName: "kube_pod_container_resource_requests_cpu_cores",
+			Matchers: []v1alpha1.PrometheusLabelMatcher{
+				{Name: "namespace", Value: "app-.*", Type: v1alpha1.PrometheusLabelMatchRegexp},
+			},
+		}),
+		newDataSource("non-default-memory-requests", namespace, &v1alpha1.PrometheusRemoteWriteConfig{
+			Matchers: []v1alpha1.PrometheusLabelMatcher{
+				{Name: "__name__", Value: "kube_pod_container_resource_requests_memory_bytes"},
+				{Name: "namespace", Value: "default", Type: v1alpha1.PrometheusLabelMatchNotEqual},
+			},
+		}),
+		newDataSource("pull-datasource", namespace, nil),
+		newDataSource("other-namespace-cpu-requests", "other", &v1alpha1.PrometheusRemoteWriteConfig{
+			MetricName: "kube_pod_container_resource_requests_cpu_cores",
+		}),
+	}
+
+	newSeries := func(name, namespace string) *promremote.TimeSeries {
+		return &promremote.TimeSeries{
+			Labels: []*promremote.Label{
+				{Name: promremote.MetricNameLabel, Value: name},
+				{Name: "namespace", Value: namespace},
+			},
+			Samples: []*promremote.Sample{{Value: 1, Timestamp: 1546300800000}},
+		}
+	}
+	req := &promremote.WriteRequest{
+		Timeseries: []*promremote.TimeSeries{
+			newSeries("kube_pod_container_resource_requests_cpu_cores", "default"),
+			newSeries("kube_pod_container_resource_requests_cpu_cores", "app-frontend"),
+			newSeries("kube_pod_container_resource_requests_memory_bytes", "default"),
+			newSeries("kube_pod_container_resource_requests_memory_bytes", "app-frontend"),
+			newSeries("up", "default"),
+		},
+	}
+
+	tests := map[string]struct {
+		apiPath            string
+		body               []byte
+		storeErr           error
+		failingDataSource  string
+		expectedStatusCode int
+		expectedStored     map[string][]string
+	}{
+		"all namespaces": {
+			apiPath:            APIV1PrometheusRemoteWriteEndpoint,
+			body:               encodeRemoteWriteRequest(t, req),
+			expectedStatusCode: http.StatusNoContent,
+			expectedStored: map[string][]string{
+				"default/cpu-requests":                {"default", "app-frontend"},
+				"default/app-cpu-requests":            {"app-frontend"},
+				"default/non-default-memory-requests": {"app-frontend"},
+				"other/other-namespace-cpu-requests":  {"default", "app-frontend"},
+			},
+		},
+		"single namespace": {
+			apiPath:            path.Join(APIV1PrometheusRemoteWriteEndpoint, "other"),
+			body:               encodeRemoteWriteRequest(t, req),
+			expectedStatusCode: http.StatusNoContent,
+			expectedStored: map[string][]string{
+				"other/other-namespace-cpu-requests": {"default", "app-frontend"},
+			},
+		},
+		"invalid body": {
+			apiPath:            APIV1PrometheusRemoteWriteEndpoint,
+			body:               []byte("not snappy"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedStored:     map[string][]string{},
+		},
+		"store error": {
+			apiPath:            path.Join(APIV1PrometheusRemoteWriteEndpoint, "other"),
+			body:               encodeRemoteWriteRequest(t, req),
+			storeErr:           errors.New("presto is down"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedStored: map[string][]string{
+				"other/other-namespace-cpu-requests": {"default", "app-frontend"},
+			},
+		},
+		"store error for one ReportDataSource": {
+			apiPath:            APIV1PrometheusRemoteWriteEndpoint,
+			body:               encodeRemoteWriteRequest(t, req),
+			storeErr:           errors.New("presto is down"),
+			failingDataSource:  "default/cpu-requests",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedStored: map[string][]string{
+				"default/cpu-requests":                {"default", "app-frontend"},
+				"default/app-cpu-requests":            {"app-frontend"},
+				"default/non-default-memory-requests": {"app-frontend"},
+				"other/other-namespace-cpu-requests":  {"default", "app-frontend"},
+			},
+		},
+	}
+
+	for testName, tt := range tests {
+		tt := tt
+		t.Run(testName, func(t *testing.T) {
+			reportDataSourceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, dataSource := range dataSources {
+				require.NoError(t, reportDataSourceIndexer.Add(dataSource))
+			}
+
+			stored := make(map[string][]string)
+			storeFunc := func(ctx context.Context, dataSource *v1alpha1.ReportDataSource, series []*promremote.TimeSeries) (int, error) {
+				key := dataSource.Namespace + "/" + dataSource.Name
+				for _, s := range series {
+					stored[key] = append(stored[key], s.LabelsMap()["namespace"])
+				}
+				if tt.failingDataSource != "" && tt.failingDataSource != key {
+					return len(series), nil
+				}
+				return len(series), tt.storeErr
+			}
+
+			router := newRouter(testLogger, testRand, &fakePrometheusMetricsRepo{}, &fakeReportResultsGetter{}, &fakeReportQueryRunner{}, noopPrometheusImporterFunc, storeFunc,
+				listers.NewReportLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+				listers.NewReportGenerationQueryLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+				listers.NewReportDataSourceLister(reportDataSourceIndexer),
+				listers.NewPrestoTableLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+				listers.NewRateCardLister(cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})),
+			)
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			resp, err := server.Client().Post(server.URL+tt.apiPath, "application/x-protobuf", bytes.NewReader(tt.body))
+			require.NoError(t, err, "expected making http request to not return error")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode, "Expected http status code to match")
+			assert.Equal(t, tt.expectedStored, stored, "expected series to be stored for the matching ReportDataSources")
+		})
+	}
+}
@@ -20,7 +20,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/kubernetes/scheme"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	"k8s.io/client-go/rest"
@@ -51,11 +50,22 @@ const (
 	defaultResyncPeriod = time.Minute * 15
 	prestoUsername      = "reporting-operator"
 
-	DefaultPrometheusQueryInterval                       = time.Minute * 5  // Query Prometheus every 5 minutes
-	DefaultPrometheusQueryStepSize                       = time.Minute      // Query data from Prometheus at a 60 second resolution (one data point per minute max)
-	DefaultPrometheusQueryChunkSize                      = 5 * time.Minute  // the default value for how much data we will insert into Presto per Prometheus query.
-	DefaultPrometheusDataSourceMaxQueryRangeDuration     = 10 * time.Minute // how much data we will query from Prometheus at once
-	DefaultPrometheusDataSourceMaxBackfillImportDuration = 2 * time.Hour    // how far we will query for backlogged data.
+	DefaultPrometheusQueryInterval                       = time.Minute * 5     // Query Prometheus every 5 minutes
+	DefaultPrometheusQueryStepSize                       = time.Minute         // Query data from Prometheus at a 60 second resolution (one data point per minute max)
+	DefaultPrometheusQueryChunkSize                      = 5 * time.Minute     // the default value for how much data we will insert into Presto per Prometheus query.
+	DefaultPrometheusDataSourceMaxQueryRangeDuration     = 10 * time.Minute    // how much data we will query from Prometheus at once
+	DefaultPrometheusDataSourceMaxBackfillImportDuration = 2 * time.Hour       // how far we will query for backlogged data.
+	DefaultPrometheusDataSourceGapCheckInterval          = time.Hour           // how often we check tables for missing data.
+	DefaultPrometheusDataSourceRetention                 = 15 * 24 * time.Hour // how far back Prometheus has data we can backfill gaps from.
+
+	DefaultReportRunHistoryLimit = 10 // how many runs are recorded in a Report's status.
+
+	DefaultRetentionSweepInterval = time.Hour // how often we remove expired data from tables.
+	DefaultViewSweepInterval      = time.Hour // how often we drop views without a ReportGenerationQuery.
+
+	DefaultOrphanedTableSweepInterval = time.Hour      // how often we look for tables without a resource owning them.
+	DefaultOrphanedTableGracePeriod   = 24 * time.Hour // how long tables are orphaned before we drop them.
+	DefaultOrphanedTableDryRun        = true           // whether orphaned tables are only reported, and never dropped.
 )
 
 type TLSConfig struct {
@@ -97,6 +107,9 @@ type Config struct {
 	MetricsListen string
 	PprofListen   string
 
+	EnableWebhook bool
+	WebhookListen string
+
 	HiveHost                string
 	PrestoHost              string
 	DisablePromsum          bool
@@ -105,6 +118,25 @@ type Config struct {
 
 	PrestoMaxQueryLength int
 
+	ReportRunHistoryLimit int
+
+	RetentionSweepInterval time.Duration
+	DefaultRetention       *cbTypes.RetentionPolicy
+
+	ViewSweepInterval time.Duration
+
+	OrphanedTableSweepInterval time.Duration
+	OrphanedTableGracePeriod   time.Duration
+	OrphanedTableDryRun        bool
+
+	// ExportDirectory is the directory Reports with local exports write
+	// to. Local exports are disabled if it's empty.
+	ExportDirectory string
+	// ExportAllowedHosts are the hosts the HTTP exports and S3 endpoints of
+	// Reports can send results to. HTTP exports and custom S3 endpoints are
+	// disabled if it's empty.
+	ExportAllowedHosts []string
+
 	LogDMLQueries bool
 	LogDDLQueries bool
 
@@ -112,11 +144,14 @@ type Config struct {
 	PrometheusDataSourceMaxQueryRangeDuration     time.Duration
 	PrometheusDataSourceMaxBackfillImportDuration time.Duration
 	PrometheusDataSourceGlobalImportFromTime      *time.Time
+	PrometheusDataSourceGapCheckInterval          time.Duration
+	PrometheusDataSourceRetention                 time.Duration
 
 	LeaderLeaseDuration time.Duration
 
 	APITLSConfig     TLSConfig
 	MetricsTLSConfig TLSConfig
+	WebhookTLSConfig TLSConfig
 	PrometheusConfig PrometheusConfig
 }
 
@@ -134,6 +169,9 @@ type Reporting struct {
 	reportGenerationQueryLister listers.ReportGenerationQueryLister
 	reportPrometheusQueryLister listers.ReportPrometheusQueryLister
 	reportLister                listers.ReportLister
+	reportRerunLister           listers.ReportRerunLister
+	rateCardLister              listers.RateCardLister
+	reportNotificationLister    listers.ReportNotificationLister
 	storageLocationLister       listers.StorageLocationLister
 
 	queueList                  []workqueue.RateLimitingInterface
@@ -141,12 +179,33 @@ type Reporting struct {
 	reportDataSourceQueue      workqueue.RateLimitingInterface
 	reportGenerationQueryQueue workqueue.RateLimitingInterface
 	prestoTableQueue           workqueue.RateLimitingInterface
+	reportRerunQueue           workqueue.RateLimitingInterface
+	rateCardQueue              workqueue.RateLimitingInterface
+	reportNotificationQueue    workqueue.RateLimitingInterface
+	reportExportQueue          workqueue.RateLimitingInterface
 
 	reportResultsRepo     prestostore.ReportResultsRepo
 	prometheusMetricsRepo prestostore.PrometheusMetricsRepo
+	rateCardRepo          prestostore.RateCardRepo
 	reportGenerator       reporting.ReportGenerator
 
-	prestoViewCreator        PrestoViewCreator
+	webhookValidator *webhookValidator
+	// watchedNamespaces are the namespaces our informers watch. If empty,
+	// they watch all namespaces.
+	watchedNamespaces []string
+
+	// orphanedTablesFirstSeen is when each orphaned table was first found.
+	// It's only used by the orphaned table sweeper.
+	orphanedTablesFirstSeen map[string]time.Time
+	// orphanedViewsFirstSeen is when each orphaned view was first found.
+	// It's only used by the view sweeper.
+	orphanedViewsFirstSeen map[string]time.Time
+
+	eventBroadcaster record.EventBroadcaster
+	eventRecorder    record.EventRecorder
+
+	prestoViewManager        PrestoViewManager
+	prestoSchemaLister       PrestoSchemaLister
 	tableManager             reporting.TableManager
 	awsTablePartitionManager reporting.AWSTablePartitionManager
 
@@ -165,6 +224,23 @@ type Reporting struct {
 
 	importersMu sync.Mutex
 	importers   map[string]*prestostore.PrometheusImporter
+
+	remoteWriteMu          sync.Mutex
+	remoteWriteStatuses    map[string]*remoteWriteImportStatus
+	remoteWriteStoredSteps map[string]map[string]time.Time
+
+	reportTableLocksMu sync.Mutex
+	reportTableLocks   map[string]*sync.Mutex
+	// reportStagingCleanups are the staging tables of failed runs which
+	// couldn't be dropped, by table, and reportStagingTablesChecked are the
+	// tables whose leftover staging tables have been dropped.
+	reportStagingCleanups      map[string][]*reportStagingCleanup
+	reportStagingTablesChecked map[string]bool
+
+	// pendingReportExportStatuses are the outcomes of exports which haven't
+	// been saved in the status of their Report yet, by Report key.
+	reportExportsMu             sync.Mutex
+	pendingReportExportStatuses map[string][]cbTypes.ReportExportStatus
 }
 
 func New(logger log.FieldLogger, cfg Config) (*Reporting, error) {
@@ -174,6 +250,18 @@ func New(logger log.FieldLogger, cfg Config) (*Reporting, error) {
 	if err := cfg.MetricsTLSConfig.Valid(); err != nil {
 		return nil, err
 	}
+	if cfg.EnableWebhook {
+		// the Kubernetes API server only calls webhooks over HTTPS
+		webhookTLSConfig := cfg.WebhookTLSConfig
+		webhookTLSConfig.UseTLS = true
+		if err := webhookTLSConfig.Valid(); err != nil {
+			return nil, fmt.Errorf("invalid webhook TLS configuration: %v", err)
+		}
+	}
+
+	if err := validateRetentionPolicy(cfg.DefaultRetention, "default retention"); err != nil {
+		return nil, err
+	}
 
 	logger.Debugf("config: %s", spew.Sprintf("%+v", cfg))
 
@@ -251,18 +339,31 @@ func newReportingOperator(
 	reportGenerationQueryInformer := informerFactory.Metering().V1alpha1().ReportGenerationQueries()
 	reportPrometheusQueryInformer := informerFactory.Metering().V1alpha1().ReportPrometheusQueries()
 	reportInformer := informerFactory.Metering().V1alpha1().Reports()
+	reportRerunInformer := informerFactory.Metering().V1alpha1().ReportReruns()
+	rateCardInformer := informerFactory.Metering().V1alpha1().RateCards()
+	reportNotificationInformer := informerFactory.Metering().V1alpha1().ReportNotifications()
 	storageLocationInformer := informerFactory.Metering().V1alpha1().StorageLocations()
 
 	reportQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "reports")
 	reportDataSourceQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "reportdatasources")
 	reportGenerationQueryQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "reportgenerationqueries")
 	prestoTableQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "prestotables")
+	reportRerunQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "reportreruns")
+	rateCardQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ratecards")
+	// events are retried with a longer backoff than resources, to give
+	// receivers time to recover.
+	reportNotificationQueue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, 10*time.Minute), "reportnotifications")
+	reportExportQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "reportexports")
 
 	queueList := []workqueue.RateLimitingInterface{
 		reportQueue,
 		reportDataSourceQueue,
 		reportGenerationQueryQueue,
 		prestoTableQueue,
+		reportRerunQueue,
+		rateCardQueue,
+		reportNotificationQueue,
+		reportExportQueue,
 	}
 
 	op := &Reporting{
@@ -279,6 +380,9 @@ func newReportingOperator(
 		reportGenerationQueryLister: reportGenerationQueryInformer.Lister(),
 		reportPrometheusQueryLister: reportPrometheusQueryInformer.Lister(),
 		reportLister:                reportInformer.Lister(),
+		reportRerunLister:           reportRerunInformer.Lister(),
+		rateCardLister:              rateCardInformer.Lister(),
+		reportNotificationLister:    reportNotificationInformer.Lister(),
 		storageLocationLister:       storageLocationInformer.Lister(),
 
 		queueList:                  queueList,
@@ -286,12 +390,37 @@ func newReportingOperator(
 		reportDataSourceQueue:      reportDataSourceQueue,
 		reportGenerationQueryQueue: reportGenerationQueryQueue,
 		prestoTableQueue:           prestoTableQueue,
+		reportRerunQueue:           reportRerunQueue,
+		rateCardQueue:              rateCardQueue,
+		reportNotificationQueue:    reportNotificationQueue,
+		reportExportQueue:          reportExportQueue,
 
 		rand:      rand,
 		clock:     clock,
 		importers: make(map[string]*prestostore.PrometheusImporter),
+
+		remoteWriteStatuses:    make(map[string]*remoteWriteImportStatus),
+		remoteWriteStoredSteps: make(map[string]map[string]time.Time),
+
+		pendingReportExportStatuses: make(map[string][]cbTypes.ReportExportStatus),
 	}
 
+	// events are recorded from the start, but only sent to the API once Run
+	// starts recording them to a sink.
+	op.eventBroadcaster = record.NewBroadcaster()
+	op.eventRecorder = newRateLimitedEventRecorder(
+		op.eventBroadcaster.NewRecorder(eventScheme, v1.EventSource{Component: "reporting-operator", Host: cfg.Hostname}),
+		clock, eventRateLimitInterval,
+	)
+
+	// the webhook only validates resources in the namespaces our informers
+	// are watching, since those are the only ones the listers know about.
+	op.watchedNamespaces = cfg.TargetNamespaces
+	if informerNamespace != metav1.NamespaceAll {
+		op.watchedNamespaces = []string{informerNamespace}
+	}
+	op.webhookValidator = newWebhookValidator(logger, op.reportGenerationQueryLister, op.reportLister, op.watchedNamespaces, cfg.ExportAllowedHosts)
+
 	// all eventHandlers are wrapped in an
 	// inTargetNamespaceResourceEventHandler which verifies the resources
 	// passed to the eventHandler functions have a metadata.namespace contained
@@ -313,6 +442,7 @@ func newReportingOperator(
 	reportGenerationQueryInformer.Informer().AddEventHandler(newInTargetNamespaceEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    op.addReportGenerationQuery,
 		UpdateFunc: op.updateReportGenerationQuery,
+		DeleteFunc: op.deleteReportGenerationQuery,
 	}, op.cfg.TargetNamespaces))
 
 	prestoTableInformer.Informer().AddEventHandler(newInTargetNamespaceEventHandler(cache.ResourceEventHandlerFuncs{
@@ -321,13 +451,23 @@ func newReportingOperator(
 		DeleteFunc: op.deletePrestoTable,
 	}, op.cfg.TargetNamespaces))
 
+	reportRerunInformer.Informer().AddEventHandler(newInTargetNamespaceEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    op.addReportRerun,
+		UpdateFunc: op.updateReportRerun,
+	}, op.cfg.TargetNamespaces))
+
+	rateCardInformer.Informer().AddEventHandler(newInTargetNamespaceEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    op.addRateCard,
+		UpdateFunc: op.updateRateCard,
+	}, op.cfg.TargetNamespaces))
+
 	return op
 }
 
 func (op *Reporting) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
 	// buffered big enough to hold the errs of each server we start.
-	srvErrChan := make(chan error, 3)
+	srvErrChan := make(chan error, 4)
 
 	op.logger.Info("starting Metering operator")
 
@@ -421,8 +561,10 @@ func (op *Reporting) Run(ctx context.Context) error {
 	}
 	op.reportResultsRepo = prestostore.NewReportResultsRepo(prestoQueryer)
 	op.reportGenerator = reporting.NewReportGenerator(op.logger, op.reportResultsRepo)
-	op.prometheusMetricsRepo = prestostore.NewPrometheusMetricsRepo(prestoQueryer, prestoQueryBufferPool)
-	op.prestoViewCreator = &prestoViewCreator{queryer: prestoQueryer}
+	op.prometheusMetricsRepo = prestostore.NewPrometheusMetricsRepo(prestoQueryer, prestoQueryBufferPool, op.clock)
+	op.rateCardRepo = prestostore.NewRateCardRepo(prestoQueryer)
+	op.prestoViewManager = &prestoViewManager{queryer: prestoQueryer}
+	op.prestoSchemaLister = &prestoSchemaLister{queryer: prestoQueryer}
 
 	hiveTableManager := reporting.NewHiveTableManager(hiveQueryer)
 	op.tableManager = hiveTableManager
@@ -455,8 +597,8 @@ func (op *Reporting) Run(ctx context.Context) error {
 
 	op.logger.Infof("starting HTTP server")
 	apiRouter := newRouter(
-		op.logger, op.rand, op.prometheusMetricsRepo, op.reportResultsRepo, op.importPrometheusForTimeRange,
-		op.reportLister, op.reportGenerationQueryLister, op.prestoTableLister,
+		op.logger, op.rand, op.prometheusMetricsRepo, op.reportResultsRepo, op.reportResultsRepo, op.importPrometheusForTimeRange, op.storeRemoteWriteSeries,
+		op.reportLister, op.reportGenerationQueryLister, op.reportDataSourceLister, op.prestoTableLister, op.rateCardLister,
 	)
 	apiRouter.HandleFunc("/ready", op.readinessHandler)
 	apiRouter.HandleFunc("/healthy", op.healthinessHandler)
@@ -482,6 +624,23 @@ func (op *Reporting) Run(ctx context.Context) error {
 		srvErrChan <- fmt.Errorf("HTTP API server error: %v", srvErr)
 	}()
 
+	var webhookServer *http.Server
+	if op.cfg.EnableWebhook {
+		webhookServer = &http.Server{
+			Addr:    op.cfg.WebhookListen,
+			Handler: newWebhookRouter(op.webhookValidator),
+		}
+		// start the validating admission webhook server
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op.logger.Infof("validating webhook server listening with TLS on %s", op.cfg.WebhookListen)
+			srvErr := webhookServer.ListenAndServeTLS(op.cfg.WebhookTLSConfig.TLSCert, op.cfg.WebhookTLSConfig.TLSKey)
+			op.logger.WithError(srvErr).Info("validating webhook server exited")
+			srvErrChan <- fmt.Errorf("validating webhook server error: %v", srvErr)
+		}()
+	}
+
 	if op.cfg.DisableWriteHealthCheck {
 		op.logger.Info("configured to skip checking ability to write to presto")
 	} else {
@@ -502,10 +661,11 @@ func (op *Reporting) Run(ctx context.Context) error {
 	op.logger.Info("basic initialization completed")
 	op.setInitialized()
 
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(op.logger.Infof)
-	eventBroadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: op.kubeClient.Events(op.cfg.OwnNamespace)})
-	eventRecorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: op.cfg.Hostname})
+	op.eventBroadcaster.StartLogging(op.logger.Infof)
+	// events are created in the namespace of the object they're about, which
+	// may be any of the target namespaces.
+	op.eventBroadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: op.kubeClient.Events(metav1.NamespaceAll)})
+	eventRecorder := op.eventBroadcaster.NewRecorder(eventScheme, v1.EventSource{Component: op.cfg.Hostname})
 
 	rl, err := resourcelock.New(resourcelock.ConfigMapsResourceLock,
 		op.cfg.OwnNamespace, "reporting-operator-leader-lease", op.kubeClient,
@@ -575,6 +735,17 @@ func (op *Reporting) Run(ctx context.Context) error {
 		}
 		wg.Done()
 	}()
+	if webhookServer != nil {
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("stopping validating webhook server")
+			err := webhookServer.Shutdown(context.TODO())
+			if err != nil {
+				op.logger.WithError(err).Warnf("got an error shutting down validating webhook server")
+			}
+			wg.Done()
+		}()
+	}
 	go func() {
 		op.logger.Infof("stopping Prometheus metrics server")
 		err := promServer.Shutdown(context.TODO())
@@ -679,6 +850,68 @@ func (op *Reporting) startWorkers(wg sync.WaitGroup, ctx context.Context) {
 			wg.Done()
 			op.logger.Infof("Report worker #%d stopped", i)
 		}()
+
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting ReportRerun worker #%d", i)
+			wait.Until(op.runReportRerunWorker, time.Second, stopCh)
+			wg.Done()
+			op.logger.Infof("ReportRerun worker #%d stopped", i)
+		}()
+
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting RateCard worker #%d", i)
+			wait.Until(op.runRateCardWorker, time.Second, stopCh)
+			wg.Done()
+			op.logger.Infof("RateCard worker #%d stopped", i)
+		}()
+
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting ReportNotification worker #%d", i)
+			wait.Until(op.runReportNotificationWorker, time.Second, stopCh)
+			wg.Done()
+			op.logger.Infof("ReportNotification worker #%d stopped", i)
+		}()
+
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting ReportExport worker #%d", i)
+			wait.Until(op.runReportExportWorker, time.Second, stopCh)
+			wg.Done()
+			op.logger.Infof("ReportExport worker #%d stopped", i)
+		}()
+	}
+
+	if op.cfg.RetentionSweepInterval > 0 {
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting retention sweeper")
+			wait.Until(op.runRetentionSweeper, op.cfg.RetentionSweepInterval, stopCh)
+			wg.Done()
+			op.logger.Infof("retention sweeper stopped")
+		}()
+	}
+
+	if op.cfg.ViewSweepInterval > 0 {
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting view sweeper")
+			wait.Until(op.runViewSweeper, op.cfg.ViewSweepInterval, stopCh)
+			wg.Done()
+			op.logger.Infof("view sweeper stopped")
+		}()
+	}
+
+	if op.cfg.OrphanedTableSweepInterval > 0 {
+		wg.Add(1)
+		go func() {
+			op.logger.Infof("starting orphaned table sweeper")
+			wait.Until(op.runOrphanedTableSweeper, op.cfg.OrphanedTableSweepInterval, stopCh)
+			wg.Done()
+			op.logger.Infof("orphaned table sweeper stopped")
+		}()
 	}
 }
 
@@ -0,0 +1,276 @@
+package operator
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/db"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+var (
+	orphanedTablesGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "orphaned_tables",
+			Help:      "Number of tables without a Report, ReportDataSource, RateCard or PrestoTable found by the last orphaned table sweep.",
+		},
+	)
+
+	orphanedTablesDroppedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "orphaned_tables_dropped_total",
+			Help:      "Number of orphaned tables dropped by the orphaned table sweeper.",
+		},
+	)
+
+	orphanedTableSweepFailuresCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "orphaned_table_sweep_failures_total",
+			Help:      "Number of times the orphaned table sweeper failed to list or drop tables.",
+		},
+	)
+
+	orphanedTableSweepLastTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "orphaned_table_sweep_last_timestamp_seconds",
+			Help:      "Unix timestamp of when the last orphaned table sweep finished.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(orphanedTablesGauge)
+	prometheus.MustRegister(orphanedTablesDroppedCounter)
+	prometheus.MustRegister(orphanedTableSweepFailuresCounter)
+	prometheus.MustRegister(orphanedTableSweepLastTimestampGauge)
+}
+
+// ownedTableNames returns the names of the tables belonging to resources. Both
+// the names recorded in their status and the names they're created with are
+// included, since tables are created before their name is recorded.
+func ownedTableNames(reports []*cbTypes.Report, dataSources []*cbTypes.ReportDataSource, rateCards []*cbTypes.RateCard, prestoTables []*cbTypes.PrestoTable) map[string]bool {
+	owned := make(map[string]bool)
+	add := func(tableName string) {
+		if tableName != "" {
+			owned[tableName] = true
+		}
+	}
+	for _, report := range reports {
+		add(report.Status.TableName)
+		add(reportingutil.ReportTableName(report.Namespace, report.Name))
+	}
+	for _, dataSource := range dataSources {
+		add(dataSource.Status.TableName)
+		add(reportingutil.DataSourceTableName(dataSource.Namespace, dataSource.Name))
+		for _, rollup := range dataSource.Status.Rollups {
+			add(rollup.TableName)
+		}
+		for granularity := range reportingutil.PrometheusMetricsRollupPeriods {
+			add(reportingutil.DataSourceRollupTableName(dataSource.Namespace, dataSource.Name, string(granularity)))
+		}
+	}
+	for _, rateCard := range rateCards {
+		add(rateCard.Status.TableName)
+		add(reportingutil.RateCardTableName(rateCard.Namespace, rateCard.Name))
+	}
+	for _, prestoTable := range prestoTables {
+		add(prestoTable.Status.Parameters.Name)
+	}
+	return owned
+}
+
+// namespacedTableName describes the names of the tables of a kind of
+// resource, which start with prefix, followed by the resource's namespace
+// and name. nameParts is the minimum number of '_' separated parts
+// following the namespace.
+type namespacedTableName struct {
+	prefix    string
+	nameParts int
+}
+
+// namespacedTableNames are the names of the tables of Reports,
+// ReportDataSources, RateCards, and the staging tables of Reports and
+// RateCards, which also have a random suffix after the resource's name.
+func namespacedTableNames() []namespacedTableName {
+	names := []namespacedTableName{
+		{prefix: "report_", nameParts: 1},
+		{prefix: reportingutil.ReportStagingTablePrefix + "report_", nameParts: 2},
+		{prefix: "datasource_", nameParts: 1},
+		{prefix: "ratecard_", nameParts: 1},
+		{prefix: reportingutil.ReportStagingTablePrefix + "ratecard_", nameParts: 2},
+	}
+	for granularity := range reportingutil.PrometheusMetricsRollupPeriods {
+		names = append(names, namespacedTableName{prefix: "rollup_" + string(granularity) + "_", nameParts: 1})
+	}
+	return names
+}
+
+// inNamespaces returns true if the namespace and name following the prefix
+// of a table or view name can only belong to one of namespaces. The '-' and
+// '.' in namespaces and names are replaced with '_', so
+// report_metering_dev_usage could be the table of the Report dev-usage in
+// metering, or of the Report usage in metering-dev. Names like this are
+// only in namespaces if every namespace they could belong to is, so the
+// tables of unwatched namespaces are never dropped.
+func inNamespaces(namespacedName string, nameParts int, namespaces map[string]bool) bool {
+	parts := strings.Split(namespacedName, "_")
+	if len(parts) <= nameParts {
+		return false
+	}
+	for i := 1; i <= len(parts)-nameParts; i++ {
+		if !namespaces[strings.Join(parts[:i], "_")] {
+			return false
+		}
+	}
+	return true
+}
+
+// tableNameNamespaces returns namespaces the way they appear in the names of
+// tables and views.
+func tableNameNamespaces(namespaces []string) map[string]bool {
+	tableNamespaces := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		tableNamespaces[reportingutil.TableNameNamespace(ns)] = true
+	}
+	return tableNamespaces
+}
+
+// orphanedTables returns the tables which are named like the tables of
+// resources in namespaces, but aren't in owned. If namespaces is empty,
+// tables of resources in any namespace are considered. Staging tables are
+// never owned, so ones left behind by failed runs are dropped too.
+func orphanedTables(tables []string, owned map[string]bool, namespaces []string) []string {
+	tableNamespaces := tableNameNamespaces(namespaces)
+	names := namespacedTableNames()
+
+	var orphaned []string
+	for _, table := range tables {
+		if owned[table] {
+			continue
+		}
+		for _, name := range names {
+			if !strings.HasPrefix(table, name.prefix) {
+				continue
+			}
+			if len(namespaces) == 0 || inNamespaces(strings.TrimPrefix(table, name.prefix), name.nameParts, tableNamespaces) {
+				orphaned = append(orphaned, table)
+			}
+			break
+		}
+	}
+	return orphaned
+}
+
+// runOrphanedTableSweeper drops tables left behind by Reports,
+// ReportDataSources, RateCards and PrestoTables which were deleted without
+// their tables being dropped, once they've been orphaned for the grace
+// period.
+func (op *Reporting) runOrphanedTableSweeper() {
+	logger := op.logger.WithField("component", "orphanedTableSweeper")
+
+	owned, err := op.listOwnedTableNames()
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list resources owning tables")
+		orphanedTableSweepFailuresCounter.Inc()
+		return
+	}
+	tables, err := op.prestoSchemaLister.ListTables()
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list tables")
+		orphanedTableSweepFailuresCounter.Inc()
+		return
+	}
+
+	now := op.clock.Now()
+	orphaned := orphanedTables(tables, owned, op.watchedNamespaces)
+	orphanedTablesGauge.Set(float64(len(orphaned)))
+
+	firstSeen := make(map[string]time.Time, len(orphaned))
+	for _, table := range orphaned {
+		seen, ok := op.orphanedTablesFirstSeen[table]
+		if !ok {
+			seen = now
+			logger.Warnf("found orphaned table %s", table)
+			op.recordOperatorEvent(v1.EventTypeWarning, orphanedTableFoundEventReason, "Found table %s without a Report, ReportDataSource, RateCard or PrestoTable", table)
+		}
+		firstSeen[table] = seen
+
+		if now.Sub(seen) < op.cfg.OrphanedTableGracePeriod {
+			continue
+		}
+		if op.cfg.OrphanedTableDryRun {
+			logger.Infof("dry run: would drop orphaned table %s", table)
+			continue
+		}
+		logger.Infof("dropping orphaned table %s", table)
+		if err := op.tableManager.DropTable(table, true); err != nil {
+			logger.WithError(err).Errorf("unable to drop orphaned table %s", table)
+			op.recordOperatorEvent(v1.EventTypeWarning, tableDropFailedEventReason, "Failed to drop orphaned table %s: %v", table, err)
+			orphanedTableSweepFailuresCounter.Inc()
+			continue
+		}
+		delete(firstSeen, table)
+		orphanedTablesDroppedCounter.Inc()
+		op.recordOperatorEvent(v1.EventTypeNormal, orphanedTableDroppedEventReason, "Dropped orphaned table %s", table)
+	}
+	// tables which are no longer orphaned, or were dropped, are forgotten
+	op.orphanedTablesFirstSeen = firstSeen
+	orphanedTableSweepLastTimestampGauge.Set(float64(op.clock.Now().Unix()))
+}
+
+func (op *Reporting) listOwnedTableNames() (map[string]bool, error) {
+	reports, err := op.reportLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	dataSources, err := op.reportDataSourceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	rateCards, err := op.rateCardLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	prestoTables, err := op.prestoTableLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return ownedTableNames(reports, dataSources, rateCards, prestoTables), nil
+}
+
+// recordOperatorEvent records an Event for the reporting-operator's Pod, for
+// things which don't belong to any metering resource.
+func (op *Reporting) recordOperatorEvent(eventtype, reason, messageFmt string, args ...interface{}) {
+	if op.cfg.OwnNamespace == "" || op.cfg.Hostname == "" {
+		return
+	}
+	pod := &v1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Namespace:  op.cfg.OwnNamespace,
+		Name:       op.cfg.Hostname,
+	}
+	op.eventRecorder.Eventf(pod, eventtype, reason, messageFmt, args...)
+}
+
+type PrestoSchemaLister interface {
+	ListTables() ([]string, error)
+}
+
+type prestoSchemaLister struct {
+	queryer db.Queryer
+}
+
+func (l *prestoSchemaLister) ListTables() ([]string, error) {
+	return presto.ListTables(l.queryer, prestoSchema)
+}
@@ -0,0 +1,73 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestOrphanedTables(t *testing.T) {
+	// the table is created, but not yet recorded in the status
+	report := testhelpers.NewReport("pending-table", "metering", "test-query", nil, nil, cbTypes.ReportStatus{})
+	dataSource := &cbTypes.ReportDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-cpu", Namespace: "metering"},
+		Status:     cbTypes.ReportDataSourceStatus{TableName: "datasource_metering_node_cpu"},
+	}
+	rateCard := &cbTypes.RateCard{
+		ObjectMeta: metav1.ObjectMeta{Name: "rates", Namespace: "metering"},
+		Status:     cbTypes.RateCardStatus{TableName: "ratecard_metering_rates"},
+	}
+	prestoTable := &cbTypes.PrestoTable{
+		ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: "metering"},
+		Status: cbTypes.PrestoTableStatus{
+			Parameters: cbTypes.TableParameters{Name: "report_metering_custom"},
+		},
+	}
+	owned := ownedTableNames([]*cbTypes.Report{report}, []*cbTypes.ReportDataSource{dataSource}, []*cbTypes.RateCard{rateCard}, []*cbTypes.PrestoTable{prestoTable})
+
+	tables := []string{
+		"report_metering_pending_table",
+		"datasource_metering_node_cpu",
+		"rollup_hourly_metering_node_cpu",
+		"ratecard_metering_rates",
+		"report_metering_custom",
+		"report_metering_deleted",
+		"staging_report_metering_pending_table_x7k2m9qa",
+		"staging_ratecard_metering_rates_p3n8w1zd",
+		"rollup_daily_metering_deleted",
+		"datasource_other_deleted",
+		// could belong to metering-dev
+		"report_metering_dev_usage",
+		"health_check",
+	}
+
+	tests := map[string]struct {
+		namespaces []string
+		expected   []string
+	}{
+		"all namespaces": {
+			expected: []string{"report_metering_deleted", "staging_report_metering_pending_table_x7k2m9qa", "staging_ratecard_metering_rates_p3n8w1zd", "rollup_daily_metering_deleted", "datasource_other_deleted", "report_metering_dev_usage"},
+		},
+		"watched namespaces": {
+			namespaces: []string{"metering"},
+			expected:   []string{"report_metering_deleted", "staging_ratecard_metering_rates_p3n8w1zd", "rollup_daily_metering_deleted"},
+		},
+		"watched namespaces with the same prefix": {
+			namespaces: []string{"metering", "metering-dev", "metering-pending"},
+			expected:   []string{"report_metering_deleted", "staging_report_metering_pending_table_x7k2m9qa", "staging_ratecard_metering_rates_p3n8w1zd", "rollup_daily_metering_deleted", "report_metering_dev_usage"},
+		},
+		"unwatched namespaces": {
+			namespaces: []string{"metering-dev"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, orphanedTables(tables, owned, tt.namespaces))
+		})
+	}
+}
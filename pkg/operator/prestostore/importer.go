@@ -10,6 +10,8 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/clock"
+
+	"github.com/operator-framework/operator-metering/pkg/promrelabel"
 )
 
 type ImporterMetricsCollectors struct {
@@ -50,6 +52,18 @@ type PrometheusImporter struct {
 	metricsCollectors ImporterMetricsCollectors
 }
 
+// PrometheusImportCheckpointer persists the end of the last time range
+// successfully imported by a PrometheusImporter, so that importing can resume
+// from it without querying the table for the last timestamp.
+type PrometheusImportCheckpointer interface {
+	// GetImportCheckpoint returns the end of the last time range imported,
+	// or nil if there is no checkpoint.
+	GetImportCheckpoint() (*time.Time, error)
+	// StoreImportCheckpoint is called after each time range has been
+	// imported.
+	StoreImportCheckpoint(timeRange prom.Range) error
+}
+
 type Config struct {
 	PrometheusQuery           string
 	PrestoTableName           string
@@ -59,6 +73,9 @@ type Config struct {
 	MaxQueryRangeDuration     time.Duration
 	ImportFromTime            *time.Time
 	MaxBackfillImportDuration time.Duration
+	// RelabelConfigs are applied to the labels of each series before it's
+	// stored.
+	RelabelConfigs []*promrelabel.Config
 }
 
 func NewPrometheusImporter(logger logrus.FieldLogger, promConn prom.API, prometheusMetricsRepo PrometheusMetricsRepo, clock clock.Clock, cfg Config, collectors ImporterMetricsCollectors) *PrometheusImporter {
@@ -93,9 +110,12 @@ func (importer *PrometheusImporter) UpdateConfig(cfg Config) {
 // queried and stores the results in a Presto table.
 // The importer will track the last time series it retrieved and will query
 // the next time range starting from where it left off if paused or stopped.
+// If checkpointer is non-nil, the end of each time range imported is stored
+// in it, and when the importer doesn't know where it left off, it resumes from
+// the checkpoint instead of querying the table for the last timestamp.
 // For more details on how querying Prometheus is done, see the package
 // pkg/promquery.
-func (importer *PrometheusImporter) ImportFromLastTimestamp(ctx context.Context, allowIncompleteChunks bool) (*PrometheusImportResults, error) {
+func (importer *PrometheusImporter) ImportFromLastTimestamp(ctx context.Context, allowIncompleteChunks bool, checkpointer PrometheusImportCheckpointer) (*PrometheusImportResults, error) {
 	importer.importLock.Lock()
 	importer.logger.Debugf("PrometheusImporter ImportFromLastTimestamp started")
 	defer importer.logger.Debugf("PrometheusImporter ImportFromLastTimestamp finished")
@@ -107,8 +127,22 @@ func (importer *PrometheusImporter) ImportFromLastTimestamp(ctx context.Context,
 
 	// if importer.lastTimestamp is null then it's because we haven't run
 	// before, we have been restarted (error, or not) and do not know the
-	// last time we collected and need to re-query Presto to figure out
-	// the last timestamp
+	// last time we collected. Use the checkpoint if there is one, since
+	// querying Presto for the last timestamp can be slow on large tables.
+	if importer.lastTimestamp == nil && checkpointer != nil {
+		var err error
+		importer.lastTimestamp, err = checkpointer.GetImportCheckpoint()
+		if err != nil {
+			importer.logger.WithError(err).Errorf("unable to get import checkpoint for table %s", cfg.PrestoTableName)
+			return nil, err
+		}
+		if importer.lastTimestamp != nil {
+			importer.logger.Debugf("lastTimestamp for table %s: using import checkpoint %s", cfg.PrestoTableName, importer.lastTimestamp.String())
+		}
+	}
+
+	// if there's still no lastTimestamp, we need to re-query Presto to figure
+	// out the last timestamp
 	if importer.lastTimestamp == nil {
 		var err error
 		importer.logger.Debugf("lastTimestamp for table %s: isn't known, querying for timestamp", cfg.PrestoTableName)
@@ -151,7 +185,19 @@ func (importer *PrometheusImporter) ImportFromLastTimestamp(ctx context.Context,
 		endTime = startTime.Add(cfg.MaxQueryRangeDuration)
 	}
 
-	importResults, err := ImportFromTimeRange(importer.logger, importer.clock, importer.promConn, importer.prometheusMetricsRepo, importer.metricsCollectors, ctx, startTime, endTime, cfg, allowIncompleteChunks)
+	var checkpoint func(prom.Range)
+	if checkpointer != nil {
+		checkpoint = func(timeRange prom.Range) {
+			// The metrics are already stored, so importing continues even if
+			// the checkpoint can't be stored. At worst the time range is
+			// imported again after a restart.
+			if err := checkpointer.StoreImportCheckpoint(timeRange); err != nil {
+				importer.logger.WithError(err).Errorf("unable to store import checkpoint %s for table %s", timeRange.End, cfg.PrestoTableName)
+			}
+		}
+	}
+
+	importResults, err := importFromTimeRange(importer.logger, importer.clock, importer.promConn, importer.prometheusMetricsRepo, importer.metricsCollectors, ctx, startTime, endTime, cfg, allowIncompleteChunks, checkpoint)
 	if err != nil {
 		importer.logger.WithError(err).Error("error collecting metrics")
 		// at this point we cannot be sure what is in Presto and what
@@ -167,7 +213,30 @@ func (importer *PrometheusImporter) ImportFromLastTimestamp(ctx context.Context,
 	return &importResults, nil
 }
 
-func promMatrixToPrometheusMetrics(timeRange prom.Range, matrix model.Matrix) []*PrometheusMetric {
+// ImportFromTimeRange executes a Presto query for the time range between start
+// and end and stores the results in a Presto table. Unlike
+// ImportFromLastTimestamp, it doesn't affect where the next
+// ImportFromLastTimestamp starts from, which makes it suitable for
+// re-importing older time ranges.
+func (importer *PrometheusImporter) ImportFromTimeRange(ctx context.Context, startTime, endTime time.Time, allowIncompleteChunks bool) (*PrometheusImportResults, error) {
+	importer.importLock.Lock()
+	importer.logger.Debugf("PrometheusImporter ImportFromTimeRange started")
+	defer importer.logger.Debugf("PrometheusImporter ImportFromTimeRange finished")
+	defer importer.importLock.Unlock()
+
+	importResults, err := ImportFromTimeRange(importer.logger, importer.clock, importer.promConn, importer.prometheusMetricsRepo, importer.metricsCollectors, ctx, startTime, endTime, importer.cfg, allowIncompleteChunks)
+	if err != nil {
+		importer.logger.WithError(err).Error("error collecting metrics")
+		return &importResults, err
+	}
+	return &importResults, nil
+}
+
+// promMatrixToPrometheusMetrics converts a query result into
+// PrometheusMetrics, applying relabelConfigs to the labels of each series and
+// skipping the series they drop. Series which end up with the same labels
+// are summed.
+func promMatrixToPrometheusMetrics(timeRange prom.Range, matrix model.Matrix, relabelConfigs []*promrelabel.Config) []*PrometheusMetric {
 	var metrics []*PrometheusMetric
 	// iterate over segments of contiguous billing metrics
 	for _, sampleStream := range matrix {
@@ -175,6 +244,10 @@ func promMatrixToPrometheusMetrics(timeRange prom.Range, matrix model.Matrix) []
 		for k, v := range sampleStream.Metric {
 			labels[string(k)] = string(v)
 		}
+		labels = promrelabel.Process(labels, relabelConfigs)
+		if labels == nil {
+			continue
+		}
 		for _, value := range sampleStream.Values {
 			metric := &PrometheusMetric{
 				Labels:    labels,
@@ -185,5 +258,10 @@ func promMatrixToPrometheusMetrics(timeRange prom.Range, matrix model.Matrix) []
 			metrics = append(metrics, metric)
 		}
 	}
+	if len(relabelConfigs) != 0 {
+		// the series in a query result have distinct labels, so only
+		// relabeling can make them the same
+		metrics = SumPrometheusMetrics(metrics)
+	}
 	return metrics
 }
@@ -0,0 +1,247 @@
+package prestostore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"github.com/operator-framework/operator-metering/pkg/promrelabel"
+)
+
+type fakePromAPI struct {
+	queriedRanges []prom.Range
+}
+
+func (f *fakePromAPI) Query(ctx context.Context, query string, ts time.Time) (model.Value, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakePromAPI) QueryRange(ctx context.Context, query string, r prom.Range) (model.Value, error) {
+	f.queriedRanges = append(f.queriedRanges, r)
+	return model.Matrix{
+		&model.SampleStream{
+			Metric: model.Metric{"pod": "pod-1"},
+			Values: []model.SamplePair{{Timestamp: model.TimeFromUnixNano(r.Start.UnixNano()), Value: 1}},
+		},
+	}, nil
+}
+
+func (f *fakePromAPI) LabelValues(ctx context.Context, label string) (model.LabelValues, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+type fakePrometheusMetricsRepo struct {
+	lastTimestamp        *time.Time
+	lastTimestampQueries int
+	storedMetrics        []*PrometheusMetric
+}
+
+func (f *fakePrometheusMetricsRepo) StorePrometheusMetrics(ctx context.Context, tableName string, metrics []*PrometheusMetric) error {
+	f.storedMetrics = append(f.storedMetrics, metrics...)
+	return nil
+}
+
+func (f *fakePrometheusMetricsRepo) GetPrometheusMetrics(tableName string, start, end time.Time) ([]*PrometheusMetric, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakePrometheusMetricsRepo) GetLastTimestampForTable(tableName string) (*time.Time, error) {
+	f.lastTimestampQueries++
+	return f.lastTimestamp, nil
+}
+
+func (f *fakePrometheusMetricsRepo) GetTimestampGapsForTable(tableName string, start, end time.Time, stepSize time.Duration) ([]prom.Range, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakePrometheusMetricsRepo) GetTimestampPartitionsForTable(tableName string) ([]string, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakePrometheusMetricsRepo) StorePrometheusMetricsRollup(tableName, sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) error {
+	return fmt.Errorf("not implemented")
+}
+
+type fakeImportCheckpointer struct {
+	checkpoint *time.Time
+	stored     []prom.Range
+}
+
+func (f *fakeImportCheckpointer) GetImportCheckpoint() (*time.Time, error) {
+	return f.checkpoint, nil
+}
+
+func (f *fakeImportCheckpointer) StoreImportCheckpoint(timeRange prom.Range) error {
+	f.stored = append(f.stored, timeRange)
+	end := timeRange.End
+	f.checkpoint = &end
+	return nil
+}
+
+func newTestImporterMetricsCollectors() ImporterMetricsCollectors {
+	return ImporterMetricsCollectors{
+		TotalImportsCounter:              prometheus.NewCounter(prometheus.CounterOpts{Name: "total_imports"}),
+		FailedImportsCounter:             prometheus.NewCounter(prometheus.CounterOpts{Name: "failed_imports"}),
+		ImportDurationHistogram:          prometheus.NewHistogram(prometheus.HistogramOpts{Name: "import_duration"}),
+		TotalPrometheusQueriesCounter:    prometheus.NewCounter(prometheus.CounterOpts{Name: "total_queries"}),
+		FailedPrometheusQueriesCounter:   prometheus.NewCounter(prometheus.CounterOpts{Name: "failed_queries"}),
+		PrometheusQueryDurationHistogram: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "query_duration"}),
+		TotalPrestoStoresCounter:         prometheus.NewCounter(prometheus.CounterOpts{Name: "total_stores"}),
+		FailedPrestoStoresCounter:        prometheus.NewCounter(prometheus.CounterOpts{Name: "failed_stores"}),
+		PrestoStoreDurationHistogram:     prometheus.NewHistogram(prometheus.HistogramOpts{Name: "store_duration"}),
+		MetricsScrapedCounter:            prometheus.NewCounter(prometheus.CounterOpts{Name: "metrics_scraped"}),
+		MetricsImportedCounter:           prometheus.NewCounter(prometheus.CounterOpts{Name: "metrics_imported"}),
+		ImportsRunningGauge:              prometheus.NewGauge(prometheus.GaugeOpts{Name: "imports_running"}),
+	}
+}
+
+func TestImportFromLastTimestampCheckpoints(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	cfg := Config{
+		PrestoTableName:       "datasource_test",
+		ChunkSize:             5 * time.Minute,
+		StepSize:              time.Minute,
+		MaxQueryRangeDuration: 10 * time.Minute,
+	}
+	tableLastTimestamp := janOne.Add(-time.Hour)
+
+	tests := map[string]struct {
+		checkpointer          *fakeImportCheckpointer
+		expectedStart         time.Time
+		expectedTableQueries  int
+		expectedCheckpointEnd *time.Time
+	}{
+		"checkpoint is used instead of querying the table": {
+			checkpointer:          &fakeImportCheckpointer{checkpoint: &janOne},
+			expectedStart:         janOne.Add(time.Minute),
+			expectedTableQueries:  0,
+			expectedCheckpointEnd: func() *time.Time { t := janOne.Add(11 * time.Minute); return &t }(),
+		},
+		"table is queried without a checkpoint": {
+			checkpointer:          &fakeImportCheckpointer{},
+			expectedStart:         tableLastTimestamp.Add(time.Minute),
+			expectedTableQueries:  1,
+			expectedCheckpointEnd: func() *time.Time { t := tableLastTimestamp.Add(11 * time.Minute); return &t }(),
+		},
+		"table is queried without a checkpointer": {
+			expectedStart:        tableLastTimestamp.Add(time.Minute),
+			expectedTableQueries: 1,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			promConn := &fakePromAPI{}
+			repo := &fakePrometheusMetricsRepo{lastTimestamp: &tableLastTimestamp}
+			fakeClock := clock.NewFakeClock(janOne.Add(time.Hour))
+			importer := NewPrometheusImporter(logrus.New(), promConn, repo, fakeClock, cfg, newTestImporterMetricsCollectors())
+
+			var checkpointer PrometheusImportCheckpointer
+			if test.checkpointer != nil {
+				checkpointer = test.checkpointer
+			}
+			results, err := importer.ImportFromLastTimestamp(context.Background(), true, checkpointer)
+			require.NoError(t, err)
+			require.NotEmpty(t, results.ProcessedTimeRanges)
+
+			assert.Equal(t, test.expectedTableQueries, repo.lastTimestampQueries)
+			assert.Equal(t, test.expectedStart, promConn.queriedRanges[0].Start)
+			if test.checkpointer != nil {
+				assert.Equal(t, results.ProcessedTimeRanges, test.checkpointer.stored, "expected every time range imported to be checkpointed")
+				assert.Equal(t, test.expectedCheckpointEnd, test.checkpointer.checkpoint)
+			}
+
+			// the importer continues from where it left off, without
+			// needing the checkpoint or table again
+			repo.lastTimestampQueries = 0
+			promConn.queriedRanges = nil
+			fakeClock.Step(time.Hour)
+			_, err = importer.ImportFromLastTimestamp(context.Background(), true, checkpointer)
+			require.NoError(t, err)
+			assert.Equal(t, 0, repo.lastTimestampQueries)
+			assert.Equal(t, results.ProcessedTimeRanges[len(results.ProcessedTimeRanges)-1].End.Add(time.Minute), promConn.queriedRanges[0].Start)
+		})
+	}
+}
+
+func TestPromMatrixToPrometheusMetricsRelabels(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	timeRange := prom.Range{Start: janOne, End: janOne.Add(time.Minute), Step: time.Minute}
+	matrix := model.Matrix{
+		&model.SampleStream{
+			Metric: model.Metric{"namespace": "default", "pod": "pod-1", "uid": "123"},
+			Values: []model.SamplePair{{Timestamp: model.TimeFromUnixNano(janOne.UnixNano()), Value: 1}},
+		},
+		&model.SampleStream{
+			Metric: model.Metric{"namespace": "kube-system", "pod": "pod-2", "uid": "456"},
+			Values: []model.SamplePair{{Timestamp: model.TimeFromUnixNano(janOne.UnixNano()), Value: 2}},
+		},
+	}
+	dropRegex, err := promrelabel.NewRegexp("kube-system")
+	require.NoError(t, err)
+	labelDropRegex, err := promrelabel.NewRegexp("uid")
+	require.NoError(t, err)
+	relabelConfigs := []*promrelabel.Config{
+		{SourceLabels: []string{"namespace"}, Separator: promrelabel.DefaultSeparator, Regex: dropRegex, Action: promrelabel.Drop},
+		{Regex: labelDropRegex, Action: promrelabel.LabelDrop},
+	}
+
+	metrics := promMatrixToPrometheusMetrics(timeRange, matrix, relabelConfigs)
+	expected := []*PrometheusMetric{
+		{
+			Labels:    map[string]string{"namespace": "default", "pod": "pod-1"},
+			Amount:    1,
+			StepSize:  time.Minute,
+			Timestamp: janOne,
+		},
+	}
+	assert.Equal(t, expected, metrics)
+}
+
+func TestPromMatrixToPrometheusMetricsRelabelCollisions(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	timeRange := prom.Range{Start: janOne, End: janOne.Add(time.Minute), Step: time.Minute}
+	values := func(amounts ...float64) []model.SamplePair {
+		pairs := make([]model.SamplePair, len(amounts))
+		for i, amount := range amounts {
+			pairs[i] = model.SamplePair{Timestamp: model.TimeFromUnixNano(janOne.Add(time.Duration(i) * time.Minute).UnixNano()), Value: model.SampleValue(amount)}
+		}
+		return pairs
+	}
+	// dropping the pod label gives the first two series the same labels
+	matrix := model.Matrix{
+		&model.SampleStream{Metric: model.Metric{"namespace": "default", "pod": "pod-1"}, Values: values(1, 2)},
+		&model.SampleStream{Metric: model.Metric{"namespace": "default", "pod": "pod-2"}, Values: values(3, 4)},
+		&model.SampleStream{Metric: model.Metric{"namespace": "other", "pod": "pod-3"}, Values: values(5, 6)},
+	}
+	labelDropRegex, err := promrelabel.NewRegexp("pod")
+	require.NoError(t, err)
+	relabelConfigs := []*promrelabel.Config{{Regex: labelDropRegex, Action: promrelabel.LabelDrop}}
+
+	metrics := promMatrixToPrometheusMetrics(timeRange, matrix, relabelConfigs)
+	newMetric := func(namespace string, amount float64, offset time.Duration) *PrometheusMetric {
+		return &PrometheusMetric{
+			Labels:    map[string]string{"namespace": namespace},
+			Amount:    amount,
+			StepSize:  time.Minute,
+			Timestamp: janOne.Add(offset),
+		}
+	}
+	expected := []*PrometheusMetric{
+		newMetric("default", 4, 0),
+		newMetric("default", 6, time.Minute),
+		newMetric("other", 5, 0),
+		newMetric("other", 6, time.Minute),
+	}
+	assert.Equal(t, expected, metrics, "expected series with the same labels after relabeling to be summed")
+}
@@ -6,6 +6,7 @@ package mockprestostore
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	prestostore "github.com/operator-framework/operator-metering/pkg/operator/prestostore"
 	presto "github.com/operator-framework/operator-metering/pkg/presto"
 	reflect "reflect"
 )
@@ -33,6 +34,19 @@ func (m *MockReportResultsRepo) EXPECT() *MockReportResultsRepoMockRecorder {
 	return m.recorder
 }
 
+// CountReportResults mocks base method
+func (m *MockReportResultsRepo) CountReportResults(arg0 string) (int64, error) {
+	ret := m.ctrl.Call(m, "CountReportResults", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReportResults indicates an expected call of CountReportResults
+func (mr *MockReportResultsRepoMockRecorder) CountReportResults(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReportResults", reflect.TypeOf((*MockReportResultsRepo)(nil).CountReportResults), arg0)
+}
+
 // DeleteReportResults mocks base method
 func (m *MockReportResultsRepo) DeleteReportResults(arg0 string) error {
 	ret := m.ctrl.Call(m, "DeleteReportResults", arg0)
@@ -58,14 +72,54 @@ func (mr *MockReportResultsRepoMockRecorder) GetReportResults(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportResults", reflect.TypeOf((*MockReportResultsRepo)(nil).GetReportResults), arg0, arg1)
 }
 
+// GetReportResultsIterator mocks base method
+func (m *MockReportResultsRepo) GetReportResultsIterator(arg0 string, arg1 []presto.Column, arg2 prestostore.ReportResultsQuery) (presto.RowIterator, error) {
+	ret := m.ctrl.Call(m, "GetReportResultsIterator", arg0, arg1, arg2)
+	ret0, _ := ret[0].(presto.RowIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportResultsIterator indicates an expected call of GetReportResultsIterator
+func (mr *MockReportResultsRepoMockRecorder) GetReportResultsIterator(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportResultsIterator", reflect.TypeOf((*MockReportResultsRepo)(nil).GetReportResultsIterator), arg0, arg1, arg2)
+}
+
+// GetReportResultsPeriods mocks base method
+func (m *MockReportResultsRepo) GetReportResultsPeriods(arg0 string) ([]prestostore.ReportResultsPeriod, error) {
+	ret := m.ctrl.Call(m, "GetReportResultsPeriods", arg0)
+	ret0, _ := ret[0].([]prestostore.ReportResultsPeriod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportResultsPeriods indicates an expected call of GetReportResultsPeriods
+func (mr *MockReportResultsRepoMockRecorder) GetReportResultsPeriods(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportResultsPeriods", reflect.TypeOf((*MockReportResultsRepo)(nil).GetReportResultsPeriods), arg0)
+}
+
+// RunReportQuery mocks base method
+func (m *MockReportResultsRepo) RunReportQuery(arg0 string) ([]presto.Row, error) {
+	ret := m.ctrl.Call(m, "RunReportQuery", arg0)
+	ret0, _ := ret[0].([]presto.Row)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunReportQuery indicates an expected call of RunReportQuery
+func (mr *MockReportResultsRepoMockRecorder) RunReportQuery(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunReportQuery", reflect.TypeOf((*MockReportResultsRepo)(nil).RunReportQuery), arg0)
+}
+
 // StoreReportResults mocks base method
-func (m *MockReportResultsRepo) StoreReportResults(arg0, arg1 string) error {
-	ret := m.ctrl.Call(m, "StoreReportResults", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+func (m *MockReportResultsRepo) StoreReportResults(arg0 string, arg1 []presto.Column, arg2 string) (int64, error) {
+	ret := m.ctrl.Call(m, "StoreReportResults", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // StoreReportResults indicates an expected call of StoreReportResults
-func (mr *MockReportResultsRepoMockRecorder) StoreReportResults(arg0, arg1 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreReportResults", reflect.TypeOf((*MockReportResultsRepo)(nil).StoreReportResults), arg0, arg1)
+func (mr *MockReportResultsRepoMockRecorder) StoreReportResults(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreReportResults", reflect.TypeOf((*MockReportResultsRepo)(nil).StoreReportResults), arg0, arg1, arg2)
 }
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	prom "github.com/prometheus/client_golang/api/prometheus/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
 	"github.com/operator-framework/operator-metering/pkg/db"
 	"github.com/operator-framework/operator-metering/pkg/hive"
 	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
@@ -78,24 +82,37 @@ type PrometheusMetricTimestampTracker interface {
 	GetLastTimestampForTable(tableName string) (*time.Time, error)
 }
 
+type PrometheusMetricTimestampGapFinder interface {
+	GetTimestampGapsForTable(tableName string, start, end time.Time, stepSize time.Duration) ([]prom.Range, error)
+}
+
+type PrometheusMetricTimestampPartitionLister interface {
+	GetTimestampPartitionsForTable(tableName string) ([]string, error)
+}
+
 type PrometheusMetricsRepo interface {
 	PrometheusMetricsGetter
 	PrometheusMetricsStorer
 	PrometheusMetricTimestampTracker
+	PrometheusMetricTimestampGapFinder
+	PrometheusMetricTimestampPartitionLister
+	PrometheusMetricsRollupStorer
 }
 
 type prometheusMetricRepo struct {
 	queryer         db.Queryer
 	queryBufferPool sync.Pool
+	clock           clock.Clock
 }
 
-func NewPrometheusMetricsRepo(queryer db.Queryer, queryBufferPool *sync.Pool) *prometheusMetricRepo {
+func NewPrometheusMetricsRepo(queryer db.Queryer, queryBufferPool *sync.Pool, clock clock.Clock) *prometheusMetricRepo {
 	if queryBufferPool == nil {
 		queryBufferPool = &defaultQueryBufferPool
 	}
 	return &prometheusMetricRepo{
 		queryer:         queryer,
 		queryBufferPool: *queryBufferPool,
+		clock:           clock,
 	}
 }
 
@@ -110,15 +127,42 @@ func (r *prometheusMetricRepo) GetPrometheusMetrics(tableName string, start, end
 	return GetPrometheusMetrics(r.queryer, tableName, start, end)
 }
 
+// lastTimestampPartitionLookbacks are how far back GetLastTimestampForTable
+// looks for the last timestamp, from shortest to longest, before falling back
+// to querying the whole table.
+var lastTimestampPartitionLookbacks = []time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	31 * 24 * time.Hour,
+	366 * 24 * time.Hour,
+}
+
 func (r *prometheusMetricRepo) GetLastTimestampForTable(tableName string) (*time.Time, error) {
+	// Tables are partitioned by day, so start by only querying the most
+	// recent partitions, and look further back if they have no metrics.
+	// Most tables have recent metrics, which avoids reading every
+	// partition to find the last timestamp.
+	now := r.clock.Now().UTC()
+	for _, lookback := range lastTimestampPartitionLookbacks {
+		whereClause := fmt.Sprintf("WHERE dt >= '%s'", PrometheusMetricTimestampPartition(now.Add(-lookback)))
+		ts, err := getLastTimestampForTable(r.queryer, tableName, whereClause)
+		if err != nil || ts != nil {
+			return ts, err
+		}
+	}
+	return getLastTimestampForTable(r.queryer, tableName, "")
+}
+
+func getLastTimestampForTable(queryer db.Queryer, tableName, whereClause string) (*time.Time, error) {
 	// Get the most recent timestamp in the table for this query
 	getLastTimestampQuery := fmt.Sprintf(`
 				SELECT "timestamp"
 				FROM %s
+				%s
 				ORDER BY "timestamp" DESC
-				LIMIT 1`, tableName)
+				LIMIT 1`, tableName, whereClause)
 
-	results, err := presto.ExecuteSelect(r.queryer, getLastTimestampQuery)
+	results, err := presto.ExecuteSelect(queryer, getLastTimestampQuery)
 	if err != nil {
 		return nil, fmt.Errorf("error getting last timestamp for table %s, maybe table doesn't exist yet? %v", tableName, err)
 	}
@@ -130,6 +174,75 @@ func (r *prometheusMetricRepo) GetLastTimestampForTable(tableName string) (*time
 	return nil, nil
 }
 
+// GetTimestampGapsForTable returns the ranges of missing timestamps between
+// start and end in the table, at stepSize resolution. The Start and End of
+// each range are the first and last missing timestamps. Only gaps between
+// stored timestamps are found, so start and end should be timestamps known
+// to be in the table.
+func (r *prometheusMetricRepo) GetTimestampGapsForTable(tableName string, start, end time.Time, stepSize time.Duration) ([]prom.Range, error) {
+	// Compare each distinct timestamp with the previous one, and only
+	// return pairs which are at least two steps apart, meaning there is at
+	// least one missing timestamp between them. Filtering on dt allows
+	// Presto to only read the partitions within the time range.
+	getGapsQuery := fmt.Sprintf(`
+				SELECT previous_timestamp, "timestamp"
+				FROM (
+					SELECT "timestamp", lag("timestamp") OVER (ORDER BY "timestamp") AS previous_timestamp
+					FROM (
+						SELECT DISTINCT "timestamp"
+						FROM %s
+						WHERE dt >= '%s' AND dt <= '%s'
+						AND "timestamp" >= timestamp '%s' AND "timestamp" <= timestamp '%s'
+					)
+				)
+				WHERE previous_timestamp IS NOT NULL
+				AND date_diff('millisecond', previous_timestamp, "timestamp") >= %d
+				ORDER BY "timestamp" ASC`,
+		tableName,
+		PrometheusMetricTimestampPartition(start), PrometheusMetricTimestampPartition(end),
+		start.UTC().Format(presto.TimestampFormat), end.UTC().Format(presto.TimestampFormat),
+		int64(2*stepSize/time.Millisecond),
+	)
+
+	results, err := presto.ExecuteSelect(r.queryer, getGapsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error getting timestamp gaps for table %s: %v", tableName, err)
+	}
+
+	gaps := make([]prom.Range, len(results))
+	for i, row := range results {
+		previousTimestamp := row["previous_timestamp"].(time.Time)
+		timestamp := row["timestamp"].(time.Time)
+		gaps[i] = prom.Range{
+			Start: previousTimestamp.Add(stepSize).UTC(),
+			End:   timestamp.Add(-stepSize).UTC(),
+			Step:  stepSize,
+		}
+	}
+	return gaps, nil
+}
+
+// GetTimestampPartitionsForTable returns the values of the dt partition
+// column in the table, sorted from oldest to newest. They're read from the
+// table's $partitions metadata table, so the table's data isn't scanned.
+func (r *prometheusMetricRepo) GetTimestampPartitionsForTable(tableName string) ([]string, error) {
+	getPartitionsQuery := fmt.Sprintf(`
+				SELECT dt
+				FROM "%s$partitions"
+				ORDER BY dt ASC`, tableName)
+
+	results, err := presto.ExecuteSelect(r.queryer, getPartitionsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error getting partitions for table %s: %v", tableName, err)
+	}
+
+	partitions := make([]string, len(results))
+	for i, row := range results {
+		partitions[i] = row[dtColumnName].(string)
+	}
+	return partitions, nil
+}
+
 // PrometheusMetric is a receipt of a usage determined by a query within a specific time range.
 type PrometheusMetric struct {
 	Labels    map[string]string `json:"labels"`
@@ -139,6 +252,50 @@ type PrometheusMetric struct {
 	Dt        string            `json:"dt"`
 }
 
+// PrometheusMetricLabelsKey returns a string uniquely identifying the series
+// with the labels specified.
+func PrometheusMetricLabelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte(0)
+		key.WriteString(labels[name])
+		key.WriteByte(0)
+	}
+	return key.String()
+}
+
+// SumPrometheusMetrics combines the metrics which have the same labels and
+// timestamp into a single metric with the sum of their amounts, the same as
+// a PromQL sum by all of their labels would. Relabeling can give distinct
+// series the same labels, and storing a row for each of them would count
+// the series more than once wherever the table's rows are expected to be
+// unique. The order of the metrics is kept.
+func SumPrometheusMetrics(metrics []*PrometheusMetric) []*PrometheusMetric {
+	type seriesTimestamp struct {
+		labels    string
+		timestamp int64
+	}
+	summed := make([]*PrometheusMetric, 0, len(metrics))
+	seen := make(map[seriesTimestamp]*PrometheusMetric, len(metrics))
+	for _, metric := range metrics {
+		key := seriesTimestamp{labels: PrometheusMetricLabelsKey(metric.Labels), timestamp: metric.Timestamp.UnixNano()}
+		if existing, exists := seen[key]; exists {
+			existing.Amount += metric.Amount
+			continue
+		}
+		sum := *metric
+		seen[key] = &sum
+		summed = append(summed, &sum)
+	}
+	return summed
+}
+
 // storePrometheusMetricsWithBuffer handles storing Prometheus metrics into the
 // specified Presto table.
 func StorePrometheusMetricsWithBuffer(queryBuf *bytes.Buffer, ctx context.Context, queryer db.Queryer, tableName string, metrics []*PrometheusMetric) error {
@@ -248,6 +405,10 @@ func generatePrometheusMetricSQLValues(metric *PrometheusMetric) string {
 	)
 }
 
+// PrometheusMetricTimestampPartitionColumnName is the column Prometheus
+// ReportDataSource tables are partitioned by.
+const PrometheusMetricTimestampPartitionColumnName = dtColumnName
+
 const PrometheusMetricTimestampPartitionFormat = "2006-01-02"
 
 func PrometheusMetricTimestampPartition(t time.Time) string {
@@ -30,6 +30,12 @@ type PrometheusImportResults struct {
 // final chunk up to the endTime will be included even if the duration of
 // endTime - startTime isn't perfectly divisible by chunkSize.
 func ImportFromTimeRange(logger logrus.FieldLogger, clock clock.Clock, promConn prom.API, prometheusMetricsStorer PrometheusMetricsStorer, metricsCollectors ImporterMetricsCollectors, ctx context.Context, startTime, endTime time.Time, cfg Config, allowIncompleteChunks bool) (PrometheusImportResults, error) {
+	return importFromTimeRange(logger, clock, promConn, prometheusMetricsStorer, metricsCollectors, ctx, startTime, endTime, cfg, allowIncompleteChunks, nil)
+}
+
+// importFromTimeRange is ImportFromTimeRange, but calls checkpoint if it's
+// non-nil after each time range is imported.
+func importFromTimeRange(logger logrus.FieldLogger, clock clock.Clock, promConn prom.API, prometheusMetricsStorer PrometheusMetricsStorer, metricsCollectors ImporterMetricsCollectors, ctx context.Context, startTime, endTime time.Time, cfg Config, allowIncompleteChunks bool, checkpoint func(prom.Range)) (PrometheusImportResults, error) {
 	metricsCollectors.ImportsRunningGauge.Inc()
 
 	queryRangeDuration := endTime.Sub(startTime)
@@ -100,7 +106,7 @@ func ImportFromTimeRange(logger logrus.FieldLogger, clock clock.Clock, promConn
 			return importResults, fmt.Errorf("expected a matrix in response to query, got a %v", pVal.Type())
 		}
 
-		metrics := promMatrixToPrometheusMetrics(timeRange, matrix)
+		metrics := promMatrixToPrometheusMetrics(timeRange, matrix, cfg.RelabelConfigs)
 		numMetrics := len(metrics)
 		metricsCollectors.MetricsScrapedCounter.Add(float64(numMetrics))
 
@@ -143,6 +149,9 @@ func ImportFromTimeRange(logger logrus.FieldLogger, clock clock.Clock, promConn
 		}
 
 		importResults.ProcessedTimeRanges = append(importResults.ProcessedTimeRanges, timeRange)
+		if checkpoint != nil {
+			checkpoint(timeRange)
+		}
 	}
 
 	if len(importResults.ProcessedTimeRanges) != 0 {
@@ -160,6 +169,12 @@ func getTimeRangesChunked(beginTime, endTime time.Time, chunkSize, stepSize time
 	chunkStart := truncateToSecond(beginTime)
 	chunkEnd := truncateToSecond(chunkStart.Add(chunkSize))
 
+	// A time range of a single timestamp, such as a gap of one missing
+	// metric, can't be chunked so query it as is.
+	if allowIncompleteChunks && beginTime.Equal(endTime) && chunkStart.Equal(beginTime) {
+		return []prom.Range{{Start: chunkStart.UTC(), End: chunkStart.UTC(), Step: stepSize}}
+	}
+
 	// don't set a limit if negative or zero
 	disableMax := maxTimeRanges <= 0
 
@@ -97,6 +97,20 @@ func TestGetTimeRanges(t *testing.T) {
 				},
 			},
 		},
+		"period is a single timestamp with allowIncompleteChunks": {
+			startTime:             janOne,
+			endTime:               janOne,
+			chunkSize:             time.Hour,
+			stepSize:              time.Minute,
+			allowIncompleteChunks: true,
+			expectedRanges: []prom.Range{
+				{
+					Start: janOne,
+					End:   janOne,
+					Step:  time.Minute,
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -0,0 +1,102 @@
+package prestostore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/operator-metering/pkg/db"
+	"github.com/operator-framework/operator-metering/pkg/hive"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+var (
+	RateCardHiveTableColumns = []hive.Column{
+		{Name: "unit", Type: "string"},
+		{Name: "price", Type: "double"},
+		{Name: "currency", Type: "string"},
+		{Name: "namespace", Type: "string"},
+		{Name: "node_labels", Type: "map<string, string>"},
+		{Name: "effective_from", Type: "timestamp"},
+		{Name: "effective_to", Type: "timestamp"},
+	}
+)
+
+// RateCardRate is a single row of a RateCard table.
+type RateCardRate struct {
+	Unit          string
+	Price         float64
+	Currency      string
+	Namespace     string
+	NodeLabels    map[string]string
+	EffectiveFrom *time.Time
+	EffectiveTo   *time.Time
+}
+
+type RateCardStorer interface {
+	StoreRateCardRates(tableName string, rates []RateCardRate) error
+}
+
+type RateCardRepo interface {
+	RateCardStorer
+}
+
+type rateCardRepo struct {
+	queryer db.Queryer
+}
+
+func NewRateCardRepo(queryer db.Queryer) *rateCardRepo {
+	return &rateCardRepo{queryer: queryer}
+}
+
+// StoreRateCardRates adds rates to tableName.
+func (r *rateCardRepo) StoreRateCardRates(tableName string, rates []RateCardRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	values := make([]string, len(rates))
+	for i, rate := range rates {
+		values[i] = generateRateCardRateSQLValues(rate)
+	}
+	err := presto.InsertInto(r.queryer, tableName, "VALUES "+strings.Join(values, ","))
+	if err != nil {
+		return fmt.Errorf("failed to store rates into %s: %v", tableName, err)
+	}
+	return nil
+}
+
+// generateRateCardRateSQLValues turns a RateCardRate into a SQL literal
+// suited for INSERT statements. Unset optional fields are stored as NULL, and
+// a rate without node labels has an empty node_labels map.
+func generateRateCardRateSQLValues(rate RateCardRate) string {
+	namespace := "CAST(NULL AS varchar)"
+	if rate.Namespace != "" {
+		namespace = presto.FormatStringLiteral(rate.Namespace)
+	}
+
+	// sort the labels so the generated SQL is deterministic
+	labelKeys := make([]string, 0, len(rate.NodeLabels))
+	for k := range rate.NodeLabels {
+		labelKeys = append(labelKeys, k)
+	}
+	sort.Strings(labelKeys)
+	var keys, vals []string
+	for _, k := range labelKeys {
+		keys = append(keys, presto.FormatStringLiteral(k))
+		vals = append(vals, presto.FormatStringLiteral(rate.NodeLabels[k]))
+	}
+	nodeLabels := fmt.Sprintf("CAST(map(ARRAY[%s],ARRAY[%s]) AS map(varchar,varchar))", strings.Join(keys, ","), strings.Join(vals, ","))
+
+	return fmt.Sprintf("(%s,%s,%s,%s,%s,%s,%s)",
+		presto.FormatStringLiteral(rate.Unit), fmt.Sprintf("CAST(%v AS double)", rate.Price), presto.FormatStringLiteral(rate.Currency),
+		namespace, nodeLabels, sqlTimestampOrNull(rate.EffectiveFrom), sqlTimestampOrNull(rate.EffectiveTo),
+	)
+}
+
+func sqlTimestampOrNull(t *time.Time) string {
+	if t == nil {
+		return "CAST(NULL AS timestamp)"
+	}
+	return fmt.Sprintf("timestamp '%s'", t.UTC().Format(presto.TimestampFormat))
+}
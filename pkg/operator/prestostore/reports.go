@@ -1,26 +1,69 @@
 package prestostore
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/operator-framework/operator-metering/pkg/db"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
 	"github.com/operator-framework/operator-metering/pkg/presto"
 )
 
 type ReportResultsGetter interface {
 	GetReportResults(tableName string, columns []presto.Column) ([]presto.Row, error)
+	// GetReportResultsIterator is like GetReportResults, but returns a
+	// RowIterator over the results instead of loading them into memory, and
+	// only returns the rows selected by query.
+	GetReportResultsIterator(tableName string, columns []presto.Column, query ReportResultsQuery) (presto.RowIterator, error)
+	ReportResultsPeriodsGetter
+}
+
+// ReportResultsQuery filters, orders and limits the rows returned by
+// GetReportResultsIterator. The zero value selects every row, ordered by
+// every column.
+type ReportResultsQuery struct {
+	// Where is a SQL condition rows must match. Empty matches every row.
+	Where string
+	// OrderBy are the columns to order rows by. If empty, rows are ordered
+	// by every column.
+	OrderBy []presto.OrderByColumn
+	// Offset is the number of rows to skip. Only used when Limit is set.
+	Offset int64
+	// Limit is the maximum number of rows to return. 0 means no limit.
+	Limit int64
+}
+
+type ReportResultsPeriodsGetter interface {
+	GetReportResultsPeriods(tableName string) ([]ReportResultsPeriod, error)
+}
+
+// ReportResultsPeriod is a distinct reporting period found in the
+// period_start and period_end columns of a report table.
+type ReportResultsPeriod struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
 }
 
 type ReportResultsStorer interface {
-	StoreReportResults(tableName, query string) error
+	StoreReportResults(tableName string, columns []presto.Column, query string) (int64, error)
+	CountReportResults(tableName string) (int64, error)
 }
 
 type ReportsResultsDeleter interface {
 	DeleteReportResults(tableName string) error
 }
 
+// ReportQueryRunner executes an already rendered ReportGenerationQuery and
+// returns the results without storing them in a table.
+type ReportQueryRunner interface {
+	RunReportQuery(query string) ([]presto.Row, error)
+}
+
 type ReportResultsRepo interface {
 	ReportResultsGetter
 	ReportResultsStorer
 	ReportsResultsDeleter
+	ReportQueryRunner
 }
 
 type reportResultsRepo struct {
@@ -35,10 +78,52 @@ func (r *reportResultsRepo) GetReportResults(tableName string, columns []presto.
 	return presto.GetRows(r.queryer, tableName, columns)
 }
 
-func (r *reportResultsRepo) StoreReportResults(tableName, query string) error {
-	return presto.InsertInto(r.queryer, tableName, query)
+func (r *reportResultsRepo) GetReportResultsIterator(tableName string, columns []presto.Column, query ReportResultsQuery) (presto.RowIterator, error) {
+	orderBy := query.OrderBy
+	if len(orderBy) == 0 {
+		for _, col := range columns {
+			orderBy = append(orderBy, presto.OrderByColumn{Column: col})
+		}
+	}
+	return presto.ExecuteSelectIterator(r.queryer, presto.GenerateGetRowsPageSQL(tableName, columns, query.Where, orderBy, query.Offset, query.Limit))
+}
+
+// StoreReportResults inserts the results of query into columns of tableName
+// and returns the number of rows inserted. Naming the columns allows tables
+// to have columns the query doesn't produce.
+func (r *reportResultsRepo) StoreReportResults(tableName string, columns []presto.Column, query string) (int64, error) {
+	return presto.InsertIntoWithRowCount(r.queryer, tableName, columns, query)
+}
+
+func (r *reportResultsRepo) CountReportResults(tableName string) (int64, error) {
+	return presto.CountRows(r.queryer, tableName)
 }
 
 func (r *reportResultsRepo) DeleteReportResults(tableName string) error {
 	return presto.DeleteFrom(r.queryer, tableName)
 }
+
+func (r *reportResultsRepo) RunReportQuery(query string) ([]presto.Row, error) {
+	return presto.ExecuteSelect(r.queryer, query)
+}
+
+// GetReportResultsPeriods returns the distinct reporting periods of the rows
+// in tableName, sorted by period_start and then period_end.
+func (r *reportResultsRepo) GetReportResultsPeriods(tableName string) ([]ReportResultsPeriod, error) {
+	query := fmt.Sprintf(`SELECT DISTINCT "%[1]s", "%[2]s" FROM %[3]s ORDER BY "%[1]s" ASC, "%[2]s" ASC`,
+		reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName, tableName)
+	results, err := presto.ExecuteSelect(r.queryer, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting periods for table %s: %v", tableName, err)
+	}
+	periods := make([]ReportResultsPeriod, 0, len(results))
+	for _, row := range results {
+		periodStart, startOk := row[reportingutil.PeriodStartColumnName].(time.Time)
+		periodEnd, endOk := row[reportingutil.PeriodEndColumnName].(time.Time)
+		if !startOk || !endOk {
+			continue
+		}
+		periods = append(periods, ReportResultsPeriod{PeriodStart: periodStart.UTC(), PeriodEnd: periodEnd.UTC()})
+	}
+	return periods, nil
+}
@@ -0,0 +1,134 @@
+package prestostore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/operator-framework/operator-metering/pkg/db"
+	"github.com/operator-framework/operator-metering/pkg/hive"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+const (
+	rollupAmountSumColumnName         = "amount_sum"
+	rollupAmountAvgColumnName         = "amount_avg"
+	rollupAmountMaxColumnName         = "amount_max"
+	rollupAmountWeightedSumColumnName = "amount_weighted_sum"
+	rollupSampleCountColumnName       = "sample_count"
+)
+
+var (
+	// PromsumRollupHiveTableColumns are the columns of the tables
+	// Prometheus metrics are aggregated into. The first columns are the
+	// same as PromsumHiveTableColumns: each row's timestamp is the start of
+	// the period it aggregates, timePrecision is the length of the period
+	// in seconds, and amount is the average of the metric over the period,
+	// weighted by the timePrecision of each metric. This means
+	// amount * timePrecision is the same for a rollup row as the sum of it
+	// for the metrics it aggregates, so queries using it work the same on
+	// either table.
+	PromsumRollupHiveTableColumns = []hive.Column{
+		{Name: amountColumnName, Type: "double"},
+		{Name: timestampColumnName, Type: "timestamp"},
+		{Name: timePrecisionColumnName, Type: "double"},
+		{Name: labelsColumnName, Type: "map<string, string>"},
+		{Name: rollupAmountSumColumnName, Type: "double"},
+		{Name: rollupAmountAvgColumnName, Type: "double"},
+		{Name: rollupAmountMaxColumnName, Type: "double"},
+		{Name: rollupAmountWeightedSumColumnName, Type: "double"},
+		{Name: rollupSampleCountColumnName, Type: "bigint"},
+	}
+)
+
+type PrometheusMetricsRollupStorer interface {
+	StorePrometheusMetricsRollup(tableName, sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) error
+}
+
+// StorePrometheusMetricsRollup aggregates the metrics in sourceTableName
+// with timestamps from start up until end into tableName, grouped by their
+// labels into periods of an hour or a day. If sourceIsRollup is true,
+// sourceTableName is a rollup table of a shorter period rather than a table
+// of raw metrics.
+//
+// The dt partitions of tableName from start up until end are deleted first,
+// so aggregating a range again replaces its rows rather than adding to them.
+// Since partitions hold a day each, start must be the start of a day.
+func (r *prometheusMetricRepo) StorePrometheusMetricsRollup(tableName, sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) error {
+	return StorePrometheusMetricsRollup(r.queryer, tableName, sourceTableName, sourceIsRollup, start, end, period)
+}
+
+func StorePrometheusMetricsRollup(queryer db.Queryer, tableName, sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) error {
+	if !start.Equal(start.UTC().Truncate(24 * time.Hour)) {
+		return fmt.Errorf("rollup of %s into %s must start at the start of a day, not %s", sourceTableName, tableName, start)
+	}
+	query, err := generateRollupQuery(sourceTableName, sourceIsRollup, start, end, period)
+	if err != nil {
+		return err
+	}
+	err = presto.DeleteFromWhere(queryer, tableName, generateRollupDeleteWhere(start, end))
+	if err != nil {
+		return fmt.Errorf("failed to delete the existing rollup of %s from %s: %v", sourceTableName, tableName, err)
+	}
+	err = presto.InsertInto(queryer, tableName, query)
+	if err != nil {
+		return fmt.Errorf("failed to store rollup of %s into %s: %v", sourceTableName, tableName, err)
+	}
+	return nil
+}
+
+// generateRollupDeleteWhere returns a where clause matching the dt
+// partitions of a rollup table which contain the periods from start up
+// until end.
+func generateRollupDeleteWhere(start, end time.Time) string {
+	return fmt.Sprintf(`dt >= '%s' AND dt <= '%s'`,
+		PrometheusMetricTimestampPartition(start), PrometheusMetricTimestampPartition(end.Add(-time.Nanosecond)))
+}
+
+func generateRollupQuery(sourceTableName string, sourceIsRollup bool, start, end time.Time, period time.Duration) (string, error) {
+	var truncateUnit string
+	switch period {
+	case time.Hour:
+		truncateUnit = "hour"
+	case 24 * time.Hour:
+		truncateUnit = "day"
+	default:
+		return "", fmt.Errorf("unsupported rollup period %s", period)
+	}
+
+	// raw metrics are each a single sample, whereas rollups are already
+	// aggregated and are combined.
+	sourceColumns := fmt.Sprintf(`amount AS %s, amount AS %s, amount * "timePrecision" AS %s, 1 AS %s`,
+		rollupAmountSumColumnName, rollupAmountMaxColumnName, rollupAmountWeightedSumColumnName, rollupSampleCountColumnName)
+	if sourceIsRollup {
+		sourceColumns = fmt.Sprintf(`%s, %s, %s, %s`,
+			rollupAmountSumColumnName, rollupAmountMaxColumnName, rollupAmountWeightedSumColumnName, rollupSampleCountColumnName)
+	}
+
+	periodSeconds := int64(period / time.Second)
+	query := fmt.Sprintf(`
+			SELECT
+				sum(%[1]s) / %[2]d AS amount,
+				period_start AS "timestamp",
+				CAST(%[2]d AS double) AS "timePrecision",
+				labels,
+				sum(%[3]s) AS %[3]s,
+				sum(%[3]s) / sum(%[4]s) AS %[5]s,
+				max(%[6]s) AS %[6]s,
+				sum(%[1]s) AS %[1]s,
+				sum(%[4]s) AS %[4]s,
+				date_format(period_start, '%%Y-%%m-%%d') AS dt
+			FROM (
+				SELECT date_trunc('%[7]s', "timestamp") AS period_start, labels, %[8]s
+				FROM %[9]s
+				WHERE dt >= '%[10]s' AND dt <= '%[11]s'
+				AND "timestamp" >= timestamp '%[12]s' AND "timestamp" < timestamp '%[13]s'
+			)
+			GROUP BY period_start, labels`,
+		rollupAmountWeightedSumColumnName, periodSeconds,
+		rollupAmountSumColumnName, rollupSampleCountColumnName, rollupAmountAvgColumnName, rollupAmountMaxColumnName,
+		truncateUnit, sourceColumns, sourceTableName,
+		PrometheusMetricTimestampPartition(start), PrometheusMetricTimestampPartition(end),
+		start.UTC().Format(presto.TimestampFormat), end.UTC().Format(presto.TimestampFormat),
+	)
+	return query, nil
+}
@@ -0,0 +1,36 @@
+package prestostore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRollupQuery(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	query, err := generateRollupQuery("datasource_test", false, janOne, janOne.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, query, `date_trunc('hour', "timestamp")`)
+	assert.Contains(t, query, `amount * "timePrecision" AS amount_weighted_sum, 1 AS sample_count`)
+	assert.Contains(t, query, `sum(amount_weighted_sum) / 3600 AS amount`)
+	assert.Contains(t, query, `WHERE dt >= '2019-01-01' AND dt <= '2019-01-01'`)
+	assert.Contains(t, query, `"timestamp" >= timestamp '2019-01-01 00:00:00.000' AND "timestamp" < timestamp '2019-01-01 02:00:00.000'`)
+
+	query, err = generateRollupQuery("rollup_hourly_test", true, janOne, janOne.Add(48*time.Hour), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, query, `date_trunc('day', "timestamp")`)
+	assert.Contains(t, query, `labels, amount_sum, amount_max, amount_weighted_sum, sample_count`)
+	assert.Contains(t, query, `CAST(86400 AS double) AS "timePrecision"`)
+
+	_, err = generateRollupQuery("datasource_test", false, janOne, janOne.Add(time.Hour), time.Minute)
+	assert.Error(t, err, "expected an error for an unsupported period")
+}
+
+func TestGenerateRollupDeleteWhere(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, `dt >= '2019-01-01' AND dt <= '2019-01-01'`, generateRollupDeleteWhere(janOne, janOne.Add(5*time.Hour)))
+	assert.Equal(t, `dt >= '2019-01-01' AND dt <= '2019-01-02'`, generateRollupDeleteWhere(janOne, janOne.Add(48*time.Hour)), "expected the partition starting at the end to not be deleted")
+}
@@ -2,14 +2,15 @@ package operator
 
 import (
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
 
 	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	"github.com/operator-framework/operator-metering/pkg/hive"
-	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
 	"github.com/operator-framework/operator-metering/pkg/presto"
 	"github.com/operator-framework/operator-metering/pkg/util/slice"
 )
@@ -96,10 +97,8 @@ func (op *Reporting) handlePrestoTable(logger log.FieldLogger, prestoTable *cbTy
 	return nil
 }
 
-func (op *Reporting) createPrestoTableCR(obj metav1.Object, gvk schema.GroupVersionKind, params hive.TableParameters, properties hive.TableProperties, partitions []presto.TablePartition) error {
+func (op *Reporting) createPrestoTableCR(obj metav1.Object, gvk schema.GroupVersionKind, resourceName string, params hive.TableParameters, properties hive.TableProperties, partitions []presto.TablePartition) error {
 	apiVersion := gvk.GroupVersion().String()
-	kind := gvk.Kind
-	name := obj.GetName()
 	namespace := obj.GetNamespace()
 	objLabels := obj.GetLabels()
 	ownerRef := metav1.NewControllerRef(obj, gvk)
@@ -109,7 +108,6 @@ func (op *Reporting) createPrestoTableCR(obj metav1.Object, gvk schema.GroupVers
 		finalizers = []string{prestoTableFinalizer}
 	}
 
-	resourceName := reportingutil.PrestoTableResourceNameFromKind(kind, namespace, name)
 	prestoTableCR := cbTypes.PrestoTable{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PrestoTable",
@@ -145,6 +143,11 @@ func (op *Reporting) createPrestoTableCR(obj metav1.Object, gvk schema.GroupVers
 	}
 
 	_, err := op.meteringClient.MeteringV1alpha1().PrestoTables(namespace).Create(&prestoTableCR)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		if runtimeObj, ok := obj.(runtime.Object); ok {
+			op.eventRecorder.Eventf(runtimeObj, v1.EventTypeWarning, tableCreateFailedEventReason, "Failed to create PrestoTable %s for table %s: %v", resourceName, params.Name, err)
+		}
+	}
 	return err
 }
 
@@ -186,8 +189,10 @@ func (op *Reporting) dropPrestoTable(prestoTable *cbTypes.PrestoTable) error {
 	err := op.tableManager.DropTable(tableName, true)
 	if err != nil {
 		logger.WithError(err).Error("unable to drop presto table")
+		op.eventRecorder.Eventf(prestoTable, v1.EventTypeWarning, tableDropFailedEventReason, "Failed to drop table %s: %v", tableName, err)
 		return err
 	}
 	logger.Infof("successfully deleted table %s", tableName)
+	op.eventRecorder.Eventf(prestoTable, v1.EventTypeNormal, tableDroppedEventReason, "Dropped table %s", tableName)
 	return nil
 }
@@ -178,7 +178,8 @@ func (op *Reporting) importPrometheusForTimeRange(ctx context.Context, namespace
 
 	for _, reportDataSource := range reportDataSources.Items {
 		reportDataSource := reportDataSource
-		if reportDataSource.Spec.Promsum == nil {
+		// remote write ReportDataSources don't have a query to import with
+		if reportDataSource.Spec.Promsum == nil || reportDataSource.Spec.Promsum.RemoteWrite != nil {
 			continue
 		}
 
@@ -198,7 +199,10 @@ func (op *Reporting) importPrometheusForTimeRange(ctx context.Context, namespace
 				"reportDataSource": reportDataSource.Name,
 				"tableName":        reportDataSource.Status.TableName,
 			})
-			importCfg := op.newPromImporterCfg(reportDataSource, reportPromQuery)
+			importCfg, err := op.newPromImporterCfg(reportDataSource, reportPromQuery)
+			if err != nil {
+				return fmt.Errorf("invalid ReportDataSource %s: %v", reportDataSource.Name, err)
+			}
 			// ignore any global ImportFrom configuration since this is an
 			// on-demand import
 			importCfg.ImportFromTime = nil
@@ -261,7 +265,12 @@ func (op *Reporting) getQueryIntervalForReportDataSource(reportDataSource *cbTyp
 	return queryInterval
 }
 
-func (op *Reporting) newPromImporterCfg(reportDataSource *cbTypes.ReportDataSource, reportPromQuery *cbTypes.ReportPrometheusQuery) prestostore.Config {
+func (op *Reporting) newPromImporterCfg(reportDataSource *cbTypes.ReportDataSource, reportPromQuery *cbTypes.ReportPrometheusQuery) (prestostore.Config, error) {
+	relabelConfigs, err := newRelabelConfigs(reportDataSource.Spec.Promsum.RelabelConfigs)
+	if err != nil {
+		return prestostore.Config{}, err
+	}
+
 	chunkSize := op.cfg.PrometheusQueryConfig.ChunkSize.Duration
 	stepSize := op.cfg.PrometheusQueryConfig.StepSize.Duration
 
@@ -301,7 +310,8 @@ func (op *Reporting) newPromImporterCfg(reportDataSource *cbTypes.ReportDataSour
 		MaxQueryRangeDuration:     op.cfg.PrometheusDataSourceMaxQueryRangeDuration,
 		MaxBackfillImportDuration: op.cfg.PrometheusDataSourceMaxBackfillImportDuration,
 		ImportFromTime:            op.cfg.PrometheusDataSourceGlobalImportFromTime,
-	}
+		RelabelConfigs:            relabelConfigs,
+	}, nil
 }
 
 func (op *Reporting) newPromImporter(logger logrus.FieldLogger, reportDataSource *cbTypes.ReportDataSource, reportPromQuery *cbTypes.ReportPrometheusQuery, cfg prestostore.Config) (*prestostore.PrometheusImporter, error) {
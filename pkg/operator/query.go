@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
@@ -13,6 +14,15 @@ import (
 	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
 	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
 	"github.com/operator-framework/operator-metering/pkg/presto"
+	"github.com/operator-framework/operator-metering/pkg/util/slice"
+)
+
+const (
+	reportGenerationQueryFinalizer = cbTypes.GroupName + "/reportgenerationquery"
+
+	// prestoSchema is the schema of the Presto catalog tables and views
+	// are created in.
+	prestoSchema = "default"
 )
 
 func (op *Reporting) runReportGenerationQueryWorker() {
@@ -45,14 +55,41 @@ func (op *Reporting) syncReportGenerationQuery(logger log.FieldLogger, key strin
 		return err
 	}
 	q := reportGenerationQuery.DeepCopy()
+
+	if q.DeletionTimestamp != nil {
+		logger.Infof("ReportGenerationQuery is marked for deletion, performing cleanup")
+		if err := op.dropReportGenerationQueryView(logger, q); err != nil {
+			return err
+		}
+		_, err = op.removeReportGenerationQueryFinalizer(q)
+		return err
+	}
+
 	return op.handleReportGenerationQuery(logger, q)
 }
 
 func (op *Reporting) handleReportGenerationQuery(logger log.FieldLogger, generationQuery *cbTypes.ReportGenerationQuery) error {
+	if op.cfg.EnableFinalizers && reportGenerationQueryNeedsFinalizer(generationQuery) {
+		var err error
+		generationQuery, err = op.addReportGenerationQueryFinalizer(generationQuery)
+		if err != nil {
+			return err
+		}
+	}
+
 	var viewName string
 	createView := false
 	if generationQuery.Spec.View.Disabled {
 		logger.Infof("ReportGenerationQuery has spec.view.disabled=true, skipping view creation")
+		if generationQuery.Status.ViewName != "" {
+			// the view was disabled after it was created
+			if err := op.dropReportGenerationQueryView(logger, generationQuery); err != nil {
+				return err
+			}
+			if err := op.updateReportQueryViewName(logger, generationQuery, ""); err != nil {
+				return err
+			}
+		}
 	} else if generationQuery.Status.ViewName == "" {
 		logger.Infof("new ReportGenerationQuery discovered")
 		viewName = reportingutil.GenerationQueryViewName(generationQuery.Namespace, generationQuery.Name)
@@ -103,7 +140,7 @@ func (op *Reporting) handleReportGenerationQuery(logger log.FieldLogger, generat
 		}
 
 		logger.Infof("creating view %s", viewName)
-		err = op.prestoViewCreator.CreateView(viewName, renderedQuery)
+		err = op.prestoViewManager.CreateView(viewName, renderedQuery)
 		if err != nil {
 			return fmt.Errorf("error creating view %s for ReportGenerationQuery %s: %v", viewName, generationQuery.Name, err)
 		}
@@ -137,6 +174,55 @@ func (op *Reporting) updateReportQueryViewName(logger log.FieldLogger, generatio
 	return nil
 }
 
+// dropReportGenerationQueryView drops the view of generationQuery, if it has
+// one.
+func (op *Reporting) dropReportGenerationQueryView(logger log.FieldLogger, generationQuery *cbTypes.ReportGenerationQuery) error {
+	viewName := generationQuery.Status.ViewName
+	if viewName == "" {
+		return nil
+	}
+	logger.Infof("dropping view %s", viewName)
+	err := op.prestoViewManager.DropView(viewName, true)
+	if err != nil {
+		op.eventRecorder.Eventf(generationQuery, v1.EventTypeWarning, viewDropFailedEventReason, "Failed to drop view %s: %v", viewName, err)
+		return fmt.Errorf("error dropping view %s for ReportGenerationQuery %s: %v", viewName, generationQuery.Name, err)
+	}
+	logger.Infof("dropped view %s", viewName)
+	op.eventRecorder.Eventf(generationQuery, v1.EventTypeNormal, viewDroppedEventReason, "Dropped view %s", viewName)
+	return nil
+}
+
+func (op *Reporting) addReportGenerationQueryFinalizer(generationQuery *cbTypes.ReportGenerationQuery) (*cbTypes.ReportGenerationQuery, error) {
+	generationQuery.Finalizers = append(generationQuery.Finalizers, reportGenerationQueryFinalizer)
+	newReportGenerationQuery, err := op.meteringClient.MeteringV1alpha1().ReportGenerationQueries(generationQuery.Namespace).Update(generationQuery)
+	logger := op.logger.WithFields(log.Fields{"reportGenerationQuery": generationQuery.Name, "namespace": generationQuery.Namespace})
+	if err != nil {
+		logger.WithError(err).Errorf("error adding %s finalizer to ReportGenerationQuery: %s/%s", reportGenerationQueryFinalizer, generationQuery.Namespace, generationQuery.Name)
+		return nil, err
+	}
+	logger.Infof("added %s finalizer to ReportGenerationQuery: %s/%s", reportGenerationQueryFinalizer, generationQuery.Namespace, generationQuery.Name)
+	return newReportGenerationQuery, nil
+}
+
+func (op *Reporting) removeReportGenerationQueryFinalizer(generationQuery *cbTypes.ReportGenerationQuery) (*cbTypes.ReportGenerationQuery, error) {
+	if !slice.ContainsString(generationQuery.ObjectMeta.Finalizers, reportGenerationQueryFinalizer, nil) {
+		return generationQuery, nil
+	}
+	generationQuery.Finalizers = slice.RemoveString(generationQuery.Finalizers, reportGenerationQueryFinalizer, nil)
+	newReportGenerationQuery, err := op.meteringClient.MeteringV1alpha1().ReportGenerationQueries(generationQuery.Namespace).Update(generationQuery)
+	logger := op.logger.WithFields(log.Fields{"reportGenerationQuery": generationQuery.Name, "namespace": generationQuery.Namespace})
+	if err != nil {
+		logger.WithError(err).Errorf("error removing %s finalizer from ReportGenerationQuery: %s/%s", reportGenerationQueryFinalizer, generationQuery.Namespace, generationQuery.Name)
+		return nil, err
+	}
+	logger.Infof("removed %s finalizer from ReportGenerationQuery: %s/%s", reportGenerationQueryFinalizer, generationQuery.Namespace, generationQuery.Name)
+	return newReportGenerationQuery, nil
+}
+
+func reportGenerationQueryNeedsFinalizer(generationQuery *cbTypes.ReportGenerationQuery) bool {
+	return generationQuery.ObjectMeta.DeletionTimestamp == nil && !slice.ContainsString(generationQuery.ObjectMeta.Finalizers, reportGenerationQueryFinalizer, nil)
+}
+
 func (op *Reporting) uninitialiedDependendenciesHandler() *reporting.UninitialiedDependendenciesHandler {
 	return &reporting.UninitialiedDependendenciesHandler{
 		HandleUninitializedReportGenerationQuery: op.enqueueReportGenerationQuery,
@@ -185,14 +271,24 @@ func (op *Reporting) queueDependentReportsForQuery(generationQuery *cbTypes.Repo
 	return nil
 }
 
-type PrestoViewCreator interface {
+type PrestoViewManager interface {
 	CreateView(viewName, query string) error
+	DropView(viewName string, ignoreNotExists bool) error
+	ListViews() ([]string, error)
 }
 
-type prestoViewCreator struct {
+type prestoViewManager struct {
 	queryer db.Queryer
 }
 
-func (c *prestoViewCreator) CreateView(viewName, query string) error {
-	return presto.CreateView(c.queryer, viewName, query, true)
+func (m *prestoViewManager) CreateView(viewName, query string) error {
+	return presto.CreateView(m.queryer, viewName, query, true)
+}
+
+func (m *prestoViewManager) DropView(viewName string, ignoreNotExists bool) error {
+	return presto.DropView(m.queryer, viewName, ignoreNotExists)
+}
+
+func (m *prestoViewManager) ListViews() ([]string, error) {
+	return presto.ListViews(m.queryer, prestoSchema)
 }
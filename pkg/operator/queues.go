@@ -185,8 +185,10 @@ func (op *Reporting) updateReportGenerationQuery(prev, cur interface{}) {
 	prevReportGenerationQuery := prev.(*cbTypes.ReportGenerationQuery)
 	logger := op.logger.WithFields(log.Fields{"reportGenerationQuery": curReportGenerationQuery.Name, "namespace": curReportGenerationQuery.Namespace})
 
-	// Only skip queuing if we're not missing a view
-	if curReportGenerationQuery.Spec.View.Disabled && curReportGenerationQuery.Status.ViewName != "" {
+	// Only skip queuing if we're not missing a view, have no view to drop,
+	// and aren't being deleted
+	viewUpToDate := curReportGenerationQuery.Spec.View.Disabled == (curReportGenerationQuery.Status.ViewName == "")
+	if curReportGenerationQuery.DeletionTimestamp == nil && viewUpToDate {
 		if curReportGenerationQuery.ResourceVersion == prevReportGenerationQuery.ResourceVersion {
 			// Periodic resyncs will send update events for all known ReportGenerationQuerys.
 			// Two different versions of the same reportGenerationQuery will always have
@@ -203,6 +205,36 @@ func (op *Reporting) updateReportGenerationQuery(prev, cur interface{}) {
 	op.enqueueReportGenerationQuery(curReportGenerationQuery)
 }
 
+func (op *Reporting) deleteReportGenerationQuery(obj interface{}) {
+	generationQuery, ok := obj.(*cbTypes.ReportGenerationQuery)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			op.logger.Errorf("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		generationQuery, ok = tombstone.Obj.(*cbTypes.ReportGenerationQuery)
+		if !ok {
+			op.logger.Errorf("Tombstone contained object that is not a ReportGenerationQuery %#v", obj)
+			return
+		}
+	}
+	logger := op.logger.WithFields(log.Fields{"reportGenerationQuery": generationQuery.Name, "namespace": generationQuery.Namespace})
+	// when finalizers aren't enabled, it's pretty likely by the time our
+	// worker get the event from the queue that the resource will no longer
+	// exist in our store, so we eagerly drop the view upon seeing the delete
+	// event when finalizers are disabled
+	if !op.cfg.EnableFinalizers {
+		_ = op.dropReportGenerationQueryView(logger, generationQuery)
+	}
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(generationQuery)
+	if err != nil {
+		logger.WithError(err).Errorf("couldn't get key for object: %#v", generationQuery)
+		return
+	}
+	op.reportGenerationQueryQueue.Add(key)
+}
+
 func (op *Reporting) enqueueReportGenerationQuery(query *cbTypes.ReportGenerationQuery) {
 	key, err := cache.MetaNamespaceKeyFunc(query)
 	if err != nil {
@@ -272,6 +304,70 @@ func (op *Reporting) enqueuePrestoTable(table *cbTypes.PrestoTable) {
 	op.prestoTableQueue.Add(key)
 }
 
+func (op *Reporting) addReportRerun(obj interface{}) {
+	rerun := obj.(*cbTypes.ReportRerun)
+	op.logger.Infof("adding ReportRerun %s/%s", rerun.Namespace, rerun.Name)
+	op.enqueueReportRerun(rerun)
+}
+
+func (op *Reporting) updateReportRerun(prev, cur interface{}) {
+	prevReportRerun := prev.(*cbTypes.ReportRerun)
+	curReportRerun := cur.(*cbTypes.ReportRerun)
+	if curReportRerun.ResourceVersion == prevReportRerun.ResourceVersion {
+		op.logger.Debugf("ReportRerun %s/%s resourceVersion is unchanged, skipping update", curReportRerun.Namespace, curReportRerun.Name)
+		return
+	}
+	// the worker requeues the ReportRerun itself after processing each
+	// period, so only spec changes need to be queued here
+	if reflect.DeepEqual(prevReportRerun.Spec, curReportRerun.Spec) {
+		op.logger.Debugf("ReportRerun %s/%s spec is unchanged, skipping update", curReportRerun.Namespace, curReportRerun.Name)
+		return
+	}
+	op.logger.Infof("updating ReportRerun %s/%s", curReportRerun.Namespace, curReportRerun.Name)
+	op.enqueueReportRerun(curReportRerun)
+}
+
+func (op *Reporting) enqueueReportRerun(rerun *cbTypes.ReportRerun) {
+	key, err := cache.MetaNamespaceKeyFunc(rerun)
+	if err != nil {
+		op.logger.WithFields(log.Fields{"reportRerun": rerun.Name, "namespace": rerun.Namespace}).WithError(err).Errorf("couldn't get key for object: %#v", rerun)
+		return
+	}
+	op.reportRerunQueue.Add(key)
+}
+
+func (op *Reporting) addRateCard(obj interface{}) {
+	rateCard := obj.(*cbTypes.RateCard)
+	op.logger.Infof("adding RateCard %s/%s", rateCard.Namespace, rateCard.Name)
+	op.enqueueRateCard(rateCard)
+}
+
+func (op *Reporting) updateRateCard(prev, cur interface{}) {
+	prevRateCard := prev.(*cbTypes.RateCard)
+	curRateCard := cur.(*cbTypes.RateCard)
+	if curRateCard.ResourceVersion == prevRateCard.ResourceVersion {
+		op.logger.Debugf("RateCard %s/%s resourceVersion is unchanged, skipping update", curRateCard.Namespace, curRateCard.Name)
+		return
+	}
+	// the rates only need to be stored again if the spec changed, status
+	// updates come from the worker setting the table name
+	if reflect.DeepEqual(prevRateCard.Spec, curRateCard.Spec) {
+		op.logger.Debugf("RateCard %s/%s spec is unchanged, skipping update", curRateCard.Namespace, curRateCard.Name)
+		return
+	}
+	op.logger.Infof("updating RateCard %s/%s", curRateCard.Namespace, curRateCard.Name)
+	op.enqueueRateCard(curRateCard)
+}
+
+func (op *Reporting) enqueueRateCard(rateCard *cbTypes.RateCard) {
+	key, err := cache.MetaNamespaceKeyFunc(rateCard)
+	if err != nil {
+		op.logger.WithFields(log.Fields{"rateCard": rateCard.Name, "namespace": rateCard.Namespace}).WithError(err).Errorf("couldn't get key for object: %#v", rateCard)
+		return
+	}
+	op.rateCardQueue.Add(key)
+}
+
 type workerProcessFunc func(logger log.FieldLogger) bool
 
 func (op *Reporting) processResource(logger log.FieldLogger, handlerFunc syncHandler, objType string, queue workqueue.RateLimitingInterface, maxRequeues int) bool {
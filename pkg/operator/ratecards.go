@@ -0,0 +1,295 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+const (
+	// rateCardCostColumnSuffix is appended to the name of a column to get the
+	// name of the column containing its cost.
+	rateCardCostColumnSuffix = "_cost"
+
+	// namespaceUnit is the Unit of ReportGenerationQueryColumns containing
+	// the namespace the usage in a row belongs to.
+	namespaceUnit = "kubernetes_namespace"
+)
+
+func (op *Reporting) runRateCardWorker() {
+	logger := op.logger.WithField("component", "rateCardWorker")
+	logger.Infof("RateCard worker started")
+	const maxRequeues = 5
+	for op.processResource(logger, op.syncRateCard, "RateCard", op.rateCardQueue, maxRequeues) {
+	}
+}
+
+func (op *Reporting) syncRateCard(logger log.FieldLogger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.WithError(err).Errorf("invalid resource key :%s", key)
+		return nil
+	}
+
+	logger = logger.WithFields(log.Fields{"rateCard": name, "namespace": namespace})
+	rateCard, err := op.rateCardLister.RateCards(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Infof("RateCard %s/%s does not exist anymore", namespace, name)
+			return nil
+		}
+		return err
+	}
+
+	return op.handleRateCard(logger, rateCard.DeepCopy())
+}
+
+// handleRateCard creates the table for a RateCard if it doesn't exist yet,
+// and replaces its contents with the RateCard's current rates if they've
+// changed since they were last stored.
+func (op *Reporting) handleRateCard(logger log.FieldLogger, rateCard *cbTypes.RateCard) error {
+	rates, err := convertRateCardRates(rateCard)
+	if err != nil {
+		// retrying won't help until the RateCard is updated
+		logger.WithError(err).Errorf("invalid RateCard %s", rateCard.Name)
+		return nil
+	}
+
+	tableName := rateCard.Status.TableName
+	if tableName == "" {
+		tableName = reportingutil.RateCardTableName(rateCard.Namespace, rateCard.Name)
+		logger.Infof("creating table %s for RateCard %s", tableName, rateCard.Name)
+		err = op.createTableForStorage(logger, rateCard, cbTypes.SchemeGroupVersion.WithKind("RateCard"), rateCard.Spec.Storage, tableName, prestostore.RateCardHiveTableColumns, nil)
+		if err != nil {
+			return err
+		}
+		rateCard.Status.TableName = tableName
+		rateCard, err = op.meteringClient.MeteringV1alpha1().RateCards(rateCard.Namespace).Update(rateCard)
+		if err != nil {
+			logger.WithError(err).Errorf("failed to update RateCard table name for %q", rateCard.Name)
+			return err
+		}
+	}
+
+	ratesHash, err := rateCardRatesHash(rates)
+	if err != nil {
+		return err
+	}
+	if rateCard.Status.RatesHash == ratesHash {
+		logger.Debugf("rates of RateCard %s are already stored in table %s", rateCard.Name, tableName)
+		return nil
+	}
+
+	logger.Infof("storing %d rates into table %s", len(rates), tableName)
+	err = op.storeRateCardRates(logger, rateCard, tableName, rates)
+	if err != nil {
+		return err
+	}
+	rateCard.Status.RatesHash = ratesHash
+	_, err = op.meteringClient.MeteringV1alpha1().RateCards(rateCard.Namespace).Update(rateCard)
+	if err != nil {
+		logger.WithError(err).Errorf("failed to update RateCard rates hash for %q", rateCard.Name)
+		return err
+	}
+	return nil
+}
+
+// storeRateCardRates replaces the rates in tableName, the table of rateCard.
+// The rates are stored in a staging table which then replaces the table, so
+// queries never see the table without all of the rates.
+func (op *Reporting) storeRateCardRates(logger log.FieldLogger, rateCard *cbTypes.RateCard, tableName string, rates []prestostore.RateCardRate) error {
+	unlock := op.lockReportTable(tableName)
+	defer unlock()
+
+	prestoTable, err := op.getPrestoTable("RateCard", rateCard.Namespace, rateCard.Name, tableName)
+	if err != nil {
+		return err
+	}
+	err = op.cleanupReportStagingTables(logger, prestoTable)
+	if err != nil {
+		return err
+	}
+	staging, err := op.createReportStagingTable(logger, prestoTable)
+	if err != nil {
+		return err
+	}
+	err = op.rateCardRepo.StoreRateCardRates(staging.name, rates)
+	if err == nil {
+		err = op.validateReportStagingTable(staging, int64(len(rates)))
+	}
+	if err != nil {
+		op.dropReportStagingTable(logger, staging)
+		return err
+	}
+	return op.moveReportStagingTable(logger, prestoTable, staging)
+}
+
+// rateCardRatesHash returns a hash of rates, which changes if any of the rates
+// change.
+func rateCardRatesHash(rates []prestostore.RateCardRate) (string, error) {
+	data, err := json.Marshal(rates)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash rates: %v", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// convertRateCardRates validates the rates of a RateCard and converts them
+// into rows for the RateCard's table.
+func convertRateCardRates(rateCard *cbTypes.RateCard) ([]prestostore.RateCardRate, error) {
+	if rateCard.Spec.Currency == "" {
+		return nil, fmt.Errorf("spec.currency must be set")
+	}
+	rates := make([]prestostore.RateCardRate, len(rateCard.Spec.Rates))
+	for i, rate := range rateCard.Spec.Rates {
+		if rate.Unit == "" {
+			return nil, fmt.Errorf("spec.rates[%d].unit must be set", i)
+		}
+		price, err := strconv.ParseFloat(rate.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("spec.rates[%d].price %q is not a valid decimal number: %v", i, rate.Price, err)
+		}
+		if price < 0 {
+			return nil, fmt.Errorf("spec.rates[%d].price must not be negative", i)
+		}
+		var effectiveFrom, effectiveTo *time.Time
+		if rate.EffectiveFrom != nil {
+			t := rate.EffectiveFrom.UTC()
+			effectiveFrom = &t
+		}
+		if rate.EffectiveTo != nil {
+			t := rate.EffectiveTo.UTC()
+			effectiveTo = &t
+		}
+		if effectiveFrom != nil && effectiveTo != nil && !effectiveTo.After(*effectiveFrom) {
+			return nil, fmt.Errorf("spec.rates[%d].effectiveTo must be after effectiveFrom", i)
+		}
+		rates[i] = prestostore.RateCardRate{
+			Unit:          rate.Unit,
+			Price:         price,
+			Currency:      rateCard.Spec.Currency,
+			Namespace:     rate.Namespace,
+			NodeLabels:    rate.NodeLabels,
+			EffectiveFrom: effectiveFrom,
+			EffectiveTo:   effectiveTo,
+		}
+	}
+	return rates, nil
+}
+
+// rateCardPrice returns the price of unit at time t. Rates limited to
+// namespace take precedence over rates without a namespace, and when
+// multiple rates match, the one which became effective most recently is
+// used. Rates limited to node labels are ignored, since the labels of the
+// nodes usage came from aren't known at this point.
+func rateCardPrice(rates []prestostore.RateCardRate, unit, namespace string, t time.Time) (float64, bool) {
+	var match *prestostore.RateCardRate
+	for i := range rates {
+		rate := &rates[i]
+		if rate.Unit != unit || len(rate.NodeLabels) != 0 {
+			continue
+		}
+		if rate.Namespace != "" && rate.Namespace != namespace {
+			continue
+		}
+		if (rate.EffectiveFrom != nil && t.Before(*rate.EffectiveFrom)) || (rate.EffectiveTo != nil && !t.Before(*rate.EffectiveTo)) {
+			continue
+		}
+		if match == nil {
+			match = rate
+			continue
+		}
+		if (match.Namespace == "") != (rate.Namespace == "") {
+			if rate.Namespace != "" {
+				match = rate
+			}
+			continue
+		}
+		if rate.EffectiveFrom != nil && (match.EffectiveFrom == nil || rate.EffectiveFrom.After(*match.EffectiveFrom)) {
+			match = rate
+		}
+	}
+	if match == nil {
+		return 0, false
+	}
+	return match.Price, true
+}
+
+// addRateCardCostColumns adds a cost column after each column whose Unit has
+// a price in the RateCard, and returns a function which sets the cost
+// columns of a row. The cost of each row is the column's value multiplied by
+// the price effective at the row's period_start, or defaultTime if the row
+// has no period_start.
+func addRateCardCostColumns(rateCard *cbTypes.RateCard, columns []cbTypes.ReportGenerationQueryColumn, defaultTime time.Time) ([]cbTypes.ReportGenerationQueryColumn, func(presto.Row), error) {
+	rates, err := convertRateCardRates(rateCard)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid RateCard %s: %v", rateCard.Name, err)
+	}
+
+	pricedUnits := make(map[string]bool)
+	for _, rate := range rates {
+		pricedUnits[rate.Unit] = true
+	}
+
+	var namespaceColumn string
+	for _, column := range columns {
+		if column.Unit == namespaceUnit {
+			namespaceColumn = column.Name
+			break
+		}
+	}
+
+	var newColumns, pricedColumns []cbTypes.ReportGenerationQueryColumn
+	for _, column := range columns {
+		newColumns = append(newColumns, column)
+		if column.Unit == "" || !pricedUnits[column.Unit] {
+			continue
+		}
+		newColumns = append(newColumns, cbTypes.ReportGenerationQueryColumn{
+			Name:        column.Name + rateCardCostColumnSuffix,
+			Type:        "double",
+			Unit:        rateCard.Spec.Currency,
+			TableHidden: column.TableHidden,
+		})
+		pricedColumns = append(pricedColumns, column)
+	}
+
+	addCosts := func(row presto.Row) {
+		t := defaultTime
+		if periodStart, ok := row[reportingutil.PeriodStartColumnName].(time.Time); ok {
+			t = periodStart
+		}
+		namespace, _ := row[namespaceColumn].(string)
+		for _, column := range pricedColumns {
+			costColumnName := column.Name + rateCardCostColumnSuffix
+			var amount float64
+			switch v := row[column.Name].(type) {
+			case float64:
+				amount = v
+			case int64:
+				amount = float64(v)
+			default:
+				row[costColumnName] = nil
+				continue
+			}
+			price, ok := rateCardPrice(rates, column.Unit, namespace, t)
+			if !ok {
+				row[costColumnName] = nil
+				continue
+			}
+			row[costColumnName] = amount * price
+		}
+	}
+	return newColumns, addCosts, nil
+}
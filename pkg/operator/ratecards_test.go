@@ -0,0 +1,111 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+func TestAddRateCardCostColumns(t *testing.T) {
+	jan := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	rateCard := &cbTypes.RateCard{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: cbTypes.RateCardSpec{
+			Currency: "USD",
+			Rates: []cbTypes.Rate{
+				{Unit: "cpu_core_seconds", Price: "2", EffectiveTo: &metav1.Time{Time: feb}},
+				{Unit: "cpu_core_seconds", Price: "3", EffectiveFrom: &metav1.Time{Time: feb}},
+				{Unit: "cpu_core_seconds", Price: "1", Namespace: "batch"},
+				{Unit: "cpu_core_seconds", Price: "100", NodeLabels: map[string]string{"type": "gpu"}},
+				{Unit: "memory_byte_seconds", Price: "0.5"},
+			},
+		},
+	}
+	columns := []cbTypes.ReportGenerationQueryColumn{
+		{Name: "period_start", Type: "timestamp", Unit: "date"},
+		{Name: "namespace", Type: "varchar", Unit: "kubernetes_namespace"},
+		{Name: "cpu", Type: "double", Unit: "cpu_core_seconds"},
+		{Name: "memory", Type: "double", Unit: "memory_byte_seconds", TableHidden: true},
+		{Name: "pods", Type: "bigint", Unit: "kubernetes_pod"},
+	}
+	results := []presto.Row{
+		{"period_start": jan, "namespace": "default", "cpu": float64(10), "memory": int64(4), "pods": int64(1)},
+		{"period_start": feb, "namespace": "default", "cpu": float64(10), "memory": nil, "pods": int64(1)},
+		{"period_start": feb, "namespace": "batch", "cpu": float64(10), "memory": float64(2), "pods": int64(1)},
+	}
+
+	newColumns, addCosts, err := addRateCardCostColumns(rateCard, columns, jan)
+	require.NoError(t, err)
+	for _, row := range results {
+		addCosts(row)
+	}
+
+	expectedColumns := []cbTypes.ReportGenerationQueryColumn{
+		columns[0],
+		columns[1],
+		columns[2],
+		{Name: "cpu_cost", Type: "double", Unit: "USD"},
+		columns[3],
+		{Name: "memory_cost", Type: "double", Unit: "USD", TableHidden: true},
+		columns[4],
+	}
+	assert.Equal(t, expectedColumns, newColumns)
+
+	assert.Equal(t, float64(20), results[0]["cpu_cost"], "expected the rate effective before February to be used")
+	assert.Equal(t, float64(2), results[0]["memory_cost"])
+	assert.Equal(t, float64(30), results[1]["cpu_cost"], "expected the rate effective from February to be used")
+	assert.Nil(t, results[1]["memory_cost"], "expected a nil cost for a nil value")
+	assert.Equal(t, float64(10), results[2]["cpu_cost"], "expected the namespace specific rate to be used")
+	assert.Equal(t, float64(1), results[2]["memory_cost"])
+	assert.NotContains(t, results[0], "pods_cost")
+}
+
+func TestAddRateCardCostColumnsInvalidRateCard(t *testing.T) {
+	rateCard := &cbTypes.RateCard{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: cbTypes.RateCardSpec{
+			Currency: "USD",
+			Rates:    []cbTypes.Rate{{Unit: "cpu_core_seconds", Price: "not-a-number"}},
+		},
+	}
+	_, _, err := addRateCardCostColumns(rateCard, nil, time.Now())
+	assert.Error(t, err)
+}
+
+func TestRateCardRatesHash(t *testing.T) {
+	rateCard := &cbTypes.RateCard{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: cbTypes.RateCardSpec{
+			Currency: "USD",
+			Rates: []cbTypes.Rate{
+				{Unit: "cpu_core_seconds", Price: "2", NodeLabels: map[string]string{"type": "gpu", "zone": "a"}},
+			},
+		},
+	}
+	hash := func(rateCard *cbTypes.RateCard) string {
+		rates, err := convertRateCardRates(rateCard)
+		require.NoError(t, err)
+		hash, err := rateCardRatesHash(rates)
+		require.NoError(t, err)
+		return hash
+	}
+
+	original := hash(rateCard)
+	assert.Equal(t, original, hash(rateCard.DeepCopy()), "expected the hash of the same rates to be the same")
+
+	changed := rateCard.DeepCopy()
+	changed.Spec.Rates[0].Price = "2.5"
+	assert.NotEqual(t, original, hash(changed), "expected the hash to change when a price changes")
+
+	changed = rateCard.DeepCopy()
+	changed.Spec.Currency = "EUR"
+	assert.NotEqual(t, original, hash(changed), "expected the hash to change when the currency changes")
+}
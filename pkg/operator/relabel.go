@@ -0,0 +1,51 @@
+package operator
+
+import (
+	"fmt"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/promrelabel"
+)
+
+// newRelabelConfigs validates the relabelConfigs of a Prometheus
+// ReportDataSource and converts them into promrelabel.Configs, applying the
+// same defaults as Prometheus.
+func newRelabelConfigs(relabelConfigs []cbTypes.PrometheusRelabelConfig) ([]*promrelabel.Config, error) {
+	if len(relabelConfigs) == 0 {
+		return nil, nil
+	}
+	cfgs := make([]*promrelabel.Config, len(relabelConfigs))
+	for i, relabelConfig := range relabelConfigs {
+		cfg := &promrelabel.Config{
+			SourceLabels: relabelConfig.SourceLabels,
+			Separator:    promrelabel.DefaultSeparator,
+			Modulus:      relabelConfig.Modulus,
+			TargetLabel:  relabelConfig.TargetLabel,
+			Replacement:  promrelabel.DefaultReplacement,
+			Action:       promrelabel.Replace,
+		}
+		if relabelConfig.Separator != nil {
+			cfg.Separator = *relabelConfig.Separator
+		}
+		if relabelConfig.Replacement != nil {
+			cfg.Replacement = *relabelConfig.Replacement
+		}
+		if relabelConfig.Action != "" {
+			cfg.Action = promrelabel.Action(relabelConfig.Action)
+		}
+		regex := relabelConfig.Regex
+		if regex == "" {
+			regex = promrelabel.DefaultRegex
+		}
+		var err error
+		cfg.Regex, err = promrelabel.NewRegexp(regex)
+		if err != nil {
+			return nil, fmt.Errorf("spec.promsum.relabelConfigs[%d].regex is not a valid regular expression: %v", i, err)
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("spec.promsum.relabelConfigs[%d] is invalid: %v", i, err)
+		}
+		cfgs[i] = cfg
+	}
+	return cfgs, nil
+}
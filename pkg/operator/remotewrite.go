@@ -0,0 +1,393 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/promrelabel"
+	"github.com/operator-framework/operator-metering/pkg/promremote"
+)
+
+var (
+	prometheusReportDatasourceRemoteWriteSamplesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_remote_write_samples_total",
+			Help:      "Number of samples received via Prometheus remote write and stored for a ReportDataSource.",
+		},
+		[]string{"reportdatasource", "namespace", "table_name"},
+	)
+
+	prometheusReportDatasourceFailedRemoteWriteStoresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_failed_remote_write_stores_total",
+			Help:      "Number of failed attempts to store samples received via Prometheus remote write for a ReportDataSource.",
+		},
+		[]string{"reportdatasource", "namespace", "table_name"},
+	)
+)
+
+// remoteWriteStoredStepsRetention is how long the newest step stored for a
+// series received via remote write is remembered after it stops receiving
+// samples. Prometheus doesn't resend samples older than this.
+const remoteWriteStoredStepsRetention = 2 * time.Hour
+
+func init() {
+	prometheus.MustRegister(prometheusReportDatasourceRemoteWriteSamplesCounter)
+	prometheus.MustRegister(prometheusReportDatasourceFailedRemoteWriteStoresCounter)
+}
+
+// prometheusRemoteWriteStoreFunc stores series received via remote write
+// into a ReportDataSource's table, returning the number of samples stored.
+type prometheusRemoteWriteStoreFunc func(ctx context.Context, dataSource *cbTypes.ReportDataSource, series []*promremote.TimeSeries) (int, error)
+
+// remoteWriteImportStatus tracks the time range of the metrics received via
+// remote write for a ReportDataSource, until the ReportDataSource worker
+// records it in the ReportDataSource's PrometheusMetricImportStatus.
+type remoteWriteImportStatus struct {
+	earliest, newest time.Time
+}
+
+// remoteWriteMatcher matches series against a ReportDataSource's
+// remoteWrite configuration.
+type remoteWriteMatcher struct {
+	dataSource *cbTypes.ReportDataSource
+	metricName string
+	matchers   []compiledLabelMatcher
+}
+
+type compiledLabelMatcher struct {
+	cbTypes.PrometheusLabelMatcher
+	re *regexp.Regexp
+}
+
+func newRemoteWriteMatcher(dataSource *cbTypes.ReportDataSource) (*remoteWriteMatcher, error) {
+	cfg := dataSource.Spec.Promsum.RemoteWrite
+	if cfg.MetricName == "" && len(cfg.Matchers) == 0 {
+		return nil, fmt.Errorf("spec.promsum.remoteWrite must have a metricName or matchers")
+	}
+	m := &remoteWriteMatcher{dataSource: dataSource, metricName: cfg.MetricName}
+	for i, matcher := range cfg.Matchers {
+		if matcher.Name == "" {
+			return nil, fmt.Errorf("spec.promsum.remoteWrite.matchers[%d].name must be set", i)
+		}
+		compiled := compiledLabelMatcher{PrometheusLabelMatcher: matcher}
+		switch matcher.Type {
+		case "":
+			compiled.Type = cbTypes.PrometheusLabelMatchEqual
+		case cbTypes.PrometheusLabelMatchEqual, cbTypes.PrometheusLabelMatchNotEqual:
+		case cbTypes.PrometheusLabelMatchRegexp, cbTypes.PrometheusLabelMatchNotRegexp:
+			// regular expressions must match the entire label value, the
+			// same as PromQL
+			re, err := regexp.Compile("^(?:" + matcher.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("spec.promsum.remoteWrite.matchers[%d].value is not a valid regular expression: %v", i, err)
+			}
+			compiled.re = re
+		default:
+			return nil, fmt.Errorf("spec.promsum.remoteWrite.matchers[%d].type %q must be one of =, !=, =~ or !~", i, matcher.Type)
+		}
+		m.matchers = append(m.matchers, compiled)
+	}
+	return m, nil
+}
+
+// matches returns true if the series with the labels specified has the
+// metric name configured and matches all of the label matchers. Labels which
+// aren't set are treated as having an empty value.
+func (m *remoteWriteMatcher) matches(labels map[string]string) bool {
+	if m.metricName != "" && labels[promremote.MetricNameLabel] != m.metricName {
+		return false
+	}
+	for _, matcher := range m.matchers {
+		value := labels[matcher.Name]
+		var matched bool
+		switch matcher.Type {
+		case cbTypes.PrometheusLabelMatchEqual:
+			matched = value == matcher.Value
+		case cbTypes.PrometheusLabelMatchNotEqual:
+			matched = value != matcher.Value
+		case cbTypes.PrometheusLabelMatchRegexp:
+			matched = matcher.re.MatchString(value)
+		case cbTypes.PrometheusLabelMatchNotRegexp:
+			matched = !matcher.re.MatchString(value)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// remoteWriteSeriesToPrometheusMetrics converts the samples of a series
+// into PrometheusMetrics after applying relabelConfigs to its labels. The
+// metric name label is dropped, since the ReportDataSource identifies the
+// metric, and stale markers and other NaN samples are skipped.
+//
+// Prometheus sends every sample it scrapes, which is usually more often than
+// stepSize, so the samples are downsampled to the first sample in each step,
+// with its timestamp set to the start of the step. This way each metric
+// accounts for exactly stepSize, the same as the metrics imported using a
+// range query.
+func remoteWriteSeriesToPrometheusMetrics(series *promremote.TimeSeries, stepSize time.Duration, relabelConfigs []*promrelabel.Config) []*prestostore.PrometheusMetric {
+	labels := promrelabel.Process(series.LabelsMap(), relabelConfigs)
+	if labels == nil {
+		return nil
+	}
+	delete(labels, promremote.MetricNameLabel)
+
+	var metrics []*prestostore.PrometheusMetric
+	for _, sample := range series.Samples {
+		if math.IsNaN(sample.Value) {
+			continue
+		}
+		timestamp := time.Unix(0, sample.Timestamp*int64(time.Millisecond)).UTC().Truncate(stepSize)
+		// samples are sent in order, so a metric for this step has already
+		// been created if one exists
+		if n := len(metrics); n > 0 && !timestamp.After(metrics[n-1].Timestamp) {
+			continue
+		}
+		metrics = append(metrics, &prestostore.PrometheusMetric{
+			Labels:    labels,
+			Amount:    sample.Value,
+			StepSize:  stepSize,
+			Timestamp: timestamp,
+		})
+	}
+	return metrics
+}
+
+func (op *Reporting) getStepSizeForReportDataSource(dataSource *cbTypes.ReportDataSource) time.Duration {
+	stepSize := op.cfg.PrometheusQueryConfig.StepSize.Duration
+	if queryConf := dataSource.Spec.Promsum.QueryConfig; queryConf != nil && queryConf.StepSize != nil {
+		stepSize = queryConf.StepSize.Duration
+	}
+	return stepSize
+}
+
+// storeRemoteWriteSeries stores series received via remote write into the
+// ReportDataSource's table, and records the time range they cover so the
+// ReportDataSource worker can update the ReportDataSource's status.
+func (op *Reporting) storeRemoteWriteSeries(ctx context.Context, dataSource *cbTypes.ReportDataSource, series []*promremote.TimeSeries) (int, error) {
+	tableName := dataSource.Status.TableName
+	if tableName == "" {
+		return 0, fmt.Errorf("ReportDataSource %s table has not been created yet", dataSource.Name)
+	}
+	relabelConfigs, err := newRelabelConfigs(dataSource.Spec.Promsum.RelabelConfigs)
+	if err != nil {
+		return 0, err
+	}
+	stepSize := op.getStepSizeForReportDataSource(dataSource)
+	var metrics []*prestostore.PrometheusMetric
+	for _, s := range series {
+		metrics = append(metrics, remoteWriteSeriesToPrometheusMetrics(s, stepSize, relabelConfigs)...)
+	}
+	// dropping the metric name or relabeling can give series the same labels
+	metrics = prestostore.SumPrometheusMetrics(metrics)
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+	key, err := cache.MetaNamespaceKeyFunc(dataSource)
+	if err != nil {
+		return 0, err
+	}
+	metrics, previousSteps := op.reserveRemoteWriteSteps(key, metrics)
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+	metricLabels := prometheus.Labels{
+		"reportdatasource": dataSource.Name,
+		"namespace":        dataSource.Namespace,
+		"table_name":       tableName,
+	}
+
+	err = op.prometheusMetricsRepo.StorePrometheusMetrics(ctx, tableName, metrics)
+	if err != nil {
+		// Prometheus will resend the samples, so they must be stored then
+		op.releaseRemoteWriteSteps(key, metrics, previousSteps)
+		prometheusReportDatasourceFailedRemoteWriteStoresCounter.With(metricLabels).Inc()
+		return 0, err
+	}
+	prometheusReportDatasourceRemoteWriteSamplesCounter.With(metricLabels).Add(float64(len(metrics)))
+
+	earliest, newest := metrics[0].Timestamp, metrics[0].Timestamp
+	for _, metric := range metrics {
+		if metric.Timestamp.Before(earliest) {
+			earliest = metric.Timestamp
+		}
+		if metric.Timestamp.After(newest) {
+			newest = metric.Timestamp
+		}
+	}
+
+	op.recordRemoteWriteImport(key, earliest, newest)
+	return len(metrics), nil
+}
+
+// reserveRemoteWriteSteps returns the metrics for steps newer than the
+// newest step already stored for their series, and records them as the
+// newest steps stored. Prometheus resends every sample in a request when
+// storing any of them fails, and a step's samples can be split across
+// requests, so this stops a step being stored more than once. The newest
+// steps stored before are returned for releaseRemoteWriteSteps.
+func (op *Reporting) reserveRemoteWriteSteps(key string, metrics []*prestostore.PrometheusMetric) ([]*prestostore.PrometheusMetric, map[string]time.Time) {
+	op.remoteWriteMu.Lock()
+	defer op.remoteWriteMu.Unlock()
+	storedSteps, exists := op.remoteWriteStoredSteps[key]
+	if !exists {
+		storedSteps = make(map[string]time.Time)
+		op.remoteWriteStoredSteps[key] = storedSteps
+	}
+
+	previousSteps := make(map[string]time.Time)
+	var newMetrics []*prestostore.PrometheusMetric
+	for _, metric := range metrics {
+		seriesKey := prestostore.PrometheusMetricLabelsKey(metric.Labels)
+		newest, stored := storedSteps[seriesKey]
+		if stored && !metric.Timestamp.After(newest) {
+			continue
+		}
+		if _, reserved := previousSteps[seriesKey]; !reserved {
+			previousSteps[seriesKey] = newest
+		}
+		storedSteps[seriesKey] = metric.Timestamp
+		newMetrics = append(newMetrics, metric)
+	}
+	return newMetrics, previousSteps
+}
+
+// releaseRemoteWriteSteps undoes reserveRemoteWriteSteps after metrics
+// failed to be stored, unless newer steps have been stored since.
+func (op *Reporting) releaseRemoteWriteSteps(key string, metrics []*prestostore.PrometheusMetric, previousSteps map[string]time.Time) {
+	op.remoteWriteMu.Lock()
+	defer op.remoteWriteMu.Unlock()
+	storedSteps := op.remoteWriteStoredSteps[key]
+	newestReserved := make(map[string]time.Time)
+	for _, metric := range metrics {
+		seriesKey := prestostore.PrometheusMetricLabelsKey(metric.Labels)
+		if metric.Timestamp.After(newestReserved[seriesKey]) {
+			newestReserved[seriesKey] = metric.Timestamp
+		}
+	}
+	for seriesKey, reserved := range newestReserved {
+		if !storedSteps[seriesKey].Equal(reserved) {
+			continue
+		}
+		if previous := previousSteps[seriesKey]; previous.IsZero() {
+			delete(storedSteps, seriesKey)
+		} else {
+			storedSteps[seriesKey] = previous
+		}
+	}
+}
+
+// pruneRemoteWriteSteps forgets the newest steps stored for series which
+// haven't received samples within remoteWriteStoredStepsRetention.
+func (op *Reporting) pruneRemoteWriteSteps(key string) {
+	cutoff := op.clock.Now().Add(-remoteWriteStoredStepsRetention)
+	op.remoteWriteMu.Lock()
+	defer op.remoteWriteMu.Unlock()
+	storedSteps := op.remoteWriteStoredSteps[key]
+	for seriesKey, newest := range storedSteps {
+		if newest.Before(cutoff) {
+			delete(storedSteps, seriesKey)
+		}
+	}
+	if len(storedSteps) == 0 {
+		delete(op.remoteWriteStoredSteps, key)
+	}
+}
+
+func (op *Reporting) recordRemoteWriteImport(key string, earliest, newest time.Time) {
+	op.remoteWriteMu.Lock()
+	defer op.remoteWriteMu.Unlock()
+	status, exists := op.remoteWriteStatuses[key]
+	if !exists {
+		op.remoteWriteStatuses[key] = &remoteWriteImportStatus{earliest: earliest, newest: newest}
+		return
+	}
+	if earliest.Before(status.earliest) {
+		status.earliest = earliest
+	}
+	if newest.After(status.newest) {
+		status.newest = newest
+	}
+}
+
+// handleRemoteWriteDataSource records the time range of the metrics
+// received via remote write since it was last called in the
+// ReportDataSource's PrometheusMetricImportStatus, and queues the
+// ReportDataSource to do so again after its query interval.
+func (op *Reporting) handleRemoteWriteDataSource(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource) error {
+	if _, err := newRemoteWriteMatcher(dataSource); err != nil {
+		// retrying won't help until the ReportDataSource is updated
+		logger.WithError(err).Errorf("invalid remoteWrite configuration for ReportDataSource %s", dataSource.Name)
+		return nil
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(dataSource)
+	if err != nil {
+		return err
+	}
+	op.pruneRemoteWriteSteps(key)
+	op.remoteWriteMu.Lock()
+	status := op.remoteWriteStatuses[key]
+	delete(op.remoteWriteStatuses, key)
+	op.remoteWriteMu.Unlock()
+
+	queryInterval := op.getQueryIntervalForReportDataSource(dataSource)
+	if status == nil {
+		logger.Debugf("no metrics received via remote write for ReportDataSource %s", dataSource.Name)
+		op.enqueueReportDataSourceAfter(dataSource, queryInterval)
+		return nil
+	}
+
+	importStatus := dataSource.Status.PrometheusMetricImportStatus
+	if importStatus == nil {
+		importStatus = &cbTypes.PrometheusMetricImportStatus{}
+		dataSource.Status.PrometheusMetricImportStatus = importStatus
+	}
+	importStatus.LastImportTime = &metav1.Time{Time: op.clock.Now().UTC()}
+	if importStatus.ImportDataStartTime == nil || status.earliest.Before(importStatus.ImportDataStartTime.Time) {
+		importStatus.ImportDataStartTime = &metav1.Time{Time: status.earliest}
+	}
+	if importStatus.ImportDataEndTime == nil || status.newest.After(importStatus.ImportDataEndTime.Time) {
+		importStatus.ImportDataEndTime = &metav1.Time{Time: status.newest}
+	}
+	if importStatus.EarliestImportedMetricTime == nil || status.earliest.Before(importStatus.EarliestImportedMetricTime.Time) {
+		importStatus.EarliestImportedMetricTime = &metav1.Time{Time: status.earliest}
+	}
+	if importStatus.NewestImportedMetricTime == nil || status.newest.After(importStatus.NewestImportedMetricTime.Time) {
+		importStatus.NewestImportedMetricTime = &metav1.Time{Time: status.newest}
+	}
+
+	updatedDataSource, err := op.meteringClient.MeteringV1alpha1().ReportDataSources(dataSource.Namespace).Update(dataSource)
+	if err != nil {
+		// put the time range back so it's recorded on the next attempt
+		op.recordRemoteWriteImport(key, status.earliest, status.newest)
+		return fmt.Errorf("unable to update ReportDataSource %s PrometheusMetricImportStatus: %v", dataSource.Name, err)
+	}
+	dataSource = updatedDataSource
+
+	if err := op.queueDependentReportGenerationQueriesForDataSource(dataSource); err != nil {
+		logger.WithError(err).Errorf("error queuing ReportGenerationQuery dependents of ReportDataSource %s", dataSource.Name)
+	}
+	if err := op.queueDependentReportsForDataSource(dataSource); err != nil {
+		logger.WithError(err).Errorf("error queuing Report dependents of ReportDataSource %s", dataSource.Name)
+	}
+
+	logger.Infof("queuing remote write ReportDataSource %s to update its import status again in %s", dataSource.Name, queryInterval)
+	op.enqueueReportDataSourceAfter(dataSource, queryInterval)
+	return nil
+}
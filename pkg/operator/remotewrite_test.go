@@ -0,0 +1,148 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/promremote"
+)
+
+func TestRemoteWriteSeriesToPrometheusMetrics(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(value float64, offset time.Duration) *promremote.Sample {
+		return &promremote.Sample{Value: value, Timestamp: janOne.Add(offset).UnixNano() / int64(time.Millisecond)}
+	}
+	series := &promremote.TimeSeries{
+		Labels: []*promremote.Label{
+			{Name: promremote.MetricNameLabel, Value: "kube_pod_container_resource_requests_cpu_cores"},
+			{Name: "namespace", Value: "default"},
+		},
+		Samples: []*promremote.Sample{
+			sample(1, 15*time.Second),
+			sample(2, 45*time.Second),
+			sample(math.NaN(), 70*time.Second),
+			sample(3, 80*time.Second),
+			sample(4, 110*time.Second),
+			sample(5, 3*time.Minute),
+		},
+	}
+
+	metrics := remoteWriteSeriesToPrometheusMetrics(series, time.Minute, nil)
+	require.Len(t, metrics, 3, "expected one metric per step")
+	for i, expected := range []struct {
+		amount    float64
+		timestamp time.Time
+	}{
+		{amount: 1, timestamp: janOne},
+		{amount: 3, timestamp: janOne.Add(time.Minute)},
+		{amount: 5, timestamp: janOne.Add(3 * time.Minute)},
+	} {
+		assert.Equal(t, expected.amount, metrics[i].Amount, "expected the first sample in the step to be used")
+		assert.Equal(t, expected.timestamp, metrics[i].Timestamp, "expected the timestamp to be the start of the step")
+		assert.Equal(t, time.Minute, metrics[i].StepSize)
+		assert.Equal(t, map[string]string{"namespace": "default"}, metrics[i].Labels)
+	}
+}
+
+func TestStoreRemoteWriteSeries(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dataSource := &cbTypes.ReportDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "cpu-requests", Namespace: "default"},
+		Spec: cbTypes.ReportDataSourceSpec{
+			Promsum: &cbTypes.PrometheusMetricsDataSource{
+				QueryConfig: &cbTypes.PrometheusQueryConfig{StepSize: &metav1.Duration{Duration: time.Minute}},
+				RemoteWrite: &cbTypes.PrometheusRemoteWriteConfig{MetricName: "kube_pod_container_resource_requests_cpu_cores"},
+			},
+		},
+		Status: cbTypes.ReportDataSourceStatus{TableName: "datasource_default_cpu_requests"},
+	}
+	newSeries := func(namespace string, offsets ...time.Duration) *promremote.TimeSeries {
+		series := &promremote.TimeSeries{
+			Labels: []*promremote.Label{
+				{Name: promremote.MetricNameLabel, Value: "kube_pod_container_resource_requests_cpu_cores"},
+				{Name: "namespace", Value: namespace},
+			},
+		}
+		for _, offset := range offsets {
+			series.Samples = append(series.Samples, &promremote.Sample{Value: 1, Timestamp: janOne.Add(offset).UnixNano() / int64(time.Millisecond)})
+		}
+		return series
+	}
+
+	repo := &fakePrometheusMetricsRepo{metrics: make(map[string][]*prestostore.PrometheusMetric)}
+	op := &Reporting{
+		cfg: Config{
+			PrometheusQueryConfig: cbTypes.PrometheusQueryConfig{StepSize: &metav1.Duration{Duration: 5 * time.Minute}},
+		},
+		prometheusMetricsRepo:  repo,
+		clock:                  clock.NewFakeClock(janOne.Add(time.Hour)),
+		remoteWriteStatuses:    make(map[string]*remoteWriteImportStatus),
+		remoteWriteStoredSteps: make(map[string]map[string]time.Time),
+	}
+	ctx := context.Background()
+	storedTimestamps := func(namespace string) []time.Time {
+		var timestamps []time.Time
+		for _, metric := range repo.metrics[dataSource.Status.TableName] {
+			if metric.Labels["namespace"] == namespace {
+				timestamps = append(timestamps, metric.Timestamp)
+			}
+		}
+		return timestamps
+	}
+
+	stored, err := op.storeRemoteWriteSeries(ctx, dataSource, []*promremote.TimeSeries{newSeries("default", 10*time.Second, 30*time.Second)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+
+	// the rest of the first step's samples, and the first sample of the next step
+	stored, err = op.storeRemoteWriteSeries(ctx, dataSource, []*promremote.TimeSeries{newSeries("default", 50*time.Second, 70*time.Second)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored, "expected a step which was already stored to be skipped")
+
+	// failing to store a request means Prometheus resends it
+	repo.err = errors.New("presto is down")
+	retried := []*promremote.TimeSeries{
+		newSeries("default", 70*time.Second, 130*time.Second),
+		newSeries("app", 10*time.Second),
+	}
+	_, err = op.storeRemoteWriteSeries(ctx, dataSource, retried)
+	require.Error(t, err)
+	repo.err = nil
+	stored, err = op.storeRemoteWriteSeries(ctx, dataSource, retried)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored, "expected the steps which failed to be stored to be stored when the request is resent")
+	stored, err = op.storeRemoteWriteSeries(ctx, dataSource, retried)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored, "expected a request which was resent after being stored to be skipped")
+
+	assert.Equal(t, []time.Time{janOne, janOne.Add(time.Minute), janOne.Add(2 * time.Minute)}, storedTimestamps("default"))
+	assert.Equal(t, []time.Time{janOne}, storedTimestamps("app"))
+
+	// series with the same labels once the metric name is dropped are summed
+	collidingSeries := []*promremote.TimeSeries{newSeries("collide", 10*time.Second), newSeries("collide", 20*time.Second)}
+	collidingSeries[1].Labels[0].Value = "kube_pod_container_resource_limits_cpu_cores"
+	stored, err = op.storeRemoteWriteSeries(ctx, dataSource, collidingSeries)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored, "expected a single metric for the series with the same labels")
+	assert.Equal(t, []time.Time{janOne}, storedTimestamps("collide"))
+	for _, metric := range repo.metrics[dataSource.Status.TableName] {
+		if metric.Labels["namespace"] == "collide" {
+			assert.Equal(t, float64(2), metric.Amount, "expected the amounts of the series with the same labels to be summed")
+		}
+	}
+
+	// the steps stored are forgotten once their series stop receiving samples
+	op.clock = clock.NewFakeClock(janOne.Add(2*time.Minute + remoteWriteStoredStepsRetention + time.Second))
+	op.pruneRemoteWriteSteps("default/cpu-requests")
+	assert.Empty(t, op.remoteWriteStoredSteps)
+}
@@ -0,0 +1,681 @@
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+	"github.com/operator-framework/operator-metering/pkg/util/orderedmap"
+)
+
+const (
+	defaultReportExportFormat = "csv"
+	defaultReportExportRegion = "us-east-1"
+
+	// reportExportHTTPTimeout is how long an HTTP export can take before
+	// it's cancelled.
+	reportExportHTTPTimeout = 5 * time.Minute
+)
+
+var (
+	reportExportPrometheusMetricLabels = []string{"report", "namespace", "export"}
+
+	reportExportTotalCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "report_exports_total",
+			Help:      "Number of times the results of a Report were exported.",
+		},
+		reportExportPrometheusMetricLabels,
+	)
+
+	reportExportFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "report_exports_failed_total",
+			Help:      "Number of times exporting the results of a Report failed.",
+		},
+		reportExportPrometheusMetricLabels,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reportExportTotalCounter)
+	prometheus.MustRegister(reportExportFailedCounter)
+}
+
+// reportExportFormats are the formats results can be exported in, and the
+// file extension and content type of each.
+var reportExportFormats = map[string]struct {
+	extension   string
+	contentType string
+}{
+	"csv":     {extension: "csv", contentType: "text/csv"},
+	"json":    {extension: "json", contentType: "application/json"},
+	"ndjson":  {extension: "ndjson", contentType: "application/x-ndjson"},
+	"parquet": {extension: "parquet", contentType: "application/vnd.apache.parquet"},
+}
+
+// reportExportPathData is used to render the path template of a
+// ReportExport.
+type reportExportPathData struct {
+	Namespace   string
+	Report      string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Extension   string
+}
+
+func reportExportFormat(export cbTypes.ReportExport) string {
+	if export.Format == "" {
+		return defaultReportExportFormat
+	}
+	return export.Format
+}
+
+func parseReportExportPath(export cbTypes.ReportExport) (*template.Template, error) {
+	pathTmpl := export.Path
+	if pathTmpl == "" {
+		pathTmpl = cbTypes.DefaultReportExportPath
+	}
+	return template.New("path").Option("missingkey=error").Parse(pathTmpl)
+}
+
+// validateReportExport returns an error if export isn't valid. The hosts of
+// HTTP exports and S3 endpoints must be in allowedHosts.
+func validateReportExport(export cbTypes.ReportExport, allowedHosts []string) error {
+	if export.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	if _, ok := reportExportFormats[reportExportFormat(export)]; !ok {
+		return fmt.Errorf("format must be one of: csv, json, ndjson or parquet")
+	}
+	if _, err := parseReportExportPath(export); err != nil {
+		return fmt.Errorf("invalid path template: %v", err)
+	}
+	targets := 0
+	if export.S3 != nil {
+		targets++
+		if export.S3.Bucket == "" {
+			return fmt.Errorf("s3.bucket must be set")
+		}
+		if export.S3.Endpoint != "" {
+			u, err := parseS3Endpoint(export.S3.Endpoint)
+			if err != nil {
+				return fmt.Errorf("invalid s3.endpoint: %v", err)
+			}
+			if !reportExportHostAllowed(allowedHosts, u.Host) {
+				return fmt.Errorf("s3.endpoint host %q isn't one of the reporting-operator's allowed export hosts", u.Host)
+			}
+		}
+	}
+	if export.Local != nil {
+		targets++
+	}
+	if export.HTTP != nil {
+		targets++
+		u, err := url.Parse(export.HTTP.URL)
+		if err != nil {
+			return fmt.Errorf("invalid http.url: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("http.url must be an http or https URL")
+		}
+		if !reportExportHostAllowed(allowedHosts, u.Host) {
+			return fmt.Errorf("http.url host %q isn't one of the reporting-operator's allowed export hosts", u.Host)
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("exactly one of s3, local or http must be set")
+	}
+	return nil
+}
+
+// parseS3Endpoint parses the endpoint of an S3 export, which defaults to
+// https if it has no scheme, the same as the AWS SDK.
+func parseS3Endpoint(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", endpoint)
+	}
+	return u, nil
+}
+
+// reportExportHostAllowed returns true if host, which may include a port,
+// matches one of allowedHosts. Allowed hosts without a port match any port,
+// and allowed hosts starting with "*." match any subdomain of the rest of
+// the host.
+func reportExportHostAllowed(allowedHosts []string, host string) bool {
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		name := hostname
+		if _, _, err := net.SplitHostPort(allowed); err == nil {
+			name = host
+		}
+		if name == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(name, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderReportExportPath renders the path template of export. The path is
+// cleaned and made relative, and can't refer to a parent directory of the
+// export's target.
+func renderReportExportPath(export cbTypes.ReportExport, namespace, reportName string, periodStart, periodEnd time.Time) (string, error) {
+	tmpl, err := parseReportExportPath(export)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, reportExportPathData{
+		Namespace:   namespace,
+		Report:      reportName,
+		PeriodStart: periodStart.UTC(),
+		PeriodEnd:   periodEnd.UTC(),
+		Extension:   reportExportFormats[reportExportFormat(export)].extension,
+	})
+	if err != nil {
+		return "", err
+	}
+	exportPath := strings.TrimPrefix(path.Clean("/"+buf.String()), "/")
+	if exportPath == "" {
+		return "", fmt.Errorf("path %q is empty", buf.String())
+	}
+	return exportPath, nil
+}
+
+func newReportExportEncoder(format string, columns []cbTypes.ReportGenerationQueryColumn, w io.Writer) (resultsEncoder, error) {
+	switch format {
+	case "csv":
+		return newCSVResultsEncoder(columns, w, ','), nil
+	case "json":
+		return newJSONArrayResultsEncoder(w, "", "", func(row presto.Row) (interface{}, error) {
+			return orderedmap.NewFromMap(row)
+		}), nil
+	case "ndjson":
+		return newNDJSONResultsEncoder(w), nil
+	case "parquet":
+		return newParquetResultsEncoder(columns, w)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// reportExportTarget writes exported results to their destination.
+type reportExportTarget interface {
+	// Write writes size bytes read from r to exportPath, returning the
+	// location the results were written to.
+	Write(exportPath, contentType string, r io.ReadSeeker, size int64) (string, error)
+}
+
+// localReportExportTarget writes exports to files within directory.
+type localReportExportTarget struct {
+	directory string
+}
+
+func (t *localReportExportTarget) Write(exportPath, contentType string, r io.ReadSeeker, size int64) (string, error) {
+	filePath := filepath.Join(t.directory, filepath.FromSlash(exportPath))
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// write to a temporary file which is renamed once it's complete, so
+	// readers never see a partially written export.
+	tmpFile, err := ioutil.TempFile(dir, "."+filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = io.Copy(tmpFile, r)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// s3ReportExportTarget writes exports to objects in an S3 bucket.
+type s3ReportExportTarget struct {
+	s3API  s3iface.S3API
+	bucket string
+	prefix string
+}
+
+func (t *s3ReportExportTarget) Write(exportPath, contentType string, r io.ReadSeeker, size int64) (string, error) {
+	key := exportPath
+	if t.prefix != "" {
+		key = strings.TrimSuffix(t.prefix, "/") + "/" + exportPath
+	}
+	_, err := t.s3API.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(t.bucket),
+		Key:           aws.String(key),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", t.bucket, key), nil
+}
+
+// httpReportExportTarget sends exports as the body of a POST request to url.
+// The Report and period are sent in the X-Metering-* headers.
+type httpReportExportTarget struct {
+	client  *http.Client
+	url     string
+	headers http.Header
+}
+
+func (t *httpReportExportTarget) Write(exportPath, contentType string, r io.ReadSeeker, size int64) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, t.url, ioutil.NopCloser(r))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	for key, vals := range t.headers {
+		req.Header[key] = vals
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment;filename=%s", path.Base(exportPath)))
+	req.Header.Set("X-Metering-Export-Path", exportPath)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s returned status %d: %s", t.url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return t.url, nil
+}
+
+// reportExportJob exports the results of a reporting period of a Report
+// which has finished running. Jobs are queued by pointer, so each is
+// processed independently.
+type reportExportJob struct {
+	namespace string
+	report    string
+	run       cbTypes.ReportRun
+}
+
+// queueReportExports queues the export of the reporting period of run to
+// each of the Report's spec.exports. The ReportNotifications for run are sent
+// once the exports finish, so the events include their outcomes.
+func (op *Reporting) queueReportExports(logger log.FieldLogger, report *cbTypes.Report, run cbTypes.ReportRun) {
+	logger.Debugf("queueing exports of Report %s for period [%s to %s]", report.Name, run.PeriodStart.Time, run.PeriodEnd.Time)
+	op.reportExportQueue.Add(&reportExportJob{
+		namespace: report.Namespace,
+		report:    report.Name,
+		run:       run,
+	})
+}
+
+func (op *Reporting) runReportExportWorker() {
+	logger := op.logger.WithField("component", "reportExportWorker")
+	logger.Infof("ReportExport worker started")
+	for op.processReportExportJob(logger) {
+	}
+}
+
+// processReportExportJob runs the next queued export job. Failed exports
+// aren't retried, and their outcomes are recorded in the Report's status by
+// the Report worker.
+func (op *Reporting) processReportExportJob(logger log.FieldLogger) bool {
+	obj, quit := op.reportExportQueue.Get()
+	if quit {
+		logger.Infof("queue is shutting down, exiting ReportExport worker")
+		return false
+	}
+	defer op.reportExportQueue.Done(obj)
+	op.reportExportQueue.Forget(obj)
+
+	job, ok := obj.(*reportExportJob)
+	if !ok {
+		logger.Errorf("expected *reportExportJob in work queue but got %#v", obj)
+		return true
+	}
+	logger = logger.WithFields(log.Fields{"report": job.report, "namespace": job.namespace})
+
+	report, err := op.reportLister.Reports(job.namespace).Get(job.report)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Infof("Report %s no longer exists, not exporting it", job.report)
+		} else {
+			logger.WithError(err).Errorf("unable to get Report %s, not exporting it", job.report)
+		}
+		return true
+	}
+	genQuery, err := op.getReportGenerationQueryForReport(report)
+	if err != nil {
+		logger.WithError(err).Errorf("unable to get ReportGenerationQuery %s, not exporting Report %s", report.Spec.GenerationQueryName, report.Name)
+		return true
+	}
+
+	report = report.DeepCopy()
+	statuses := op.runReportExports(logger, report, genQuery, job.run.PeriodStart.Time, job.run.PeriodEnd.Time)
+	op.addPendingReportExportStatuses(report, statuses)
+	for _, status := range statuses {
+		report.Status.Exports = mergeReportExportStatus(report.Status.Exports, status)
+	}
+	op.notifyReportRun(logger, report, genQuery, job.run)
+	op.enqueueReport(report)
+	return true
+}
+
+// runReportExports exports the results of a reporting period to each of the
+// Report's spec.exports, and returns the outcome of each.
+func (op *Reporting) runReportExports(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, periodStart, periodEnd time.Time) []cbTypes.ReportExportStatus {
+	var statuses []cbTypes.ReportExportStatus
+	for _, export := range report.Spec.Exports {
+		exportLogger := logger.WithField("export", export.Name)
+		metricLabels := prometheus.Labels{
+			"report":    report.Name,
+			"namespace": report.Namespace,
+			"export":    export.Name,
+		}
+		reportExportTotalCounter.With(metricLabels).Inc()
+
+		location, err := op.exportReport(report, genQuery, export, periodStart, periodEnd)
+		status := cbTypes.ReportExportStatus{
+			Name:        export.Name,
+			PeriodStart: metav1.Time{Time: periodStart},
+			PeriodEnd:   metav1.Time{Time: periodEnd},
+			ExportTime:  metav1.Time{Time: op.clock.Now().UTC()},
+			Location:    location,
+			Outcome:     cbTypes.ReportRunSucceeded,
+		}
+		if err != nil {
+			reportExportFailedCounter.With(metricLabels).Inc()
+			exportLogger.WithError(err).Errorf("failed to export Report %s", report.Name)
+			status.Outcome = cbTypes.ReportRunFailed
+			status.Error = err.Error()
+		} else {
+			exportLogger.Infof("exported Report %s to %s", report.Name, location)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// mergeReportExportStatus sets the status of an export in statuses, unless
+// statuses already has the status of a later reporting period of the export,
+// as the exports of consecutive periods can finish out of order.
+func mergeReportExportStatus(statuses []cbTypes.ReportExportStatus, status cbTypes.ReportExportStatus) []cbTypes.ReportExportStatus {
+	for i := range statuses {
+		if statuses[i].Name == status.Name {
+			if !statuses[i].PeriodEnd.After(status.PeriodEnd.Time) {
+				statuses[i] = status
+			}
+			return statuses
+		}
+	}
+	return append(statuses, status)
+}
+
+func reportExportStatusKey(report *cbTypes.Report) string {
+	return report.Namespace + "/" + report.Name
+}
+
+// addPendingReportExportStatuses stores the outcomes of exports until the
+// Report worker saves them in the Report's status.
+func (op *Reporting) addPendingReportExportStatuses(report *cbTypes.Report, statuses []cbTypes.ReportExportStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	key := reportExportStatusKey(report)
+	op.reportExportsMu.Lock()
+	defer op.reportExportsMu.Unlock()
+	if op.pendingReportExportStatuses == nil {
+		op.pendingReportExportStatuses = make(map[string][]cbTypes.ReportExportStatus)
+	}
+	pending := op.pendingReportExportStatuses[key]
+	for _, status := range statuses {
+		pending = mergeReportExportStatus(pending, status)
+	}
+	op.pendingReportExportStatuses[key] = pending
+}
+
+// forgetPendingReportExportStatuses discards the outcomes of the exports of a
+// Report which was deleted.
+func (op *Reporting) forgetPendingReportExportStatuses(namespace, name string) {
+	op.reportExportsMu.Lock()
+	delete(op.pendingReportExportStatuses, namespace+"/"+name)
+	op.reportExportsMu.Unlock()
+}
+
+// saveReportExportStatuses saves the outcomes of the exports of report which
+// finished since it was last updated in its status.exports, and removes the
+// status of exports which were removed from its spec. The outcomes are saved
+// by the Report worker rather than the export workers, so a Report is only
+// updated by one worker at a time.
+func (op *Reporting) saveReportExportStatuses(logger log.FieldLogger, report *cbTypes.Report) (*cbTypes.Report, error) {
+	key := reportExportStatusKey(report)
+	op.reportExportsMu.Lock()
+	pending := op.pendingReportExportStatuses[key]
+	delete(op.pendingReportExportStatuses, key)
+	op.reportExportsMu.Unlock()
+
+	exports := append([]cbTypes.ReportExportStatus(nil), report.Status.Exports...)
+	for _, status := range pending {
+		exports = mergeReportExportStatus(exports, status)
+	}
+	exportNames := make(map[string]bool)
+	for _, export := range report.Spec.Exports {
+		exportNames[export.Name] = true
+	}
+	var exportStatuses []cbTypes.ReportExportStatus
+	for _, status := range exports {
+		if exportNames[status.Name] {
+			exportStatuses = append(exportStatuses, status)
+		}
+	}
+	if reflect.DeepEqual(exportStatuses, report.Status.Exports) {
+		return report, nil
+	}
+
+	report.Status.Exports = exportStatuses
+	updated, err := op.meteringClient.MeteringV1alpha1().Reports(report.Namespace).Update(report)
+	if err != nil {
+		// keep the outcomes until the Report is processed again
+		op.addPendingReportExportStatuses(report, pending)
+		logger.WithError(err).Errorf("unable to update Report status with the outcome of its exports")
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (op *Reporting) exportReport(report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, export cbTypes.ReportExport, periodStart, periodEnd time.Time) (string, error) {
+	if err := validateReportExport(export, op.cfg.ExportAllowedHosts); err != nil {
+		return "", fmt.Errorf("invalid export: %v", err)
+	}
+	exportPath, err := renderReportExportPath(export, report.Namespace, report.Name, periodStart, periodEnd)
+	if err != nil {
+		return "", fmt.Errorf("unable to render export path: %v", err)
+	}
+	target, err := op.newReportExportTarget(report, export, periodStart, periodEnd)
+	if err != nil {
+		return "", err
+	}
+	return exportReportResults(op.reportResultsRepo, target, report.Status.TableName, report.Status.TablePartitionedByPeriod, genQuery, reportExportFormat(export), exportPath, periodStart, periodEnd)
+}
+
+func (op *Reporting) newReportExportTarget(report *cbTypes.Report, export cbTypes.ReportExport, periodStart, periodEnd time.Time) (reportExportTarget, error) {
+	switch {
+	case export.Local != nil:
+		if op.cfg.ExportDirectory == "" {
+			return nil, fmt.Errorf("local exports are disabled, the reporting-operator has no export directory configured")
+		}
+		// each namespace has its own directory so Reports can't overwrite
+		// the exports of other namespaces.
+		directory := strings.TrimPrefix(path.Clean("/"+export.Local.Directory), "/")
+		return &localReportExportTarget{
+			directory: filepath.Join(op.cfg.ExportDirectory, report.Namespace, filepath.FromSlash(directory)),
+		}, nil
+	case export.S3 != nil:
+		region := export.S3.Region
+		if region == "" {
+			region = defaultReportExportRegion
+		}
+		awsConfig := aws.NewConfig().WithRegion(region)
+		if export.S3.Endpoint != "" {
+			awsConfig = awsConfig.WithEndpoint(export.S3.Endpoint).WithS3ForcePathStyle(true)
+		}
+		if export.S3.CredentialsSecretName != "" {
+			secret, err := op.kubeClient.Secrets(report.Namespace).Get(export.S3.CredentialsSecretName, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("unable to get credentials secret %s: %v", export.S3.CredentialsSecretName, err)
+			}
+			accessKeyID, secretAccessKey := string(secret.Data["aws-access-key-id"]), string(secret.Data["aws-secret-access-key"])
+			if accessKeyID == "" || secretAccessKey == "" {
+				return nil, fmt.Errorf("credentials secret %s must contain the aws-access-key-id and aws-secret-access-key keys", export.S3.CredentialsSecretName)
+			}
+			awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""))
+		}
+		awsSession, err := session.NewSession(awsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return &s3ReportExportTarget{
+			s3API:  s3.New(awsSession),
+			bucket: export.S3.Bucket,
+			prefix: export.S3.Prefix,
+		}, nil
+	case export.HTTP != nil:
+		return &httpReportExportTarget{
+			client: newReportExportHTTPClient(op.cfg.ExportAllowedHosts),
+			url:    export.HTTP.URL,
+			headers: http.Header{
+				"X-Metering-Report-Namespace": []string{report.Namespace},
+				"X-Metering-Report-Name":      []string{report.Name},
+				"X-Metering-Period-Start":     []string{periodStart.UTC().Format(time.RFC3339)},
+				"X-Metering-Period-End":       []string{periodEnd.UTC().Format(time.RFC3339)},
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("exactly one of s3, local or http must be set")
+}
+
+// newReportExportHTTPClient returns the client used by HTTP exports, which
+// only follows redirects to allowedHosts.
+func newReportExportHTTPClient(allowedHosts []string) *http.Client {
+	return &http.Client{
+		Timeout: reportExportHTTPTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if !reportExportHostAllowed(allowedHosts, req.URL.Host) {
+				return fmt.Errorf("redirect to %s isn't allowed, %q isn't one of the reporting-operator's allowed export hosts", req.URL, req.URL.Host)
+			}
+			return nil
+		},
+	}
+}
+
+// exportReportResults writes the results of the reporting period from
+// periodStart to periodEnd in tableName to exportPath in target. If
+// partitionedByPeriod is true, only the partition of the reporting period is
+// read. Otherwise, if the ReportGenerationQuery has no period columns, every
+// row is exported.
+func exportReportResults(getter prestostore.ReportResultsGetter, target reportExportTarget, tableName string, partitionedByPeriod bool, genQuery *cbTypes.ReportGenerationQuery, format, exportPath string, periodStart, periodEnd time.Time) (string, error) {
+	prestoColumns, err := reportingutil.GeneratePrestoColumns(genQuery)
+	if err != nil {
+		return "", err
+	}
+	var query prestostore.ReportResultsQuery
+	switch {
+	case partitionedByPeriod:
+		query.Where = fmt.Sprintf(`"%s" = '%s'`, reportingutil.PeriodPartitionColumnName, reportingutil.PeriodPartition(periodStart, periodEnd))
+	case reportingutil.HasPeriodColumns(genQuery):
+		query.Where = reportingutil.GeneratePeriodWhereClause(periodStart, periodEnd)
+	}
+
+	// results are written to a temporary file first, as uploads need to
+	// know their size.
+	file, err := ioutil.TempFile("", "report-export-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	enc, err := newReportExportEncoder(format, genQuery.Spec.Columns, file)
+	if err != nil {
+		return "", err
+	}
+	results, err := getter.GetReportResultsIterator(tableName, prestoColumns, query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read report results: %v", err)
+	}
+	defer results.Close()
+	for results.Next() {
+		if err := enc.WriteRow(results.Row()); err != nil {
+			return "", fmt.Errorf("failed to encode report results: %v", err)
+		}
+	}
+	if err := results.Err(); err != nil {
+		return "", fmt.Errorf("failed to read report results: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode report results: %v", err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return target.Write(exportPath, reportExportFormats[format].contentType, file, size)
+}
@@ -0,0 +1,471 @@
+package operator
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/fake"
+	listers "github.com/operator-framework/operator-metering/pkg/generated/listers/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	mockprestostore "github.com/operator-framework/operator-metering/pkg/operator/prestostore/mock"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestRenderReportExportPath(t *testing.T) {
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	tests := map[string]struct {
+		export       cbTypes.ReportExport
+		expectedPath string
+		expectErr    bool
+	}{
+		"default path": {
+			export:       cbTypes.ReportExport{Name: "export"},
+			expectedPath: "default/test-report/20190101T000000Z-20190201T000000Z.csv",
+		},
+		"custom path": {
+			export: cbTypes.ReportExport{
+				Name:   "export",
+				Format: "parquet",
+				Path:   `billing/{{.PeriodStart.Format "2006/01"}}/{{.Namespace}}-{{.Report}}.{{.Extension}}`,
+			},
+			expectedPath: "billing/2019/01/default-test-report.parquet",
+		},
+		"path outside of the target": {
+			export:       cbTypes.ReportExport{Name: "export", Path: "/../../etc/{{.Report}}"},
+			expectedPath: "etc/test-report",
+		},
+		"unknown field": {
+			export:    cbTypes.ReportExport{Name: "export", Path: "{{.Bucket}}"},
+			expectErr: true,
+		},
+		"empty path": {
+			export:    cbTypes.ReportExport{Name: "export", Path: "/"},
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			exportPath, err := renderReportExportPath(tt.export, "default", "test-report", periodStart, periodEnd)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPath, exportPath)
+		})
+	}
+}
+
+func TestValidateReportExport(t *testing.T) {
+	allowedHosts := []string{"example.com", "*.s3.example.org", "minio:9000"}
+	tests := map[string]struct {
+		export    cbTypes.ReportExport
+		expectErr bool
+	}{
+		"valid s3 export": {
+			export: cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket"}},
+		},
+		"valid local export": {
+			export: cbTypes.ReportExport{Name: "export", Format: "ndjson", Local: &cbTypes.LocalReportExportTarget{}},
+		},
+		"valid http export": {
+			export: cbTypes.ReportExport{Name: "export", HTTP: &cbTypes.HTTPReportExportTarget{URL: "https://example.com/reports"}},
+		},
+		"valid s3 export with an endpoint": {
+			export: cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket", Endpoint: "http://minio:9000"}},
+		},
+		"valid s3 export with an endpoint without a scheme": {
+			export: cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket", Endpoint: "us-east.s3.example.org"}},
+		},
+		"http export to a host which isn't allowed": {
+			export:    cbTypes.ReportExport{Name: "export", HTTP: &cbTypes.HTTPReportExportTarget{URL: "http://169.254.169.254/latest/meta-data"}},
+			expectErr: true,
+		},
+		"s3 endpoint which isn't allowed": {
+			export:    cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket", Endpoint: "https://metering-presto:8080"}},
+			expectErr: true,
+		},
+		"s3 endpoint with a port which isn't allowed": {
+			export:    cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket", Endpoint: "minio:9001"}},
+			expectErr: true,
+		},
+		"missing name": {
+			export:    cbTypes.ReportExport{Local: &cbTypes.LocalReportExportTarget{}},
+			expectErr: true,
+		},
+		"invalid format": {
+			export:    cbTypes.ReportExport{Name: "export", Format: "tabular", Local: &cbTypes.LocalReportExportTarget{}},
+			expectErr: true,
+		},
+		"invalid path template": {
+			export:    cbTypes.ReportExport{Name: "export", Path: "{{.Report", Local: &cbTypes.LocalReportExportTarget{}},
+			expectErr: true,
+		},
+		"no target": {
+			export:    cbTypes.ReportExport{Name: "export"},
+			expectErr: true,
+		},
+		"multiple targets": {
+			export:    cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{Bucket: "bucket"}, Local: &cbTypes.LocalReportExportTarget{}},
+			expectErr: true,
+		},
+		"s3 without bucket": {
+			export:    cbTypes.ReportExport{Name: "export", S3: &cbTypes.S3ReportExportTarget{}},
+			expectErr: true,
+		},
+		"http without http url": {
+			export:    cbTypes.ReportExport{Name: "export", HTTP: &cbTypes.HTTPReportExportTarget{URL: "file:///etc/passwd"}},
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := validateReportExport(tt.export, allowedHosts)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	err := validateReportExport(cbTypes.ReportExport{Name: "export", HTTP: &cbTypes.HTTPReportExportTarget{URL: "https://example.com/reports"}}, nil)
+	assert.Error(t, err, "expected http exports to be disabled without allowed hosts")
+}
+
+func TestReportExportHostAllowed(t *testing.T) {
+	allowedHosts := []string{"Example.com", "*.s3.example.org", "minio:9000", " "}
+	tests := map[string]bool{
+		"example.com":           true,
+		"EXAMPLE.COM:8443":      true,
+		"sub.example.com":       false,
+		"us.s3.example.org":     true,
+		"a.b.s3.example.org:80": true,
+		"s3.example.org":        false,
+		"evil-s3.example.org":   false,
+		"minio:9000":            true,
+		"minio":                 false,
+		"minio:9001":            false,
+		"169.254.169.254":       false,
+		"":                      false,
+	}
+	for host, expected := range tests {
+		assert.Equal(t, expected, reportExportHostAllowed(allowedHosts, host), "host %q", host)
+	}
+}
+
+func TestReportExportHTTPClientRedirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer internal.Close()
+	internalURL, err := url.Parse(internal.URL)
+	require.NoError(t, err)
+
+	var redirectTo string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, redirectTo, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := newReportExportHTTPClient([]string{serverURL.Host})
+	redirectTo = internal.URL
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "expected a redirect to a host which isn't allowed to fail")
+	assert.Contains(t, err.Error(), internalURL.Host)
+
+	client = newReportExportHTTPClient([]string{serverURL.Host, internalURL.Host})
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// recordingS3API records the objects put to it.
+type recordingS3API struct {
+	s3iface.S3API
+	input *s3.PutObjectInput
+	body  []byte
+}
+
+func (api *recordingS3API) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	api.input = input
+	api.body = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestExportReportResults(t *testing.T) {
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	genQuery := testhelpers.NewReportGenerationQuery("test-query", "default", []cbTypes.ReportGenerationQueryColumn{
+		{Name: "period_start", Type: "timestamp"},
+		{Name: "period_end", Type: "timestamp"},
+		{Name: "namespace", Type: "string"},
+		{Name: "pods", Type: "bigint"},
+	})
+	rows := []presto.Row{
+		{"period_start": periodStart, "period_end": periodEnd, "namespace": "default", "pods": int64(2)},
+		{"period_start": periodStart, "period_end": periodEnd, "namespace": "kube-system", "pods": int64(5)},
+	}
+	const expectedCSV = "period_start,period_end,namespace,pods\n" +
+		"2019-01-01 00:00:00 +0000 UTC,2019-02-01 00:00:00 +0000 UTC,default,2\n" +
+		"2019-01-01 00:00:00 +0000 UTC,2019-02-01 00:00:00 +0000 UTC,kube-system,5\n"
+
+	newResultsGetter := func(t *testing.T, ctrl *gomock.Controller) prestostore.ReportResultsGetter {
+		repo := mockprestostore.NewMockReportResultsRepo(ctrl)
+		expectedQuery := prestostore.ReportResultsQuery{
+			Where: `"period_start" >= timestamp '2019-01-01 00:00:00.000' AND "period_end" <= timestamp '2019-02-01 00:00:00.000'`,
+		}
+		repo.EXPECT().GetReportResultsIterator("report_default_test_report", gomock.Any(), expectedQuery).Return(presto.NewRowSliceIterator(rows), nil)
+		return repo
+	}
+
+	t.Run("local", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		dir, err := ioutil.TempDir("", "report-exports")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		target := &localReportExportTarget{directory: dir}
+		location, err := exportReportResults(newResultsGetter(t, ctrl), target, "report_default_test_report", false, genQuery, "csv", "reports/test.csv", periodStart, periodEnd)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "reports", "test.csv"), location)
+		contents, err := ioutil.ReadFile(location)
+		require.NoError(t, err)
+		assert.Equal(t, expectedCSV, string(contents))
+
+		files, err := ioutil.ReadDir(filepath.Join(dir, "reports"))
+		require.NoError(t, err)
+		assert.Len(t, files, 1, "expected temporary files to be removed")
+	})
+
+	t.Run("partitioned by period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		dir, err := ioutil.TempDir("", "report-exports")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		repo := mockprestostore.NewMockReportResultsRepo(ctrl)
+		expectedQuery := prestostore.ReportResultsQuery{
+			Where: `"report_period" = '20190101T000000Z-20190201T000000Z'`,
+		}
+		repo.EXPECT().GetReportResultsIterator("report_default_test_report", gomock.Any(), expectedQuery).Return(presto.NewRowSliceIterator(rows), nil)
+
+		target := &localReportExportTarget{directory: dir}
+		location, err := exportReportResults(repo, target, "report_default_test_report", true, genQuery, "csv", "reports/test.csv", periodStart, periodEnd)
+		require.NoError(t, err)
+		contents, err := ioutil.ReadFile(location)
+		require.NoError(t, err)
+		assert.Equal(t, expectedCSV, string(contents))
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		api := &recordingS3API{}
+		target := &s3ReportExportTarget{s3API: api, bucket: "finance", prefix: "metering/"}
+		location, err := exportReportResults(newResultsGetter(t, ctrl), target, "report_default_test_report", false, genQuery, "csv", "reports/test.csv", periodStart, periodEnd)
+		require.NoError(t, err)
+		assert.Equal(t, "s3://finance/metering/reports/test.csv", location)
+		require.NotNil(t, api.input)
+		assert.Equal(t, "finance", aws.StringValue(api.input.Bucket))
+		assert.Equal(t, "metering/reports/test.csv", aws.StringValue(api.input.Key))
+		assert.Equal(t, "text/csv", aws.StringValue(api.input.ContentType))
+		assert.Equal(t, int64(len(expectedCSV)), aws.Int64Value(api.input.ContentLength))
+		assert.Equal(t, expectedCSV, string(api.body))
+	})
+
+	t.Run("http", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var req *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req = r
+			body, _ = ioutil.ReadAll(r.Body)
+		}))
+		defer server.Close()
+
+		target := &httpReportExportTarget{
+			client:  server.Client(),
+			url:     server.URL,
+			headers: http.Header{"X-Metering-Report-Name": []string{"test-report"}},
+		}
+		location, err := exportReportResults(newResultsGetter(t, ctrl), target, "report_default_test_report", false, genQuery, "csv", "reports/test.csv", periodStart, periodEnd)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL, location)
+		require.NotNil(t, req)
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "text/csv", req.Header.Get("Content-Type"))
+		assert.Equal(t, "test-report", req.Header.Get("X-Metering-Report-Name"))
+		assert.Equal(t, "reports/test.csv", req.Header.Get("X-Metering-Export-Path"))
+		assert.Equal(t, expectedCSV, string(body))
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "try again later")
+		}))
+		defer server.Close()
+
+		target := &httpReportExportTarget{client: server.Client(), url: server.URL}
+		_, err := target.Write("reports/test.csv", "text/csv", strings.NewReader(expectedCSV), int64(len(expectedCSV)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "try again later")
+	})
+}
+
+func TestProcessReportExportJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, err := ioutil.TempDir("", "report-exports")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	genQuery := testhelpers.NewReportGenerationQuery("test-query", "default", []cbTypes.ReportGenerationQueryColumn{{Name: "namespace", Type: "string"}})
+	report := testhelpers.NewReport("test-report", "default", genQuery.Name, nil, nil, cbTypes.ReportStatus{
+		TableName: "report_default_test_report",
+		Exports: []cbTypes.ReportExportStatus{
+			{Name: "removed", Outcome: cbTypes.ReportRunSucceeded},
+			// the export of a later period which finished first
+			{Name: "late", PeriodStart: metav1.Time{Time: periodEnd}, PeriodEnd: metav1.Time{Time: periodEnd.AddDate(0, 1, 0)}, Outcome: cbTypes.ReportRunSucceeded},
+		},
+	})
+	report.Spec.Exports = []cbTypes.ReportExport{
+		{Name: "archive", Path: "test.csv", Local: &cbTypes.LocalReportExportTarget{}},
+		{Name: "late", Path: "late.csv", Local: &cbTypes.LocalReportExportTarget{}},
+		{Name: "webhook", HTTP: &cbTypes.HTTPReportExportTarget{URL: "http://169.254.169.254/latest"}},
+	}
+	notification := &cbTypes.ReportNotification{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-notification", Namespace: "default"},
+		Spec:       cbTypes.ReportNotificationSpec{URL: "https://example.com/events"},
+	}
+
+	reportIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, reportIndexer.Add(report))
+	genQueryIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, genQueryIndexer.Add(genQuery))
+	notificationIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, notificationIndexer.Add(notification))
+
+	repo := mockprestostore.NewMockReportResultsRepo(ctrl)
+	repo.EXPECT().GetReportResultsIterator(report.Status.TableName, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(string, []presto.Column, prestostore.ReportResultsQuery) (presto.RowIterator, error) {
+			return presto.NewRowSliceIterator([]presto.Row{{"namespace": "default"}}), nil
+		},
+	).Times(2)
+
+	now := periodEnd.Add(time.Hour)
+	op := &Reporting{
+		cfg:                         Config{ExportDirectory: dir},
+		logger:                      logrus.New(),
+		clock:                       clock.NewFakeClock(now),
+		meteringClient:              fake.NewSimpleClientset(report),
+		reportLister:                listers.NewReportLister(reportIndexer),
+		reportGenerationQueryLister: listers.NewReportGenerationQueryLister(genQueryIndexer),
+		reportNotificationLister:    listers.NewReportNotificationLister(notificationIndexer),
+		reportResultsRepo:           repo,
+		reportQueue:                 workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		reportExportQueue:           workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		reportNotificationQueue:     workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	run := cbTypes.ReportRun{
+		PeriodStart: metav1.Time{Time: periodStart},
+		PeriodEnd:   metav1.Time{Time: periodEnd},
+		StartTime:   metav1.Time{Time: now},
+		FinishTime:  metav1.Time{Time: now},
+		Outcome:     cbTypes.ReportRunSucceeded,
+	}
+	op.queueReportExports(op.logger, report, run)
+	require.Equal(t, 1, op.reportExportQueue.Len())
+	assert.True(t, op.processReportExportJob(op.logger))
+	assert.Equal(t, 0, op.reportExportQueue.Len())
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "default", "test.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "namespace\ndefault\n", string(contents))
+	assert.Equal(t, 1, op.reportQueue.Len(), "expected the Report to be queued to save the outcome of its exports")
+
+	// the event is sent once the exports finish, and includes their outcomes
+	require.Equal(t, 1, op.reportNotificationQueue.Len())
+	obj, _ := op.reportNotificationQueue.Get()
+	var event reportCloudEvent
+	require.NoError(t, json.Unmarshal(obj.(*reportNotificationDelivery).body, &event))
+	require.Len(t, event.Data.Exports, 2)
+	assert.Equal(t, "archive", event.Data.Exports[0].Name)
+	assert.Equal(t, "webhook", event.Data.Exports[1].Name)
+	assert.Equal(t, cbTypes.ReportRunFailed, event.Data.Exports[1].Outcome, "expected the export to a host which isn't allowed to fail")
+
+	// the Report worker saves the outcomes
+	saved, err := op.saveReportExportStatuses(op.logger, report.DeepCopy())
+	require.NoError(t, err)
+	require.Len(t, saved.Status.Exports, 3, "expected the status of the removed export to be removed")
+	assert.Equal(t, "late", saved.Status.Exports[0].Name)
+	assert.Equal(t, periodEnd, saved.Status.Exports[0].PeriodStart.Time.UTC(), "expected the status of a later period to be kept")
+	assert.Equal(t, "archive", saved.Status.Exports[1].Name)
+	assert.Equal(t, cbTypes.ReportRunSucceeded, saved.Status.Exports[1].Outcome)
+	assert.Equal(t, filepath.Join(dir, "default", "test.csv"), saved.Status.Exports[1].Location)
+	assert.Equal(t, "webhook", saved.Status.Exports[2].Name)
+	assert.Equal(t, cbTypes.ReportRunFailed, saved.Status.Exports[2].Outcome)
+	assert.Empty(t, op.pendingReportExportStatuses)
+
+	stored, err := op.meteringClient.MeteringV1alpha1().Reports("default").Get(report.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, saved.Status.Exports, stored.Status.Exports)
+
+	// nothing is updated once the outcomes are saved
+	unchanged, err := op.saveReportExportStatuses(op.logger, saved)
+	require.NoError(t, err)
+	assert.True(t, unchanged == saved)
+}
+
+func TestSaveReportExportStatusesUpdateError(t *testing.T) {
+	report := testhelpers.NewReport("test-report", "default", "test-query", nil, nil, cbTypes.ReportStatus{})
+	report.Spec.Exports = []cbTypes.ReportExport{{Name: "archive", Local: &cbTypes.LocalReportExportTarget{}}}
+	status := cbTypes.ReportExportStatus{Name: "archive", Outcome: cbTypes.ReportRunFailed, Error: "disk full"}
+
+	// the Report doesn't exist, so updating it fails
+	op := &Reporting{meteringClient: fake.NewSimpleClientset()}
+	op.addPendingReportExportStatuses(report, []cbTypes.ReportExportStatus{status})
+	_, err := op.saveReportExportStatuses(logrus.New(), report.DeepCopy())
+	require.Error(t, err)
+	assert.Equal(t, []cbTypes.ReportExportStatus{status}, op.pendingReportExportStatuses["default/test-report"], "expected the outcomes to be kept until the Report is updated")
+
+	op.forgetPendingReportExportStatuses("default", "test-report")
+	assert.Empty(t, op.pendingReportExportStatuses)
+}
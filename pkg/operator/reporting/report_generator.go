@@ -9,6 +9,7 @@ import (
 
 	metering "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/presto"
 )
 
 const (
@@ -22,8 +23,11 @@ var (
 	errEmptyQueryField                  = errors.New("ReportGenerationQuery spec.query cannot be empty")
 )
 
+// ReportGenerator renders a ReportGenerationQuery and adds the results to the
+// columns of a table named after the query's columns, so the table may have
+// other columns. GenerateReport returns the number of rows stored.
 type ReportGenerator interface {
-	GenerateReport(tableName, namespace string, reportStart, reportEnd *time.Time, generationQuery *metering.ReportGenerationQuery, dynamicReportGenerationQueries []*metering.ReportGenerationQuery, inputs []metering.ReportGenerationQueryInputValue, deleteExistingData bool) error
+	GenerateReport(tableName, namespace string, reportStart, reportEnd *time.Time, generationQuery *metering.ReportGenerationQuery, dynamicReportGenerationQueries []*metering.ReportGenerationQuery, dataSources []*metering.ReportDataSource, inputs []metering.ReportGenerationQueryInputValue) (int64, error)
 }
 
 type reportGenerator struct {
@@ -38,18 +42,18 @@ func NewReportGenerator(logger log.FieldLogger, reportResultsRepo prestostore.Re
 	}
 }
 
-func (g *reportGenerator) GenerateReport(tableName, namespace string, reportStart, reportEnd *time.Time, generationQuery *metering.ReportGenerationQuery, dynamicReportGenerationQueries []*metering.ReportGenerationQuery, inputs []metering.ReportGenerationQueryInputValue, deleteExistingData bool) error {
+func (g *reportGenerator) GenerateReport(tableName, namespace string, reportStart, reportEnd *time.Time, generationQuery *metering.ReportGenerationQuery, dynamicReportGenerationQueries []*metering.ReportGenerationQuery, dataSources []*metering.ReportDataSource, inputs []metering.ReportGenerationQueryInputValue) (int64, error) {
 	if generationQuery == nil {
 		panic("GenerateReport: must specify generationQuery")
 	}
 	if tableName == "" {
-		return errInvalidTableName
+		return 0, errInvalidTableName
 	}
 	if generationQuery.Name == "" {
-		return errInvalidReportGenerationQueryName
+		return 0, errInvalidReportGenerationQueryName
 	}
 	if generationQuery.Spec.Query == "" {
-		return errEmptyQueryField
+		return 0, errEmptyQueryField
 	}
 
 	logger := g.logger.WithFields(log.Fields{
@@ -60,11 +64,12 @@ func (g *reportGenerator) GenerateReport(tableName, namespace string, reportStar
 
 	reportQueryInputs, err := ValidateReportGenerationQueryInputs(generationQuery, inputs)
 	if err != nil {
-		return fmt.Errorf("unable to GenerateReport for Report Table %s, ReportGenerationQuery %s, failed to validate ReportGenerationQueryInputs: %s", tableName, generationQuery.Name, err)
+		return 0, fmt.Errorf("unable to GenerateReport for Report Table %s, ReportGenerationQuery %s, failed to validate ReportGenerationQueryInputs: %s", tableName, generationQuery.Name, err)
 	}
 
 	tmplCtx := &ReportQueryTemplateContext{
 		DynamicDependentQueries: dynamicReportGenerationQueries,
+		ReportDataSources:       dataSources,
 		Report: &ReportTemplateInfo{
 			ReportingStart: reportStart,
 			ReportingEnd:   reportEnd,
@@ -73,23 +78,21 @@ func (g *reportGenerator) GenerateReport(tableName, namespace string, reportStar
 	}
 	query, err := RenderQuery(generationQuery.Spec.Query, namespace, tmplCtx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if deleteExistingData {
-		logger.Debugf("deleting any preexisting rows in %s", tableName)
-		err = g.reportResultsRepo.DeleteReportResults(tableName)
-		if err != nil {
-			return fmt.Errorf("couldn't empty table %s of preexisting rows: %v", tableName, err)
-		}
+	columns := make([]presto.Column, len(generationQuery.Spec.Columns))
+	for i, col := range generationQuery.Spec.Columns {
+		columns[i] = presto.Column{Name: col.Name}
 	}
 
 	logger.Debugf("StoreReportResults: executing ReportGenerationQuery")
-	err = g.reportResultsRepo.StoreReportResults(tableName, query)
+	rowCount, err := g.reportResultsRepo.StoreReportResults(tableName, columns, query)
 	if err != nil {
 		logger.WithError(err).Errorf("creating usage report FAILED!")
-		return fmt.Errorf("Failed to execute query %s for Report table %s: %v", generationQuery.Name, tableName, err)
+		return 0, fmt.Errorf("Failed to execute query %s for Report table %s: %v", generationQuery.Name, tableName, err)
 	}
 
-	return nil
+	logger.Debugf("StoreReportResults: stored %d rows", rowCount)
+	return rowCount, nil
 }
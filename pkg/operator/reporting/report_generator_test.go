@@ -1,6 +1,7 @@
 package reporting
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 
 	metering "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	mockprestostore "github.com/operator-framework/operator-metering/pkg/operator/prestostore/mock"
+	"github.com/operator-framework/operator-metering/pkg/presto"
 )
 
 func TestGenerateReport(t *testing.T) {
@@ -21,10 +23,13 @@ func TestGenerateReport(t *testing.T) {
 			Namespace: "default",
 		},
 		Spec: metering.ReportGenerationQuerySpec{
-			Query: testSQL,
+			Query:   testSQL,
+			Columns: []metering.ReportGenerationQueryColumn{{Name: "foo", Type: "bigint"}},
 		},
 	}
 	tableName := "test-table"
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
 
 	testQueryEmptyQueryField := testQuery
 	testQueryEmptyQueryField.Spec.Query = ""
@@ -40,7 +45,7 @@ func TestGenerateReport(t *testing.T) {
 		reportGenerationQuery          *metering.ReportGenerationQuery
 		dynamicReportGenerationQueries []*metering.ReportGenerationQuery
 		inputs                         []metering.ReportGenerationQueryInputValue
-		deleteExistingData             bool
+		queryErr                       error
 
 		expectedErr string
 	}{
@@ -65,10 +70,17 @@ func TestGenerateReport(t *testing.T) {
 			expectedErr:           "error parsing query: template: report-generation-query:1: unexpected unclosed action in command",
 		},
 
-		"a table name and a ReportGenerationQuery with a query field and deleteExistingData=true will succeed": {
+		"a table name and a ReportGenerationQuery with a reporting period will succeed": {
 			tableName:             tableName,
+			reportStart:           &periodStart,
+			reportEnd:             &periodEnd,
 			reportGenerationQuery: &testQuery,
-			deleteExistingData:    true,
+		},
+		"a failing query will error": {
+			tableName:             tableName,
+			reportGenerationQuery: &testQuery,
+			queryErr:              errors.New("query failed"),
+			expectedErr:           "Failed to execute query test-query-1 for Report table test-table: query failed",
 		},
 	}
 
@@ -81,15 +93,13 @@ func TestGenerateReport(t *testing.T) {
 
 			logger := logrus.New()
 			reportResultsRepo := mockprestostore.NewMockReportResultsRepo(ctrl)
-			if tt.deleteExistingData {
-				reportResultsRepo.EXPECT().DeleteReportResults(tt.tableName).Return(nil)
-			}
-			if tt.expectedErr == "" {
-				reportResultsRepo.EXPECT().StoreReportResults(tt.tableName, tt.reportGenerationQuery.Spec.Query).Return(nil)
+			// errors other than query errors happen before the query runs
+			if tt.expectedErr == "" || tt.queryErr != nil {
+				reportResultsRepo.EXPECT().StoreReportResults(tt.tableName, []presto.Column{{Name: "foo"}}, tt.reportGenerationQuery.Spec.Query).Return(int64(2), tt.queryErr)
 			}
 
 			reportGenerator := NewReportGenerator(logger, reportResultsRepo)
-			err := reportGenerator.GenerateReport(tt.tableName, "test-ns", tt.reportStart, tt.reportEnd, tt.reportGenerationQuery, tt.dynamicReportGenerationQueries, tt.inputs, tt.deleteExistingData)
+			_, err := reportGenerator.GenerateReport(tt.tableName, "test-ns", tt.reportStart, tt.reportEnd, tt.reportGenerationQuery, tt.dynamicReportGenerationQueries, nil, tt.inputs)
 			if tt.expectedErr == "" {
 				assert.NoError(t, err, "expected GenerateReport to not error")
 			} else {
@@ -4,11 +4,18 @@ import (
 	"github.com/operator-framework/operator-metering/pkg/db"
 	"github.com/operator-framework/operator-metering/pkg/hive"
 	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
 )
 
 type TableManager interface {
 	CreateTable(params hive.TableParameters, properties hive.TableProperties) error
 	DropTable(tableName string, ignoreNotExists bool) error
+	DropTablePartition(tableName string, partitionSpec presto.PartitionSpec) error
+	AddColumns(tableName string, columns []hive.Column, cascade bool) error
+	SetTableLocation(tableName, location string) error
+	SetPartitionLocation(tableName string, partitionSpec presto.PartitionSpec, location string) error
+	SetTableExternal(tableName string, external bool) error
+	LoadTableData(tableName, location string) error
 }
 
 type AWSTablePartitionManager interface {
@@ -32,6 +39,30 @@ func (m *HiveTableManager) DropTable(tableName string, ignoreNotExists bool) err
 	return hive.ExecuteDropTable(m.queryer, tableName, ignoreNotExists)
 }
 
+func (m *HiveTableManager) DropTablePartition(tableName string, partitionSpec presto.PartitionSpec) error {
+	return hive.ExecuteDropPartition(m.queryer, tableName, partitionSpec)
+}
+
+func (m *HiveTableManager) AddColumns(tableName string, columns []hive.Column, cascade bool) error {
+	return hive.ExecuteAddColumns(m.queryer, tableName, columns, cascade)
+}
+
+func (m *HiveTableManager) SetTableLocation(tableName, location string) error {
+	return hive.ExecuteSetLocation(m.queryer, tableName, location)
+}
+
+func (m *HiveTableManager) SetPartitionLocation(tableName string, partitionSpec presto.PartitionSpec, location string) error {
+	return hive.ExecuteSetPartitionLocation(m.queryer, tableName, partitionSpec, location)
+}
+
+func (m *HiveTableManager) SetTableExternal(tableName string, external bool) error {
+	return hive.ExecuteSetExternal(m.queryer, tableName, external)
+}
+
+func (m *HiveTableManager) LoadTableData(tableName, location string) error {
+	return hive.ExecuteLoadData(m.queryer, tableName, location)
+}
+
 func (m *HiveTableManager) AddPartition(tableName, start, end, location string) error {
 	return reportingutil.AddAWSHivePartition(m.queryer, tableName, start, end, location)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
 	"text/template"
 	"time"
 
@@ -18,6 +19,9 @@ import (
 type ReportQueryTemplateContext struct {
 	Report                  *ReportTemplateInfo
 	DynamicDependentQueries []*cbTypes.ReportGenerationQuery
+	// ReportDataSources are the ReportDataSources the query depends on,
+	// used to find which of their rollup tables can be used.
+	ReportDataSources []*cbTypes.ReportDataSource
 }
 
 type ReportTemplateInfo struct {
@@ -33,8 +37,11 @@ func newQueryTemplate(queryTemplate, namespace string) (*template.Template, erro
 		"prometheusMetricPartitionFormat": PrometheusMetricPartitionFormat,
 		"reportTableName":                 reportTableNameWithNamespaceFunc(namespace),
 		"dataSourceTableName":             dataSourceTableNameWithNamespaceFunc(namespace),
+		"dataSourceRollupTableName":       dataSourceRollupTableNameWithNamespaceFunc(namespace),
 		"generationQueryViewName":         generationQueryViewNameWithNamespaceFunc(namespace),
 		"renderReportGenerationQuery":     renderReportGenerationQueryFunc(namespace),
+		"rateCardTableName":               rateCardTableNameWithNamespaceFunc(namespace),
+		"rateCardPrice":                   rateCardPriceWithNamespaceFunc(namespace),
 	}
 
 	tmpl, err := template.New("report-generation-query").Delims("{|", "|}").Funcs(templateFuncMap).Funcs(sprig.TxtFuncMap()).Parse(queryTemplate)
@@ -44,6 +51,13 @@ func newQueryTemplate(queryTemplate, namespace string) (*template.Template, erro
 	return tmpl, nil
 }
 
+// ValidateQueryTemplate checks that query parses as a
+// ReportGenerationQuery template without rendering it.
+func ValidateQueryTemplate(query, namespace string) error {
+	_, err := newQueryTemplate(query, namespace)
+	return err
+}
+
 func RenderQuery(query, namespace string, tmplCtx *ReportQueryTemplateContext) (string, error) {
 	tmpl, err := newQueryTemplate(query, namespace)
 	if err != nil {
@@ -119,6 +133,48 @@ func dataSourceTableNameWithNamespaceFunc(namespace string) func(string) string
 	}
 }
 
+func dataSourceRollupTableNameWithNamespaceFunc(namespace string) func(string, *ReportQueryTemplateContext) string {
+	return func(name string, tmplCtx *ReportQueryTemplateContext) string {
+		return DataSourceRollupTableName(namespace, name, tmplCtx)
+	}
+}
+
+// DataSourceRollupTableName returns the table of the ReportDataSource name
+// with the coarsest granularity usable for the reporting period in tmplCtx.
+// A rollup table is only usable if the reporting period starts and ends on
+// the boundaries of its periods and it has been aggregated up until the end
+// of the reporting period, otherwise the ReportDataSource's table is used.
+func DataSourceRollupTableName(namespace, name string, tmplCtx *ReportQueryTemplateContext) string {
+	tableName := reportingutil.DataSourceTableName(namespace, name)
+	if tmplCtx == nil || tmplCtx.Report == nil || tmplCtx.Report.ReportingStart == nil || tmplCtx.Report.ReportingEnd == nil {
+		return tableName
+	}
+	var dataSource *cbTypes.ReportDataSource
+	for _, ds := range tmplCtx.ReportDataSources {
+		if ds.Name == name {
+			dataSource = ds
+			break
+		}
+	}
+	if dataSource == nil {
+		return tableName
+	}
+	start, end := *tmplCtx.Report.ReportingStart, *tmplCtx.Report.ReportingEnd
+	var best time.Duration
+	for _, rollup := range dataSource.Status.Rollups {
+		period, ok := reportingutil.PrometheusMetricsRollupPeriods[rollup.Granularity]
+		if !ok || period <= best || rollup.RolledUpTo == nil || rollup.RolledUpTo.Time.Before(end) {
+			continue
+		}
+		if !start.Truncate(period).Equal(start) || !end.Truncate(period).Equal(end) {
+			continue
+		}
+		best = period
+		tableName = rollup.TableName
+	}
+	return tableName
+}
+
 func reportTableNameWithNamespaceFunc(namespace string) func(string) string {
 	return func(name string) string {
 		return reportingutil.ReportTableName(namespace, name)
@@ -130,3 +186,30 @@ func generationQueryViewNameWithNamespaceFunc(namespace string) func(string) str
 		return reportingutil.GenerationQueryViewName(namespace, name)
 	}
 }
+
+func rateCardTableNameWithNamespaceFunc(namespace string) func(string) string {
+	return func(name string) string {
+		return reportingutil.RateCardTableName(namespace, name)
+	}
+}
+
+func rateCardPriceWithNamespaceFunc(namespace string) func(string, string, interface{}) (string, error) {
+	return func(name, unit string, at interface{}) (string, error) {
+		return RateCardPrice(reportingutil.RateCardTableName(namespace, name), unit, at)
+	}
+}
+
+// RateCardPrice returns a scalar subquery selecting the price of unit at the
+// time at from the RateCard table tableName. Only rates which aren't limited
+// to a namespace or node labels are considered, and if multiple rates are
+// effective at the same time, the one which became effective most recently
+// is used. The subquery returns NULL if unit has no price.
+func RateCardPrice(tableName, unit string, at interface{}) (string, error) {
+	timestamp, err := PrestoTimestamp(at)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`(SELECT max_by(price, coalesce(effective_from, timestamp '1970-01-01 00:00:00.000')) FROM %s WHERE unit = '%s' AND namespace IS NULL AND cardinality(node_labels) = 0 AND coalesce(effective_from <= timestamp '%[3]s', true) AND coalesce(effective_to > timestamp '%[3]s', true))`,
+		tableName, strings.Replace(unit, "'", "''", -1), timestamp,
+	), nil
+}
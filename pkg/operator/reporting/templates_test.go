@@ -0,0 +1,72 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+)
+
+func TestDataSourceRollupTableName(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dataSource := &cbTypes.ReportDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-cpu", Namespace: "test-ns"},
+		Status: cbTypes.ReportDataSourceStatus{
+			TableName: "datasource_test_ns_pod_cpu",
+			Rollups: []cbTypes.PrometheusMetricsRollupStatus{
+				{Granularity: cbTypes.PrometheusMetricsRollupHourly, TableName: "rollup_hourly_test_ns_pod_cpu", RolledUpTo: &metav1.Time{Time: janOne.Add(50 * time.Hour)}},
+				{Granularity: cbTypes.PrometheusMetricsRollupDaily, TableName: "rollup_daily_test_ns_pod_cpu", RolledUpTo: &metav1.Time{Time: janOne.Add(48 * time.Hour)}},
+			},
+		},
+	}
+	newTmplCtx := func(start, end time.Time) *ReportQueryTemplateContext {
+		return &ReportQueryTemplateContext{
+			Report:            &ReportTemplateInfo{ReportingStart: &start, ReportingEnd: &end},
+			ReportDataSources: []*cbTypes.ReportDataSource{dataSource},
+		}
+	}
+
+	tests := map[string]struct {
+		tmplCtx  *ReportQueryTemplateContext
+		expected string
+	}{
+		"whole days use the daily rollup": {
+			tmplCtx:  newTmplCtx(janOne, janOne.Add(48*time.Hour)),
+			expected: "rollup_daily_test_ns_pod_cpu",
+		},
+		"days not rolled up yet use the hourly rollup": {
+			tmplCtx:  newTmplCtx(janOne.Add(24*time.Hour), janOne.Add(48*time.Hour+time.Hour)),
+			expected: "rollup_hourly_test_ns_pod_cpu",
+		},
+		"whole hours use the hourly rollup": {
+			tmplCtx:  newTmplCtx(janOne.Add(time.Hour), janOne.Add(3*time.Hour)),
+			expected: "rollup_hourly_test_ns_pod_cpu",
+		},
+		"partial hours use the raw table": {
+			tmplCtx:  newTmplCtx(janOne.Add(time.Hour), janOne.Add(3*time.Hour+time.Minute)),
+			expected: "datasource_test_ns_pod_cpu",
+		},
+		"periods after the rollups use the raw table": {
+			tmplCtx:  newTmplCtx(janOne.Add(50*time.Hour), janOne.Add(51*time.Hour)),
+			expected: "datasource_test_ns_pod_cpu",
+		},
+		"unknown ReportDataSources use the raw table": {
+			tmplCtx:  &ReportQueryTemplateContext{Report: newTmplCtx(janOne, janOne.Add(24*time.Hour)).Report},
+			expected: "datasource_test_ns_pod_cpu",
+		},
+		"without a reporting period the raw table is used": {
+			tmplCtx:  &ReportQueryTemplateContext{ReportDataSources: []*cbTypes.ReportDataSource{dataSource}},
+			expected: "datasource_test_ns_pod_cpu",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, DataSourceRollupTableName("test-ns", "pod-cpu", test.tmplCtx))
+		})
+	}
+}
@@ -10,16 +10,71 @@ import (
 	"github.com/operator-framework/operator-metering/pkg/presto"
 )
 
+const (
+	// PeriodStartColumnName and PeriodEndColumnName are the names of the
+	// columns ReportGenerationQueries use to record the reporting period a
+	// row was generated for.
+	PeriodStartColumnName = "period_start"
+	PeriodEndColumnName   = "period_end"
+
+	// PeriodPartitionColumnName is the name of the partition column of
+	// Report tables partitioned by reporting period.
+	PeriodPartitionColumnName = "report_period"
+
+	// periodPartitionTimeFormat is fixed width, so partition values sort in
+	// the same order as the start of their reporting period.
+	periodPartitionTimeFormat = "20060102T150405Z"
+)
+
+// PeriodPartitionColumns are the partition columns of Report tables
+// partitioned by reporting period.
+var PeriodPartitionColumns = []hive.Column{{Name: PeriodPartitionColumnName, Type: "string"}}
+
 var resourceNameReplacer = strings.NewReplacer("-", "_", ".", "_")
 
+// TableNameNamespace returns namespace the way it appears in the names of
+// tables and views.
+func TableNameNamespace(namespace string) string {
+	return resourceNameReplacer.Replace(namespace)
+}
+
 func DataSourceTableName(namespace, dataSourceName string) string {
 	return fmt.Sprintf("datasource_%s_%s", resourceNameReplacer.Replace(namespace), resourceNameReplacer.Replace(dataSourceName))
 }
 
+// PrometheusMetricsRollupPeriods are the length of the periods metrics are
+// aggregated into for each rollup granularity.
+var PrometheusMetricsRollupPeriods = map[cbTypes.PrometheusMetricsRollupGranularity]time.Duration{
+	cbTypes.PrometheusMetricsRollupHourly: time.Hour,
+	cbTypes.PrometheusMetricsRollupDaily:  24 * time.Hour,
+}
+
+// DataSourceRollupTableName is the name of the table the metrics of a
+// ReportDataSource are aggregated into at the given granularity.
+func DataSourceRollupTableName(namespace, dataSourceName, granularity string) string {
+	return fmt.Sprintf("rollup_%s_%s_%s", granularity, resourceNameReplacer.Replace(namespace), resourceNameReplacer.Replace(dataSourceName))
+}
+
 func ReportTableName(namespace, reportName string) string {
 	return fmt.Sprintf("report_%s_%s", resourceNameReplacer.Replace(namespace), resourceNameReplacer.Replace(reportName))
 }
 
+// ReportStagingTablePrefix is the prefix of the names of Report staging
+// tables. Staging tables are prefixed, so they can't have the name of
+// another report's table.
+const ReportStagingTablePrefix = "staging_"
+
+// ReportStagingTableName is the name of a table the results of a report are
+// stored in before being moved into the report's table, reportTableName.
+// Each run of a report uses a different suffix.
+func ReportStagingTableName(reportTableName, suffix string) string {
+	return fmt.Sprintf("%s%s_%s", ReportStagingTablePrefix, reportTableName, suffix)
+}
+
+func RateCardTableName(namespace, rateCardName string) string {
+	return fmt.Sprintf("ratecard_%s_%s", resourceNameReplacer.Replace(namespace), resourceNameReplacer.Replace(rateCardName))
+}
+
 func GenerationQueryViewName(namespace, queryName string) string {
 	return fmt.Sprintf("view_%s_%s", resourceNameReplacer.Replace(namespace), resourceNameReplacer.Replace(queryName))
 }
@@ -131,3 +186,53 @@ func HiveColumnToPrestoColumn(column hive.Column) (presto.Column, error) {
 	}
 	return presto.Column{}, fmt.Errorf("unsupported hive type: %q", column.Type)
 }
+
+// HasPeriodColumns returns true if the ReportGenerationQuery has timestamp
+// columns named period_start and period_end.
+func HasPeriodColumns(genQuery *cbTypes.ReportGenerationQuery) bool {
+	var hasStart, hasEnd bool
+	for _, col := range genQuery.Spec.Columns {
+		if strings.ToLower(col.Type) != "timestamp" {
+			continue
+		}
+		switch col.Name {
+		case PeriodStartColumnName:
+			hasStart = true
+		case PeriodEndColumnName:
+			hasEnd = true
+		}
+	}
+	return hasStart && hasEnd
+}
+
+// GeneratePeriodWhereClause returns a SQL condition matching rows with a
+// period_start and period_end within periodStart and periodEnd.
+func GeneratePeriodWhereClause(periodStart, periodEnd time.Time) string {
+	return fmt.Sprintf(`"%s" >= timestamp '%s' AND "%s" <= timestamp '%s'`,
+		PeriodStartColumnName, periodStart.UTC().Format(presto.TimestampFormat),
+		PeriodEndColumnName, periodEnd.UTC().Format(presto.TimestampFormat),
+	)
+}
+
+// PeriodPartition returns the value of the report_period partition column
+// for the reporting period from periodStart to periodEnd.
+func PeriodPartition(periodStart, periodEnd time.Time) string {
+	return periodStart.UTC().Format(periodPartitionTimeFormat) + "-" + periodEnd.UTC().Format(periodPartitionTimeFormat)
+}
+
+// PeriodPartitionSpec returns the partition of a Report table partitioned by
+// reporting period for the period from periodStart to periodEnd.
+func PeriodPartitionSpec(periodStart, periodEnd time.Time) presto.PartitionSpec {
+	return presto.PartitionSpec{PeriodPartitionColumnName: PeriodPartition(periodStart, periodEnd)}
+}
+
+// GeneratePeriodPartitionWhereClause returns a SQL condition on the
+// report_period partition column matching the partitions of every reporting
+// period starting within periodStart and periodEnd. It's used in addition to
+// GeneratePeriodWhereClause, so Presto only reads those partitions.
+func GeneratePeriodPartitionWhereClause(periodStart, periodEnd time.Time) string {
+	return fmt.Sprintf(`"%s" >= '%s' AND "%s" < '%s'`,
+		PeriodPartitionColumnName, periodStart.UTC().Format(periodPartitionTimeFormat),
+		PeriodPartitionColumnName, periodEnd.UTC().Format(periodPartitionTimeFormat),
+	)
+}
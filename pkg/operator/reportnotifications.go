@@ -0,0 +1,400 @@
+package operator
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+)
+
+const (
+	defaultReportNotificationMaxAttempts = 5
+
+	// reportNotificationHTTPTimeout is how long sending an event can take
+	// before the attempt is cancelled.
+	reportNotificationHTTPTimeout = 30 * time.Second
+
+	// reportNotificationSigningKey is the key of the signing secret
+	// containing the HMAC key.
+	reportNotificationSigningKey = "signing-key"
+
+	reportNotificationSignatureHeader = "X-Metering-Signature"
+	cloudEventsContentType            = "application/cloudevents+json"
+	cloudEventsSpecVersion            = "1.0"
+	reportCloudEventTypePrefix        = "io.openshift.metering.report."
+)
+
+var (
+	reportNotificationPrometheusMetricLabels = []string{"reportnotification", "namespace"}
+
+	reportNotificationTotalCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "report_notifications_total",
+			Help:      "Number of attempts to send a ReportNotification event.",
+		},
+		reportNotificationPrometheusMetricLabels,
+	)
+
+	reportNotificationFailedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "report_notifications_failed_total",
+			Help:      "Number of failed attempts to send a ReportNotification event.",
+		},
+		reportNotificationPrometheusMetricLabels,
+	)
+
+	reportNotificationDroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "report_notifications_dropped_total",
+			Help:      "Number of ReportNotification events dropped after exhausting their attempts.",
+		},
+		reportNotificationPrometheusMetricLabels,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reportNotificationTotalCounter)
+	prometheus.MustRegister(reportNotificationFailedCounter)
+	prometheus.MustRegister(reportNotificationDroppedCounter)
+}
+
+// reportCloudEvent is a CloudEvent in the structured JSON format, sent when a
+// Report finishes running for a reporting period.
+type reportCloudEvent struct {
+	SpecVersion     string               `json:"specversion"`
+	ID              string               `json:"id"`
+	Source          string               `json:"source"`
+	Type            string               `json:"type"`
+	Subject         string               `json:"subject"`
+	Time            time.Time            `json:"time"`
+	DataContentType string               `json:"datacontenttype"`
+	Data            reportCloudEventData `json:"data"`
+}
+
+type reportCloudEventData struct {
+	Report      string                       `json:"report"`
+	Namespace   string                       `json:"namespace"`
+	PeriodStart time.Time                    `json:"periodStart"`
+	PeriodEnd   time.Time                    `json:"periodEnd"`
+	Status      cbTypes.ReportRunOutcome     `json:"status"`
+	RowCount    int64                        `json:"rowCount"`
+	Error       string                       `json:"error,omitempty"`
+	ResultsURL  string                       `json:"resultsURL,omitempty"`
+	Exports     []cbTypes.ReportExportStatus `json:"exports,omitempty"`
+}
+
+// reportNotificationDelivery is an event waiting to be sent to the endpoint
+// of a ReportNotification. Deliveries are queued by pointer, so each is
+// retried independently.
+type reportNotificationDelivery struct {
+	namespace    string
+	notification string
+	report       string
+	eventID      string
+	body         []byte
+}
+
+func reportNotificationOutcomes(notification *cbTypes.ReportNotification) []cbTypes.ReportRunOutcome {
+	if len(notification.Spec.Outcomes) == 0 {
+		return []cbTypes.ReportRunOutcome{cbTypes.ReportRunSucceeded, cbTypes.ReportRunFailed}
+	}
+	return notification.Spec.Outcomes
+}
+
+func reportNotificationMaxAttempts(notification *cbTypes.ReportNotification) int {
+	if notification.Spec.MaxAttempts == nil {
+		return defaultReportNotificationMaxAttempts
+	}
+	return int(*notification.Spec.MaxAttempts)
+}
+
+func validateHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
+
+// validateReportNotification returns an error if notification isn't valid.
+func validateReportNotification(notification *cbTypes.ReportNotification) error {
+	if notification.Spec.URL == "" {
+		return fmt.Errorf("spec.url must be set")
+	}
+	if err := validateHTTPURL(notification.Spec.URL); err != nil {
+		return fmt.Errorf("invalid spec.url: %v", err)
+	}
+	if notification.Spec.ResultsBaseURL != "" {
+		if err := validateHTTPURL(notification.Spec.ResultsBaseURL); err != nil {
+			return fmt.Errorf("invalid spec.resultsBaseURL: %v", err)
+		}
+	}
+	if _, err := metav1.LabelSelectorAsSelector(notification.Spec.ReportSelector); err != nil {
+		return fmt.Errorf("invalid spec.reportSelector: %v", err)
+	}
+	for _, outcome := range notification.Spec.Outcomes {
+		if outcome != cbTypes.ReportRunSucceeded && outcome != cbTypes.ReportRunFailed {
+			return fmt.Errorf("spec.outcomes must contain only %s or %s, got %q", cbTypes.ReportRunSucceeded, cbTypes.ReportRunFailed, outcome)
+		}
+	}
+	if notification.Spec.MaxAttempts != nil && *notification.Spec.MaxAttempts < 1 {
+		return fmt.Errorf("spec.maxAttempts must be at least 1")
+	}
+	return nil
+}
+
+// reportNotificationMatches returns true if notification should send an event
+// for a run of report with the given outcome.
+func reportNotificationMatches(notification *cbTypes.ReportNotification, report *cbTypes.Report, outcome cbTypes.ReportRunOutcome) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(notification.Spec.ReportSelector)
+	if err != nil {
+		return false, err
+	}
+	// a nil selector matches nothing, but an unset reportSelector means
+	// every Report in the namespace.
+	if notification.Spec.ReportSelector != nil && !selector.Matches(labels.Set(report.Labels)) {
+		return false, nil
+	}
+	for _, o := range reportNotificationOutcomes(notification) {
+		if o == outcome {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reportResultsURL returns the URL of the results of the reporting period in
+// the reporting API at baseURL.
+func reportResultsURL(baseURL string, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, periodStart, periodEnd time.Time) string {
+	resultsURL := fmt.Sprintf("%s%s/%s/%s/full", strings.TrimSuffix(baseURL, "/"), APIV2ReportsEndpointPrefix, url.PathEscape(report.Namespace), url.PathEscape(report.Name))
+	vals := url.Values{"format": []string{"json"}}
+	if genQuery != nil && reportingutil.HasPeriodColumns(genQuery) {
+		vals.Set("periodStart", periodStart.UTC().Format(time.RFC3339))
+		vals.Set("periodEnd", periodEnd.UTC().Format(time.RFC3339))
+	}
+	return resultsURL + "?" + vals.Encode()
+}
+
+// newReportCloudEvent returns the event sent for run. The id of the event is
+// the same for every ReportNotification, so receivers can deduplicate events
+// they receive more than once.
+func newReportCloudEvent(report *cbTypes.Report, run cbTypes.ReportRun, resultsURL string) reportCloudEvent {
+	var exports []cbTypes.ReportExportStatus
+	for _, export := range report.Status.Exports {
+		if export.PeriodStart.Equal(&run.PeriodStart) && export.PeriodEnd.Equal(&run.PeriodEnd) {
+			exports = append(exports, export)
+		}
+	}
+	return reportCloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              fmt.Sprintf("%s-%d", report.UID, run.StartTime.UnixNano()),
+		Source:          fmt.Sprintf("/apis/%s/%s/namespaces/%s/reports/%s", cbTypes.GroupName, cbTypes.SchemeGroupVersion.Version, report.Namespace, report.Name),
+		Type:            reportCloudEventTypePrefix + strings.ToLower(string(run.Outcome)),
+		Subject:         report.Name,
+		Time:            run.FinishTime.UTC(),
+		DataContentType: "application/json",
+		Data: reportCloudEventData{
+			Report:      report.Name,
+			Namespace:   report.Namespace,
+			PeriodStart: run.PeriodStart.UTC(),
+			PeriodEnd:   run.PeriodEnd.UTC(),
+			Status:      run.Outcome,
+			RowCount:    run.RowCount,
+			Error:       run.Error,
+			ResultsURL:  resultsURL,
+			Exports:     exports,
+		},
+	}
+}
+
+// signReportNotification returns the value of the signature header for body.
+func signReportNotification(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendReportNotification POSTs body to notificationURL, signing it with
+// signingKey if it's not empty. Any non-2xx response is an error.
+func sendReportNotification(client *http.Client, notificationURL string, body, signingKey []byte) error {
+	req, err := http.NewRequest(http.MethodPost, notificationURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+	if len(signingKey) != 0 {
+		req.Header.Set(reportNotificationSignatureHeader, signReportNotification(signingKey, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned status %d: %s", notificationURL, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// notifyReportRun queues an event for run to be sent to each of the
+// ReportNotifications in the Report's namespace which match it.
+func (op *Reporting) notifyReportRun(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, run cbTypes.ReportRun) {
+	notifications, err := op.reportNotificationLister.ReportNotifications(report.Namespace).List(labels.Everything())
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list ReportNotifications")
+		return
+	}
+	for _, notification := range notifications {
+		notificationLogger := logger.WithField("reportNotification", notification.Name)
+		matches, err := reportNotificationMatches(notification, report, run.Outcome)
+		if err != nil {
+			notificationLogger.WithError(err).Errorf("invalid ReportNotification %s", notification.Name)
+			continue
+		}
+		if !matches {
+			continue
+		}
+		var resultsURL string
+		if notification.Spec.ResultsBaseURL != "" && run.Outcome == cbTypes.ReportRunSucceeded {
+			resultsURL = reportResultsURL(notification.Spec.ResultsBaseURL, report, genQuery, run.PeriodStart.Time, run.PeriodEnd.Time)
+		}
+		event := newReportCloudEvent(report, run, resultsURL)
+		body, err := json.Marshal(event)
+		if err != nil {
+			notificationLogger.WithError(err).Errorf("unable to encode event for ReportNotification %s", notification.Name)
+			continue
+		}
+		notificationLogger.Debugf("queueing event %s for ReportNotification %s", event.ID, notification.Name)
+		op.reportNotificationQueue.Add(&reportNotificationDelivery{
+			namespace:    notification.Namespace,
+			notification: notification.Name,
+			report:       report.Name,
+			eventID:      event.ID,
+			body:         body,
+		})
+	}
+}
+
+func (op *Reporting) runReportNotificationWorker() {
+	logger := op.logger.WithField("component", "reportNotificationWorker")
+	logger.Infof("ReportNotification worker started")
+	for op.processReportNotificationDelivery(logger) {
+	}
+}
+
+// processReportNotificationDelivery sends the next queued event, requeueing
+// it with an exponential backoff until it's been attempted the
+// ReportNotification's spec.maxAttempts times.
+func (op *Reporting) processReportNotificationDelivery(logger log.FieldLogger) bool {
+	obj, quit := op.reportNotificationQueue.Get()
+	if quit {
+		logger.Infof("queue is shutting down, exiting ReportNotification worker")
+		return false
+	}
+	defer op.reportNotificationQueue.Done(obj)
+
+	delivery, ok := obj.(*reportNotificationDelivery)
+	if !ok {
+		op.reportNotificationQueue.Forget(obj)
+		logger.Errorf("expected *reportNotificationDelivery in work queue but got %#v", obj)
+		return true
+	}
+	logger = logger.WithFields(log.Fields{
+		"reportNotification": delivery.notification,
+		"namespace":          delivery.namespace,
+		"report":             delivery.report,
+		"eventID":            delivery.eventID,
+	})
+
+	notification, err := op.reportNotificationLister.ReportNotifications(delivery.namespace).Get(delivery.notification)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Infof("ReportNotification %s no longer exists, dropping event %s", delivery.notification, delivery.eventID)
+		} else {
+			logger.WithError(err).Errorf("unable to get ReportNotification %s, dropping event %s", delivery.notification, delivery.eventID)
+		}
+		op.reportNotificationQueue.Forget(obj)
+		return true
+	}
+
+	metricLabels := prometheus.Labels{
+		"reportnotification": notification.Name,
+		"namespace":          notification.Namespace,
+	}
+	reportNotificationTotalCounter.With(metricLabels).Inc()
+	attempts := op.reportNotificationQueue.NumRequeues(obj) + 1
+	maxAttempts := reportNotificationMaxAttempts(notification)
+
+	err = op.sendReportNotificationDelivery(notification, delivery)
+	if err != nil {
+		reportNotificationFailedCounter.With(metricLabels).Inc()
+		if attempts < maxAttempts {
+			logger.WithError(err).Warnf("failed to send event %s, attempt %d of %d, retrying", delivery.eventID, attempts, maxAttempts)
+			op.reportNotificationQueue.AddRateLimited(obj)
+			return true
+		}
+		reportNotificationDroppedCounter.With(metricLabels).Inc()
+		logger.WithError(err).Errorf("failed to send event %s after %d attempts, dropping it", delivery.eventID, attempts)
+	} else {
+		logger.Infof("sent event %s for Report %s to ReportNotification %s", delivery.eventID, delivery.report, notification.Name)
+	}
+	op.reportNotificationQueue.Forget(obj)
+
+	status := &cbTypes.ReportNotificationDelivery{
+		EventID:  delivery.eventID,
+		Report:   delivery.report,
+		Time:     metav1.Time{Time: op.clock.Now().UTC()},
+		Attempts: int32(attempts),
+		Outcome:  cbTypes.ReportRunSucceeded,
+	}
+	if err != nil {
+		status.Outcome = cbTypes.ReportRunFailed
+		status.Error = err.Error()
+	}
+	notification = notification.DeepCopy()
+	notification.Status.LastDelivery = status
+	if _, err := op.meteringClient.MeteringV1alpha1().ReportNotifications(notification.Namespace).Update(notification); err != nil {
+		logger.WithError(err).Errorf("unable to update ReportNotification %s status", notification.Name)
+	}
+	return true
+}
+
+func (op *Reporting) sendReportNotificationDelivery(notification *cbTypes.ReportNotification, delivery *reportNotificationDelivery) error {
+	var signingKey []byte
+	if notification.Spec.SigningSecretName != "" {
+		secret, err := op.kubeClient.Secrets(notification.Namespace).Get(notification.Spec.SigningSecretName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get signing secret %s: %v", notification.Spec.SigningSecretName, err)
+		}
+		signingKey = secret.Data[reportNotificationSigningKey]
+		if len(signingKey) == 0 {
+			return fmt.Errorf("signing secret %s must contain the %s key", notification.Spec.SigningSecretName, reportNotificationSigningKey)
+		}
+	}
+	client := &http.Client{Timeout: reportNotificationHTTPTimeout}
+	return sendReportNotification(client, notification.Spec.URL, delivery.body, signingKey)
+}
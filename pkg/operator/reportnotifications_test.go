@@ -0,0 +1,203 @@
+package operator
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestValidateReportNotification(t *testing.T) {
+	zero := int32(0)
+	tests := map[string]struct {
+		spec      cbTypes.ReportNotificationSpec
+		expectErr bool
+	}{
+		"valid": {
+			spec: cbTypes.ReportNotificationSpec{
+				URL:            "https://example.com/events",
+				ResultsBaseURL: "https://metering.example.com",
+				ReportSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "finance"}},
+				Outcomes:       []cbTypes.ReportRunOutcome{cbTypes.ReportRunFailed},
+			},
+		},
+		"missing url": {
+			spec:      cbTypes.ReportNotificationSpec{},
+			expectErr: true,
+		},
+		"non http url": {
+			spec:      cbTypes.ReportNotificationSpec{URL: "file:///etc/passwd"},
+			expectErr: true,
+		},
+		"non http resultsBaseURL": {
+			spec:      cbTypes.ReportNotificationSpec{URL: "https://example.com/events", ResultsBaseURL: "ftp://example.com"},
+			expectErr: true,
+		},
+		"invalid reportSelector": {
+			spec: cbTypes.ReportNotificationSpec{
+				URL: "https://example.com/events",
+				ReportSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Like"},
+				}},
+			},
+			expectErr: true,
+		},
+		"invalid outcome": {
+			spec:      cbTypes.ReportNotificationSpec{URL: "https://example.com/events", Outcomes: []cbTypes.ReportRunOutcome{"Finished"}},
+			expectErr: true,
+		},
+		"zero maxAttempts": {
+			spec:      cbTypes.ReportNotificationSpec{URL: "https://example.com/events", MaxAttempts: &zero},
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := validateReportNotification(&cbTypes.ReportNotification{Spec: tt.spec})
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReportNotificationMatches(t *testing.T) {
+	report := testhelpers.NewReport("test-report", "default", "test-query", nil, nil, cbTypes.ReportStatus{})
+	report.Labels = map[string]string{"team": "finance"}
+
+	tests := map[string]struct {
+		spec     cbTypes.ReportNotificationSpec
+		outcome  cbTypes.ReportRunOutcome
+		expected bool
+	}{
+		"no selector or outcomes": {
+			outcome:  cbTypes.ReportRunFailed,
+			expected: true,
+		},
+		"matching selector": {
+			spec:     cbTypes.ReportNotificationSpec{ReportSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "finance"}}},
+			outcome:  cbTypes.ReportRunSucceeded,
+			expected: true,
+		},
+		"non matching selector": {
+			spec:    cbTypes.ReportNotificationSpec{ReportSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}},
+			outcome: cbTypes.ReportRunSucceeded,
+		},
+		"non matching outcome": {
+			spec:    cbTypes.ReportNotificationSpec{Outcomes: []cbTypes.ReportRunOutcome{cbTypes.ReportRunFailed}},
+			outcome: cbTypes.ReportRunSucceeded,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			matches, err := reportNotificationMatches(&cbTypes.ReportNotification{Spec: tt.spec}, report, tt.outcome)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matches)
+		})
+	}
+}
+
+func TestNewReportCloudEvent(t *testing.T) {
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	startTime := periodEnd.Add(time.Hour)
+	report := testhelpers.NewReport("test-report", "default", "test-query", nil, nil, cbTypes.ReportStatus{})
+	report.UID = "1234"
+	report.Status.Exports = []cbTypes.ReportExportStatus{
+		{Name: "current", PeriodStart: metav1.Time{Time: periodStart}, PeriodEnd: metav1.Time{Time: periodEnd}, Location: "s3://bucket/current.csv", Outcome: cbTypes.ReportRunSucceeded},
+		{Name: "previous", PeriodStart: metav1.Time{Time: periodStart.AddDate(0, -1, 0)}, PeriodEnd: metav1.Time{Time: periodStart}, Outcome: cbTypes.ReportRunSucceeded},
+	}
+	run := cbTypes.ReportRun{
+		PeriodStart: metav1.Time{Time: periodStart},
+		PeriodEnd:   metav1.Time{Time: periodEnd},
+		StartTime:   metav1.Time{Time: startTime},
+		FinishTime:  metav1.Time{Time: startTime.Add(time.Minute)},
+		RowCount:    42,
+		Outcome:     cbTypes.ReportRunSucceeded,
+	}
+	genQuery := testhelpers.NewReportGenerationQuery("test-query", "default", []cbTypes.ReportGenerationQueryColumn{
+		{Name: "period_start", Type: "timestamp"},
+		{Name: "period_end", Type: "timestamp"},
+	})
+
+	resultsURL := reportResultsURL("https://metering.example.com/", report, genQuery, periodStart, periodEnd)
+	assert.Equal(t, "https://metering.example.com/api/v2/reports/default/test-report/full?format=json&periodEnd=2019-02-01T00%3A00%3A00Z&periodStart=2019-01-01T00%3A00%3A00Z", resultsURL)
+
+	event := newReportCloudEvent(report, run, resultsURL)
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.Equal(t, "1234-1548982800000000000", event.ID)
+	assert.Equal(t, "/apis/metering.openshift.io/v1alpha1/namespaces/default/reports/test-report", event.Source)
+	assert.Equal(t, "io.openshift.metering.report.succeeded", event.Type)
+	assert.Equal(t, startTime.Add(time.Minute), event.Time)
+	assert.Equal(t, "test-report", event.Data.Report)
+	assert.Equal(t, int64(42), event.Data.RowCount)
+	assert.Equal(t, resultsURL, event.Data.ResultsURL)
+	require.Len(t, event.Data.Exports, 1)
+	assert.Equal(t, "current", event.Data.Exports[0].Name)
+
+	run.Outcome = cbTypes.ReportRunFailed
+	assert.Equal(t, "io.openshift.metering.report.failed", newReportCloudEvent(report, run, "").Type)
+}
+
+func TestSendReportNotification(t *testing.T) {
+	body, err := json.Marshal(map[string]string{"specversion": "1.0"})
+	require.NoError(t, err)
+
+	t.Run("signed", func(t *testing.T) {
+		var req *http.Request
+		var reqBody []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req = r
+			reqBody, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		err := sendReportNotification(server.Client(), server.URL, body, []byte("secret"))
+		require.NoError(t, err)
+		require.NotNil(t, req)
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/cloudevents+json", req.Header.Get("Content-Type"))
+		// echo -n '{"specversion":"1.0"}' | openssl dgst -sha256 -hmac secret
+		assert.Equal(t, "sha256=3d2b34f61d4edf98183a4b6ea9ef4c12990e2f18a6b54dc0fa85620ec2c6665f", req.Header.Get("X-Metering-Signature"))
+		assert.Equal(t, body, reqBody)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		var req *http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req = r
+		}))
+		defer server.Close()
+
+		require.NoError(t, sendReportNotification(server.Client(), server.URL, body, nil))
+		require.NotNil(t, req)
+		assert.Empty(t, req.Header.Get("X-Metering-Signature"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "upstream unavailable")
+		}))
+		defer server.Close()
+
+		err := sendReportNotification(server.Client(), server.URL, body, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "upstream unavailable")
+	})
+}
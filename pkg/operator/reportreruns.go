@@ -0,0 +1,247 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+)
+
+const reportRerunMaxRequeues = 5
+
+func (op *Reporting) runReportRerunWorker() {
+	logger := op.logger.WithField("component", "reportRerunWorker")
+	logger.Infof("ReportRerun worker started")
+	for op.processResource(logger, op.syncReportRerun, "ReportRerun", op.reportRerunQueue, reportRerunMaxRequeues) {
+	}
+}
+
+func (op *Reporting) syncReportRerun(logger log.FieldLogger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.WithError(err).Errorf("invalid resource key :%s", key)
+		return nil
+	}
+
+	logger = logger.WithFields(log.Fields{"reportRerun": name, "namespace": namespace})
+	rerun, err := op.reportRerunLister.ReportReruns(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Infof("ReportRerun %s/%s does not exist anymore", namespace, name)
+			return nil
+		}
+		return err
+	}
+
+	return op.handleReportRerun(logger, rerun.DeepCopy())
+}
+
+// handleReportRerun re-runs a single reporting period of the ReportRerun's
+// Report each time it's called, replacing the existing results for the
+// period once they've been generated again. After each period the status is updated and
+// the ReportRerun is requeued until all periods have been processed.
+func (op *Reporting) handleReportRerun(logger log.FieldLogger, rerun *cbTypes.ReportRerun) error {
+	switch rerun.Status.Phase {
+	case cbTypes.ReportRerunPhaseSucceeded, cbTypes.ReportRerunPhaseFailed:
+		logger.Infof("ReportRerun %s has already finished with phase %s, skipping", rerun.Name, rerun.Status.Phase)
+		return nil
+	}
+
+	if rerun.Spec.ReportName == "" {
+		return op.setReportRerunFailed(rerun, "spec.reportName must be set")
+	}
+	if !rerun.Spec.ReportingEnd.After(rerun.Spec.ReportingStart.Time) {
+		return op.setReportRerunFailed(rerun, fmt.Sprintf("spec.reportingEnd %s must be after spec.reportingStart %s", rerun.Spec.ReportingEnd.Time, rerun.Spec.ReportingStart.Time))
+	}
+
+	report, err := op.reportLister.Reports(rerun.Namespace).Get(rerun.Spec.ReportName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return op.setReportRerunFailed(rerun, fmt.Sprintf("Report %s does not exist", rerun.Spec.ReportName))
+		}
+		return err
+	}
+	if report.Status.TableName == "" {
+		return op.setReportRerunFailed(rerun, fmt.Sprintf("Report %s has not generated any results yet", report.Name))
+	}
+
+	genQuery, err := op.getReportGenerationQueryForReport(report)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return op.setReportRerunFailed(rerun, fmt.Sprintf("ReportGenerationQuery %s does not exist", report.Spec.GenerationQueryName))
+		}
+		return err
+	}
+
+	// when a Report overwrites its existing data each run, or has a
+	// partition for each period, there are no per-period rows to replace,
+	// otherwise we need the period columns to find the rows belonging to
+	// each period.
+	if !report.Spec.OverwriteExistingData && !report.Status.TablePartitionedByPeriod && !reportingutil.HasPeriodColumns(genQuery) {
+		return op.setReportRerunFailed(rerun, fmt.Sprintf("unable to replace existing results for Report %s: ReportGenerationQuery %s must have timestamp columns named %s and %s", report.Name, genQuery.Name, reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName))
+	}
+
+	queryDependencies, err := reporting.GetAndValidateGenerationQueryDependencies(
+		reporting.NewReportGenerationQueryListerGetter(op.reportGenerationQueryLister),
+		reporting.NewReportDataSourceListerGetter(op.reportDataSourceLister),
+		reporting.NewReportListerGetter(op.reportLister),
+		genQuery,
+		op.uninitialiedDependendenciesHandler(),
+	)
+	if err != nil {
+		if reporting.IsUninitializedDependencyError(err) {
+			rerun.Status.Phase = cbTypes.ReportRerunPhasePending
+			rerun.Status.Message = fmt.Sprintf("waiting for ReportGenerationQuery %s dependencies to be initialized: %v", genQuery.Name, err)
+			if _, updateErr := op.meteringClient.MeteringV1alpha1().ReportReruns(rerun.Namespace).Update(rerun); updateErr != nil {
+				logger.WithError(updateErr).Errorf("unable to update ReportRerun status")
+			}
+			return err
+		}
+		return op.setReportRerunFailed(rerun, fmt.Sprintf("failed to validate ReportGenerationQuery dependencies %s: %v", genQuery.Name, err))
+	}
+
+	periods, err := getReportRerunPeriods(report, rerun.Spec.ReportingStart.Time, rerun.Spec.ReportingEnd.Time)
+	if err != nil {
+		return op.setReportRerunFailed(rerun, err.Error())
+	}
+
+	if rerun.Status.StartTime == nil {
+		rerun.Status.StartTime = &metav1.Time{Time: op.clock.Now().UTC()}
+	}
+	rerun.Status.Phase = cbTypes.ReportRerunPhaseRunning
+	rerun.Status.TotalPeriods = len(periods)
+
+	if rerun.Status.CompletedPeriods < len(periods) {
+		period := periods[rerun.Status.CompletedPeriods]
+		logger = logger.WithFields(log.Fields{
+			"report":      report.Name,
+			"periodStart": period.periodStart,
+			"periodEnd":   period.periodEnd,
+		})
+
+		logger.Infof("re-running Report %s for period", report.Name)
+		rowCount, err := op.storeReportResults(logger, report, genQuery, queryDependencies, period.periodStart, period.periodEnd, true)
+		if err != nil {
+			msg := fmt.Sprintf("error occurred while generating report for period [%s to %s]: %v", period.periodStart, period.periodEnd, err)
+			// the existing results are only replaced once the new ones are
+			// stored, so the period is retried until the ReportRerun has been
+			// requeued too many times.
+			if key, keyErr := cache.MetaNamespaceKeyFunc(rerun); keyErr != nil || op.reportRerunQueue.NumRequeues(key) >= reportRerunMaxRequeues {
+				return op.setReportRerunFailed(rerun, msg)
+			}
+			rerun.Status.Message = msg
+			if _, updateErr := op.meteringClient.MeteringV1alpha1().ReportReruns(rerun.Namespace).Update(rerun); updateErr != nil {
+				logger.WithError(updateErr).Errorf("unable to update ReportRerun status")
+			}
+			return errors.New(msg)
+		}
+		logger.Infof("re-ran Report %s for period, stored %d rows", report.Name, rowCount)
+
+		rerun.Status.CompletedPeriods++
+		rerun.Status.LastCompletedPeriodEnd = &metav1.Time{Time: period.periodEnd}
+		rerun.Status.Message = fmt.Sprintf("Re-ran period [%s to %s]. %d of %d periods complete.", period.periodStart, period.periodEnd, rerun.Status.CompletedPeriods, rerun.Status.TotalPeriods)
+	}
+
+	done := rerun.Status.CompletedPeriods >= len(periods)
+	if done {
+		rerun.Status.Phase = cbTypes.ReportRerunPhaseSucceeded
+		rerun.Status.Message = fmt.Sprintf("Re-ran %d periods of Report %s between %s and %s.", len(periods), report.Name, rerun.Spec.ReportingStart.Time, rerun.Spec.ReportingEnd.Time)
+		rerun.Status.CompletionTime = &metav1.Time{Time: op.clock.Now().UTC()}
+		logger.Infof(rerun.Status.Message)
+	}
+
+	rerun, err = op.meteringClient.MeteringV1alpha1().ReportReruns(rerun.Namespace).Update(rerun)
+	if err != nil {
+		logger.WithError(err).Errorf("unable to update ReportRerun status")
+		return err
+	}
+
+	if !done {
+		op.enqueueReportRerun(rerun)
+	}
+	return nil
+}
+
+func (op *Reporting) setReportRerunFailed(rerun *cbTypes.ReportRerun, msg string) error {
+	logger := op.logger.WithFields(log.Fields{"reportRerun": rerun.Name, "namespace": rerun.Namespace})
+	logger.Errorf("ReportRerun %s failed: %s", rerun.Name, msg)
+	rerun.Status.Phase = cbTypes.ReportRerunPhaseFailed
+	rerun.Status.Message = msg
+	rerun.Status.CompletionTime = &metav1.Time{Time: op.clock.Now().UTC()}
+	_, err := op.meteringClient.MeteringV1alpha1().ReportReruns(rerun.Namespace).Update(rerun)
+	return err
+}
+
+// getReportRerunPeriods divides the time between start and end into
+// reporting periods using the Report's schedule. Reports without a schedule
+// are run-once reports, so the whole time range is a single period. For
+// schedules with a rolling window, each period covers the window ending at
+// each scheduled time, so periods may start before start and overlap.
+//
+// The periods must be the same as the periods the Report stored, otherwise
+// their existing results aren't replaced, so start and end must be the start
+// or end of one of the Report's periods.
+func getReportRerunPeriods(report *cbTypes.Report, start, end time.Time) ([]reportPeriod, error) {
+	start = start.UTC()
+	end = end.UTC()
+	if report.Spec.Schedule == nil {
+		if report.Spec.ReportingStart == nil || report.Spec.ReportingEnd == nil || !start.Equal(report.Spec.ReportingStart.Time) || !end.Equal(report.Spec.ReportingEnd.Time) {
+			return nil, fmt.Errorf("Report %s has no schedule, so it can only be re-run from its spec.reportingStart to its spec.reportingEnd", report.Name)
+		}
+		return []reportPeriod{{periodStart: start, periodEnd: end}}, nil
+	}
+
+	schedule, err := getSchedule(report.Spec.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if !isReportPeriodBoundary(report, schedule, start) {
+		return nil, fmt.Errorf("spec.reportingStart %s must be the start of one of Report %s's reporting periods", start, report.Name)
+	}
+	if !isReportPeriodBoundary(report, schedule, end) {
+		return nil, fmt.Errorf("spec.reportingEnd %s must be the end of one of Report %s's reporting periods", end, report.Name)
+	}
+
+	var periods []reportPeriod
+	periodStart := start
+	for periodStart.Before(end) {
+		period := getNextReportPeriod(schedule, report.Spec.Schedule, periodStart)
+		if !period.periodEnd.After(period.periodStart) {
+			return nil, fmt.Errorf("unable to determine the next reporting period after %s", periodStart)
+		}
+		// only the last period of a Report whose spec.reportingEnd isn't
+		// a scheduled time is cut off.
+		if period.periodEnd.After(end) {
+			period.periodEnd = end
+			if report.Spec.Schedule.Window != nil {
+				period.periodStart = end.Add(-report.Spec.Schedule.Window.Duration)
+			}
+		}
+		periods = append(periods, *period)
+		periodStart = period.periodEnd
+	}
+	return periods, nil
+}
+
+// isReportPeriodBoundary returns true if reporting periods of the Report
+// start or end at t, which are the times its schedule runs at, and its
+// spec.reportingStart and spec.reportingEnd, which its first and last periods
+// start and end at.
+func isReportPeriodBoundary(report *cbTypes.Report, schedule reportSchedule, t time.Time) bool {
+	if report.Spec.ReportingStart != nil && t.Equal(report.Spec.ReportingStart.Time) {
+		return true
+	}
+	if report.Spec.ReportingEnd != nil && t.Equal(report.Spec.ReportingEnd.Time) {
+		return true
+	}
+	// Next returns the first scheduled time after the time it's given.
+	return schedule.Next(t.Add(-time.Nanosecond)).Equal(t)
+}
@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestGetReportRerunPeriods(t *testing.T) {
+	baseTime := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+	dailyReport := testhelpers.NewReport("test-report", "default", "test-query", nil, nil, v1alpha1.ReportStatus{})
+	dailyReport.Spec.Schedule = &v1alpha1.ReportSchedule{Period: v1alpha1.ReportPeriodDaily}
+	rollingReport := testhelpers.NewReport("test-report", "default", "test-query", nil, nil, v1alpha1.ReportStatus{})
+	rollingReport.Spec.Schedule = &v1alpha1.ReportSchedule{
+		Period: v1alpha1.ReportPeriodDaily,
+		Window: &metav1.Duration{Duration: 48 * time.Hour},
+	}
+	// the Report's first period starts at its reportingStart, and its last
+	// ends at its reportingEnd
+	unalignedStart := baseTime.Add(12 * time.Hour)
+	unalignedEnd := baseTime.AddDate(0, 0, 2).Add(6 * time.Hour)
+	unalignedReport := testhelpers.NewReport("test-report", "default", "test-query", &unalignedStart, &unalignedEnd, v1alpha1.ReportStatus{})
+	unalignedReport.Spec.Schedule = &v1alpha1.ReportSchedule{Period: v1alpha1.ReportPeriodDaily}
+	runOnceEnd := baseTime.AddDate(0, 1, 0)
+	runOnceReport := testhelpers.NewReport("test-report", "default", "test-query", &baseTime, &runOnceEnd, v1alpha1.ReportStatus{})
+
+	tests := map[string]struct {
+		report          *v1alpha1.Report
+		start           time.Time
+		end             time.Time
+		expectedPeriods []reportPeriod
+		expectedErr     string
+	}{
+		"daily schedule over three days": {
+			report: dailyReport,
+			start:  baseTime,
+			end:    baseTime.AddDate(0, 0, 3),
+			expectedPeriods: []reportPeriod{
+				{periodStart: baseTime, periodEnd: baseTime.AddDate(0, 0, 1)},
+				{periodStart: baseTime.AddDate(0, 0, 1), periodEnd: baseTime.AddDate(0, 0, 2)},
+				{periodStart: baseTime.AddDate(0, 0, 2), periodEnd: baseTime.AddDate(0, 0, 3)},
+			},
+		},
+		"daily schedule with unaligned start": {
+			report:      dailyReport,
+			start:       baseTime.Add(12 * time.Hour),
+			end:         baseTime.AddDate(0, 0, 2),
+			expectedErr: "spec.reportingStart 2018-07-01 12:00:00 +0000 UTC must be the start of one of Report test-report's reporting periods",
+		},
+		"daily schedule with unaligned end": {
+			report:      dailyReport,
+			start:       baseTime,
+			end:         baseTime.AddDate(0, 0, 1).Add(6 * time.Hour),
+			expectedErr: "spec.reportingEnd 2018-07-02 06:00:00 +0000 UTC must be the end of one of Report test-report's reporting periods",
+		},
+		"daily schedule with the Report's unaligned reportingStart and reportingEnd": {
+			report: unalignedReport,
+			start:  unalignedStart,
+			end:    unalignedEnd,
+			expectedPeriods: []reportPeriod{
+				{periodStart: unalignedStart, periodEnd: baseTime.AddDate(0, 0, 1)},
+				{periodStart: baseTime.AddDate(0, 0, 1), periodEnd: baseTime.AddDate(0, 0, 2)},
+				{periodStart: baseTime.AddDate(0, 0, 2), periodEnd: unalignedEnd},
+			},
+		},
+		"daily schedule with a rolling window": {
+			report: rollingReport,
+			start:  baseTime,
+			end:    baseTime.AddDate(0, 0, 2),
+			expectedPeriods: []reportPeriod{
+				{periodStart: baseTime.AddDate(0, 0, -1), periodEnd: baseTime.AddDate(0, 0, 1)},
+				{periodStart: baseTime, periodEnd: baseTime.AddDate(0, 0, 2)},
+			},
+		},
+		"run-once report is a single period": {
+			report: runOnceReport,
+			start:  baseTime,
+			end:    runOnceEnd,
+			expectedPeriods: []reportPeriod{
+				{periodStart: baseTime, periodEnd: runOnceEnd},
+			},
+		},
+		"run-once report with a different period": {
+			report:      runOnceReport,
+			start:       baseTime,
+			end:         baseTime.AddDate(0, 0, 7),
+			expectedErr: "Report test-report has no schedule, so it can only be re-run from its spec.reportingStart to its spec.reportingEnd",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			periods, err := getReportRerunPeriods(test.report, test.start, test.end)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPeriods, periods)
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+const (
+	// maxReportResultsLimit is the largest page of results which can be
+	// requested. Pages are read into memory before they're written, to
+	// determine if there are more results after them.
+	maxReportResultsLimit = 10000
+
+	// reportResultsContinueHeader is the response header containing the
+	// continue token used to get the next page of results.
+	reportResultsContinueHeader = "X-Metering-Continue"
+)
+
+var (
+	// errReportResultsChanged is returned when a continue token was issued
+	// before the report was last generated, so the offset it contains may
+	// no longer point to the next page of results.
+	errReportResultsChanged = errors.New("the report has been regenerated since the continue token was issued, request the first page of results again")
+
+	reportResultsFilterOperators = map[string]string{
+		"eq":  "=",
+		"ne":  "<>",
+		"lt":  "<",
+		"lte": "<=",
+		"gt":  ">",
+		"gte": ">=",
+		"in":  "IN",
+	}
+)
+
+// reportResultsQueryParams are the query parameters of a v2 report request
+// which select the columns, filter, order and paginate the results.
+type reportResultsQueryParams struct {
+	// Columns are the selected columns in the order they were requested,
+	// or every column if none were selected.
+	Columns []api.ReportGenerationQueryColumn
+	// TableColumns are the columns of the report's table which need to be
+	// queried to get the selected columns.
+	TableColumns []presto.Column
+	// Query is the filter, ordering and page of results requested.
+	Query prestostore.ReportResultsQuery
+	// fingerprint identifies the parameters a continue token was issued for,
+	// so it can't be used with different parameters.
+	fingerprint string
+	reportTime  time.Time
+}
+
+// reportResultsContinueToken is the decoded form of the continue token
+// returned when there are more results than the requested limit.
+type reportResultsContinueToken struct {
+	Offset      int64     `json:"offset"`
+	Fingerprint string    `json:"fingerprint"`
+	ReportTime  time.Time `json:"reportTime"`
+}
+
+// parseReportResultsQueryParams parses the columns, filter, periodStart,
+// periodEnd, orderBy, limit and continue query parameters. columns are the
+// columns which can be selected, and filterColumns the columns which can be
+// filtered and ordered by. tableColumns are the columns of the report's
+// table, used to order map columns. reportTime is the last time the report
+// was generated. partitionedByPeriod is true if the report's table is
+// partitioned by reporting period.
+func parseReportResultsQueryParams(vals url.Values, columns, filterColumns []api.ReportGenerationQueryColumn, tableColumns []presto.Column, reportTime time.Time, partitionedByPeriod bool) (*reportResultsQueryParams, error) {
+	params := &reportResultsQueryParams{reportTime: reportTime}
+
+	columnsMap := make(map[string]api.ReportGenerationQueryColumn)
+	for _, column := range columns {
+		columnsMap[column.Name] = column
+	}
+	filterColumnsMap := make(map[string]api.ReportGenerationQueryColumn)
+	for _, column := range filterColumns {
+		filterColumnsMap[column.Name] = column
+	}
+	tableColumnsMap := make(map[string]presto.Column)
+	for _, column := range tableColumns {
+		tableColumnsMap[column.Name] = column
+	}
+
+	params.Columns = columns
+	params.TableColumns = tableColumns
+	if selected := vals.Get("columns"); selected != "" {
+		params.Columns = nil
+		params.TableColumns = nil
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(selected, ",") {
+			column, ok := columnsMap[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid columns %q, unknown column %q", selected, name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("invalid columns %q, column %q is selected more than once", selected, name)
+			}
+			seen[name] = true
+			params.Columns = append(params.Columns, column)
+			// columns which aren't in the ReportGenerationQuery, such as
+			// cost columns, are calculated from the other columns.
+			if _, ok := filterColumnsMap[name]; !ok {
+				continue
+			}
+			tableColumn, ok := tableColumnsMap[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid columns %q, column %q doesn't exist in the report's table", selected, name)
+			}
+			params.TableColumns = append(params.TableColumns, tableColumn)
+		}
+	}
+
+	var conditions []string
+	for _, filter := range vals["filter"] {
+		condition, err := parseReportResultsFilter(filter, filterColumnsMap)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	periodCondition, err := parseReportResultsPeriod(vals, filterColumnsMap, partitionedByPeriod)
+	if err != nil {
+		return nil, err
+	}
+	if periodCondition != "" {
+		conditions = append(conditions, periodCondition)
+	}
+	params.Query.Where = strings.Join(conditions, " AND ")
+
+	if orderBy := vals.Get("orderBy"); orderBy != "" {
+		ordered := make(map[string]bool)
+		for _, name := range strings.Split(orderBy, ",") {
+			descending := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			if _, ok := filterColumnsMap[name]; !ok {
+				return nil, fmt.Errorf("invalid orderBy %q, unknown column %q", orderBy, name)
+			}
+			tableColumn, ok := tableColumnsMap[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid orderBy %q, column %q doesn't exist in the report's table", orderBy, name)
+			}
+			if ordered[name] {
+				return nil, fmt.Errorf("invalid orderBy %q, column %q is ordered by more than once", orderBy, name)
+			}
+			ordered[name] = true
+			params.Query.OrderBy = append(params.Query.OrderBy, presto.OrderByColumn{Column: tableColumn, Descending: descending})
+		}
+		// order by the remaining columns as well, so rows are always
+		// returned in the same order and pages don't overlap.
+		for _, column := range tableColumns {
+			if !ordered[column.Name] {
+				params.Query.OrderBy = append(params.Query.OrderBy, presto.OrderByColumn{Column: column})
+			}
+		}
+	}
+
+	if limit := vals.Get("limit"); limit != "" {
+		var err error
+		params.Query.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || params.Query.Limit <= 0 || params.Query.Limit > maxReportResultsLimit {
+			return nil, fmt.Errorf("invalid limit %q, must be a number between 1 and %d", limit, maxReportResultsLimit)
+		}
+	}
+
+	params.fingerprint = reportResultsQueryFingerprint(vals)
+	if continueToken := vals.Get("continue"); continueToken != "" {
+		if params.Query.Limit == 0 {
+			return nil, fmt.Errorf("limit must be set when continue is set")
+		}
+		token, err := decodeReportResultsContinueToken(continueToken)
+		if err != nil {
+			return nil, err
+		}
+		if token.Fingerprint != params.fingerprint {
+			return nil, fmt.Errorf("invalid continue token, the token was issued for a request with different columns, filter, periodStart, periodEnd or orderBy parameters")
+		}
+		if !token.ReportTime.Equal(reportTime) {
+			return nil, errReportResultsChanged
+		}
+		params.Query.Offset = token.Offset
+	}
+	return params, nil
+}
+
+// ContinueToken returns the continue token used to get the page of results
+// following the current page.
+func (params *reportResultsQueryParams) ContinueToken() (string, error) {
+	b, err := json.Marshal(reportResultsContinueToken{
+		Offset:      params.Query.Offset + params.Query.Limit,
+		Fingerprint: params.fingerprint,
+		ReportTime:  params.reportTime,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeReportResultsContinueToken(continueToken string) (*reportResultsContinueToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(continueToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continue token: %v", err)
+	}
+	var token reportResultsContinueToken
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("invalid continue token: %v", err)
+	}
+	if token.Offset < 0 {
+		return nil, fmt.Errorf("invalid continue token, offset must not be negative")
+	}
+	return &token, nil
+}
+
+// reportResultsQueryFingerprint returns a hash of the query parameters which
+// determine which rows are returned and in what order.
+func reportResultsQueryFingerprint(vals url.Values) string {
+	b, _ := json.Marshal([]interface{}{vals.Get("columns"), vals["filter"], vals.Get("periodStart"), vals.Get("periodEnd"), vals.Get("orderBy")})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseReportResultsPeriod parses the periodStart and periodEnd query
+// parameters into a SQL condition matching the rows of the requested
+// reporting periods, using the period_start and period_end columns. If both
+// are set, the rows of every period within them are matched, otherwise only
+// the rows of the period starting or ending at the one which is set. If
+// partitionedByPeriod is true, a condition on the partition column is added,
+// so only the partitions of the requested periods are read.
+func parseReportResultsPeriod(vals url.Values, columns map[string]api.ReportGenerationQueryColumn, partitionedByPeriod bool) (string, error) {
+	periodStartStr, periodEndStr := vals.Get("periodStart"), vals.Get("periodEnd")
+	if periodStartStr == "" && periodEndStr == "" {
+		return "", nil
+	}
+	for _, name := range []string{reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName} {
+		if column, ok := columns[name]; !ok || strings.ToLower(column.Type) != "timestamp" {
+			return "", fmt.Errorf("periodStart and periodEnd can't be used, the ReportGenerationQuery has no %s and %s timestamp columns", reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName)
+		}
+	}
+
+	var periodStart, periodEnd time.Time
+	var err error
+	if periodStartStr != "" {
+		periodStart, err = time.Parse(time.RFC3339, periodStartStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid periodStart %q, must be in RFC3339 format", periodStartStr)
+		}
+	}
+	if periodEndStr != "" {
+		periodEnd, err = time.Parse(time.RFC3339, periodEndStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid periodEnd %q, must be in RFC3339 format", periodEndStr)
+		}
+	}
+
+	switch {
+	case periodStartStr != "" && periodEndStr != "":
+		if !periodEnd.After(periodStart) {
+			return "", fmt.Errorf("invalid periodEnd %q, must be after periodStart %q", periodEndStr, periodStartStr)
+		}
+		condition := reportingutil.GeneratePeriodWhereClause(periodStart, periodEnd)
+		if partitionedByPeriod {
+			condition += " AND " + reportingutil.GeneratePeriodPartitionWhereClause(periodStart, periodEnd)
+		}
+		return condition, nil
+	case periodStartStr != "":
+		condition := fmt.Sprintf(`"%s" = timestamp '%s'`, reportingutil.PeriodStartColumnName, periodStart.UTC().Format(presto.TimestampFormat))
+		if partitionedByPeriod {
+			// partitions are named by when their period starts, to the
+			// second.
+			condition += " AND " + reportingutil.GeneratePeriodPartitionWhereClause(periodStart, periodStart.Add(time.Second))
+		}
+		return condition, nil
+	default:
+		// partitions can't be selected by when their period ends
+		return fmt.Sprintf(`"%s" = timestamp '%s'`, reportingutil.PeriodEndColumnName, periodEnd.UTC().Format(presto.TimestampFormat)), nil
+	}
+}
+
+// parseReportResultsFilter parses a filter in the form
+// <column>:<operator>:<value> into a SQL condition. The value is converted
+// into a literal of the column's type, so filters can't be used to inject
+// SQL. The in operator takes a comma separated list of values.
+func parseReportResultsFilter(filter string, columns map[string]api.ReportGenerationQueryColumn) (string, error) {
+	parts := strings.SplitN(filter, ":", 3)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid filter %q, must be in the form <column>:<operator>:<value>", filter)
+	}
+	columnName, operator, value := parts[0], parts[1], parts[2]
+	column, ok := columns[columnName]
+	if !ok {
+		return "", fmt.Errorf("invalid filter %q, unknown column %q", filter, columnName)
+	}
+	sqlOperator, ok := reportResultsFilterOperators[operator]
+	if !ok {
+		return "", fmt.Errorf("invalid filter %q, unknown operator %q, must be one of eq, ne, lt, lte, gt, gte or in", filter, operator)
+	}
+	colType := reportingutil.SimpleHiveColumnTypeToPrestoColumnType(column.Type)
+	if colType == "BOOLEAN" && operator != "eq" && operator != "ne" && operator != "in" {
+		return "", fmt.Errorf("invalid filter %q, operator %q can't be used with boolean column %q", filter, operator, columnName)
+	}
+
+	values := []string{value}
+	if operator == "in" {
+		values = strings.Split(value, ",")
+	}
+	literals := make([]string, len(values))
+	for i, val := range values {
+		var err error
+		literals[i], err = formatReportResultsFilterValue(colType, column.Type, val)
+		if err != nil {
+			return "", fmt.Errorf("invalid filter %q: %v", filter, err)
+		}
+	}
+
+	if operator == "in" {
+		return fmt.Sprintf(`"%s" IN (%s)`, columnName, strings.Join(literals, ", ")), nil
+	}
+	return fmt.Sprintf(`"%s" %s %s`, columnName, sqlOperator, literals[0]), nil
+}
+
+// formatReportResultsFilterValue converts value into a Presto literal of
+// colType. hiveType is the type of the column used in errors.
+func formatReportResultsFilterValue(colType, hiveType, value string) (string, error) {
+	switch colType {
+	case "VARCHAR":
+		return presto.FormatStringLiteral(value), nil
+	case "BIGINT":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, hiveType)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case "DOUBLE":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("%q is not a valid %s", value, hiveType)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case "BOOLEAN":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid %s", value, hiveType)
+		}
+		return strconv.FormatBool(b), nil
+	case "TIMESTAMP":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid %s, must be in RFC3339 format", value, hiveType)
+		}
+		return fmt.Sprintf("timestamp '%s'", t.UTC().Format(presto.TimestampFormat)), nil
+	}
+	return "", fmt.Errorf("columns of type %s can't be filtered", hiveType)
+}
@@ -0,0 +1,252 @@
+package operator
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+func TestParseReportResultsFilter(t *testing.T) {
+	columns := map[string]api.ReportGenerationQueryColumn{
+		"namespace":    {Name: "namespace", Type: "string"},
+		"pods":         {Name: "pods", Type: "bigint"},
+		"cpu":          {Name: "cpu", Type: "double"},
+		"period_start": {Name: "period_start", Type: "timestamp"},
+		"running":      {Name: "running", Type: "boolean"},
+		"labels":       {Name: "labels", Type: "map<string, string>"},
+	}
+	tests := map[string]struct {
+		filter      string
+		expectedSQL string
+		expectErr   bool
+	}{
+		"string equality": {
+			filter:      "namespace:eq:default",
+			expectedSQL: `"namespace" = 'default'`,
+		},
+		"string with quotes is escaped": {
+			filter:      "namespace:eq:it's'); DROP TABLE foo; --",
+			expectedSQL: `"namespace" = 'it''s''); DROP TABLE foo; --'`,
+		},
+		"string in list": {
+			filter:      "namespace:in:default,kube-system",
+			expectedSQL: `"namespace" IN ('default', 'kube-system')`,
+		},
+		"bigint range": {
+			filter:      "pods:gte:10",
+			expectedSQL: `"pods" >= 10`,
+		},
+		"double range": {
+			filter:      "cpu:lt:1.5",
+			expectedSQL: `"cpu" < 1.5`,
+		},
+		"timestamp range": {
+			filter:      "period_start:gt:2019-01-01T00:00:00Z",
+			expectedSQL: `"period_start" > timestamp '2019-01-01 00:00:00.000'`,
+		},
+		"boolean not equal": {
+			filter:      "running:ne:true",
+			expectedSQL: `"running" <> true`,
+		},
+		"invalid bigint": {
+			filter:    "pods:eq:1 OR 1=1",
+			expectErr: true,
+		},
+		"invalid double": {
+			filter:    "cpu:eq:NaN",
+			expectErr: true,
+		},
+		"invalid timestamp": {
+			filter:    "period_start:eq:yesterday",
+			expectErr: true,
+		},
+		"boolean range": {
+			filter:    "running:gt:false",
+			expectErr: true,
+		},
+		"map column": {
+			filter:    "labels:eq:foo",
+			expectErr: true,
+		},
+		"unknown column": {
+			filter:    "doesnt_exist:eq:foo",
+			expectErr: true,
+		},
+		"unknown operator": {
+			filter:    "namespace:like:default",
+			expectErr: true,
+		},
+		"missing value": {
+			filter:    "namespace:eq",
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			sql, err := parseReportResultsFilter(tt.filter, columns)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSQL, sql)
+		})
+	}
+}
+
+func TestParseReportResultsPeriod(t *testing.T) {
+	columns := map[string]api.ReportGenerationQueryColumn{
+		"period_start": {Name: "period_start", Type: "timestamp"},
+		"period_end":   {Name: "period_end", Type: "timestamp"},
+	}
+	tests := map[string]struct {
+		periodStart string
+		periodEnd   string
+		columns     map[string]api.ReportGenerationQueryColumn
+		partitioned bool
+		expectedSQL string
+		expectErr   bool
+	}{
+		"no period": {
+			columns: columns,
+		},
+		"periods within a range": {
+			periodStart: "2019-01-01T00:00:00Z",
+			periodEnd:   "2019-03-01T00:00:00Z",
+			columns:     columns,
+			expectedSQL: `"period_start" >= timestamp '2019-01-01 00:00:00.000' AND "period_end" <= timestamp '2019-03-01 00:00:00.000'`,
+		},
+		"partitioned periods within a range": {
+			periodStart: "2019-01-01T00:00:00Z",
+			periodEnd:   "2019-03-01T00:00:00Z",
+			columns:     columns,
+			partitioned: true,
+			expectedSQL: `"period_start" >= timestamp '2019-01-01 00:00:00.000' AND "period_end" <= timestamp '2019-03-01 00:00:00.000' AND "report_period" >= '20190101T000000Z' AND "report_period" < '20190301T000000Z'`,
+		},
+		"partitioned period starting at": {
+			periodStart: "2019-02-01T00:00:00Z",
+			columns:     columns,
+			partitioned: true,
+			expectedSQL: `"period_start" = timestamp '2019-02-01 00:00:00.000' AND "report_period" >= '20190201T000000Z' AND "report_period" < '20190201T000001Z'`,
+		},
+		"period starting at": {
+			periodStart: "2019-02-01T00:00:00Z",
+			columns:     columns,
+			expectedSQL: `"period_start" = timestamp '2019-02-01 00:00:00.000'`,
+		},
+		"period ending at": {
+			periodEnd:   "2019-03-01T00:00:00+01:00",
+			columns:     columns,
+			expectedSQL: `"period_end" = timestamp '2019-02-28 23:00:00.000'`,
+		},
+		"end before start": {
+			periodStart: "2019-03-01T00:00:00Z",
+			periodEnd:   "2019-01-01T00:00:00Z",
+			columns:     columns,
+			expectErr:   true,
+		},
+		"invalid timestamp": {
+			periodEnd: "2019-03-01",
+			columns:   columns,
+			expectErr: true,
+		},
+		"no period columns": {
+			periodEnd: "2019-03-01T00:00:00Z",
+			columns:   map[string]api.ReportGenerationQueryColumn{"period_start": columns["period_start"]},
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			vals := url.Values{}
+			if tt.periodStart != "" {
+				vals.Set("periodStart", tt.periodStart)
+			}
+			if tt.periodEnd != "" {
+				vals.Set("periodEnd", tt.periodEnd)
+			}
+			sql, err := parseReportResultsPeriod(vals, tt.columns, tt.partitioned)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSQL, sql)
+		})
+	}
+}
+
+func TestParseReportResultsQueryParams(t *testing.T) {
+	columns := []api.ReportGenerationQueryColumn{
+		{Name: "namespace", Type: "string"},
+		{Name: "labels", Type: "map<string, string>"},
+		{Name: "cpu", Type: "double"},
+	}
+	tableColumns := []presto.Column{
+		{Name: "namespace", Type: "VARCHAR"},
+		{Name: "labels", Type: "map(VARCHAR,VARCHAR)"},
+		{Name: "cpu", Type: "DOUBLE"},
+	}
+	reportTime := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	vals := url.Values{
+		"columns": []string{"cpu,namespace"},
+		"filter":  []string{"namespace:eq:default", "cpu:gt:0"},
+		"orderBy": []string{"-cpu"},
+		"limit":   []string{"10"},
+	}
+	params, err := parseReportResultsQueryParams(vals, columns, columns, tableColumns, reportTime, false)
+	require.NoError(t, err)
+	assert.Equal(t, []api.ReportGenerationQueryColumn{columns[2], columns[0]}, params.Columns)
+	assert.Equal(t, []presto.Column{tableColumns[2], tableColumns[0]}, params.TableColumns)
+	assert.Equal(t, `"namespace" = 'default' AND "cpu" > 0`, params.Query.Where)
+	assert.Equal(t, []presto.OrderByColumn{
+		{Column: tableColumns[2], Descending: true},
+		{Column: tableColumns[0]},
+		{Column: tableColumns[1]},
+	}, params.Query.OrderBy, "expected the remaining columns to be ordered by after the requested columns")
+	assert.Equal(t, int64(10), params.Query.Limit)
+	assert.Equal(t, int64(0), params.Query.Offset)
+
+	// the continue token should get the next page when used with the same
+	// parameters
+	token, err := params.ContinueToken()
+	require.NoError(t, err)
+	vals.Set("continue", token)
+	params, err = parseReportResultsQueryParams(vals, columns, columns, tableColumns, reportTime, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), params.Query.Offset)
+
+	// a different limit can be used for the next page
+	vals.Set("limit", "5")
+	_, err = parseReportResultsQueryParams(vals, columns, columns, tableColumns, reportTime, false)
+	assert.NoError(t, err)
+
+	_, err = parseReportResultsQueryParams(vals, columns, columns, tableColumns, reportTime.Add(time.Hour), false)
+	assert.Equal(t, errReportResultsChanged, err, "expected the continue token to be rejected once the report was regenerated")
+
+	vals.Set("orderBy", "cpu")
+	_, err = parseReportResultsQueryParams(vals, columns, columns, tableColumns, reportTime, false)
+	assert.Error(t, err, "expected the continue token to be rejected when the parameters changed")
+
+	for _, invalid := range []url.Values{
+		{"columns": []string{"doesnt_exist"}},
+		{"columns": []string{"cpu,cpu"}},
+		{"orderBy": []string{"doesnt_exist"}},
+		{"limit": []string{"0"}},
+		{"limit": []string{"100000000"}},
+		{"continue": []string{"not-a-token"}, "limit": []string{"10"}},
+		{"continue": []string{token}},
+	} {
+		_, err := parseReportResultsQueryParams(invalid, columns, columns, tableColumns, reportTime, false)
+		assert.Error(t, err, "expected %v to be invalid", invalid)
+	}
+}
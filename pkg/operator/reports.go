@@ -17,6 +17,7 @@ import (
 
 	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	cbutil "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1/util"
+	"github.com/operator-framework/operator-metering/pkg/hive"
 	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
 	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
 	"github.com/operator-framework/operator-metering/pkg/util/slice"
@@ -84,6 +85,7 @@ func (op *Reporting) syncReport(logger log.FieldLogger, key string) error {
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			logger.Infof("report %s/%s does not exist anymore, stopping and removing any running jobs for Report", namespace, name)
+			op.forgetPendingReportExportStatuses(namespace, name)
 			return nil
 		}
 		return err
@@ -105,6 +107,24 @@ type reportSchedule interface {
 }
 
 func getSchedule(reportSched *cbTypes.ReportSchedule) (reportSchedule, error) {
+	sched, err := getCronSchedule(reportSched)
+	if err != nil {
+		return nil, err
+	}
+	if reportSched.Window != nil && reportSched.Window.Duration <= 0 {
+		return nil, fmt.Errorf("spec.schedule.window must be a positive duration, got %s", reportSched.Window.Duration)
+	}
+	if reportSched.TimeZone == "" {
+		return sched, nil
+	}
+	loc, err := time.LoadLocation(reportSched.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.schedule.timeZone %q: %v", reportSched.TimeZone, err)
+	}
+	return &locationSchedule{schedule: sched, loc: loc}, nil
+}
+
+func getCronSchedule(reportSched *cbTypes.ReportSchedule) (reportSchedule, error) {
 	var cronSpec string
 	switch reportSched.Period {
 	case cbTypes.ReportPeriodCron:
@@ -191,17 +211,63 @@ func getSchedule(reportSched *cbTypes.ReportSchedule) (reportSchedule, error) {
 }
 
 func (op *Reporting) handleReport(logger log.FieldLogger, report *cbTypes.Report) error {
+	var err error
 	if op.cfg.EnableFinalizers && reportNeedsFinalizer(report) {
-		var err error
 		report, err = op.addReportFinalizer(report)
 		if err != nil {
 			return err
 		}
 	}
+	report, err = op.saveReportExportStatuses(logger, report)
+	if err != nil {
+		return err
+	}
 
 	return op.runReport(logger, report)
 }
 
+// locationSchedule evaluates a schedule against the wall clock of loc
+// rather than UTC.
+//
+// The cron library computes the next time by adding hours to the time it's
+// given, which skips or repeats scheduled times when a daylight saving time
+// transition occurs. To avoid this, the schedule is evaluated using the wall
+// clock time of loc represented in UTC, which has no transitions, and the
+// result is converted back into loc. Scheduled times which don't exist in
+// loc, because the clocks skipped over them, are moved forward by the
+// length of the transition. Scheduled times which occur twice resolve to
+// the first occurrence.
+type locationSchedule struct {
+	schedule reportSchedule
+	loc      *time.Location
+}
+
+func (s *locationSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	wallClock := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	for {
+		wallClock = s.schedule.Next(wallClock)
+		if wallClock.IsZero() {
+			return wallClock
+		}
+		next := time.Date(wallClock.Year(), wallClock.Month(), wallClock.Day(), wallClock.Hour(), wallClock.Minute(), wallClock.Second(), wallClock.Nanosecond(), s.loc)
+		if next.Hour() != wallClock.Hour() || next.Minute() != wallClock.Minute() {
+			// the wall clock time was skipped over, time.Date may have
+			// normalized it to before the transition, so use whichever
+			// of the two offsets results in the later time.
+			_, offset := next.Zone()
+			if later := wallClock.Add(-time.Duration(offset) * time.Second).In(s.loc); later.After(next) {
+				next = later
+			}
+		}
+		// when the clocks are set back, the next wall clock time can
+		// refer to a time we've already passed.
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
 type reportPeriod struct {
 	periodEnd   time.Time
 	periodStart time.Time
@@ -275,6 +341,14 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 		return op.setReportStatusInvalidReport(report, fmt.Sprintf("failed to validate ReportGenerationQuery dependencies %s: %v", genQuery.Name, err))
 	}
 
+	if report.Status.TableName != "" {
+		var canRun bool
+		report, canRun, err = op.reconcileReportTableSchema(logger, report, genQuery)
+		if err != nil || !canRun {
+			return err
+		}
+	}
+
 	now := op.clock.Now().UTC()
 
 	var reportPeriod *reportPeriod
@@ -285,20 +359,20 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 		}
 
 		if report.Status.LastReportTime != nil {
-			reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, report.Status.LastReportTime.Time)
+			reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule, report.Status.LastReportTime.Time)
 		} else {
 			if report.Spec.ReportingStart != nil {
 				logger.Infof("no last report time for report, using spec.reportingStart %s as starting point", report.Spec.ReportingStart.Time)
-				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, report.Spec.ReportingStart.Time)
+				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule, report.Spec.ReportingStart.Time)
 			} else if report.Status.NextReportTime != nil {
 				logger.Infof("no last report time for report, using status.nextReportTime %s as starting point", report.Status.NextReportTime.Time)
-				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, report.Status.NextReportTime.Time)
+				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule, report.Status.NextReportTime.Time)
 			} else {
 				// the current period, [now, nextScheduledTime]
-				currentPeriod := getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, now)
+				currentPeriod := getNextReportPeriod(reportSchedule, report.Spec.Schedule, now)
 				// the next full report period from [nextScheduledTime, nextScheduledTime+1]
-				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, currentPeriod.periodEnd)
-				report.Status.NextReportTime = &metav1.Time{reportPeriod.periodStart}
+				reportPeriod = getNextReportPeriod(reportSchedule, report.Spec.Schedule, currentPeriod.periodEnd)
+				report.Status.NextReportTime = &metav1.Time{currentPeriod.periodEnd}
 			}
 		}
 	} else {
@@ -316,6 +390,10 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 		logger.Debugf("calculated Report periodEnd %s goes beyond spec.reportingEnd %s, setting periodEnd to reportingEnd", reportPeriod.periodEnd, report.Spec.ReportingEnd.Time)
 		// we need to truncate the reportPeriod to align with the reportingEnd
 		reportPeriod.periodEnd = report.Spec.ReportingEnd.Time
+		// rolling windows keep their duration and end at reportingEnd
+		if report.Spec.Schedule != nil && report.Spec.Schedule.Window != nil {
+			reportPeriod.periodStart = reportPeriod.periodEnd.Add(-report.Spec.Schedule.Window.Duration)
+		}
 	}
 
 	logger = logger.WithFields(log.Fields{
@@ -345,6 +423,7 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 			if err != nil {
 				return err
 			}
+			op.eventRecorder.Event(report, v1.EventTypeNormal, reportScheduledEventReason, waitMsg)
 
 			// we requeue this for later when the period we need to report on next
 			// has elapsed
@@ -352,7 +431,7 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 			return nil
 		}
 
-		var unmetDataStartDataSourceDependendencies, unmetDataEndDataSourceDependendencies, unstartedDataSourceDependencies []string
+		var unmetDataStartDataSourceDependendencies, unmetDataEndDataSourceDependendencies, unstartedDataSourceDependencies, gapDataSourceDependencies []string
 		// Validate all ReportDataSources that the Report depends on have indicated
 		// they have data available that covers the current reportPeriod.
 		for _, dataSource := range queryDependencies.ReportDataSources {
@@ -375,6 +454,10 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 						queue = true
 						unmetDataEndDataSourceDependendencies = append(unmetDataEndDataSourceDependendencies, dataSource.Name)
 					}
+					// reportPeriod overlaps data missing from the table
+					if report.Spec.RequireCompleteData && len(prometheusMetricImportGapsOverlapping(dataSource.Status.PrometheusMetricImportStatus.Gaps, reportPeriod.periodStart, reportPeriod.periodEnd)) != 0 {
+						gapDataSourceDependencies = append(gapDataSourceDependencies, dataSource.Name)
+					}
 				}
 				if queue {
 					op.enqueueReportDataSource(dataSource)
@@ -392,9 +475,9 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 			}
 		}
 
-		if len(unstartedDataSourceDependencies) != 0 || len(unmetDataStartDataSourceDependendencies) != 0 || len(unmetDataEndDataSourceDependendencies) != 0 || len(unmetReportDependendencies) != 0 {
+		if len(unstartedDataSourceDependencies) != 0 || len(unmetDataStartDataSourceDependendencies) != 0 || len(unmetDataEndDataSourceDependendencies) != 0 || len(gapDataSourceDependencies) != 0 || len(unmetReportDependendencies) != 0 {
 			unmetMsg := "The following Report dependencies do not have data currently available for the current reportPeriod being processed:"
-			if len(unstartedDataSourceDependencies) != 0 || len(unmetDataStartDataSourceDependendencies) != 0 || len(unmetDataEndDataSourceDependendencies) != 0 {
+			if len(unstartedDataSourceDependencies) != 0 || len(unmetDataStartDataSourceDependendencies) != 0 || len(unmetDataEndDataSourceDependendencies) != 0 || len(gapDataSourceDependencies) != 0 {
 				var msgs []string
 				if len(unstartedDataSourceDependencies) != 0 {
 					// sort so the message is reproducible
@@ -411,6 +494,11 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 					sort.Strings(unmetDataEndDataSourceDependendencies)
 					msgs = append(msgs, fmt.Sprintf("periodEnd %s is after importDataEndTime of [%s]", reportPeriod.periodEnd, strings.Join(unmetDataEndDataSourceDependendencies, ", ")))
 				}
+				if len(gapDataSourceDependencies) != 0 {
+					// sort so the message is reproducible
+					sort.Strings(gapDataSourceDependencies)
+					msgs = append(msgs, fmt.Sprintf("period [%s to %s] overlaps gaps in the data of [%s]", reportPeriod.periodStart, reportPeriod.periodEnd, strings.Join(gapDataSourceDependencies, ", ")))
+				}
 				unmetMsg += fmt.Sprintf(" ReportDataSources: %s", strings.Join(msgs, ", "))
 			}
 			if len(unmetReportDependendencies) != 0 {
@@ -426,6 +514,7 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 				return nil
 			}
 			logger.Warnf(unmetMsg)
+			op.eventRecorder.Event(report, v1.EventTypeNormal, reportWaitingOnDependenciesEventReason, unmetMsg)
 			_, err := op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportRunning, v1.ConditionFalse, cbutil.ReportingPeriodUnmetDependenciesReason, unmetMsg))
 			return err
 		}
@@ -439,6 +528,7 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 	if err != nil {
 		return err
 	}
+	op.eventRecorder.Eventf(report, v1.EventTypeNormal, reportStartedEventReason, "Generating results for reporting period [%s to %s]", reportPeriod.periodStart, reportPeriod.periodEnd)
 
 	tableName := reportingutil.ReportTableName(report.Namespace, report.Name)
 	// if tableName isn't set, this report is still new and we should make sure
@@ -451,13 +541,19 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 		}
 
 		columns := reportingutil.GenerateHiveColumns(genQuery)
-		err = op.createTableForStorage(logger, report, cbTypes.SchemeGroupVersion.WithKind("Report"), report.Spec.Output, tableName, columns, nil)
+		var partitions []hive.Column
+		partitions, err = reportTablePartitions(report, genQuery)
+		if err != nil {
+			return err
+		}
+		err = op.createTableForStorage(logger, report, cbTypes.SchemeGroupVersion.WithKind("Report"), report.Spec.Output, tableName, columns, partitions)
 		if err != nil {
 			logger.WithError(err).Error("error creating report table for report")
 			return err
 		}
 
 		report.Status.TableName = tableName
+		report.Status.TablePartitionedByPeriod = len(partitions) != 0
 		report, err = op.meteringClient.MeteringV1alpha1().Reports(report.Namespace).Update(report)
 		if err != nil {
 			logger.WithError(err).Errorf("unable to update Report status with tableName")
@@ -480,24 +576,30 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 
 	genReportTotalCounter.Inc()
 	generateReportStart := op.clock.Now()
-	err = op.reportGenerator.GenerateReport(
-		tableName,
-		report.Namespace,
-		&reportPeriod.periodStart,
-		&reportPeriod.periodEnd,
-		genQuery,
-		queryDependencies.DynamicReportGenerationQueries,
-		report.Spec.Inputs,
-		report.Spec.OverwriteExistingData,
-	)
+	rowCount, err := op.storeReportResults(logger, report, genQuery, queryDependencies, reportPeriod.periodStart, reportPeriod.periodEnd, false)
 	generateReportDuration := op.clock.Since(generateReportStart)
 	genReportDurationObserver.Observe(float64(generateReportDuration.Seconds()))
 
+	run := cbTypes.ReportRun{
+		PeriodStart: metav1.Time{Time: reportPeriod.periodStart},
+		PeriodEnd:   metav1.Time{Time: reportPeriod.periodEnd},
+		StartTime:   metav1.Time{Time: generateReportStart},
+		FinishTime:  metav1.Time{Time: generateReportStart.Add(generateReportDuration)},
+		Duration:    metav1.Duration{Duration: generateReportDuration},
+		RowCount:    rowCount,
+		Outcome:     cbTypes.ReportRunSucceeded,
+	}
+
 	if err != nil {
 		genReportFailedCounter.Inc()
+		run.Outcome = cbTypes.ReportRunFailed
+		run.Error = err.Error()
+		cbutil.AddReportRun(&report.Status, run, op.cfg.ReportRunHistoryLimit)
+		op.notifyReportRun(logger, report, genQuery, run)
 		// update the status to Failed with message containing the
 		// error
 		errMsg := fmt.Sprintf("error occurred while generating report: %s", err)
+		op.eventRecorder.Eventf(report, v1.EventTypeWarning, reportFailedEventReason, "Failed to generate results for reporting period [%s to %s]: %s", reportPeriod.periodStart, reportPeriod.periodEnd, err)
 		_, updateErr := op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportRunning, v1.ConditionFalse, cbutil.GenerateReportFailedReason, errMsg))
 		if updateErr != nil {
 			logger.WithError(updateErr).Errorf("unable to update Report status")
@@ -507,9 +609,17 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 	}
 
 	logger.Infof("successfully generated Report %s using query %s and periodStart: %s, periodEnd: %s", report.Name, genQuery.Name, reportPeriod.periodStart, reportPeriod.periodEnd)
+	op.eventRecorder.Eventf(report, v1.EventTypeNormal, reportSucceededEventReason, "Generated %d rows for reporting period [%s to %s]", rowCount, reportPeriod.periodStart, reportPeriod.periodEnd)
 
 	// Update the LastReportTime on the report status
 	report.Status.LastReportTime = &metav1.Time{Time: reportPeriod.periodEnd}
+	cbutil.AddReportRun(&report.Status, run, op.cfg.ReportRunHistoryLimit)
+
+	// the ReportNotifications of Reports with exports are sent once the
+	// exports finish.
+	if len(report.Spec.Exports) == 0 {
+		op.notifyReportRun(logger, report, genQuery, run)
+	}
 
 	// check if we've reached the configured ReportingEnd, and if so, update
 	// the status to indicate the report has finished
@@ -526,7 +636,7 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 			return err
 		}
 
-		nextReportPeriod := getNextReportPeriod(reportSchedule, report.Spec.Schedule.Period, report.Status.LastReportTime.Time)
+		nextReportPeriod := getNextReportPeriod(reportSchedule, report.Spec.Schedule, report.Status.LastReportTime.Time)
 
 		// update the NextReportTime on the report status
 		report.Status.NextReportTime = &metav1.Time{Time: nextReportPeriod.periodEnd}
@@ -550,6 +660,12 @@ func (op *Reporting) runReport(logger log.FieldLogger, report *cbTypes.Report) e
 		return err
 	}
 
+	// exports are queued once the LastReportTime is saved, so a period is
+	// only exported once it won't be generated again.
+	if len(report.Spec.Exports) != 0 {
+		op.queueReportExports(logger, report, run)
+	}
+
 	if err := op.queueDependentReportGenerationQueriesForReport(report); err != nil {
 		logger.WithError(err).Errorf("error queuing ReportGenerationQuery dependents of Report %s", report.Name)
 	}
@@ -570,9 +686,14 @@ func getRunOnceReportPeriod(report *cbTypes.Report) (*reportPeriod, error) {
 	return reportPeriod, nil
 }
 
-func getNextReportPeriod(schedule reportSchedule, period cbTypes.ReportPeriod, lastScheduled time.Time) *reportPeriod {
+func getNextReportPeriod(schedule reportSchedule, reportSched *cbTypes.ReportSchedule, lastScheduled time.Time) *reportPeriod {
 	periodStart := lastScheduled.UTC()
 	periodEnd := schedule.Next(periodStart)
+	// rolling window schedules report on a fixed amount of data ending at
+	// the scheduled time, regardless of when the previous run was.
+	if reportSched.Window != nil {
+		periodStart = periodEnd.Add(-reportSched.Window.Duration)
+	}
 	return &reportPeriod{
 		periodStart: periodStart.Truncate(time.Millisecond).UTC(),
 		periodEnd:   periodEnd.Truncate(time.Millisecond).UTC(),
@@ -667,6 +788,7 @@ func (op *Reporting) setReportStatusInvalidReport(report *cbTypes.Report, msg st
 	}
 
 	logger.Errorf("Report %s failed validation: %s", report.Name, msg)
+	op.eventRecorder.Event(report, v1.EventTypeWarning, invalidReportEventReason, msg)
 	cond := cbutil.NewReportCondition(cbTypes.ReportRunning, v1.ConditionFalse, cbutil.InvalidReportReason, msg)
 	_, err := op.updateReportStatus(report, cond)
 	return err
@@ -7,6 +7,7 @@ import (
 	"github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestGetNextReportPeriod(t *testing.T) {
@@ -84,7 +85,7 @@ func TestGetNextReportPeriod(t *testing.T) {
 			lastScheduled := baseTime
 
 			for _, expectedReportPeriod := range test.expectReportPeriods {
-				reportPeriod := getNextReportPeriod(schedule, test.period, lastScheduled)
+				reportPeriod := getNextReportPeriod(schedule, apiSched, lastScheduled)
 				assert.Equal(t, &expectedReportPeriod, reportPeriod)
 				lastScheduled = expectedReportPeriod.periodEnd
 			}
@@ -92,3 +93,160 @@ func TestGetNextReportPeriod(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNextReportPeriodScheduleOptions(t *testing.T) {
+	tests := map[string]struct {
+		schedule            v1alpha1.ReportSchedule
+		lastScheduled       time.Time
+		expectReportPeriods []reportPeriod
+	}{
+		"daily at local midnight across spring forward": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodDaily,
+				TimeZone: "America/New_York",
+			},
+			lastScheduled: time.Date(2018, time.March, 10, 5, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.March, 10, 5, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.March, 11, 5, 0, 0, 0, time.UTC),
+				},
+				{
+					// the 11th is only 23 hours long
+					periodStart: time.Date(2018, time.March, 11, 5, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.March, 12, 4, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		"daily at a skipped wall clock time moves forward": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodDaily,
+				Daily:    &v1alpha1.ReportScheduleDaily{Hour: 2, Minute: 30},
+				TimeZone: "America/New_York",
+			},
+			lastScheduled: time.Date(2018, time.March, 10, 7, 30, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					// 02:30 doesn't exist on the 11th, so the period ends at 03:30 EDT
+					periodStart: time.Date(2018, time.March, 10, 7, 30, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.March, 11, 7, 30, 0, 0, time.UTC),
+				},
+				{
+					periodStart: time.Date(2018, time.March, 11, 7, 30, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.March, 12, 6, 30, 0, 0, time.UTC),
+				},
+			},
+		},
+		"hourly across fall back": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodHourly,
+				TimeZone: "America/New_York",
+			},
+			lastScheduled: time.Date(2018, time.November, 4, 4, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.November, 4, 4, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.November, 4, 5, 0, 0, 0, time.UTC),
+				},
+				{
+					// 01:00 occurs twice, the repeated hour is part of this period
+					periodStart: time.Date(2018, time.November, 4, 5, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.November, 4, 7, 0, 0, 0, time.UTC),
+				},
+				{
+					periodStart: time.Date(2018, time.November, 4, 7, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.November, 4, 8, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		"monthly": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodMonthly,
+				TimeZone: "Europe/Berlin",
+			},
+			lastScheduled: time.Date(2018, time.February, 28, 23, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.February, 28, 23, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.March, 31, 22, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		"daily trailing 7 day window": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodDaily,
+				TimeZone: "UTC",
+				Window:   &meta.Duration{Duration: 7 * 24 * time.Hour},
+			},
+			lastScheduled: time.Date(2018, time.July, 10, 0, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.July, 4, 0, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.July, 11, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					periodStart: time.Date(2018, time.July, 5, 0, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.July, 12, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		"hourly trailing 30 day window": {
+			schedule: v1alpha1.ReportSchedule{
+				Period: v1alpha1.ReportPeriodHourly,
+				Window: &meta.Duration{Duration: 30 * 24 * time.Hour},
+			},
+			lastScheduled: time.Date(2018, time.July, 31, 0, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.July, 1, 1, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.July, 31, 1, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		"cron": {
+			schedule: v1alpha1.ReportSchedule{
+				Period:   v1alpha1.ReportPeriodCron,
+				Cron:     &v1alpha1.ReportScheduleCron{Expression: "0 9 * * *"},
+				TimeZone: "Asia/Tokyo",
+			},
+			lastScheduled: time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC),
+			expectReportPeriods: []reportPeriod{
+				{
+					periodStart: time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC),
+					periodEnd:   time.Date(2018, time.July, 2, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			schedule, err := getSchedule(&test.schedule)
+			require.NoError(t, err)
+
+			lastScheduled := test.lastScheduled
+			for _, expectedReportPeriod := range test.expectReportPeriods {
+				reportPeriod := getNextReportPeriod(schedule, &test.schedule, lastScheduled)
+				assert.Equal(t, &expectedReportPeriod, reportPeriod)
+				lastScheduled = expectedReportPeriod.periodEnd
+			}
+		})
+	}
+}
+
+func TestGetScheduleInvalidTimeZone(t *testing.T) {
+	_, err := getSchedule(&v1alpha1.ReportSchedule{
+		Period:   v1alpha1.ReportPeriodDaily,
+		TimeZone: "Not/A_Zone",
+	})
+	assert.Error(t, err)
+}
+
+func TestGetScheduleInvalidWindow(t *testing.T) {
+	_, err := getSchedule(&v1alpha1.ReportSchedule{
+		Period: v1alpha1.ReportPeriodDaily,
+		Window: &meta.Duration{Duration: -time.Hour},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,271 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	cbutil "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1/util"
+	"github.com/operator-framework/operator-metering/pkg/hive"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+)
+
+// tableColumnsDiff is the difference between the columns a table has, and
+// the columns it should have.
+type tableColumnsDiff struct {
+	// added are the columns the table doesn't have.
+	added []hive.Column
+	// removed are the columns the table has, but shouldn't.
+	removed []hive.Column
+	// changed are the names of the columns the table has with a different
+	// type.
+	changed []string
+}
+
+func (d tableColumnsDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+func (d tableColumnsDiff) String() string {
+	var msgs []string
+	if len(d.added) != 0 {
+		msgs = append(msgs, fmt.Sprintf("added columns [%s]", columnNames(d.added)))
+	}
+	if len(d.removed) != 0 {
+		msgs = append(msgs, fmt.Sprintf("removed columns [%s]", columnNames(d.removed)))
+	}
+	if len(d.changed) != 0 {
+		msgs = append(msgs, fmt.Sprintf("changed the type of columns [%s]", strings.Join(d.changed, ", ")))
+	}
+	return strings.Join(msgs, ", ")
+}
+
+func columnNames(columns []hive.Column) string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// diffTableColumns compares the existing columns of a table to the desired
+// columns. Hive column names and types are case insensitive.
+func diffTableColumns(existing, desired []hive.Column) tableColumnsDiff {
+	existingTypes := make(map[string]string, len(existing))
+	for _, col := range existing {
+		existingTypes[strings.ToLower(col.Name)] = col.Type
+	}
+	desiredNames := make(map[string]bool, len(desired))
+
+	var diff tableColumnsDiff
+	for _, col := range desired {
+		name := strings.ToLower(col.Name)
+		desiredNames[name] = true
+		existingType, ok := existingTypes[name]
+		switch {
+		case !ok:
+			diff.added = append(diff.added, col)
+		case !strings.EqualFold(existingType, col.Type):
+			diff.changed = append(diff.changed, col.Name)
+		}
+	}
+	for _, col := range existing {
+		if !desiredNames[strings.ToLower(col.Name)] {
+			diff.removed = append(diff.removed, col)
+		}
+	}
+	return diff
+}
+
+// reportTablePartitions returns the partition columns the table of report is
+// created with.
+func reportTablePartitions(report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery) ([]hive.Column, error) {
+	if !report.Spec.PartitionByPeriod {
+		return nil, nil
+	}
+	for _, col := range genQuery.Spec.Columns {
+		if strings.EqualFold(col.Name, reportingutil.PeriodPartitionColumnName) {
+			return nil, fmt.Errorf("cannot partition the table of Report %s by period, ReportGenerationQuery %s has a column named %s", report.Name, genQuery.Name, reportingutil.PeriodPartitionColumnName)
+		}
+	}
+	return reportingutil.PeriodPartitionColumns, nil
+}
+
+// reconcileReportTableSchema updates the table of report when the columns of
+// it's ReportGenerationQuery have changed since the table was created.
+// Columns which were added are added to the table. Other changes are handled
+// according to the Report's spec.tableSchemaChangePolicy. If the Report
+// can't run because of the change, the returned bool is false.
+func (op *Reporting) reconcileReportTableSchema(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery) (*cbTypes.Report, bool, error) {
+	prestoTableName := reportingutil.PrestoTableResourceNameFromKind("Report", report.Namespace, report.Name)
+	// get the PrestoTable from the API rather than the lister, so columns
+	// we just added aren't added again.
+	prestoTable, err := op.meteringClient.MeteringV1alpha1().PrestoTables(report.Namespace).Get(prestoTableName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Warnf("PrestoTable %s does not exist, unable to check if the columns of table %s match ReportGenerationQuery %s", prestoTableName, report.Status.TableName, genQuery.Name)
+			return report, true, nil
+		}
+		return nil, false, err
+	}
+
+	desired := reportingutil.GenerateHiveColumns(genQuery)
+	diff := diffTableColumns(prestoTable.Status.Parameters.Columns, desired)
+	policy := report.Spec.TableSchemaChangePolicy
+	if policy == "" {
+		policy = cbTypes.ReportTableSchemaChangeFail
+	}
+	schemaCond := cbutil.GetReportCondition(report.Status, cbTypes.ReportTableSchemaUpToDate)
+	// columns removed from the ReportGenerationQuery are kept when adding
+	// columns.
+	canAddColumns := len(diff.changed) == 0 && (len(diff.removed) == 0 || policy == cbTypes.ReportTableSchemaChangeAddColumns)
+
+	switch {
+	case canAddColumns && len(diff.added) == 0:
+		// nothing to change, but clear a previous mismatch
+		if schemaCond != nil && schemaCond.Status == v1.ConditionFalse {
+			msg := fmt.Sprintf("Table %s has the columns of ReportGenerationQuery %s.", report.Status.TableName, genQuery.Name)
+			report, err = op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportTableSchemaUpToDate, v1.ConditionTrue, cbutil.TableSchemaMatchesReason, msg))
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		return report, true, nil
+	case canAddColumns:
+		return op.addReportTableColumns(logger, report, genQuery, prestoTable, diff)
+	case policy == cbTypes.ReportTableSchemaChangeRecreate:
+		return op.recreateReportTable(logger, report, genQuery, prestoTable, diff)
+	default:
+		msg := fmt.Sprintf("ReportGenerationQuery %s %s of table %s, which spec.tableSchemaChangePolicy %s doesn't allow.", genQuery.Name, diff, report.Status.TableName, policy)
+		runningCond := cbutil.GetReportCondition(report.Status, cbTypes.ReportRunning)
+		if schemaCond != nil && schemaCond.Status == v1.ConditionFalse && schemaCond.Message == msg && runningCond != nil && runningCond.Reason == cbutil.TableSchemaMismatchReason {
+			logger.Debugf("Report %s already has TableSchemaUpToDate condition=false with an unchanged message, skipping update", report.Name)
+			return report, false, nil
+		}
+		logger.Warnf(msg)
+		op.eventRecorder.Event(report, v1.EventTypeWarning, tableSchemaMismatchEventReason, msg)
+		cbutil.SetReportCondition(&report.Status, *cbutil.NewReportCondition(cbTypes.ReportTableSchemaUpToDate, v1.ConditionFalse, cbutil.TableSchemaMismatchReason, msg))
+		report, err = op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportRunning, v1.ConditionFalse, cbutil.TableSchemaMismatchReason, msg))
+		return report, false, err
+	}
+}
+
+// addReportTableColumns adds the columns of diff which were added to the
+// table of report, and records them in the table's PrestoTable.
+func (op *Reporting) addReportTableColumns(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, prestoTable *cbTypes.PrestoTable, diff tableColumnsDiff) (*cbTypes.Report, bool, error) {
+	tableName := report.Status.TableName
+	logger.Infof("adding columns [%s] to table %s", columnNames(diff.added), tableName)
+	// partitioned tables have the columns of their existing partitions
+	// updated too, so they're readable.
+	cascade := len(prestoTable.Status.Parameters.Partitions) != 0
+	err := op.tableManager.AddColumns(tableName, diff.added, cascade)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to add columns to table %s for Report %s: %v", tableName, report.Name, err)
+	}
+
+	prestoTable = prestoTable.DeepCopy()
+	prestoTable.Status.Parameters.Columns = append(prestoTable.Status.Parameters.Columns, diff.added...)
+	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to update the columns of PrestoTable %s: %v", prestoTable.Name, err)
+	}
+
+	msg := fmt.Sprintf("Added columns [%s] of ReportGenerationQuery %s to table %s.", columnNames(diff.added), genQuery.Name, tableName)
+	if len(diff.removed) != 0 {
+		msg += fmt.Sprintf(" Removed columns [%s] are kept, and are null in new results.", columnNames(diff.removed))
+	}
+	op.eventRecorder.Event(report, v1.EventTypeNormal, tableSchemaUpdatedEventReason, msg)
+	report, err = op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportTableSchemaUpToDate, v1.ConditionTrue, cbutil.ColumnsAddedReason, msg))
+	if err != nil {
+		return nil, false, err
+	}
+	return report, true, nil
+}
+
+// recreateReportTable drops the table of report, and resets the Report's
+// status so the table is created again with the columns of the
+// ReportGenerationQuery, and every reporting period in it is generated again.
+func (op *Reporting) recreateReportTable(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, prestoTable *cbTypes.PrestoTable, diff tableColumnsDiff) (*cbTypes.Report, bool, error) {
+	tableName := report.Status.TableName
+	rerunFrom := op.getReportTableRerunStart(logger, report)
+	storageProperties, err := op.getHiveTableProperties(logger, report.Spec.Output, "Report", report.Namespace)
+	if err != nil {
+		return nil, false, fmt.Errorf("storage incorrectly configured for Report %s: %v", report.Name, err)
+	}
+	tableProperties, err := addTableNameToLocation(*storageProperties, tableName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	logger.Infof("recreating table %s: ReportGenerationQuery %s %s", tableName, genQuery.Name, diff)
+	err = op.tableManager.DropTable(tableName, true)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to drop table %s for Report %s: %v", tableName, report.Name, err)
+	}
+
+	// the PrestoTable is kept, since deleting it would drop the table
+	// we're about to create when finalizers are enabled.
+	prestoTable = prestoTable.DeepCopy()
+	prestoTable.Status.Parameters.Columns = reportingutil.GenerateHiveColumns(genQuery)
+	// the table is created again according to spec.partitionByPeriod
+	prestoTable.Status.Parameters.Partitions, err = reportTablePartitions(report, genQuery)
+	if err != nil {
+		return nil, false, err
+	}
+	// results moved into the table from staging tables changed the
+	// location of it and its partitions, so they're reset to the location
+	// the table is created with.
+	prestoTable.Status.Properties = cbTypes.TableProperties(tableProperties)
+	prestoTable.Status.Partitions = nil
+	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to update the columns of PrestoTable %s: %v", prestoTable.Name, err)
+	}
+
+	msg := fmt.Sprintf("Recreated table %s because ReportGenerationQuery %s %s.", tableName, genQuery.Name, diff)
+	if rerunFrom != nil {
+		msg += fmt.Sprintf(" Generating results again from %s.", rerunFrom.Time)
+	}
+	op.eventRecorder.Event(report, v1.EventTypeNormal, tableSchemaUpdatedEventReason, msg)
+
+	report.Status.TableName = ""
+	report.Status.TablePartitionedByPeriod = false
+	report.Status.LastReportTime = rerunFrom
+	// finished reports run again
+	cbutil.RemoveReportCondition(&report.Status, cbTypes.ReportRunning)
+	report, err = op.updateReportStatus(report, cbutil.NewReportCondition(cbTypes.ReportTableSchemaUpToDate, v1.ConditionTrue, cbutil.TableRecreatedReason, msg))
+	if err != nil {
+		return nil, false, err
+	}
+	return report, true, nil
+}
+
+// getReportTableRerunStart returns what the lastReportTime of report should
+// be to generate all of the reporting periods in it's table again. Reports
+// with a spec.reportingStart start from it, so nil is returned.
+func (op *Reporting) getReportTableRerunStart(logger log.FieldLogger, report *cbTypes.Report) *metav1.Time {
+	if report.Spec.ReportingStart != nil || report.Spec.Schedule == nil {
+		return nil
+	}
+	var earliest *metav1.Time
+	periods, err := op.reportResultsRepo.GetReportResultsPeriods(report.Status.TableName)
+	if err != nil {
+		logger.WithError(err).Warnf("unable to get the reporting periods of table %s, only the periods in the Report's status will be generated again", report.Status.TableName)
+	} else if len(periods) != 0 {
+		earliest = &metav1.Time{Time: periods[0].PeriodStart}
+	}
+	for _, run := range report.Status.Runs {
+		if earliest == nil || run.PeriodStart.Before(earliest) {
+			earliest = &metav1.Time{Time: run.PeriodStart.Time}
+		}
+	}
+	if earliest == nil {
+		return report.Status.LastReportTime
+	}
+	return earliest
+}
@@ -0,0 +1,54 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/operator-framework/operator-metering/pkg/hive"
+)
+
+func TestDiffTableColumns(t *testing.T) {
+	existing := []hive.Column{
+		{Name: "namespace", Type: "string"},
+		{Name: "pod_usage_cpu_core_seconds", Type: "double"},
+	}
+	tests := map[string]struct {
+		desired  []hive.Column
+		expected tableColumnsDiff
+	}{
+		"unchanged": {
+			// hive column names and types are case insensitive
+			desired: []hive.Column{
+				{Name: "Namespace", Type: "STRING"},
+				{Name: "pod_usage_cpu_core_seconds", Type: "double"},
+			},
+		},
+		"added": {
+			desired: append(existing, hive.Column{Name: "node", Type: "string"}),
+			expected: tableColumnsDiff{
+				added: []hive.Column{{Name: "node", Type: "string"}},
+			},
+		},
+		"removed and changed": {
+			desired: []hive.Column{
+				{Name: "pod_usage_cpu_core_seconds", Type: "bigint"},
+			},
+			expected: tableColumnsDiff{
+				removed: []hive.Column{{Name: "namespace", Type: "string"}},
+				changed: []string{"pod_usage_cpu_core_seconds"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			diff := diffTableColumns(existing, tt.desired)
+			assert.Equal(t, tt.expected, diff)
+			assert.Equal(t, tt.expected.empty(), diff.empty())
+		})
+	}
+
+	diff := diffTableColumns(existing, []hive.Column{{Name: "node", Type: "string"}, {Name: "namespace", Type: "int"}})
+	assert.Equal(t, "added columns [node], removed columns [pod_usage_cpu_core_seconds], changed the type of columns [namespace]", diff.String())
+}
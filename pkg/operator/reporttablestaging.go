@@ -0,0 +1,648 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/hive"
+	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+const reportStagingTableSuffixLength = 8
+
+// reportStagingTable is a table the results of a Report, or the rates of a
+// RateCard, are stored in before they're moved into its table, tableName.
+// It's a managed table while it's filled, since Presto only writes to
+// managed tables, and is made external before its location is moved into
+// the Report's table, so dropping it never deletes the Report's results.
+type reportStagingTable struct {
+	name      string
+	tableName string
+	location  string
+	columns   []presto.Column
+	external  bool
+}
+
+// reportStagingCleanup drops a staging table which wasn't moved into its
+// table, first undoing any change to the table's location.
+type reportStagingCleanup struct {
+	staging *reportStagingTable
+	// restoreLocation changes the location of the table, or of its
+	// partition, back from the location of the staging table. It's nil if
+	// the location wasn't changed or has been restored.
+	restoreLocation func() error
+	// keepResults is set if the table reads the results in the staging
+	// table, in which case only the staging table is dropped, and not its
+	// results.
+	keepResults bool
+}
+
+// storeReportResults generates the results of report for the reporting
+// period from periodStart to periodEnd into a staging table, and then moves
+// them into the Report's table. Tables partitioned by period have the
+// partition of the period replaced, spec.overwriteExistingData replaces the
+// whole table, and otherwise the results are added to the table. If
+// replacePeriod is true, existing results for the period in tables which
+// aren't partitioned are replaced too, by staging the rest of the table's
+// rows with the results and replacing the whole table. The Report's table
+// isn't changed until all of the results are stored, and if moving them
+// fails the table is changed back and the staging table is dropped.
+func (op *Reporting) storeReportResults(logger log.FieldLogger, report *cbTypes.Report, genQuery *cbTypes.ReportGenerationQuery, dependencies *reporting.ReportGenerationQueryDependencies, periodStart, periodEnd time.Time, replacePeriod bool) (int64, error) {
+	unlock := op.lockReportTable(report.Status.TableName)
+	defer unlock()
+
+	prestoTable, err := op.getReportPrestoTable(report)
+	if err != nil {
+		return 0, err
+	}
+	err = op.cleanupReportStagingTables(logger, prestoTable)
+	if err != nil {
+		return 0, err
+	}
+	staging, err := op.createReportStagingTable(logger, prestoTable)
+	if err != nil {
+		return 0, err
+	}
+
+	keepRows := replacePeriod && !report.Status.TablePartitionedByPeriod && !report.Spec.OverwriteExistingData
+	var keptRowCount int64
+	if keepRows {
+		keptRowCount, err = op.stageKeptReportResults(staging, prestoTable, reportingutil.GeneratePeriodWhereClause(periodStart, periodEnd))
+		if err != nil {
+			op.dropReportStagingTable(logger, staging)
+			return 0, err
+		}
+	}
+
+	rowCount, err := op.reportGenerator.GenerateReport(
+		staging.name,
+		report.Namespace,
+		&periodStart,
+		&periodEnd,
+		genQuery,
+		dependencies.DynamicReportGenerationQueries,
+		dependencies.ReportDataSources,
+		report.Spec.Inputs,
+	)
+	if err == nil {
+		err = op.validateReportStagingTable(staging, keptRowCount+rowCount)
+	}
+	if err != nil {
+		op.dropReportStagingTable(logger, staging)
+		return 0, err
+	}
+
+	switch {
+	case report.Status.TablePartitionedByPeriod:
+		err = op.moveReportStagingTablePartition(logger, prestoTable, staging, reportingutil.PeriodPartitionSpec(periodStart, periodEnd))
+	case report.Spec.OverwriteExistingData, keepRows:
+		err = op.moveReportStagingTable(logger, prestoTable, staging)
+	default:
+		err = op.loadReportStagingTable(logger, prestoTable, staging)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rowCount, nil
+}
+
+// deleteReportResults deletes the rows of the table of report matching the
+// deleteWhere SQL condition. The Hive connector can only delete entire
+// partitions, so the rest of the rows are copied into a staging table which
+// then replaces the table.
+func (op *Reporting) deleteReportResults(logger log.FieldLogger, report *cbTypes.Report, deleteWhere string) error {
+	unlock := op.lockReportTable(report.Status.TableName)
+	defer unlock()
+
+	prestoTable, err := op.getReportPrestoTable(report)
+	if err != nil {
+		return err
+	}
+	err = op.cleanupReportStagingTables(logger, prestoTable)
+	if err != nil {
+		return err
+	}
+	staging, err := op.createReportStagingTable(logger, prestoTable)
+	if err != nil {
+		return err
+	}
+	keptRowCount, err := op.stageKeptReportResults(staging, prestoTable, deleteWhere)
+	if err == nil {
+		err = op.validateReportStagingTable(staging, keptRowCount)
+	}
+	if err != nil {
+		op.dropReportStagingTable(logger, staging)
+		return err
+	}
+	return op.moveReportStagingTable(logger, prestoTable, staging)
+}
+
+// dropReportTablePartition drops the partition of the table of report
+// matching partitionSpec, deleting its results, and removes it from the
+// table's PrestoTable.
+func (op *Reporting) dropReportTablePartition(logger log.FieldLogger, report *cbTypes.Report, partitionSpec presto.PartitionSpec) error {
+	unlock := op.lockReportTable(report.Status.TableName)
+	defer unlock()
+
+	prestoTable, err := op.getReportPrestoTable(report)
+	if err != nil {
+		return err
+	}
+	err = op.cleanupReportStagingTables(logger, prestoTable)
+	if err != nil {
+		return err
+	}
+	err = op.tableManager.DropTablePartition(prestoTable.Status.Parameters.Name, partitionSpec)
+	if err != nil {
+		return err
+	}
+
+	partitions := make([]cbTypes.TablePartition, 0, len(prestoTable.Status.Partitions))
+	for _, partition := range prestoTable.Status.Partitions {
+		if !reflect.DeepEqual(partition.PartitionSpec, partitionSpec) {
+			partitions = append(partitions, partition)
+		}
+	}
+	if len(partitions) == len(prestoTable.Status.Partitions) {
+		return nil
+	}
+	prestoTable = prestoTable.DeepCopy()
+	prestoTable.Status.Partitions = partitions
+	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
+	if err != nil {
+		return fmt.Errorf("unable to remove the dropped partition from PrestoTable %s: %v", prestoTable.Name, err)
+	}
+	return nil
+}
+
+// lockReportTable serializes changes to the results in tableName, so
+// replacing the table with a staging table never loses results another run
+// added after they were staged. The returned func unlocks the table.
+func (op *Reporting) lockReportTable(tableName string) func() {
+	op.reportTableLocksMu.Lock()
+	if op.reportTableLocks == nil {
+		op.reportTableLocks = make(map[string]*sync.Mutex)
+	}
+	mu, ok := op.reportTableLocks[tableName]
+	if !ok {
+		mu = &sync.Mutex{}
+		op.reportTableLocks[tableName] = mu
+	}
+	op.reportTableLocksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// getReportPrestoTable gets the PrestoTable of the table of report from the
+// API rather than the lister, so the location of the table is up to date.
+func (op *Reporting) getReportPrestoTable(report *cbTypes.Report) (*cbTypes.PrestoTable, error) {
+	return op.getPrestoTable("Report", report.Namespace, report.Name, report.Status.TableName)
+}
+
+// getPrestoTable gets the PrestoTable of tableName, the table of the
+// resource of kind named name, from the API.
+func (op *Reporting) getPrestoTable(kind, namespace, name, tableName string) (*cbTypes.PrestoTable, error) {
+	prestoTableName := reportingutil.PrestoTableResourceNameFromKind(kind, namespace, name)
+	prestoTable, err := op.meteringClient.MeteringV1alpha1().PrestoTables(namespace).Get(prestoTableName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("PrestoTable %s of table %s does not exist, unable to store results", prestoTableName, tableName)
+		}
+		return nil, err
+	}
+	return prestoTable, nil
+}
+
+// createReportStagingTable creates an empty staging table with the columns
+// and storage of the table of prestoTable. Each staging table has a random
+// suffix, so runs of the same Report never use each other's staging tables.
+// Staging tables are stored next to the table they're for, so their
+// location can be moved into it.
+func (op *Reporting) createReportStagingTable(logger log.FieldLogger, prestoTable *cbTypes.PrestoTable) (*reportStagingTable, error) {
+	tableName := prestoTable.Status.Parameters.Name
+	name := reportingutil.ReportStagingTableName(tableName, strings.ToLower(randomString(op.rand, reportStagingTableSuffixLength)))
+	location, err := reportStagingTableLocation(prestoTable.Status.Properties.Location, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create staging table for table %s: %v", tableName, err)
+	}
+
+	params := hive.TableParameters{
+		Name:    name,
+		Columns: prestoTable.Status.Parameters.Columns,
+	}
+	properties := hive.TableProperties(prestoTable.Status.Properties)
+	properties.Location = location
+	properties.External = false
+	err = op.createTable(logger, params, properties)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create staging table %s for table %s: %v", name, tableName, err)
+	}
+
+	columns := make([]presto.Column, len(params.Columns))
+	for i, col := range params.Columns {
+		columns[i] = presto.Column{Name: col.Name}
+	}
+	return &reportStagingTable{name: name, tableName: tableName, location: location, columns: columns}, nil
+}
+
+// reportStagingTableLocation returns the location of the staging table
+// stagingTableName, which is next to tableLocation.
+func reportStagingTableLocation(tableLocation, stagingTableName string) (string, error) {
+	if tableLocation == "" {
+		return "", errors.New("table has no location")
+	}
+	u, err := url.Parse(tableLocation)
+	if err != nil {
+		return "", err
+	}
+	if !path.IsAbs(u.Path) {
+		return "", fmt.Errorf("table location %s is not an absolute path", tableLocation)
+	}
+	u.Path = path.Join(path.Dir(u.Path), stagingTableName)
+	return u.String(), nil
+}
+
+// stageKeptReportResults copies the rows of the table of prestoTable which
+// don't match the deleteWhere SQL condition into staging, and returns the
+// number of rows copied.
+func (op *Reporting) stageKeptReportResults(staging *reportStagingTable, prestoTable *cbTypes.PrestoTable, deleteWhere string) (int64, error) {
+	tableName := prestoTable.Status.Parameters.Name
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE NOT coalesce(%s, false)", presto.GenerateQuotedColumnsListSQL(staging.columns), tableName, deleteWhere)
+	rowCount, err := op.reportResultsRepo.StoreReportResults(staging.name, staging.columns, query)
+	if err != nil {
+		return 0, fmt.Errorf("unable to copy the results to keep from table %s into staging table %s: %v", tableName, staging.name, err)
+	}
+	return rowCount, nil
+}
+
+// validateReportStagingTable checks staging has the number of rows which
+// were stored in it.
+func (op *Reporting) validateReportStagingTable(staging *reportStagingTable, expectedRowCount int64) error {
+	rowCount, err := op.reportResultsRepo.CountReportResults(staging.name)
+	if err != nil {
+		return fmt.Errorf("unable to validate staging table %s: %v", staging.name, err)
+	}
+	if rowCount != expectedRowCount {
+		return fmt.Errorf("staging table %s has %d rows, expected %d", staging.name, rowCount, expectedRowCount)
+	}
+	return nil
+}
+
+// moveReportStagingTable replaces the results in the table of prestoTable
+// with staging by changing the table's location to the location of staging.
+// The previous results are deleted afterwards.
+func (op *Reporting) moveReportStagingTable(logger log.FieldLogger, prestoTable *cbTypes.PrestoTable, staging *reportStagingTable) error {
+	tableName := prestoTable.Status.Parameters.Name
+	cleanup := &reportStagingCleanup{staging: staging}
+	// the table may be external even if making it external fails
+	staging.external = true
+	err := op.tableManager.SetTableExternal(staging.name, true)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, err)
+	}
+
+	replacedLocation := prestoTable.Status.Properties.Location
+	cleanup.restoreLocation = func() error {
+		return op.tableManager.SetTableLocation(tableName, replacedLocation)
+	}
+	err = op.tableManager.SetTableLocation(tableName, staging.location)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, err)
+	}
+
+	prestoTable = prestoTable.DeepCopy()
+	prestoTable.Status.Properties.Location = staging.location
+	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, fmt.Errorf("unable to record the location %s of the table in PrestoTable %s: %v", staging.location, prestoTable.Name, err))
+	}
+	op.dropReplacedReportResults(logger, staging, replacedLocation)
+	return nil
+}
+
+// moveReportStagingTablePartition replaces the partition of the table of
+// prestoTable matching partitionSpec with staging by changing the
+// partition's location to the location of staging. The partition is added
+// if it doesn't exist. The previous results of the partition are deleted
+// afterwards.
+func (op *Reporting) moveReportStagingTablePartition(logger log.FieldLogger, prestoTable *cbTypes.PrestoTable, staging *reportStagingTable, partitionSpec presto.PartitionSpec) error {
+	tableName := prestoTable.Status.Parameters.Name
+	cleanup := &reportStagingCleanup{staging: staging}
+	// the table may be external even if making it external fails
+	staging.external = true
+	err := op.tableManager.SetTableExternal(staging.name, true)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, err)
+	}
+
+	prestoTable = prestoTable.DeepCopy()
+	var replacedLocation string
+	prestoTable.Status.Partitions, replacedLocation = setTablePartitionLocation(prestoTable.Status.Partitions, partitionSpec, staging.location)
+	if replacedLocation == "" {
+		// partitions written by Presto are stored in the table's location
+		replacedLocation = tablePartitionDefaultLocation(prestoTable.Status.Properties.Location, partitionSpec)
+	}
+	cleanup.restoreLocation = func() error {
+		return op.tableManager.SetPartitionLocation(tableName, partitionSpec, replacedLocation)
+	}
+	err = op.tableManager.SetPartitionLocation(tableName, partitionSpec, staging.location)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, err)
+	}
+
+	_, err = op.meteringClient.MeteringV1alpha1().PrestoTables(prestoTable.Namespace).Update(prestoTable)
+	if err != nil {
+		return op.abortReportStagingTable(cleanup, fmt.Errorf("unable to record the location %s of the partition in PrestoTable %s: %v", staging.location, prestoTable.Name, err))
+	}
+	op.dropReplacedReportResults(logger, staging, replacedLocation)
+	return nil
+}
+
+// loadReportStagingTable adds the results in staging to the table of
+// prestoTable by moving the files of staging into the table's location, and
+// drops the emptied staging table.
+func (op *Reporting) loadReportStagingTable(logger log.FieldLogger, prestoTable *cbTypes.PrestoTable, staging *reportStagingTable) error {
+	err := op.tableManager.LoadTableData(prestoTable.Status.Parameters.Name, staging.location)
+	if err != nil {
+		return op.abortReportStagingTable(&reportStagingCleanup{staging: staging}, err)
+	}
+	op.dropReportStagingTable(logger, staging)
+	return nil
+}
+
+// abortReportStagingTable undoes the failed move of a staging table into its
+// table with cleanup, and returns err, the reason the move failed. If the
+// move can't be undone, it's retried before the table is next changed.
+func (op *Reporting) abortReportStagingTable(cleanup *reportStagingCleanup, err error) error {
+	staging := cleanup.staging
+	cleanupErr := op.cleanupReportStagingTable(cleanup)
+	if cleanupErr != nil {
+		op.addReportStagingCleanup(cleanup)
+		return fmt.Errorf("unable to move the results in staging table %s into table %s: %v, and unable to undo the move, which will be retried before the table is next changed: %v", staging.name, staging.tableName, err, cleanupErr)
+	}
+	return fmt.Errorf("unable to move the results in staging table %s into table %s, the table was left unchanged: %v", staging.name, staging.tableName, err)
+}
+
+// cleanupReportStagingTable restores the location of the table of the
+// staging table of cleanup if it was changed, and drops the staging table.
+func (op *Reporting) cleanupReportStagingTable(cleanup *reportStagingCleanup) error {
+	staging := cleanup.staging
+	if cleanup.restoreLocation != nil {
+		err := cleanup.restoreLocation()
+		if err != nil {
+			return fmt.Errorf("unable to restore the location of table %s: %v", staging.tableName, err)
+		}
+		cleanup.restoreLocation = nil
+	}
+	// dropping a managed table deletes its results, so it's only managed if
+	// they aren't kept
+	if staging.external != cleanup.keepResults {
+		err := op.tableManager.SetTableExternal(staging.name, cleanup.keepResults)
+		if err != nil {
+			return fmt.Errorf("unable to drop staging table %s: %v", staging.name, err)
+		}
+		staging.external = cleanup.keepResults
+	}
+	err := op.tableManager.DropTable(staging.name, true)
+	if err != nil {
+		return fmt.Errorf("unable to drop staging table %s: %v", staging.name, err)
+	}
+	return nil
+}
+
+// addReportStagingCleanup records cleanup to be retried before the table of
+// its staging table is next changed.
+func (op *Reporting) addReportStagingCleanup(cleanup *reportStagingCleanup) {
+	op.reportTableLocksMu.Lock()
+	defer op.reportTableLocksMu.Unlock()
+	if op.reportStagingCleanups == nil {
+		op.reportStagingCleanups = make(map[string][]*reportStagingCleanup)
+	}
+	tableName := cleanup.staging.tableName
+	op.reportStagingCleanups[tableName] = append(op.reportStagingCleanups[tableName], cleanup)
+}
+
+// cleanupReportStagingTables drops the staging tables of the table of
+// prestoTable left behind by failed runs, and must be called with the table
+// locked before it's changed. The cleanups recorded by failed runs of this
+// process are retried, and an error is returned if a location changed by a
+// failed run still can't be restored. The first time it's called for a
+// table, the staging tables of runs interrupted before they could be
+// cleaned up, such as by a restart, are dropped too. The table, and its
+// partitions, are first set back to the locations recorded in prestoTable,
+// which may have been changed by an interrupted run.
+func (op *Reporting) cleanupReportStagingTables(logger log.FieldLogger, prestoTable *cbTypes.PrestoTable) error {
+	tableName := prestoTable.Status.Parameters.Name
+	op.reportTableLocksMu.Lock()
+	cleanups := op.reportStagingCleanups[tableName]
+	delete(op.reportStagingCleanups, tableName)
+	checked := op.reportStagingTablesChecked[tableName]
+	op.reportTableLocksMu.Unlock()
+
+	var restoreErr error
+	for _, cleanup := range cleanups {
+		restoring := cleanup.restoreLocation != nil
+		err := op.cleanupReportStagingTable(cleanup)
+		if err == nil {
+			continue
+		}
+		op.addReportStagingCleanup(cleanup)
+		if restoring && restoreErr == nil {
+			restoreErr = fmt.Errorf("unable to undo the failed move of staging table %s into table %s: %v", cleanup.staging.name, tableName, err)
+		} else {
+			logger.WithError(err).Warnf("unable to drop staging table %s of table %s", cleanup.staging.name, tableName)
+		}
+	}
+	if restoreErr != nil {
+		return restoreErr
+	}
+	if checked {
+		return nil
+	}
+
+	tableNames, err := op.prestoSchemaLister.ListTables()
+	if err != nil {
+		return fmt.Errorf("unable to list the staging tables of table %s: %v", tableName, err)
+	}
+	restored := false
+	for _, name := range tableNames {
+		if !isReportStagingTable(tableName, name) {
+			continue
+		}
+		location, err := reportStagingTableLocation(prestoTable.Status.Properties.Location, name)
+		if err != nil {
+			return fmt.Errorf("unable to drop staging table %s of table %s: %v", name, tableName, err)
+		}
+		inUse := reportTableUsesLocation(prestoTable, location)
+		if !inUse && !restored {
+			err = op.restoreReportTableLocations(prestoTable)
+			if err != nil {
+				return err
+			}
+			restored = true
+		}
+		// whether the staging table is external isn't known, so it's always
+		// changed to match whether its results are kept
+		cleanup := &reportStagingCleanup{
+			staging:     &reportStagingTable{name: name, tableName: tableName, location: location, external: !inUse},
+			keepResults: inUse,
+		}
+		err = op.cleanupReportStagingTable(cleanup)
+		if err != nil {
+			logger.WithError(err).Warnf("unable to drop staging table %s of table %s", name, tableName)
+			op.addReportStagingCleanup(cleanup)
+		}
+	}
+
+	op.reportTableLocksMu.Lock()
+	if op.reportStagingTablesChecked == nil {
+		op.reportStagingTablesChecked = make(map[string]bool)
+	}
+	op.reportStagingTablesChecked[tableName] = true
+	op.reportTableLocksMu.Unlock()
+	return nil
+}
+
+// restoreReportTableLocations sets the location of the table of
+// prestoTable, and of its partitions, to the locations recorded in
+// prestoTable. Partitions added by an interrupted run aren't recorded and
+// are left unchanged, since the period they're for hasn't been stored and
+// is generated again.
+func (op *Reporting) restoreReportTableLocations(prestoTable *cbTypes.PrestoTable) error {
+	tableName := prestoTable.Status.Parameters.Name
+	err := op.tableManager.SetTableLocation(tableName, prestoTable.Status.Properties.Location)
+	if err != nil {
+		return fmt.Errorf("unable to restore the location of table %s: %v", tableName, err)
+	}
+	for _, partition := range prestoTable.Status.Partitions {
+		if partition.Location == "" {
+			continue
+		}
+		err = op.tableManager.SetPartitionLocation(tableName, partition.PartitionSpec, partition.Location)
+		if err != nil {
+			return fmt.Errorf("unable to restore the location of partition %v of table %s: %v", partition.PartitionSpec, tableName, err)
+		}
+	}
+	return nil
+}
+
+// isReportStagingTable returns true if name is the name of a staging table
+// of tableName.
+func isReportStagingTable(tableName, name string) bool {
+	prefix := reportingutil.ReportStagingTableName(tableName, "")
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	suffix := strings.TrimPrefix(name, prefix)
+	return len(suffix) == reportStagingTableSuffixLength && !strings.Contains(suffix, "_")
+}
+
+// reportTableUsesLocation returns true if location is the location of the
+// table of prestoTable, or of one of its partitions.
+func reportTableUsesLocation(prestoTable *cbTypes.PrestoTable, location string) bool {
+	if prestoTable.Status.Properties.Location == location {
+		return true
+	}
+	for _, partition := range prestoTable.Status.Partitions {
+		if partition.Location == location {
+			return true
+		}
+	}
+	return false
+}
+
+// dropReportStagingTable drops staging, deleting the results in it unless it
+// was made external. If it can't be dropped, it's retried before its table
+// is next changed.
+func (op *Reporting) dropReportStagingTable(logger log.FieldLogger, staging *reportStagingTable) {
+	cleanup := &reportStagingCleanup{staging: staging, keepResults: staging.external}
+	err := op.cleanupReportStagingTable(cleanup)
+	if err != nil {
+		logger.WithError(err).Warnf("unable to drop staging table %s, it will be dropped before table %s is next changed", staging.name, staging.tableName)
+		op.addReportStagingCleanup(cleanup)
+	}
+}
+
+// dropReplacedReportResults deletes the results at replacedLocation which
+// were replaced by staging, by moving staging to replacedLocation, making it
+// a managed table again, and dropping it. If staging can't be moved, it's
+// dropped without deleting any results, since the table reads them.
+func (op *Reporting) dropReplacedReportResults(logger log.FieldLogger, staging *reportStagingTable, replacedLocation string) {
+	cleanup := &reportStagingCleanup{staging: staging, keepResults: true}
+	if replacedLocation != "" && replacedLocation != staging.location {
+		err := op.tableManager.SetTableLocation(staging.name, replacedLocation)
+		if err != nil {
+			logger.WithError(err).Warnf("unable to delete the replaced results at %s", replacedLocation)
+		} else {
+			staging.location = replacedLocation
+			cleanup.keepResults = false
+		}
+	}
+	err := op.cleanupReportStagingTable(cleanup)
+	if err != nil {
+		logger.WithError(err).Warnf("unable to drop staging table %s, it will be dropped before table %s is next changed", staging.name, staging.tableName)
+		op.addReportStagingCleanup(cleanup)
+	}
+}
+
+// setTablePartitionLocation sets the location of the partition matching
+// partitionSpec in partitions, adding the partition if it's not in
+// partitions. The partition's previous location is returned, or an empty
+// string if it was added.
+func setTablePartitionLocation(partitions []cbTypes.TablePartition, partitionSpec presto.PartitionSpec, location string) ([]cbTypes.TablePartition, string) {
+	updated := make([]cbTypes.TablePartition, 0, len(partitions)+1)
+	var previousLocation string
+	found := false
+	for _, partition := range partitions {
+		if reflect.DeepEqual(partition.PartitionSpec, partitionSpec) {
+			previousLocation = partition.Location
+			partition.Location = location
+			found = true
+		}
+		updated = append(updated, partition)
+	}
+	if !found {
+		updated = append(updated, cbTypes.TablePartition{Location: location, PartitionSpec: partitionSpec})
+	}
+	return updated, previousLocation
+}
+
+// tablePartitionDefaultLocation returns the location of the partition
+// matching partitionSpec in a table at tableLocation, if the partition was
+// added without a location. An empty string is returned if the table has no
+// location.
+func tablePartitionDefaultLocation(tableLocation string, partitionSpec presto.PartitionSpec) string {
+	if tableLocation == "" {
+		return ""
+	}
+	u, err := url.Parse(tableLocation)
+	if err != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(partitionSpec))
+	for key := range partitionSpec {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	elems := []string{u.Path}
+	for _, key := range keys {
+		elems = append(elems, fmt.Sprintf("%s=%s", key, partitionSpec[key]))
+	}
+	u.Path = path.Join(elems...)
+	return u.String()
+}
@@ -0,0 +1,434 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/generated/clientset/versioned/fake"
+	"github.com/operator-framework/operator-metering/pkg/hive"
+	mockprestostore "github.com/operator-framework/operator-metering/pkg/operator/prestostore/mock"
+	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+func TestReportStagingTableLocation(t *testing.T) {
+	tests := map[string]struct {
+		tableLocation string
+		expected      string
+		expectedErr   string
+	}{
+		"hdfs": {
+			tableLocation: "hdfs://hdfs-namenode-proxy:9820/operator_metering/storage/report_metering_test",
+			expected:      "hdfs://hdfs-namenode-proxy:9820/operator_metering/storage/staging_report_metering_test_abcd1234",
+		},
+		"previously staged": {
+			tableLocation: "hdfs://hdfs-namenode-proxy:9820/operator_metering/storage/staging_report_metering_test_efgh5678",
+			expected:      "hdfs://hdfs-namenode-proxy:9820/operator_metering/storage/staging_report_metering_test_abcd1234",
+		},
+		"s3 bucket root": {
+			tableLocation: "s3a://bucket/report_metering_test",
+			expected:      "s3a://bucket/staging_report_metering_test_abcd1234",
+		},
+		"no location": {
+			expectedErr: "table has no location",
+		},
+		"relative location": {
+			tableLocation: "report_metering_test",
+			expectedErr:   "table location report_metering_test is not an absolute path",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			location, err := reportStagingTableLocation(tt.tableLocation, "staging_report_metering_test_abcd1234")
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, location)
+		})
+	}
+}
+
+func TestSetTablePartitionLocation(t *testing.T) {
+	january := presto.PartitionSpec{"report_period": "20190101T000000Z-20190201T000000Z"}
+	february := presto.PartitionSpec{"report_period": "20190201T000000Z-20190301T000000Z"}
+	partitions := []cbTypes.TablePartition{
+		{Location: "hdfs://namenode/storage/staging_report_a", PartitionSpec: january},
+	}
+
+	updated, previous := setTablePartitionLocation(partitions, january, "hdfs://namenode/storage/staging_report_b")
+	assert.Equal(t, "hdfs://namenode/storage/staging_report_a", previous)
+	assert.Equal(t, []cbTypes.TablePartition{
+		{Location: "hdfs://namenode/storage/staging_report_b", PartitionSpec: january},
+	}, updated)
+	// the existing partitions aren't modified
+	assert.Equal(t, "hdfs://namenode/storage/staging_report_a", partitions[0].Location)
+
+	updated, previous = setTablePartitionLocation(partitions, february, "hdfs://namenode/storage/staging_report_c")
+	assert.Equal(t, "", previous)
+	assert.Equal(t, []cbTypes.TablePartition{
+		{Location: "hdfs://namenode/storage/staging_report_a", PartitionSpec: january},
+		{Location: "hdfs://namenode/storage/staging_report_c", PartitionSpec: february},
+	}, updated)
+}
+
+func TestTablePartitionDefaultLocation(t *testing.T) {
+	spec := presto.PartitionSpec{"report_period": "20190101T000000Z-20190201T000000Z"}
+	assert.Equal(t, "hdfs://namenode:9820/storage/report_metering_test/report_period=20190101T000000Z-20190201T000000Z", tablePartitionDefaultLocation("hdfs://namenode:9820/storage/report_metering_test", spec))
+	assert.Equal(t, "", tablePartitionDefaultLocation("", spec))
+}
+
+func TestIsReportStagingTable(t *testing.T) {
+	assert.True(t, isReportStagingTable("report_default_test", "staging_report_default_test_abcd1234"))
+	assert.False(t, isReportStagingTable("report_default_test", "report_default_test"))
+	assert.False(t, isReportStagingTable("report_default_test", "staging_report_default_test_other_abcd1234"), "expected the staging tables of report_default_test_other to be ignored")
+	assert.False(t, isReportStagingTable("report_default_test", "staging_report_default_test_abc"))
+}
+
+// fakeTableManager records the changes made to tables, and fails the
+// changes in failures the number of times they're mapped to.
+type fakeTableManager struct {
+	calls    []string
+	failures map[string]int
+}
+
+func (m *fakeTableManager) call(format string, args ...interface{}) error {
+	call := fmt.Sprintf(format, args...)
+	m.calls = append(m.calls, call)
+	if m.failures[call] > 0 {
+		m.failures[call]--
+		return errors.New("failed to " + call)
+	}
+	return nil
+}
+
+func (m *fakeTableManager) CreateTable(params hive.TableParameters, properties hive.TableProperties) error {
+	return m.call("CreateTable %s %s", params.Name, properties.Location)
+}
+
+func (m *fakeTableManager) DropTable(tableName string, ignoreNotExists bool) error {
+	return m.call("DropTable %s", tableName)
+}
+
+func (m *fakeTableManager) DropTablePartition(tableName string, partitionSpec presto.PartitionSpec) error {
+	return m.call("DropTablePartition %s %v", tableName, partitionSpec)
+}
+
+func (m *fakeTableManager) AddColumns(tableName string, columns []hive.Column, cascade bool) error {
+	return m.call("AddColumns %s", tableName)
+}
+
+func (m *fakeTableManager) SetTableLocation(tableName, location string) error {
+	return m.call("SetTableLocation %s %s", tableName, location)
+}
+
+func (m *fakeTableManager) SetPartitionLocation(tableName string, partitionSpec presto.PartitionSpec, location string) error {
+	return m.call("SetPartitionLocation %s %v %s", tableName, partitionSpec, location)
+}
+
+func (m *fakeTableManager) SetTableExternal(tableName string, external bool) error {
+	return m.call("SetTableExternal %s %t", tableName, external)
+}
+
+func (m *fakeTableManager) LoadTableData(tableName, location string) error {
+	return m.call("LoadTableData %s %s", tableName, location)
+}
+
+type fakeReportGenerator struct {
+	rowCount int64
+	err      error
+}
+
+func (g *fakeReportGenerator) GenerateReport(tableName, namespace string, reportStart, reportEnd *time.Time, generationQuery *cbTypes.ReportGenerationQuery, dynamicReportGenerationQueries []*cbTypes.ReportGenerationQuery, dataSources []*cbTypes.ReportDataSource, inputs []cbTypes.ReportGenerationQueryInputValue) (int64, error) {
+	return g.rowCount, g.err
+}
+
+type fakePrestoSchemaLister struct {
+	tables []string
+	calls  int
+}
+
+func (l *fakePrestoSchemaLister) ListTables() ([]string, error) {
+	l.calls++
+	return l.tables, nil
+}
+
+const (
+	testReportTableName     = "report_default_test"
+	testReportTableLocation = "hdfs://namenode:9820/storage/report_default_test"
+)
+
+func newTestReportTableReport(partitioned, overwrite bool) *cbTypes.Report {
+	return &cbTypes.Report{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       cbTypes.ReportSpec{OverwriteExistingData: overwrite},
+		Status: cbTypes.ReportStatus{
+			TableName:                testReportTableName,
+			TablePartitionedByPeriod: partitioned,
+		},
+	}
+}
+
+func newTestReportTablePrestoTable(location string) *cbTypes.PrestoTable {
+	return &cbTypes.PrestoTable{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reportingutil.PrestoTableResourceNameFromKind("Report", "default", "test"),
+			Namespace: "default",
+		},
+		Status: cbTypes.PrestoTableStatus{
+			Parameters: cbTypes.TableParameters{
+				Name:    testReportTableName,
+				Columns: []hive.Column{{Name: "namespace", Type: "string"}},
+			},
+			Properties: cbTypes.TableProperties{Location: location},
+		},
+	}
+}
+
+// newTestReportStagingOperator returns a Reporting storing the results of
+// Reports with tm, and the names of the staging tables it creates.
+func newTestReportStagingOperator(t *testing.T, prestoTable *cbTypes.PrestoTable, tm *fakeTableManager, generator reporting.ReportGenerator, rowCount int64) (*Reporting, *fake.Clientset, func() string) {
+	ctrl := gomock.NewController(t)
+	repo := mockprestostore.NewMockReportResultsRepo(ctrl)
+	repo.EXPECT().CountReportResults(gomock.Any()).Return(rowCount, nil).AnyTimes()
+
+	client := fake.NewSimpleClientset(prestoTable)
+	op := &Reporting{
+		rand:               rand.New(rand.NewSource(0)),
+		meteringClient:     client,
+		reportResultsRepo:  repo,
+		reportGenerator:    generator,
+		prestoSchemaLister: &fakePrestoSchemaLister{},
+		tableManager:       tm,
+	}
+	stagingRand := rand.New(rand.NewSource(0))
+	nextStagingTable := func() string {
+		return reportingutil.ReportStagingTableName(testReportTableName, strings.ToLower(randomString(stagingRand, reportStagingTableSuffixLength)))
+	}
+	return op, client, nextStagingTable
+}
+
+func TestStoreReportResults(t *testing.T) {
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	spec := reportingutil.PeriodPartitionSpec(periodStart, periodEnd)
+	partitionLocation := tablePartitionDefaultLocation(testReportTableLocation, spec)
+	staging := reportingutil.ReportStagingTableName(testReportTableName, strings.ToLower(randomString(rand.New(rand.NewSource(0)), reportStagingTableSuffixLength)))
+	stagingLocation := "hdfs://namenode:9820/storage/" + staging
+
+	tests := map[string]struct {
+		partitioned bool
+		overwrite   bool
+		generateErr error
+		failures    map[string]int
+		failUpdate  bool
+
+		expectedCalls      []string
+		expectedErr        string
+		expectedLocation   string
+		expectedPartitions []cbTypes.TablePartition
+	}{
+		"results are loaded into the table": {
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"LoadTableData " + testReportTableName + " " + stagingLocation,
+				"DropTable " + staging,
+			},
+			expectedLocation: testReportTableLocation,
+		},
+		"the partition of the period is replaced": {
+			partitioned: true,
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"SetTableExternal " + staging + " true",
+				fmt.Sprintf("SetPartitionLocation %s %v %s", testReportTableName, spec, stagingLocation),
+				"SetTableLocation " + staging + " " + partitionLocation,
+				"SetTableExternal " + staging + " false",
+				"DropTable " + staging,
+			},
+			expectedLocation:   testReportTableLocation,
+			expectedPartitions: []cbTypes.TablePartition{{Location: stagingLocation, PartitionSpec: spec}},
+		},
+		"the table is replaced": {
+			overwrite: true,
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"SetTableExternal " + staging + " true",
+				"SetTableLocation " + testReportTableName + " " + stagingLocation,
+				"SetTableLocation " + staging + " " + testReportTableLocation,
+				"SetTableExternal " + staging + " false",
+				"DropTable " + staging,
+			},
+			expectedLocation: stagingLocation,
+		},
+		"generating the results fails": {
+			generateErr: errors.New("query failed"),
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"DropTable " + staging,
+			},
+			expectedErr:      "query failed",
+			expectedLocation: testReportTableLocation,
+		},
+		"loading the results fails": {
+			failures: map[string]int{"LoadTableData " + testReportTableName + " " + stagingLocation: 1},
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"LoadTableData " + testReportTableName + " " + stagingLocation,
+				"DropTable " + staging,
+			},
+			expectedErr:      "the table was left unchanged",
+			expectedLocation: testReportTableLocation,
+		},
+		"changing the location of the table fails": {
+			overwrite: true,
+			failures:  map[string]int{"SetTableLocation " + testReportTableName + " " + stagingLocation: 1},
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"SetTableExternal " + staging + " true",
+				"SetTableLocation " + testReportTableName + " " + stagingLocation,
+				"SetTableLocation " + testReportTableName + " " + testReportTableLocation,
+				"SetTableExternal " + staging + " false",
+				"DropTable " + staging,
+			},
+			expectedErr:      "the table was left unchanged",
+			expectedLocation: testReportTableLocation,
+		},
+		"recording the location of the partition fails": {
+			partitioned: true,
+			failUpdate:  true,
+			expectedCalls: []string{
+				"CreateTable " + staging + " " + stagingLocation,
+				"SetTableExternal " + staging + " true",
+				fmt.Sprintf("SetPartitionLocation %s %v %s", testReportTableName, spec, stagingLocation),
+				fmt.Sprintf("SetPartitionLocation %s %v %s", testReportTableName, spec, partitionLocation),
+				"SetTableExternal " + staging + " false",
+				"DropTable " + staging,
+			},
+			expectedErr:      "the table was left unchanged",
+			expectedLocation: testReportTableLocation,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tm := &fakeTableManager{failures: tt.failures}
+			op, client, _ := newTestReportStagingOperator(t, newTestReportTablePrestoTable(testReportTableLocation), tm, &fakeReportGenerator{rowCount: 5, err: tt.generateErr}, 5)
+			if tt.failUpdate {
+				client.PrependReactor("update", "prestotables", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("update failed")
+				})
+			}
+
+			report := newTestReportTableReport(tt.partitioned, tt.overwrite)
+			rowCount, err := op.storeReportResults(logrus.New(), report, &cbTypes.ReportGenerationQuery{}, &reporting.ReportGenerationQueryDependencies{}, periodStart, periodEnd, false)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, int64(5), rowCount)
+			}
+			assert.Equal(t, tt.expectedCalls, tm.calls)
+			assert.Empty(t, op.reportStagingCleanups, "expected the staging table to be dropped")
+
+			prestoTable, err := op.getReportPrestoTable(report)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedLocation, prestoTable.Status.Properties.Location)
+			assert.Equal(t, tt.expectedPartitions, prestoTable.Status.Partitions)
+		})
+	}
+}
+
+func TestStoreReportResultsRetriesRestoringLocation(t *testing.T) {
+	periodStart := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	report := newTestReportTableReport(false, true)
+	tm := &fakeTableManager{}
+	op, _, nextStagingTable := newTestReportStagingOperator(t, newTestReportTablePrestoTable(testReportTableLocation), tm, &fakeReportGenerator{rowCount: 5}, 5)
+	staging := nextStagingTable()
+	stagingLocation := "hdfs://namenode:9820/storage/" + staging
+	restoreCall := "SetTableLocation " + testReportTableName + " " + testReportTableLocation
+	tm.failures = map[string]int{
+		"SetTableLocation " + testReportTableName + " " + stagingLocation: 1,
+		restoreCall: 2,
+	}
+
+	store := func() error {
+		_, err := op.storeReportResults(logrus.New(), report, &cbTypes.ReportGenerationQuery{}, &reporting.ReportGenerationQueryDependencies{}, periodStart, periodEnd, false)
+		return err
+	}
+
+	err := store()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "will be retried before the table is next changed")
+	assert.Equal(t, restoreCall, tm.calls[len(tm.calls)-1], "expected the staging table to be kept while the table reads it")
+
+	// the table can't be changed until its location is restored
+	tm.calls = nil
+	err = store()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to undo the failed move of staging table "+staging)
+	assert.Equal(t, []string{restoreCall}, tm.calls)
+
+	tm.calls = nil
+	require.NoError(t, store())
+	assert.Equal(t, []string{
+		restoreCall,
+		"SetTableExternal " + staging + " false",
+		"DropTable " + staging,
+	}, tm.calls[:3])
+	nextStaging := nextStagingTable()
+	assert.Equal(t, "CreateTable "+nextStaging+" hdfs://namenode:9820/storage/"+nextStaging, tm.calls[3])
+	assert.Empty(t, op.reportStagingCleanups)
+}
+
+func TestCleanupReportStagingTablesLeftBehind(t *testing.T) {
+	inUse := "staging_report_default_test_inuse123"
+	unused := "staging_report_default_test_unused12"
+	inUseLocation := "hdfs://namenode:9820/storage/" + inUse
+	prestoTable := newTestReportTablePrestoTable(inUseLocation)
+	tm := &fakeTableManager{}
+	op, _, _ := newTestReportStagingOperator(t, prestoTable, tm, &fakeReportGenerator{}, 0)
+	lister := &fakePrestoSchemaLister{tables: []string{
+		testReportTableName,
+		inUse,
+		unused,
+		"staging_report_default_test_other_abcd1234",
+		"staging_report_default_other_abcd1234",
+	}}
+	op.prestoSchemaLister = lister
+
+	require.NoError(t, op.cleanupReportStagingTables(logrus.New(), prestoTable))
+	assert.Equal(t, []string{
+		// the table reads the results of inUse, so they're kept
+		"SetTableExternal " + inUse + " true",
+		"DropTable " + inUse,
+		// the table may have been left reading the results of unused, so
+		// its location is restored before they're deleted
+		"SetTableLocation " + testReportTableName + " " + inUseLocation,
+		"SetTableExternal " + unused + " false",
+		"DropTable " + unused,
+	}, tm.calls)
+
+	tm.calls = nil
+	require.NoError(t, op.cleanupReportStagingTables(logrus.New(), prestoTable))
+	assert.Empty(t, tm.calls)
+	assert.Equal(t, 1, lister.calls, "expected the staging tables of a table to only be listed once")
+}
@@ -0,0 +1,258 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+	"github.com/operator-framework/operator-metering/pkg/presto"
+)
+
+var (
+	retentionSweepPartitionsDroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "retention_sweep_partitions_dropped_total",
+			Help:      "Number of expired partitions dropped from the table of a ReportDataSource by the retention sweeper.",
+		},
+		[]string{"reportdatasource", "namespace", "table_name"},
+	)
+
+	retentionSweepReportPeriodsDeletedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "retention_sweep_report_periods_deleted_total",
+			Help:      "Number of expired reporting periods deleted from the table of a Report by the retention sweeper.",
+		},
+		[]string{"report", "namespace", "table_name"},
+	)
+
+	retentionSweepFailuresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "retention_sweep_failures_total",
+			Help:      "Number of times the retention sweeper failed to remove expired data from a Report or ReportDataSource.",
+		},
+		[]string{"kind", "name", "namespace"},
+	)
+
+	retentionSweepDurationGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "retention_sweep_duration_seconds",
+			Help:      "Duration of the last retention sweep.",
+		},
+	)
+
+	retentionSweepLastTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "retention_sweep_last_timestamp_seconds",
+			Help:      "Unix timestamp of when the last retention sweep finished.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(retentionSweepPartitionsDroppedCounter)
+	prometheus.MustRegister(retentionSweepReportPeriodsDeletedCounter)
+	prometheus.MustRegister(retentionSweepFailuresCounter)
+	prometheus.MustRegister(retentionSweepDurationGauge)
+	prometheus.MustRegister(retentionSweepLastTimestampGauge)
+}
+
+// validateRetentionPolicy returns an error if retention is invalid. field
+// is the path to retention used in the error.
+func validateRetentionPolicy(retention *cbTypes.RetentionPolicy, field string) error {
+	if retention == nil {
+		return nil
+	}
+	switch {
+	case retention.Duration != nil && retention.Periods != nil:
+		return fmt.Errorf("only one of %s.duration or %s.periods can be set", field, field)
+	case retention.Duration != nil && retention.Duration.Duration <= 0:
+		return fmt.Errorf("%s.duration must be a positive duration, got %s", field, retention.Duration.Duration)
+	case retention.Periods != nil && *retention.Periods <= 0:
+		return fmt.Errorf("%s.periods must be a positive number, got %d", field, *retention.Periods)
+	}
+	return nil
+}
+
+// getRetentionPolicy returns retention if it's set, otherwise the default
+// retention of the reporting-operator. Returns nil if data should be kept
+// forever.
+func (op *Reporting) getRetentionPolicy(retention *cbTypes.RetentionPolicy) *cbTypes.RetentionPolicy {
+	if retention != nil {
+		return retention
+	}
+	return op.cfg.DefaultRetention
+}
+
+// runRetentionSweeper removes the expired data of every Report and
+// Prometheus ReportDataSource with a retention policy.
+func (op *Reporting) runRetentionSweeper() {
+	logger := op.logger.WithField("component", "retentionSweeper")
+	start := op.clock.Now()
+
+	dataSources, err := op.reportDataSourceLister.List(labels.Everything())
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list ReportDataSources")
+	}
+	for _, dataSource := range dataSources {
+		retention := op.getRetentionPolicy(dataSource.Spec.Retention)
+		if dataSource.Spec.Promsum == nil || dataSource.Status.TableName == "" || retention == nil {
+			continue
+		}
+		dataSourceLogger := logger.WithFields(log.Fields{"reportDataSource": dataSource.Name, "namespace": dataSource.Namespace})
+		if err := op.sweepReportDataSourceRetention(dataSourceLogger, dataSource, retention); err != nil {
+			dataSourceLogger.WithError(err).Errorf("unable to remove expired data from ReportDataSource %s", dataSource.Name)
+			retentionSweepFailuresCounter.WithLabelValues("ReportDataSource", dataSource.Name, dataSource.Namespace).Inc()
+		}
+	}
+
+	reports, err := op.reportLister.List(labels.Everything())
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list Reports")
+	}
+	for _, report := range reports {
+		retention := op.getRetentionPolicy(report.Spec.Retention)
+		// reports overwriting their data each run only contain the most
+		// recent period.
+		if report.Status.TableName == "" || report.Spec.OverwriteExistingData || retention == nil {
+			continue
+		}
+		reportLogger := logger.WithFields(log.Fields{"report": report.Name, "namespace": report.Namespace})
+		if err := op.sweepReportRetention(reportLogger, report, retention); err != nil {
+			reportLogger.WithError(err).Errorf("unable to remove expired data from Report %s", report.Name)
+			retentionSweepFailuresCounter.WithLabelValues("Report", report.Name, report.Namespace).Inc()
+		}
+	}
+
+	finish := op.clock.Now()
+	retentionSweepDurationGauge.Set(finish.Sub(start).Seconds())
+	retentionSweepLastTimestampGauge.Set(float64(finish.Unix()))
+}
+
+// sweepReportDataSourceRetention drops the dt partitions of a Prometheus
+// ReportDataSource's table which are older than its retention.
+func (op *Reporting) sweepReportDataSourceRetention(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource, retention *cbTypes.RetentionPolicy) error {
+	if err := validateRetentionPolicy(retention, "spec.retention"); err != nil {
+		return err
+	}
+	tableName := dataSource.Status.TableName
+	partitions, err := op.prometheusMetricsRepo.GetTimestampPartitionsForTable(tableName)
+	if err != nil {
+		return err
+	}
+	expired := expiredTimestampPartitions(partitions, retention, op.clock.Now())
+	droppedCounter := retentionSweepPartitionsDroppedCounter.WithLabelValues(dataSource.Name, dataSource.Namespace, tableName)
+	for _, partition := range expired {
+		logger.Infof("dropping expired partition %s=%s from table %s", prestostore.PrometheusMetricTimestampPartitionColumnName, partition, tableName)
+		err := op.tableManager.DropTablePartition(tableName, presto.PartitionSpec{prestostore.PrometheusMetricTimestampPartitionColumnName: partition})
+		if err != nil {
+			return fmt.Errorf("unable to drop partition %s=%s from table %s: %v", prestostore.PrometheusMetricTimestampPartitionColumnName, partition, tableName, err)
+		}
+		droppedCounter.Inc()
+	}
+	return nil
+}
+
+// sweepReportRetention deletes the rows of a Report's table for reporting
+// periods which are older than its retention.
+func (op *Reporting) sweepReportRetention(logger log.FieldLogger, report *cbTypes.Report, retention *cbTypes.RetentionPolicy) error {
+	if err := validateRetentionPolicy(retention, "spec.retention"); err != nil {
+		return err
+	}
+	genQuery, err := op.getReportGenerationQueryForReport(report)
+	if err != nil {
+		return err
+	}
+	if !reportingutil.HasPeriodColumns(genQuery) {
+		logger.Debugf("skipping Report %s: ReportGenerationQuery %s has no %s and %s columns to determine the age of its results", report.Name, genQuery.Name, reportingutil.PeriodStartColumnName, reportingutil.PeriodEndColumnName)
+		return nil
+	}
+	tableName := report.Status.TableName
+	periods, err := op.reportResultsRepo.GetReportResultsPeriods(tableName)
+	if err != nil {
+		return err
+	}
+	cutoff, expired := expiredReportResultsPeriods(periods, retention, op.clock.Now())
+	if expired == 0 {
+		return nil
+	}
+	logger.Infof("deleting %d expired reporting periods ending at or before %s from table %s", expired, cutoff, tableName)
+	if report.Status.TablePartitionedByPeriod {
+		for _, period := range periods {
+			if period.PeriodEnd.After(cutoff) {
+				continue
+			}
+			if err := op.dropReportTablePartition(logger, report, reportingutil.PeriodPartitionSpec(period.PeriodStart, period.PeriodEnd)); err != nil {
+				return fmt.Errorf("unable to drop the partition of period [%s to %s] from table %s: %v", period.PeriodStart, period.PeriodEnd, tableName, err)
+			}
+		}
+	} else {
+		deleteWhere := fmt.Sprintf(`"%s" <= timestamp '%s'`, reportingutil.PeriodEndColumnName, cutoff.UTC().Format(presto.TimestampFormat))
+		if err := op.deleteReportResults(logger, report, deleteWhere); err != nil {
+			return fmt.Errorf("unable to delete rows from table %s: %v", tableName, err)
+		}
+	}
+	retentionSweepReportPeriodsDeletedCounter.WithLabelValues(report.Name, report.Namespace, tableName).Add(float64(expired))
+	return nil
+}
+
+// expiredTimestampPartitions returns the partitions, sorted from oldest to
+// newest, which are expired according to retention. Each partition holds a
+// day of data, which is the period used when retention is a number of
+// periods.
+func expiredTimestampPartitions(partitions []string, retention *cbTypes.RetentionPolicy, now time.Time) []string {
+	switch {
+	case retention.Duration != nil:
+		// a partition is only expired once all of its data is older than
+		// the cutoff, which is true for every day before the cutoff's day.
+		cutoff := prestostore.PrometheusMetricTimestampPartition(now.Add(-retention.Duration.Duration))
+		var expired []string
+		for _, partition := range partitions {
+			if partition < cutoff {
+				expired = append(expired, partition)
+			}
+		}
+		return expired
+	case retention.Periods != nil:
+		if int64(len(partitions)) <= *retention.Periods {
+			return nil
+		}
+		return partitions[:int64(len(partitions))-*retention.Periods]
+	}
+	return nil
+}
+
+// expiredReportResultsPeriods returns the cutoff at or before which
+// reporting periods end are expired according to retention, and the number
+// of periods which are expired. periods must be sorted from oldest to newest.
+func expiredReportResultsPeriods(periods []prestostore.ReportResultsPeriod, retention *cbTypes.RetentionPolicy, now time.Time) (time.Time, int) {
+	var cutoff time.Time
+	switch {
+	case retention.Duration != nil:
+		cutoff = now.Add(-retention.Duration.Duration).UTC()
+	case retention.Periods != nil:
+		if int64(len(periods)) <= *retention.Periods {
+			return time.Time{}, 0
+		}
+		cutoff = periods[int64(len(periods))-*retention.Periods-1].PeriodEnd
+	default:
+		return time.Time{}, 0
+	}
+	expired := 0
+	for _, period := range periods {
+		if !period.PeriodEnd.After(cutoff) {
+			expired++
+		}
+	}
+	return cutoff, expired
+}
@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+)
+
+func newRetentionPolicy(duration time.Duration, periods int64) *cbTypes.RetentionPolicy {
+	retention := &cbTypes.RetentionPolicy{}
+	if duration != 0 {
+		retention.Duration = &metav1.Duration{Duration: duration}
+	}
+	if periods != 0 {
+		retention.Periods = &periods
+	}
+	return retention
+}
+
+func TestValidateRetentionPolicy(t *testing.T) {
+	assert.NoError(t, validateRetentionPolicy(nil, "spec.retention"))
+	assert.NoError(t, validateRetentionPolicy(newRetentionPolicy(time.Hour, 0), "spec.retention"))
+	assert.NoError(t, validateRetentionPolicy(newRetentionPolicy(0, 3), "spec.retention"))
+	assert.Error(t, validateRetentionPolicy(newRetentionPolicy(time.Hour, 3), "spec.retention"), "expected an error when both duration and periods are set")
+	assert.Error(t, validateRetentionPolicy(newRetentionPolicy(-time.Hour, 0), "spec.retention"), "expected an error for a negative duration")
+	assert.Error(t, validateRetentionPolicy(newRetentionPolicy(0, -1), "spec.retention"), "expected an error for a negative number of periods")
+}
+
+func TestExpiredTimestampPartitions(t *testing.T) {
+	now := time.Date(2019, time.January, 10, 12, 0, 0, 0, time.UTC)
+	partitions := []string{"2019-01-07", "2019-01-08", "2019-01-09", "2019-01-10"}
+
+	tests := map[string]struct {
+		retention *cbTypes.RetentionPolicy
+		expected  []string
+	}{
+		"duration only expires days entirely before the cutoff": {
+			retention: newRetentionPolicy(36*time.Hour, 0),
+			expected:  []string{"2019-01-07", "2019-01-08"},
+		},
+		"duration longer than the data": {
+			retention: newRetentionPolicy(30*24*time.Hour, 0),
+			expected:  nil,
+		},
+		"periods keeps the newest partitions": {
+			retention: newRetentionPolicy(0, 3),
+			expected:  []string{"2019-01-07"},
+		},
+		"periods more than the number of partitions": {
+			retention: newRetentionPolicy(0, 10),
+			expected:  nil,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, expiredTimestampPartitions(partitions, test.retention, now))
+		})
+	}
+}
+
+func TestExpiredReportResultsPeriods(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	newPeriod := func(start, end time.Duration) prestostore.ReportResultsPeriod {
+		return prestostore.ReportResultsPeriod{PeriodStart: janOne.Add(start), PeriodEnd: janOne.Add(end)}
+	}
+	periods := []prestostore.ReportResultsPeriod{
+		newPeriod(0, day),
+		newPeriod(day, 2*day),
+		newPeriod(2*day, 3*day),
+		newPeriod(3*day, 4*day),
+	}
+	now := janOne.Add(4 * day)
+
+	tests := map[string]struct {
+		retention       *cbTypes.RetentionPolicy
+		expectedCutoff  time.Time
+		expectedExpired int
+	}{
+		"duration": {
+			retention:       newRetentionPolicy(2*day, 0),
+			expectedCutoff:  janOne.Add(2 * day),
+			expectedExpired: 2,
+		},
+		"duration longer than the data": {
+			retention:       newRetentionPolicy(30*day, 0),
+			expectedCutoff:  janOne.Add(-26 * day),
+			expectedExpired: 0,
+		},
+		"periods keeps the newest periods": {
+			retention:       newRetentionPolicy(0, 3),
+			expectedCutoff:  janOne.Add(day),
+			expectedExpired: 1,
+		},
+		"periods more than the number of periods": {
+			retention:       newRetentionPolicy(0, 4),
+			expectedExpired: 0,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			cutoff, expired := expiredReportResultsPeriods(periods, test.retention, now)
+			assert.Equal(t, test.expectedCutoff, cutoff)
+			assert.Equal(t, test.expectedExpired, expired)
+		})
+	}
+}
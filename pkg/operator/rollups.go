@@ -0,0 +1,228 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/prestostore"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+)
+
+const (
+	// maxRollupDuration limits how much data is aggregated into a rollup
+	// table at once, so that catching up on a large backlog of metrics
+	// is split across multiple imports.
+	maxRollupDuration = 7 * 24 * time.Hour
+
+	// rollupPartitionDuration is the period of the dt partitions of rollup
+	// tables.
+	rollupPartitionDuration = 24 * time.Hour
+)
+
+var (
+	prometheusReportDatasourceRollupLabels = []string{
+		"reportdatasource",
+		"namespace",
+		"granularity",
+	}
+
+	prometheusReportDatasourceRollupsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_rollups_total",
+			Help:      "Number of times metrics of a Prometheus ReportDataSource were aggregated into a rollup table.",
+		},
+		prometheusReportDatasourceRollupLabels,
+	)
+
+	prometheusReportDatasourceFailedRollupsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "prometheus_reportdatasource_failed_rollups_total",
+			Help:      "Number of failed attempts to aggregate metrics of a Prometheus ReportDataSource into a rollup table.",
+		},
+		prometheusReportDatasourceRollupLabels,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(prometheusReportDatasourceRollupsCounter)
+	prometheus.MustRegister(prometheusReportDatasourceFailedRollupsCounter)
+}
+
+// prometheusMetricsRollupGranularities are the granularities of rollups,
+// ordered from finest to coarsest so coarser rollups can be aggregated from
+// finer ones.
+var prometheusMetricsRollupGranularities = []cbTypes.PrometheusMetricsRollupGranularity{
+	cbTypes.PrometheusMetricsRollupHourly,
+	cbTypes.PrometheusMetricsRollupDaily,
+}
+
+func prometheusMetricsRollupEnabled(rollups *cbTypes.PrometheusMetricsRollups, granularity cbTypes.PrometheusMetricsRollupGranularity) bool {
+	if rollups == nil {
+		return false
+	}
+	switch granularity {
+	case cbTypes.PrometheusMetricsRollupHourly:
+		return rollups.Hourly
+	case cbTypes.PrometheusMetricsRollupDaily:
+		return rollups.Daily
+	}
+	return false
+}
+
+func getPrometheusMetricsRollupStatus(dataSource *cbTypes.ReportDataSource, granularity cbTypes.PrometheusMetricsRollupGranularity) *cbTypes.PrometheusMetricsRollupStatus {
+	for i := range dataSource.Status.Rollups {
+		if dataSource.Status.Rollups[i].Granularity == granularity {
+			return &dataSource.Status.Rollups[i]
+		}
+	}
+	return nil
+}
+
+// createPrometheusMetricsDataSourceRollupTables creates the rollup tables
+// enabled in a Prometheus ReportDataSource's spec which don't exist yet, and
+// records them in its status.
+func (op *Reporting) createPrometheusMetricsDataSourceRollupTables(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource) (*cbTypes.ReportDataSource, error) {
+	gvk := cbTypes.SchemeGroupVersion.WithKind("ReportDataSource")
+	created := false
+	for _, granularity := range prometheusMetricsRollupGranularities {
+		if !prometheusMetricsRollupEnabled(dataSource.Spec.Promsum.Rollups, granularity) || getPrometheusMetricsRollupStatus(dataSource, granularity) != nil {
+			continue
+		}
+		tableName := reportingutil.DataSourceRollupTableName(dataSource.Namespace, dataSource.Name, string(granularity))
+		resourceName := reportingutil.PrestoTableResourceNameFromKind(gvk.Kind, dataSource.Namespace, fmt.Sprintf("%s-%s-rollup", dataSource.Name, granularity))
+		logger.Infof("creating %s rollup table %s", granularity, tableName)
+		err := op.createTableForStorageWithResourceName(logger, dataSource, gvk, dataSource.Spec.Promsum.Storage, resourceName, tableName, prestostore.PromsumRollupHiveTableColumns, prestostore.PromsumHivePartitionColumns)
+		if err != nil {
+			return nil, err
+		}
+		dataSource.Status.Rollups = append(dataSource.Status.Rollups, cbTypes.PrometheusMetricsRollupStatus{
+			Granularity: granularity,
+			TableName:   tableName,
+		})
+		created = true
+	}
+	if !created {
+		return dataSource, nil
+	}
+	dataSource, err := op.meteringClient.MeteringV1alpha1().ReportDataSources(dataSource.Namespace).Update(dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update ReportDataSource %s rollups: %v", dataSource.Name, err)
+	}
+	return dataSource, nil
+}
+
+// updatePrometheusMetricsDataSourceRollups aggregates the metrics imported
+// since the last time into the rollup tables of a Prometheus
+// ReportDataSource. Only periods which have ended before the newest
+// imported metric are aggregated, since the metrics for later periods may
+// not all be imported yet. Returns true if the status was changed and needs
+// to be updated.
+func (op *Reporting) updatePrometheusMetricsDataSourceRollups(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource) bool {
+	importStatus := dataSource.Status.PrometheusMetricImportStatus
+	if importStatus == nil || importStatus.EarliestImportedMetricTime == nil || importStatus.NewestImportedMetricTime == nil {
+		return false
+	}
+
+	changed := false
+	var sourceRollup *cbTypes.PrometheusMetricsRollupStatus
+	for _, granularity := range prometheusMetricsRollupGranularities {
+		rollup := getPrometheusMetricsRollupStatus(dataSource, granularity)
+		if rollup == nil || !prometheusMetricsRollupEnabled(dataSource.Spec.Promsum.Rollups, granularity) {
+			continue
+		}
+
+		sourceTableName := dataSource.Status.TableName
+		newestImported := importStatus.NewestImportedMetricTime.Time
+		if sourceRollup != nil {
+			if sourceRollup.RolledUpTo == nil {
+				continue
+			}
+			sourceTableName = sourceRollup.TableName
+			newestImported = sourceRollup.RolledUpTo.Time
+		}
+
+		start, end, ok := getPrometheusMetricsRollupRange(rollup, importStatus.EarliestImportedMetricTime.Time, newestImported)
+		if ok {
+			metricLabels := prometheus.Labels{
+				"reportdatasource": dataSource.Name,
+				"namespace":        dataSource.Namespace,
+				"granularity":      string(granularity),
+			}
+			prometheusReportDatasourceRollupsCounter.With(metricLabels).Inc()
+			logger.Infof("aggregating metrics from %s to %s into %s rollup table %s", start, end, granularity, rollup.TableName)
+			period := reportingutil.PrometheusMetricsRollupPeriods[granularity]
+			err := op.prometheusMetricsRepo.StorePrometheusMetricsRollup(rollup.TableName, sourceTableName, sourceRollup != nil, start, end, period)
+			if err != nil {
+				prometheusReportDatasourceFailedRollupsCounter.With(metricLabels).Inc()
+				logger.WithError(err).Errorf("unable to aggregate metrics into %s rollup table %s", granularity, rollup.TableName)
+				// coarser rollups can still catch up to this one
+				sourceRollup = rollup
+				continue
+			}
+			rollup.RolledUpTo = &metav1.Time{Time: end}
+			changed = true
+		}
+		sourceRollup = rollup
+	}
+	return changed
+}
+
+// getPrometheusMetricsRollupRange returns the range of time to aggregate
+// into rollup next, given the timestamps of the earliest and newest metrics
+// in the table the rollup is aggregated from. Returns false if no periods
+// have ended since the rollup was last aggregated.
+//
+// Aggregating a range replaces the rollup's dt partitions, which hold a day
+// each, so the range always starts at the start of the day, and the hours
+// of the day which have already been aggregated are aggregated again.
+func getPrometheusMetricsRollupRange(rollup *cbTypes.PrometheusMetricsRollupStatus, earliest, newest time.Time) (time.Time, time.Time, bool) {
+	period := reportingutil.PrometheusMetricsRollupPeriods[rollup.Granularity]
+	rolledUpTo := earliest.UTC().Truncate(period)
+	if rollup.RolledUpTo != nil {
+		rolledUpTo = rollup.RolledUpTo.Time.UTC()
+	}
+	start := rolledUpTo.Truncate(rollupPartitionDuration)
+	end := newest.UTC().Truncate(period)
+	if maxEnd := start.Add(maxRollupDuration).Truncate(period); end.After(maxEnd) {
+		end = maxEnd
+	}
+	if !end.After(rolledUpTo) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// resetPrometheusMetricsDataSourceRollups makes the rollups of a Prometheus
+// ReportDataSource aggregate the metrics from the start of the day of from
+// again, after metrics there have been backfilled. Days which have been
+// removed from the ReportDataSource's table by its retention are left alone,
+// since aggregating them again would replace their rollups with nothing.
+func (op *Reporting) resetPrometheusMetricsDataSourceRollups(logger log.FieldLogger, dataSource *cbTypes.ReportDataSource, from time.Time) {
+	if len(dataSource.Status.Rollups) == 0 {
+		return
+	}
+	start := from.UTC().Truncate(rollupPartitionDuration)
+	partitions, err := op.prometheusMetricsRepo.GetTimestampPartitionsForTable(dataSource.Status.TableName)
+	if err != nil {
+		logger.WithError(err).Errorf("unable to aggregate the metrics backfilled from %s into the rollups again", from)
+		return
+	}
+	if len(partitions) == 0 || partitions[0] > prestostore.PrometheusMetricTimestampPartition(start) {
+		logger.Warnf("not aggregating the metrics backfilled from %s into the rollups again, the table no longer has the metrics for the day", from)
+		return
+	}
+	for i := range dataSource.Status.Rollups {
+		rollup := &dataSource.Status.Rollups[i]
+		if rollup.RolledUpTo != nil && rollup.RolledUpTo.Time.After(start) {
+			logger.Infof("aggregating metrics into %s rollup table %s again from %s", rollup.Granularity, rollup.TableName, start)
+			rollup.RolledUpTo = &metav1.Time{Time: start}
+		}
+	}
+}
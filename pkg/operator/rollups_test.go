@@ -0,0 +1,119 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+)
+
+func TestGetPrometheusMetricsRollupRange(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newRollup := func(granularity cbTypes.PrometheusMetricsRollupGranularity, rolledUpTo *time.Time) *cbTypes.PrometheusMetricsRollupStatus {
+		rollup := &cbTypes.PrometheusMetricsRollupStatus{Granularity: granularity}
+		if rolledUpTo != nil {
+			rollup.RolledUpTo = &metav1.Time{Time: *rolledUpTo}
+		}
+		return rollup
+	}
+	timePtr := func(t time.Time) *time.Time { return &t }
+
+	tests := map[string]struct {
+		rollup        *cbTypes.PrometheusMetricsRollupStatus
+		earliest      time.Time
+		newest        time.Time
+		expectedStart time.Time
+		expectedEnd   time.Time
+		expectedOk    bool
+	}{
+		"first hourly rollup starts at the day of the earliest metric": {
+			rollup:        newRollup(cbTypes.PrometheusMetricsRollupHourly, nil),
+			earliest:      janOne.Add(90 * time.Minute),
+			newest:        janOne.Add(4*time.Hour + 30*time.Minute),
+			expectedStart: janOne,
+			expectedEnd:   janOne.Add(4 * time.Hour),
+			expectedOk:    true,
+		},
+		"hourly rollup continues from the start of the day it left off in": {
+			rollup:        newRollup(cbTypes.PrometheusMetricsRollupHourly, timePtr(janOne.Add(4*time.Hour))),
+			earliest:      janOne,
+			newest:        janOne.Add(6*time.Hour + time.Minute),
+			expectedStart: janOne,
+			expectedEnd:   janOne.Add(6 * time.Hour),
+			expectedOk:    true,
+		},
+		"hourly rollup continues on the next day": {
+			rollup:        newRollup(cbTypes.PrometheusMetricsRollupHourly, timePtr(janOne.Add(24*time.Hour))),
+			earliest:      janOne,
+			newest:        janOne.Add(26*time.Hour + time.Minute),
+			expectedStart: janOne.Add(24 * time.Hour),
+			expectedEnd:   janOne.Add(26 * time.Hour),
+			expectedOk:    true,
+		},
+		"the current hour isn't rolled up until it's over": {
+			rollup:   newRollup(cbTypes.PrometheusMetricsRollupHourly, timePtr(janOne.Add(4*time.Hour))),
+			earliest: janOne,
+			newest:   janOne.Add(4*time.Hour + 59*time.Minute),
+		},
+		"daily rollup of a large backlog is limited": {
+			rollup:        newRollup(cbTypes.PrometheusMetricsRollupDaily, nil),
+			earliest:      janOne,
+			newest:        janOne.Add(30 * 24 * time.Hour),
+			expectedStart: janOne,
+			expectedEnd:   janOne.Add(maxRollupDuration),
+			expectedOk:    true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			start, end, ok := getPrometheusMetricsRollupRange(test.rollup, test.earliest, test.newest)
+			assert.Equal(t, test.expectedOk, ok)
+			assert.Equal(t, test.expectedStart, start)
+			assert.Equal(t, test.expectedEnd, end)
+		})
+	}
+}
+
+func TestResetPrometheusMetricsDataSourceRollups(t *testing.T) {
+	janOne := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newDataSource := func() *cbTypes.ReportDataSource {
+		return &cbTypes.ReportDataSource{
+			Status: cbTypes.ReportDataSourceStatus{
+				TableName: "datasource_default_test",
+				Rollups: []cbTypes.PrometheusMetricsRollupStatus{
+					{Granularity: cbTypes.PrometheusMetricsRollupHourly, RolledUpTo: &metav1.Time{Time: janOne.Add(50 * time.Hour)}},
+					{Granularity: cbTypes.PrometheusMetricsRollupDaily, RolledUpTo: &metav1.Time{Time: janOne.Add(48 * time.Hour)}},
+				},
+			},
+		}
+	}
+	rolledUpTo := func(dataSource *cbTypes.ReportDataSource) []time.Time {
+		var times []time.Time
+		for _, rollup := range dataSource.Status.Rollups {
+			times = append(times, rollup.RolledUpTo.Time)
+		}
+		return times
+	}
+	op := &Reporting{
+		prometheusMetricsRepo: &fakePrometheusMetricsRepo{partitions: map[string][]string{
+			"datasource_default_test": {"2019-01-02", "2019-01-03"},
+		}},
+	}
+
+	dataSource := newDataSource()
+	op.resetPrometheusMetricsDataSourceRollups(testLogger, dataSource, janOne.Add(30*time.Hour))
+	assert.Equal(t, []time.Time{janOne.Add(24 * time.Hour), janOne.Add(24 * time.Hour)}, rolledUpTo(dataSource), "expected the rollups to start again from the day of the backfilled metrics")
+
+	dataSource = newDataSource()
+	op.resetPrometheusMetricsDataSourceRollups(testLogger, dataSource, janOne.Add(49*time.Hour))
+	assert.Equal(t, []time.Time{janOne.Add(48 * time.Hour), janOne.Add(48 * time.Hour)}, rolledUpTo(dataSource), "expected only rollups past the day of the backfilled metrics to be changed")
+
+	dataSource = newDataSource()
+	op.resetPrometheusMetricsDataSourceRollups(testLogger, dataSource, janOne.Add(time.Hour))
+	assert.Equal(t, []time.Time{janOne.Add(50 * time.Hour), janOne.Add(48 * time.Hour)}, rolledUpTo(dataSource), "expected days no longer in the table to not be aggregated again")
+}
@@ -0,0 +1,122 @@
+package operator
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/reportingutil"
+)
+
+var (
+	viewSweepViewsDroppedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "view_sweep_views_dropped_total",
+			Help:      "Number of views without a ReportGenerationQuery dropped by the view sweeper.",
+		},
+	)
+
+	viewSweepFailuresCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "view_sweep_failures_total",
+			Help:      "Number of times the view sweeper failed to list or drop views.",
+		},
+	)
+
+	viewSweepLastTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusMetricNamespace,
+			Name:      "view_sweep_last_timestamp_seconds",
+			Help:      "Unix timestamp of when the last view sweep finished.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(viewSweepViewsDroppedCounter)
+	prometheus.MustRegister(viewSweepFailuresCounter)
+	prometheus.MustRegister(viewSweepLastTimestampGauge)
+}
+
+// orphanedViews returns the views which are named like the views of
+// ReportGenerationQueries in namespaces, but don't belong to any of queries.
+// If namespaces is empty, views of ReportGenerationQueries in any namespace
+// are considered.
+func orphanedViews(views []string, queries []*cbTypes.ReportGenerationQuery, namespaces []string) []string {
+	owned := make(map[string]bool)
+	for _, query := range queries {
+		if query.Status.ViewName != "" {
+			owned[query.Status.ViewName] = true
+		}
+		// the view may have been created but not yet recorded in the
+		// status.
+		if !query.Spec.View.Disabled {
+			owned[reportingutil.GenerationQueryViewName(query.Namespace, query.Name)] = true
+		}
+	}
+
+	const prefix = "view_"
+	viewNamespaces := tableNameNamespaces(namespaces)
+
+	var orphaned []string
+	for _, view := range views {
+		if owned[view] || !strings.HasPrefix(view, prefix) {
+			continue
+		}
+		if len(namespaces) == 0 || inNamespaces(strings.TrimPrefix(view, prefix), 1, viewNamespaces) {
+			orphaned = append(orphaned, view)
+		}
+	}
+	return orphaned
+}
+
+// runViewSweeper drops the views left behind by ReportGenerationQueries which
+// were deleted while the reporting-operator wasn't running, once they've been
+// orphaned for the orphaned table grace period. The grace period gives the
+// ReportGenerationQuery of a newly created view time to be listed.
+func (op *Reporting) runViewSweeper() {
+	logger := op.logger.WithField("component", "viewSweeper")
+
+	queries, err := op.reportGenerationQueryLister.List(labels.Everything())
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list ReportGenerationQueries")
+		viewSweepFailuresCounter.Inc()
+		return
+	}
+	views, err := op.prestoViewManager.ListViews()
+	if err != nil {
+		logger.WithError(err).Errorf("unable to list views")
+		viewSweepFailuresCounter.Inc()
+		return
+	}
+
+	now := op.clock.Now()
+	firstSeen := make(map[string]time.Time)
+	for _, view := range orphanedViews(views, queries, op.watchedNamespaces) {
+		seen, ok := op.orphanedViewsFirstSeen[view]
+		if !ok {
+			seen = now
+		}
+		firstSeen[view] = seen
+
+		if now.Sub(seen) < op.cfg.OrphanedTableGracePeriod {
+			continue
+		}
+		logger.Infof("dropping view %s, it has no ReportGenerationQuery", view)
+		if err := op.prestoViewManager.DropView(view, true); err != nil {
+			logger.WithError(err).Errorf("unable to drop view %s", view)
+			viewSweepFailuresCounter.Inc()
+			continue
+		}
+		delete(firstSeen, view)
+		viewSweepViewsDroppedCounter.Inc()
+	}
+	// views which are no longer orphaned, or were dropped, are forgotten
+	op.orphanedViewsFirstSeen = firstSeen
+	viewSweepLastTimestampGauge.Set(float64(op.clock.Now().Unix()))
+}
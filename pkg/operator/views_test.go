@@ -0,0 +1,54 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestOrphanedViews(t *testing.T) {
+	withView := testhelpers.NewReportGenerationQuery("with-view", "metering", nil)
+	withView.Status.ViewName = "view_metering_with_view"
+	// the view is created, but not yet recorded in the status
+	pendingView := testhelpers.NewReportGenerationQuery("pending-view", "metering", nil)
+	disabledView := testhelpers.NewReportGenerationQuery("disabled-view", "metering", nil)
+	disabledView.Spec.View.Disabled = true
+	queries := []*cbTypes.ReportGenerationQuery{withView, pendingView, disabledView}
+
+	views := []string{
+		"view_metering_with_view",
+		"view_metering_pending_view",
+		"view_metering_disabled_view",
+		"view_metering_deleted",
+		"view_other_deleted",
+		// could belong to metering-dev
+		"view_metering_dev_usage",
+		"user_created_view",
+	}
+
+	tests := map[string]struct {
+		namespaces []string
+		expected   []string
+	}{
+		"all namespaces": {
+			expected: []string{"view_metering_disabled_view", "view_metering_deleted", "view_other_deleted", "view_metering_dev_usage"},
+		},
+		"watched namespaces": {
+			namespaces: []string{"metering"},
+			expected:   []string{"view_metering_deleted"},
+		},
+		"watched namespaces with the same prefix": {
+			namespaces: []string{"metering", "metering-dev", "metering-disabled"},
+			expected:   []string{"view_metering_disabled_view", "view_metering_deleted", "view_metering_dev_usage"},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, orphanedViews(views, queries, tt.namespaces))
+		})
+	}
+}
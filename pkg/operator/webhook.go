@@ -0,0 +1,400 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	cbTypes "github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	listers "github.com/operator-framework/operator-metering/pkg/generated/listers/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/pkg/operator/reporting"
+)
+
+const (
+	WebhookValidateEndpoint = "/validate"
+
+	admissionReviewAPIVersion = "admission.k8s.io/v1beta1"
+	admissionReviewKind       = "AdmissionReview"
+)
+
+// admissionReview, admissionRequest and admissionResponse mirror the subset of
+// the admission.k8s.io/v1beta1 AdmissionReview types used by the validating
+// webhook, since the k8s.io/api/admission package is not vendored.
+type admissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *admissionRequest  `json:"request,omitempty"`
+	Response        *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       types.UID                   `json:"uid"`
+	Kind      metav1.GroupVersionKind     `json:"kind"`
+	Resource  metav1.GroupVersionResource `json:"resource"`
+	Name      string                      `json:"name,omitempty"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Operation string                      `json:"operation"`
+	Object    runtime.RawExtension        `json:"object,omitempty"`
+	OldObject runtime.RawExtension        `json:"oldObject,omitempty"`
+}
+
+type admissionResponse struct {
+	UID     types.UID      `json:"uid"`
+	Allowed bool           `json:"allowed"`
+	Result  *metav1.Status `json:"result,omitempty"`
+}
+
+// webhookValidator validates metering resources before they're persisted
+// by the Kubernetes API server. Dependencies of the resources being
+// validated are looked up using the operator's listers, but resources are
+// never rejected because their dependencies don't exist, since resources are
+// often created before their dependencies, and the operator waits for
+// dependencies itself.
+type webhookValidator struct {
+	logger log.FieldLogger
+
+	reportGenerationQueryLister listers.ReportGenerationQueryLister
+	reportLister                listers.ReportLister
+
+	// targetNamespaces is the list of namespaces the operator watches, if
+	// empty, all namespaces are watched.
+	targetNamespaces []string
+	// exportAllowedHosts are the hosts Report exports can send results to.
+	exportAllowedHosts []string
+}
+
+func newWebhookValidator(
+	logger log.FieldLogger,
+	reportGenerationQueryLister listers.ReportGenerationQueryLister,
+	reportLister listers.ReportLister,
+	targetNamespaces []string,
+	exportAllowedHosts []string,
+) *webhookValidator {
+	return &webhookValidator{
+		logger:                      logger.WithField("component", "webhook"),
+		reportGenerationQueryLister: reportGenerationQueryLister,
+		reportLister:                reportLister,
+		targetNamespaces:            targetNamespaces,
+		exportAllowedHosts:          exportAllowedHosts,
+	}
+}
+
+func newWebhookRouter(validator *webhookValidator) chi.Router {
+	router := chi.NewRouter()
+	router.Post(WebhookValidateEndpoint, validator.validateHandler)
+	return router
+}
+
+func (v *webhookValidator) validateHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		v.logger.WithError(err).Errorf("unable to read admission request body")
+		http.Error(w, fmt.Sprintf("unable to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		v.logger.WithError(err).Errorf("unable to decode AdmissionReview")
+		http.Error(w, fmt.Sprintf("unable to decode AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview request must be set", http.StatusBadRequest)
+		return
+	}
+
+	resp := &admissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+	if err := v.validate(review.Request); err != nil {
+		v.logger.Infof("rejecting %s %s %s/%s: %v", review.Request.Operation, review.Request.Kind.Kind, review.Request.Namespace, review.Request.Name, err)
+		resp.Allowed = false
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&admissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionReviewAPIVersion,
+			Kind:       admissionReviewKind,
+		},
+		Response: resp,
+	})
+	if err != nil {
+		v.logger.WithError(err).Errorf("unable to encode AdmissionReview response")
+	}
+}
+
+// validate returns an error describing why the object in the request is
+// invalid, or nil if it should be admitted.
+func (v *webhookValidator) validate(req *admissionRequest) error {
+	if req.Operation != "CREATE" && req.Operation != "UPDATE" {
+		return nil
+	}
+	// resources outside the namespaces we watch are never processed by the
+	// operator, and our listers have no knowledge of their dependencies.
+	if !v.inTargetNamespace(req.Namespace) {
+		return nil
+	}
+
+	var (
+		obj      interface{}
+		validate func() error
+	)
+	switch req.Kind.Kind {
+	case "Report":
+		report := &cbTypes.Report{}
+		obj, validate = report, func() error { return v.validateReport(report) }
+	case "ReportGenerationQuery":
+		genQuery := &cbTypes.ReportGenerationQuery{}
+		obj, validate = genQuery, func() error { return validateReportGenerationQuery(genQuery) }
+	case "ReportDataSource":
+		dataSource := &cbTypes.ReportDataSource{}
+		obj, validate = dataSource, func() error { return validateReportDataSource(dataSource) }
+	case "ReportPrometheusQuery":
+		promQuery := &cbTypes.ReportPrometheusQuery{}
+		obj, validate = promQuery, func() error { return validateReportPrometheusQuery(promQuery) }
+	case "StorageLocation":
+		storageLocation := &cbTypes.StorageLocation{}
+		obj, validate = storageLocation, func() error { return validateStorageLocation(storageLocation) }
+	case "RateCard":
+		rateCard := &cbTypes.RateCard{}
+		obj, validate = rateCard, func() error {
+			_, err := convertRateCardRates(rateCard)
+			return err
+		}
+	case "ReportNotification":
+		notification := &cbTypes.ReportNotification{}
+		obj, validate = notification, func() error { return validateReportNotification(notification) }
+	case "ReportRerun":
+		rerun := &cbTypes.ReportRerun{}
+		obj, validate = rerun, func() error { return v.validateReportRerun(rerun) }
+	default:
+		return nil
+	}
+
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return fmt.Errorf("unable to decode %s: %v", req.Kind.Kind, err)
+	}
+	metaObj, ok := obj.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("unable to decode %s: not an object", req.Kind.Kind)
+	}
+	// resources being deleted only have their finalizers removed.
+	if metaObj.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	// these resources have no status subresource, so the operator updates
+	// their status, finalizers and checkpoints by updating the whole
+	// object, which must be allowed even if the spec has since become
+	// invalid, for example because a dependency was deleted.
+	if req.Operation == "UPDATE" {
+		unchanged, err := specUnchanged(req.Object.Raw, req.OldObject.Raw)
+		if err != nil {
+			return fmt.Errorf("unable to decode %s: %v", req.Kind.Kind, err)
+		}
+		if unchanged {
+			return nil
+		}
+	}
+	if metaObj.GetNamespace() == "" {
+		metaObj.SetNamespace(req.Namespace)
+	}
+	return validate()
+}
+
+// specUnchanged returns true if the spec of the objects is the same.
+func specUnchanged(raw, oldRaw []byte) (bool, error) {
+	if len(oldRaw) == 0 {
+		return false, nil
+	}
+	var obj, oldObj struct {
+		Spec interface{} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(oldRaw, &oldObj); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(obj.Spec, oldObj.Spec), nil
+}
+
+func (v *webhookValidator) inTargetNamespace(namespace string) bool {
+	if len(v.targetNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range v.targetNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *webhookValidator) validateReport(report *cbTypes.Report) error {
+	if report.Spec.GenerationQueryName == "" {
+		return fmt.Errorf("spec.generationQuery must be set")
+	}
+	if report.Spec.ReportingStart != nil && report.Spec.ReportingEnd != nil && !report.Spec.ReportingEnd.After(report.Spec.ReportingStart.Time) {
+		return fmt.Errorf("spec.reportingEnd %s must be after spec.reportingStart %s", report.Spec.ReportingEnd.Time, report.Spec.ReportingStart.Time)
+	}
+	if report.Spec.Schedule != nil {
+		if _, err := getSchedule(report.Spec.Schedule); err != nil {
+			return fmt.Errorf("invalid spec.schedule: %v", err)
+		}
+	} else if _, err := getRunOnceReportPeriod(report); err != nil {
+		return err
+	}
+	if err := validateRetentionPolicy(report.Spec.Retention, "spec.retention"); err != nil {
+		return err
+	}
+	exportNames := make(map[string]bool)
+	for i, export := range report.Spec.Exports {
+		if err := validateReportExport(export, v.exportAllowedHosts); err != nil {
+			return fmt.Errorf("invalid spec.exports[%d]: %v", i, err)
+		}
+		if exportNames[export.Name] {
+			return fmt.Errorf("invalid spec.exports[%d]: duplicate export name %q", i, export.Name)
+		}
+		exportNames[export.Name] = true
+	}
+
+	switch report.Spec.TableSchemaChangePolicy {
+	case "", cbTypes.ReportTableSchemaChangeFail, cbTypes.ReportTableSchemaChangeAddColumns, cbTypes.ReportTableSchemaChangeRecreate:
+	default:
+		return fmt.Errorf("spec.tableSchemaChangePolicy must be one of %s, %s or %s, got %q", cbTypes.ReportTableSchemaChangeFail, cbTypes.ReportTableSchemaChangeAddColumns, cbTypes.ReportTableSchemaChangeRecreate, report.Spec.TableSchemaChangePolicy)
+	}
+
+	// the inputs can only be checked once the ReportGenerationQuery
+	// exists, otherwise the Report waits for it.
+	genQuery, err := v.reportGenerationQueryLister.ReportGenerationQueries(report.Namespace).Get(report.Spec.GenerationQueryName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if _, err := reporting.ValidateReportGenerationQueryInputs(genQuery, report.Spec.Inputs); err != nil {
+		return err
+	}
+	if _, err := reportTablePartitions(report, genQuery); err != nil {
+		return fmt.Errorf("invalid spec.partitionByPeriod: %v", err)
+	}
+	return nil
+}
+
+func validateReportGenerationQuery(genQuery *cbTypes.ReportGenerationQuery) error {
+	if len(genQuery.Spec.Columns) == 0 {
+		return fmt.Errorf("spec.columns must contain at least one column")
+	}
+	for i, col := range genQuery.Spec.Columns {
+		if col.Name == "" || col.Type == "" {
+			return fmt.Errorf("spec.columns[%d] must have both a name and a type", i)
+		}
+	}
+	for i, input := range genQuery.Spec.Inputs {
+		if input.Name == "" {
+			return fmt.Errorf("spec.inputs[%d].name must be set", i)
+		}
+		switch strings.ToLower(input.Type) {
+		case "", "string", "time", "int", "integer":
+		default:
+			return fmt.Errorf("spec.inputs[%d] %s has unsupported type %s", i, input.Name, input.Type)
+		}
+	}
+	if err := reporting.ValidateQueryTemplate(genQuery.Spec.Query, genQuery.Namespace); err != nil {
+		return fmt.Errorf("invalid spec.query: %v", err)
+	}
+	return nil
+}
+
+func validateReportDataSource(dataSource *cbTypes.ReportDataSource) error {
+	switch {
+	case dataSource.Spec.Promsum != nil && dataSource.Spec.AWSBilling != nil:
+		return fmt.Errorf("only one of spec.promsum or spec.awsBilling can be set")
+	case dataSource.Spec.Promsum != nil && dataSource.Spec.Promsum.RemoteWrite != nil:
+		if _, err := newRemoteWriteMatcher(dataSource); err != nil {
+			return err
+		}
+		if dataSource.Spec.Promsum.Rollups != nil {
+			return fmt.Errorf("spec.promsum.rollups is not supported with spec.promsum.remoteWrite")
+		}
+	case dataSource.Spec.Promsum != nil:
+		if dataSource.Spec.Promsum.Query == "" {
+			return fmt.Errorf("spec.promsum.query must be set")
+		}
+	case dataSource.Spec.AWSBilling != nil:
+		source := dataSource.Spec.AWSBilling.Source
+		if source == nil || source.Bucket == "" || source.Region == "" {
+			return fmt.Errorf("spec.awsBilling.source must have a bucket and region")
+		}
+	default:
+		return fmt.Errorf("one of spec.promsum or spec.awsBilling must be set")
+	}
+	if dataSource.Spec.Promsum != nil {
+		if _, err := newRelabelConfigs(dataSource.Spec.Promsum.RelabelConfigs); err != nil {
+			return err
+		}
+	} else if dataSource.Spec.Retention != nil {
+		return fmt.Errorf("spec.retention is only supported with spec.promsum")
+	}
+	if err := validateRetentionPolicy(dataSource.Spec.Retention, "spec.retention"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (v *webhookValidator) validateReportRerun(rerun *cbTypes.ReportRerun) error {
+	if rerun.Spec.ReportName == "" {
+		return fmt.Errorf("spec.reportName must be set")
+	}
+	if !rerun.Spec.ReportingEnd.After(rerun.Spec.ReportingStart.Time) {
+		return fmt.Errorf("spec.reportingEnd %s must be after spec.reportingStart %s", rerun.Spec.ReportingEnd.Time, rerun.Spec.ReportingStart.Time)
+	}
+	// the periods can only be checked once the Report exists, otherwise
+	// the ReportRerun fails when it's processed.
+	report, err := v.reportLister.Reports(rerun.Namespace).Get(rerun.Spec.ReportName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	_, err = getReportRerunPeriods(report, rerun.Spec.ReportingStart.Time, rerun.Spec.ReportingEnd.Time)
+	return err
+}
+
+func validateReportPrometheusQuery(promQuery *cbTypes.ReportPrometheusQuery) error {
+	if strings.TrimSpace(promQuery.Spec.Query) == "" {
+		return fmt.Errorf("spec.query must be set")
+	}
+	return nil
+}
+
+func validateStorageLocation(storageLocation *cbTypes.StorageLocation) error {
+	if storageLocation.Spec.Hive == nil {
+		return fmt.Errorf("spec.hive must be set")
+	}
+	props := storageLocation.Spec.Hive.TableProperties
+	if props.External && props.Location == "" {
+		return fmt.Errorf("spec.hive.tableProperties.location must be set for external tables")
+	}
+	return nil
+}
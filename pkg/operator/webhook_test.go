@@ -0,0 +1,449 @@
+package operator
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/operator-framework/operator-metering/pkg/apis/metering/v1alpha1"
+	listers "github.com/operator-framework/operator-metering/pkg/generated/listers/metering/v1alpha1"
+	"github.com/operator-framework/operator-metering/test/testhelpers"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	const namespace = "default"
+	start := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	columns := []v1alpha1.ReportGenerationQueryColumn{{Name: "timestamp", Type: "timestamp"}}
+	existingQuery := testhelpers.NewReportGenerationQuery("existing-query", namespace, columns)
+	existingQuery.Spec.Inputs = []v1alpha1.ReportGenerationQueryInputDefinition{{Name: "Required", Required: true}}
+	partitionColumnQuery := testhelpers.NewReportGenerationQuery("partition-column-query", namespace, []v1alpha1.ReportGenerationQueryColumn{{Name: "report_period", Type: "string"}})
+	dailyReport := testhelpers.NewReport("daily-report", namespace, existingQuery.Name, nil, nil, v1alpha1.ReportStatus{})
+	dailyReport.Spec.Schedule = &v1alpha1.ReportSchedule{Period: v1alpha1.ReportPeriodDaily}
+
+	newReport := func(modify func(*v1alpha1.Report)) *v1alpha1.Report {
+		report := testhelpers.NewReport("test-report", namespace, existingQuery.Name, &start, &end, v1alpha1.ReportStatus{})
+		requiredVal := json.RawMessage(`"value"`)
+		report.Spec.Inputs = []v1alpha1.ReportGenerationQueryInputValue{{Name: "Required", Value: &requiredVal}}
+		if modify != nil {
+			modify(report)
+		}
+		return report
+	}
+	newReportRerun := func(reportName string, rerunStart, rerunEnd time.Time) *v1alpha1.ReportRerun {
+		return &v1alpha1.ReportRerun{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-rerun", Namespace: namespace},
+			Spec: v1alpha1.ReportRerunSpec{
+				ReportName:     reportName,
+				ReportingStart: metav1.Time{Time: rerunStart},
+				ReportingEnd:   metav1.Time{Time: rerunEnd},
+			},
+		}
+	}
+	newGenQuery := func(modify func(*v1alpha1.ReportGenerationQuery)) *v1alpha1.ReportGenerationQuery {
+		genQuery := testhelpers.NewReportGenerationQuery("test-query", namespace, columns)
+		genQuery.Spec.Query = `SELECT * FROM {| dataSourceTableName "existing-datasource" |}`
+		if modify != nil {
+			modify(genQuery)
+		}
+		return genQuery
+	}
+
+	tests := map[string]struct {
+		kind      string
+		namespace string
+		obj       interface{}
+		// oldObj is set for UPDATE requests
+		oldObj        interface{}
+		expectAllowed bool
+	}{
+		"valid run-once report": {
+			kind:          "Report",
+			obj:           newReport(nil),
+			expectAllowed: true,
+		},
+		"report with reportingEnd before reportingStart": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.ReportingEnd = &metav1.Time{Time: start.Add(-time.Hour)}
+			}),
+		},
+		"report with invalid schedule": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Schedule = &v1alpha1.ReportSchedule{Period: v1alpha1.ReportPeriodCron}
+			}),
+		},
+		"report with both retention duration and periods": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				periods := int64(3)
+				report.Spec.Retention = &v1alpha1.RetentionPolicy{
+					Duration: &metav1.Duration{Duration: time.Hour},
+					Periods:  &periods,
+				}
+			}),
+		},
+		"report with valid export": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Exports = []v1alpha1.ReportExport{
+					{Name: "finance", S3: &v1alpha1.S3ReportExportTarget{Bucket: "finance"}},
+					{Name: "billing", HTTP: &v1alpha1.HTTPReportExportTarget{URL: "https://exports.example.com/billing"}},
+				}
+			}),
+			expectAllowed: true,
+		},
+		"report with http export to a host which isn't allowed": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Exports = []v1alpha1.ReportExport{{Name: "billing", HTTP: &v1alpha1.HTTPReportExportTarget{URL: "http://169.254.169.254/latest/meta-data"}}}
+			}),
+		},
+		"report with duplicate export names": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Exports = []v1alpha1.ReportExport{
+					{Name: "finance", S3: &v1alpha1.S3ReportExportTarget{Bucket: "finance"}},
+					{Name: "finance", HTTP: &v1alpha1.HTTPReportExportTarget{URL: "https://example.com"}},
+				}
+			}),
+		},
+		"report with export without a target": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Exports = []v1alpha1.ReportExport{{Name: "finance"}}
+			}),
+		},
+		"report with invalid tableSchemaChangePolicy": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.TableSchemaChangePolicy = "Ignore"
+			}),
+		},
+		"report partitioned by period": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.PartitionByPeriod = true
+			}),
+			expectAllowed: true,
+		},
+		"report partitioned by period with a report_period column": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.GenerationQueryName = partitionColumnQuery.Name
+				report.Spec.Inputs = nil
+				report.Spec.PartitionByPeriod = true
+			}),
+		},
+		"report with missing ReportGenerationQuery": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.GenerationQueryName = "does-not-exist"
+			}),
+			expectAllowed: true,
+		},
+		"invalid report with an unchanged spec": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Inputs = nil
+				report.Status.TableName = "report_default_test_report"
+			}),
+			oldObj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Inputs = nil
+			}),
+			expectAllowed: true,
+		},
+		"invalid report with a changed spec": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Inputs = nil
+			}),
+			oldObj: newReport(nil),
+		},
+		"invalid report being deleted": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Inputs = nil
+				report.DeletionTimestamp = &metav1.Time{Time: start}
+			}),
+			expectAllowed: true,
+		},
+		"report missing required input": {
+			kind: "Report",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Spec.Inputs = nil
+			}),
+		},
+		"report outside of target namespaces is not validated": {
+			kind:      "Report",
+			namespace: "other",
+			obj: newReport(func(report *v1alpha1.Report) {
+				report.Namespace = "other"
+				report.Spec.GenerationQueryName = "does-not-exist"
+			}),
+			expectAllowed: true,
+		},
+		"valid ReportGenerationQuery": {
+			kind: "ReportGenerationQuery",
+			obj: newGenQuery(func(genQuery *v1alpha1.ReportGenerationQuery) {
+				genQuery.Spec.DataSources = []string{"existing-datasource"}
+				genQuery.Spec.ReportQueries = []string{existingQuery.Name}
+			}),
+			expectAllowed: true,
+		},
+		"ReportGenerationQuery with unparseable template": {
+			kind: "ReportGenerationQuery",
+			obj: newGenQuery(func(genQuery *v1alpha1.ReportGenerationQuery) {
+				genQuery.Spec.Query = "SELECT {| invalid"
+			}),
+		},
+		"ReportGenerationQuery with missing datasource dependency": {
+			kind: "ReportGenerationQuery",
+			obj: newGenQuery(func(genQuery *v1alpha1.ReportGenerationQuery) {
+				genQuery.Spec.DataSources = []string{"does-not-exist"}
+			}),
+			expectAllowed: true,
+		},
+		"ReportGenerationQuery with unsupported input type": {
+			kind: "ReportGenerationQuery",
+			obj: newGenQuery(func(genQuery *v1alpha1.ReportGenerationQuery) {
+				genQuery.Spec.Inputs = []v1alpha1.ReportGenerationQueryInputDefinition{{Name: "foo", Type: "float"}}
+			}),
+		},
+		"valid promsum ReportDataSource": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{Query: "existing-promquery"},
+				},
+			},
+			expectAllowed: true,
+		},
+		"promsum ReportDataSource with missing ReportPrometheusQuery": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{Query: "does-not-exist"},
+				},
+			},
+			expectAllowed: true,
+		},
+		"remote write ReportDataSource without a query": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{
+						RemoteWrite: &v1alpha1.PrometheusRemoteWriteConfig{MetricName: "up"},
+					},
+				},
+			},
+			expectAllowed: true,
+		},
+		"remote write ReportDataSource with invalid regexp matcher": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{
+						RemoteWrite: &v1alpha1.PrometheusRemoteWriteConfig{
+							Matchers: []v1alpha1.PrometheusLabelMatcher{{Name: "job", Value: "(", Type: v1alpha1.PrometheusLabelMatchRegexp}},
+						},
+					},
+				},
+			},
+		},
+		"import checkpoint of a ReportDataSource with an invalid spec": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{},
+				},
+				Status: v1alpha1.ReportDataSourceStatus{
+					PrometheusMetricImportStatus: &v1alpha1.PrometheusMetricImportStatus{
+						ImportDataEndTime: &metav1.Time{Time: start},
+					},
+				},
+			},
+			oldObj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{},
+				},
+			},
+			expectAllowed: true,
+		},
+		"remote write ReportDataSource with relabel configs": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{
+						RemoteWrite: &v1alpha1.PrometheusRemoteWriteConfig{MetricName: "up"},
+						RelabelConfigs: []v1alpha1.PrometheusRelabelConfig{
+							{Regex: "pod_template_hash|uid", Action: v1alpha1.PrometheusRelabelLabelDrop},
+							{SourceLabels: []string{"instance"}, Regex: "(.*):.*", TargetLabel: "host"},
+						},
+					},
+				},
+			},
+			expectAllowed: true,
+		},
+		"remote write ReportDataSource with rollups": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{
+						RemoteWrite: &v1alpha1.PrometheusRemoteWriteConfig{MetricName: "up"},
+						Rollups:     &v1alpha1.PrometheusMetricsRollups{Hourly: true},
+					},
+				},
+			},
+		},
+		"ReportDataSource with invalid relabel config": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					Promsum: &v1alpha1.PrometheusMetricsDataSource{
+						RemoteWrite:    &v1alpha1.PrometheusRemoteWriteConfig{MetricName: "up"},
+						RelabelConfigs: []v1alpha1.PrometheusRelabelConfig{{Action: v1alpha1.PrometheusRelabelHashMod, TargetLabel: "shard"}},
+					},
+				},
+			},
+		},
+		"awsBilling ReportDataSource with retention": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+				Spec: v1alpha1.ReportDataSourceSpec{
+					AWSBilling: &v1alpha1.AWSBillingDataSource{Source: &v1alpha1.S3Bucket{Bucket: "bucket", Region: "us-east-1"}},
+					Retention:  &v1alpha1.RetentionPolicy{Duration: &metav1.Duration{Duration: time.Hour}},
+				},
+			},
+		},
+		"ReportDataSource without a source": {
+			kind: "ReportDataSource",
+			obj: &v1alpha1.ReportDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-datasource", Namespace: namespace},
+			},
+		},
+		"ReportPrometheusQuery with empty query": {
+			kind: "ReportPrometheusQuery",
+			obj: &v1alpha1.ReportPrometheusQuery{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-promquery", Namespace: namespace},
+			},
+		},
+		"external StorageLocation without a location": {
+			kind: "StorageLocation",
+			obj: &v1alpha1.StorageLocation{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-storage", Namespace: namespace},
+				Spec: v1alpha1.StorageLocationSpec{
+					Hive: &v1alpha1.HiveStorage{TableProperties: v1alpha1.TableProperties{External: true}},
+				},
+			},
+		},
+		"ReportRerun of a Report's periods": {
+			kind:          "ReportRerun",
+			obj:           newReportRerun(dailyReport.Name, start, start.AddDate(0, 0, 7)),
+			expectAllowed: true,
+		},
+		"ReportRerun with part of a Report's period": {
+			kind: "ReportRerun",
+			obj:  newReportRerun(dailyReport.Name, start, start.AddDate(0, 0, 7).Add(time.Hour)),
+		},
+		"ReportRerun with reportingEnd before reportingStart": {
+			kind: "ReportRerun",
+			obj:  newReportRerun(dailyReport.Name, start, start.AddDate(0, 0, -1)),
+		},
+		"ReportRerun of a Report which doesn't exist yet": {
+			kind:          "ReportRerun",
+			obj:           newReportRerun("does-not-exist", start, start.Add(time.Hour)),
+			expectAllowed: true,
+		},
+		"ReportNotification without a URL": {
+			kind: "ReportNotification",
+			obj: &v1alpha1.ReportNotification{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-notification", Namespace: namespace},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			reportGenerationQueryIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			reportIndexer := cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, reportGenerationQueryIndexer.Add(existingQuery))
+			require.NoError(t, reportGenerationQueryIndexer.Add(partitionColumnQuery))
+			require.NoError(t, reportIndexer.Add(dailyReport))
+
+			validator := newWebhookValidator(
+				logrus.New(),
+				listers.NewReportGenerationQueryLister(reportGenerationQueryIndexer),
+				listers.NewReportLister(reportIndexer),
+				[]string{namespace},
+				[]string{"exports.example.com"},
+			)
+			router := newWebhookRouter(validator)
+
+			requestNamespace := test.namespace
+			if requestNamespace == "" {
+				requestNamespace = namespace
+			}
+			raw, err := json.Marshal(test.obj)
+			require.NoError(t, err)
+			operation := "CREATE"
+			var oldRaw []byte
+			if test.oldObj != nil {
+				operation = "UPDATE"
+				oldRaw, err = json.Marshal(test.oldObj)
+				require.NoError(t, err)
+			}
+			body, err := json.Marshal(&admissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: admissionReviewAPIVersion, Kind: admissionReviewKind},
+				Request: &admissionRequest{
+					UID:       "test-uid",
+					Kind:      metav1.GroupVersionKind{Group: v1alpha1.GroupName, Version: v1alpha1.SchemeGroupVersion.Version, Kind: test.kind},
+					Namespace: requestNamespace,
+					Operation: operation,
+					Object:    runtime.RawExtension{Raw: raw},
+					OldObject: runtime.RawExtension{Raw: oldRaw},
+				},
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, WebhookValidateEndpoint, bytes.NewReader(body))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			var review admissionReview
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &review))
+			require.NotNil(t, review.Response)
+			assert.Equal(t, "test-uid", string(review.Response.UID))
+			assert.Equal(t, test.expectAllowed, review.Response.Allowed, "unexpected admission result: %+v", review.Response.Result)
+			if !test.expectAllowed {
+				require.NotNil(t, review.Response.Result)
+				assert.NotEmpty(t, review.Response.Result.Message)
+			}
+		})
+	}
+}
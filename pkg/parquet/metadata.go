@@ -0,0 +1,216 @@
+package parquet
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// The values of the enums in the Parquet thrift definitions, see
+// https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	physicalTypeBoolean   int32 = 0
+	physicalTypeInt64     int32 = 2
+	physicalTypeDouble    int32 = 5
+	physicalTypeByteArray int32 = 6
+
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+	repetitionRepeated int32 = 2
+
+	convertedTypeUTF8            int32 = 0
+	convertedTypeMap             int32 = 1
+	convertedTypeTimestampMillis int32 = 9
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	codecUncompressed int32 = 0
+
+	pageTypeDataPage int32 = 0
+)
+
+const createdBy = "operator-metering"
+
+type schemaElement struct {
+	name         string
+	hasType      bool
+	physicalType int32
+	// repetition is -1 for the root of the schema, which has no repetition.
+	repetition  int32
+	numChildren int32
+	// convertedType is -1 for elements without a converted type.
+	convertedType int32
+}
+
+type rowGroup struct {
+	columns       []columnChunk
+	totalByteSize int64
+	numRows       int64
+}
+
+type columnChunk struct {
+	path      []string
+	typ       int32
+	offset    int64
+	numValues int64
+	totalSize int64
+}
+
+// thriftEncoder wraps a thrift compact protocol, and records the first error
+// so that encoding functions don't need to check every write.
+type thriftEncoder struct {
+	buf   *thrift.TMemoryBuffer
+	proto *thrift.TCompactProtocol
+	err   error
+}
+
+func newThriftEncoder() *thriftEncoder {
+	buf := thrift.NewTMemoryBuffer()
+	return &thriftEncoder{buf: buf, proto: thrift.NewTCompactProtocol(buf)}
+}
+
+func (e *thriftEncoder) do(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *thriftEncoder) structBegin(name string) {
+	e.do(e.proto.WriteStructBegin(name))
+}
+
+func (e *thriftEncoder) structEnd() {
+	e.do(e.proto.WriteFieldStop())
+	e.do(e.proto.WriteStructEnd())
+}
+
+func (e *thriftEncoder) fieldBegin(name string, typ thrift.TType, id int16) {
+	e.do(e.proto.WriteFieldBegin(name, typ, id))
+}
+
+func (e *thriftEncoder) i32Field(name string, id int16, v int32) {
+	e.fieldBegin(name, thrift.I32, id)
+	e.do(e.proto.WriteI32(v))
+	e.do(e.proto.WriteFieldEnd())
+}
+
+func (e *thriftEncoder) i64Field(name string, id int16, v int64) {
+	e.fieldBegin(name, thrift.I64, id)
+	e.do(e.proto.WriteI64(v))
+	e.do(e.proto.WriteFieldEnd())
+}
+
+func (e *thriftEncoder) stringField(name string, id int16, v string) {
+	e.fieldBegin(name, thrift.STRING, id)
+	e.do(e.proto.WriteString(v))
+	e.do(e.proto.WriteFieldEnd())
+}
+
+func (e *thriftEncoder) listBegin(name string, id int16, elemType thrift.TType, size int) {
+	e.fieldBegin(name, thrift.LIST, id)
+	e.do(e.proto.WriteListBegin(elemType, size))
+}
+
+func (e *thriftEncoder) listEnd() {
+	e.do(e.proto.WriteListEnd())
+	e.do(e.proto.WriteFieldEnd())
+}
+
+func (e *thriftEncoder) bytes() ([]byte, error) {
+	e.do(e.proto.Flush())
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// encodeDataPageHeader encodes the PageHeader of an uncompressed data page.
+func encodeDataPageHeader(numValues, pageSize int32) ([]byte, error) {
+	e := newThriftEncoder()
+	e.structBegin("PageHeader")
+	e.i32Field("type", 1, pageTypeDataPage)
+	e.i32Field("uncompressed_page_size", 2, pageSize)
+	e.i32Field("compressed_page_size", 3, pageSize)
+
+	e.fieldBegin("data_page_header", thrift.STRUCT, 5)
+	e.structBegin("DataPageHeader")
+	e.i32Field("num_values", 1, numValues)
+	e.i32Field("encoding", 2, encodingPlain)
+	e.i32Field("definition_level_encoding", 3, encodingRLE)
+	e.i32Field("repetition_level_encoding", 4, encodingRLE)
+	e.structEnd()
+	e.do(e.proto.WriteFieldEnd())
+
+	e.structEnd()
+	return e.bytes()
+}
+
+// encodeFileMetaData encodes the FileMetaData written in the footer of a
+// file.
+func encodeFileMetaData(schema []schemaElement, numRows int64, rowGroups []rowGroup) ([]byte, error) {
+	e := newThriftEncoder()
+	e.structBegin("FileMetaData")
+	e.i32Field("version", 1, 1)
+
+	e.listBegin("schema", 2, thrift.STRUCT, len(schema))
+	for _, element := range schema {
+		e.structBegin("SchemaElement")
+		if element.hasType {
+			e.i32Field("type", 1, element.physicalType)
+		}
+		if element.repetition >= 0 {
+			e.i32Field("repetition_type", 3, element.repetition)
+		}
+		e.stringField("name", 4, element.name)
+		if element.numChildren > 0 {
+			e.i32Field("num_children", 5, element.numChildren)
+		}
+		if element.convertedType >= 0 {
+			e.i32Field("converted_type", 6, element.convertedType)
+		}
+		e.structEnd()
+	}
+	e.listEnd()
+
+	e.i64Field("num_rows", 3, numRows)
+
+	e.listBegin("row_groups", 4, thrift.STRUCT, len(rowGroups))
+	for _, group := range rowGroups {
+		e.structBegin("RowGroup")
+		e.listBegin("columns", 1, thrift.STRUCT, len(group.columns))
+		for _, chunk := range group.columns {
+			e.structBegin("ColumnChunk")
+			e.i64Field("file_offset", 2, chunk.offset)
+
+			e.fieldBegin("meta_data", thrift.STRUCT, 3)
+			e.structBegin("ColumnMetaData")
+			e.i32Field("type", 1, chunk.typ)
+			e.listBegin("encodings", 2, thrift.I32, 2)
+			e.do(e.proto.WriteI32(encodingPlain))
+			e.do(e.proto.WriteI32(encodingRLE))
+			e.listEnd()
+			e.listBegin("path_in_schema", 3, thrift.STRING, len(chunk.path))
+			for _, p := range chunk.path {
+				e.do(e.proto.WriteString(p))
+			}
+			e.listEnd()
+			e.i32Field("codec", 4, codecUncompressed)
+			e.i64Field("num_values", 5, chunk.numValues)
+			e.i64Field("total_uncompressed_size", 6, chunk.totalSize)
+			e.i64Field("total_compressed_size", 7, chunk.totalSize)
+			e.i64Field("data_page_offset", 9, chunk.offset)
+			e.structEnd()
+			e.do(e.proto.WriteFieldEnd())
+
+			e.structEnd()
+		}
+		e.listEnd()
+		e.i64Field("total_byte_size", 2, group.totalByteSize)
+		e.i64Field("num_rows", 3, group.numRows)
+		e.structEnd()
+	}
+	e.listEnd()
+
+	e.stringField("created_by", 6, createdBy)
+	e.structEnd()
+	return e.bytes()
+}
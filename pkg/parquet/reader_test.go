@@ -0,0 +1,481 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/stretchr/testify/require"
+)
+
+// This file contains a reader for the subset of the Parquet format used by
+// the writer, which is used to check the files it writes. It's written from
+// the format specification at https://github.com/apache/parquet-format and
+// shares no code with the writer: the metadata is decoded field by field
+// with the thrift library's compact protocol, the levels are decoded with
+// both the RLE and the bit-packed runs of the hybrid encoding, and the
+// columns are assembled into rows using the levels and the schema rather
+// than the writer's Columns.
+
+// Values of the Parquet thrift enums, repeated rather than shared with the
+// writer so that a wrong value in either is caught.
+const (
+	testTypeBoolean   = 0
+	testTypeInt64     = 2
+	testTypeDouble    = 5
+	testTypeByteArray = 6
+
+	testRepetitionRequired = 0
+	testRepetitionRepeated = 2
+
+	testConvertedUTF8            = 0
+	testConvertedMap             = 1
+	testConvertedTimestampMillis = 9
+
+	testEncodingPlain = 0
+	testEncodingRLE   = 3
+)
+
+type testSchemaNode struct {
+	name          string
+	physicalType  int32
+	repetition    int32
+	convertedType int32
+	numChildren   int32
+
+	children []*testSchemaNode
+	// path, maxDef and maxRep are computed from the node's ancestors.
+	path           []string
+	maxDef, maxRep int
+	// values are the values of a leaf node decoded from each column chunk
+	// of the current row group.
+	values []testLeafValue
+}
+
+type testLeafValue struct {
+	def, rep int
+	value    interface{}
+}
+
+type testColumnChunk struct {
+	path           []string
+	physicalType   int32
+	codec          int32
+	numValues      int64
+	dataPageOffset int64
+}
+
+type testRowGroup struct {
+	numRows int64
+	columns []testColumnChunk
+}
+
+// readTestFile decodes every row of a Parquet file. Each row contains every
+// top level column of the schema, with nil for null values.
+func readTestFile(t *testing.T, file []byte) []map[string]interface{} {
+	require.True(t, len(file) > 12, "file is too small")
+	require.Equal(t, "PAR1", string(file[:4]))
+	require.Equal(t, "PAR1", string(file[len(file)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	require.True(t, footerLen+12 <= len(file), "invalid footer length")
+	proto, _ := newTestProtocol(t, file[len(file)-8-footerLen:len(file)-8])
+
+	var (
+		elements  []*testSchemaNode
+		numRows   int64
+		rowGroups []testRowGroup
+	)
+	readStruct(t, proto, func(id int16, typ thrift.TType) {
+		switch id {
+		case 2:
+			readList(t, proto, func() {
+				node := &testSchemaNode{physicalType: -1, repetition: -1, convertedType: -1}
+				readStruct(t, proto, func(id int16, typ thrift.TType) {
+					switch id {
+					case 1:
+						node.physicalType = readTestI32(t, proto)
+					case 3:
+						node.repetition = readTestI32(t, proto)
+					case 4:
+						name, err := proto.ReadString()
+						require.NoError(t, err)
+						node.name = name
+					case 5:
+						node.numChildren = readTestI32(t, proto)
+					case 6:
+						node.convertedType = readTestI32(t, proto)
+					default:
+						require.NoError(t, proto.Skip(typ))
+					}
+				})
+				elements = append(elements, node)
+			})
+		case 3:
+			numRows = readTestI64(t, proto)
+		case 4:
+			readList(t, proto, func() {
+				rowGroups = append(rowGroups, readTestRowGroup(t, proto))
+			})
+		default:
+			require.NoError(t, proto.Skip(typ))
+		}
+	})
+
+	require.NotEmpty(t, elements, "expected a schema")
+	root := elements[0]
+	rest := buildTestSchema(t, root, elements[1:])
+	require.Empty(t, rest, "expected every schema element to be a descendant of the root")
+	var leaves []*testSchemaNode
+	collectTestLeaves(root, &leaves)
+
+	var rows []map[string]interface{}
+	for _, group := range rowGroups {
+		require.Len(t, group.columns, len(leaves), "expected a column chunk for each leaf of the schema")
+		for i, leaf := range leaves {
+			chunk := group.columns[i]
+			require.Equal(t, leaf.path, chunk.path)
+			require.Equal(t, leaf.physicalType, chunk.physicalType)
+			require.Equal(t, int32(0), chunk.codec, "expected column chunks to be uncompressed")
+			leaf.values = readTestColumnChunk(t, file, leaf, chunk)
+		}
+		groupRows := assembleTestRows(t, root)
+		require.Len(t, groupRows, int(group.numRows))
+		rows = append(rows, groupRows...)
+	}
+	require.Len(t, rows, int(numRows))
+	return rows
+}
+
+func newTestProtocol(t *testing.T, b []byte) (thrift.TProtocol, *thrift.TMemoryBuffer) {
+	buf := thrift.NewTMemoryBuffer()
+	_, err := buf.Write(b)
+	require.NoError(t, err)
+	return thrift.NewTCompactProtocol(buf), buf
+}
+
+func readTestI32(t *testing.T, proto thrift.TProtocol) int32 {
+	v, err := proto.ReadI32()
+	require.NoError(t, err)
+	return v
+}
+
+func readTestI64(t *testing.T, proto thrift.TProtocol) int64 {
+	v, err := proto.ReadI64()
+	require.NoError(t, err)
+	return v
+}
+
+func readTestRowGroup(t *testing.T, proto thrift.TProtocol) testRowGroup {
+	var group testRowGroup
+	readStruct(t, proto, func(id int16, typ thrift.TType) {
+		switch id {
+		case 1:
+			readList(t, proto, func() {
+				var chunk testColumnChunk
+				readStruct(t, proto, func(id int16, typ thrift.TType) {
+					if id != 3 {
+						require.NoError(t, proto.Skip(typ))
+						return
+					}
+					readStruct(t, proto, func(id int16, typ thrift.TType) {
+						switch id {
+						case 1:
+							chunk.physicalType = readTestI32(t, proto)
+						case 3:
+							readList(t, proto, func() {
+								p, err := proto.ReadString()
+								require.NoError(t, err)
+								chunk.path = append(chunk.path, p)
+							})
+						case 4:
+							chunk.codec = readTestI32(t, proto)
+						case 5:
+							chunk.numValues = readTestI64(t, proto)
+						case 9:
+							chunk.dataPageOffset = readTestI64(t, proto)
+						default:
+							require.NoError(t, proto.Skip(typ))
+						}
+					})
+				})
+				group.columns = append(group.columns, chunk)
+			})
+		case 3:
+			group.numRows = readTestI64(t, proto)
+		default:
+			require.NoError(t, proto.Skip(typ))
+		}
+	})
+	return group
+}
+
+// buildTestSchema adds the children of parent from the depth first list of
+// schema elements, and returns the elements which follow them.
+func buildTestSchema(t *testing.T, parent *testSchemaNode, elements []*testSchemaNode) []*testSchemaNode {
+	for i := int32(0); i < parent.numChildren; i++ {
+		require.NotEmpty(t, elements, "schema element %q is missing children", parent.name)
+		child := elements[0]
+		elements = elements[1:]
+		require.NotEqual(t, int32(-1), child.repetition, "schema element %q has no repetition", child.name)
+		child.path = append(append([]string{}, parent.path...), child.name)
+		child.maxDef, child.maxRep = parent.maxDef, parent.maxRep
+		if child.repetition != testRepetitionRequired {
+			child.maxDef++
+		}
+		if child.repetition == testRepetitionRepeated {
+			child.maxRep++
+		}
+		parent.children = append(parent.children, child)
+		elements = buildTestSchema(t, child, elements)
+	}
+	return elements
+}
+
+func collectTestLeaves(node *testSchemaNode, leaves *[]*testSchemaNode) {
+	if len(node.children) == 0 {
+		*leaves = append(*leaves, node)
+		return
+	}
+	for _, child := range node.children {
+		collectTestLeaves(child, leaves)
+	}
+}
+
+// readTestColumnChunk decodes the levels and values of every data page in a
+// column chunk.
+func readTestColumnChunk(t *testing.T, file []byte, leaf *testSchemaNode, chunk testColumnChunk) []testLeafValue {
+	var values []testLeafValue
+	offset := chunk.dataPageOffset
+	for int64(len(values)) < chunk.numValues {
+		require.True(t, offset >= 4 && offset < int64(len(file)), "invalid page offset %d", offset)
+		proto, buf := newTestProtocol(t, file[offset:])
+		var pageType, uncompressedSize, compressedSize, numValues, encoding, defEncoding, repEncoding int32 = -1, -1, -1, -1, -1, -1, -1
+		readStruct(t, proto, func(id int16, typ thrift.TType) {
+			switch id {
+			case 1:
+				pageType = readTestI32(t, proto)
+			case 2:
+				uncompressedSize = readTestI32(t, proto)
+			case 3:
+				compressedSize = readTestI32(t, proto)
+			case 5:
+				readStruct(t, proto, func(id int16, typ thrift.TType) {
+					switch id {
+					case 1:
+						numValues = readTestI32(t, proto)
+					case 2:
+						encoding = readTestI32(t, proto)
+					case 3:
+						defEncoding = readTestI32(t, proto)
+					case 4:
+						repEncoding = readTestI32(t, proto)
+					default:
+						require.NoError(t, proto.Skip(typ))
+					}
+				})
+			default:
+				require.NoError(t, proto.Skip(typ))
+			}
+		})
+		require.Equal(t, int32(0), pageType, "expected a data page")
+		require.Equal(t, uncompressedSize, compressedSize)
+		require.Equal(t, int32(testEncodingPlain), encoding)
+		require.Equal(t, int32(testEncodingRLE), defEncoding)
+		require.Equal(t, int32(testEncodingRLE), repEncoding)
+		require.True(t, compressedSize >= 0 && int(compressedSize) <= buf.Len(), "invalid page size %d", compressedSize)
+		headerLen := int64(len(file[offset:]) - buf.Len())
+		page := file[offset+headerLen : offset+headerLen+int64(compressedSize)]
+		offset += headerLen + int64(compressedSize)
+
+		n := int(numValues)
+		require.True(t, n > 0, "expected data pages to contain values")
+		reps := make([]int, n)
+		if leaf.maxRep > 0 {
+			reps, page = readTestLevels(t, page, leaf.maxRep, n)
+		}
+		defs := make([]int, n)
+		if leaf.maxDef > 0 {
+			defs, page = readTestLevels(t, page, leaf.maxDef, n)
+		}
+		var numDefined int
+		for _, def := range defs {
+			require.True(t, def <= leaf.maxDef, "definition level %d is greater than %d", def, leaf.maxDef)
+			if def == leaf.maxDef {
+				numDefined++
+			}
+		}
+		decoded := readTestPlainValues(t, page, leaf, numDefined)
+		for i := 0; i < n; i++ {
+			v := testLeafValue{def: defs[i], rep: reps[i]}
+			if defs[i] == leaf.maxDef {
+				v.value, decoded = decoded[0], decoded[1:]
+			}
+			values = append(values, v)
+		}
+	}
+	require.Len(t, values, int(chunk.numValues))
+	return values
+}
+
+// readTestLevels decodes n levels stored with the RLE/bit-packing hybrid
+// encoding, prefixed by their length, and returns them with the rest of the
+// page.
+func readTestLevels(t *testing.T, page []byte, maxLevel, n int) ([]int, []byte) {
+	require.True(t, len(page) >= 4, "page is too small")
+	length := int(binary.LittleEndian.Uint32(page))
+	require.True(t, length <= len(page)-4, "invalid levels length %d", length)
+	data, rest := page[4:4+length], page[4+length:]
+
+	bitWidth := 0
+	for v := maxLevel; v > 0; v >>= 1 {
+		bitWidth++
+	}
+	var levels []int
+	for len(levels) < n {
+		header, k := binary.Uvarint(data)
+		require.True(t, k > 0, "invalid run header")
+		data = data[k:]
+		if header&1 == 0 {
+			// an RLE run of a value stored in little endian
+			byteWidth := (bitWidth + 7) / 8
+			require.True(t, len(data) >= byteWidth, "RLE run is truncated")
+			var v int
+			for b := 0; b < byteWidth; b++ {
+				v |= int(data[b]) << uint(8*b)
+			}
+			data = data[byteWidth:]
+			for i := uint64(0); i < header>>1; i++ {
+				levels = append(levels, v)
+			}
+			continue
+		}
+		// bit-packed groups of 8 values, starting with the least
+		// significant bit
+		numGroups := int(header >> 1)
+		numBytes := numGroups * bitWidth
+		require.True(t, len(data) >= numBytes, "bit-packed run is truncated")
+		for i := 0; i < numGroups*8; i++ {
+			var v int
+			for b := 0; b < bitWidth; b++ {
+				bit := i*bitWidth + b
+				if data[bit/8]&(1<<uint(bit%8)) != 0 {
+					v |= 1 << uint(b)
+				}
+			}
+			levels = append(levels, v)
+		}
+		data = data[numBytes:]
+	}
+	require.Empty(t, data, "unexpected data after the levels")
+	// the last bit-packed group may be padded
+	return levels[:n], rest
+}
+
+func readTestPlainValues(t *testing.T, page []byte, leaf *testSchemaNode, n int) []interface{} {
+	var values []interface{}
+	for i := 0; i < n; i++ {
+		switch leaf.physicalType {
+		case testTypeBoolean:
+			require.True(t, i/8 < len(page), "page is too small")
+			values = append(values, page[i/8]&(1<<uint(i%8)) != 0)
+		case testTypeInt64, testTypeDouble:
+			require.True(t, len(page) >= 8, "page is too small")
+			bits := binary.LittleEndian.Uint64(page)
+			page = page[8:]
+			switch {
+			case leaf.physicalType == testTypeDouble:
+				values = append(values, math.Float64frombits(bits))
+			case leaf.convertedType == testConvertedTimestampMillis:
+				values = append(values, time.Unix(0, int64(bits)*int64(time.Millisecond)).UTC())
+			default:
+				values = append(values, int64(bits))
+			}
+		case testTypeByteArray:
+			require.True(t, len(page) >= 4, "page is too small")
+			length := int(binary.LittleEndian.Uint32(page))
+			require.True(t, len(page) >= 4+length, "page is too small")
+			require.Equal(t, int32(testConvertedUTF8), leaf.convertedType, "expected byte arrays to be strings")
+			values = append(values, string(page[4:4+length]))
+			page = page[4+length:]
+		default:
+			t.Fatalf("unsupported physical type %d", leaf.physicalType)
+		}
+	}
+	if leaf.physicalType == testTypeBoolean {
+		require.Len(t, page, (n+7)/8, "unexpected data after the values")
+	} else {
+		require.Empty(t, page, "unexpected data after the values")
+	}
+	return values
+}
+
+// assembleTestRows assembles the values decoded for the leaves of root into
+// rows. Only optional primitive columns and maps are supported.
+func assembleTestRows(t *testing.T, root *testSchemaNode) []map[string]interface{} {
+	var rows []map[string]interface{}
+	positions := make(map[*testSchemaNode]int)
+	for {
+		var row map[string]interface{}
+		for _, column := range root.children {
+			if len(column.children) == 0 {
+				require.Equal(t, 0, column.maxRep)
+				i := positions[column]
+				if i == len(column.values) {
+					continue
+				}
+				positions[column]++
+				if row == nil {
+					row = make(map[string]interface{})
+				}
+				row[column.name] = column.values[i].value
+				continue
+			}
+
+			require.Equal(t, int32(testConvertedMap), column.convertedType, "column %q: expected a map", column.name)
+			require.Len(t, column.children, 1, "column %q: expected a repeated key_value group", column.name)
+			keyValue := column.children[0]
+			require.Equal(t, int32(testRepetitionRepeated), keyValue.repetition)
+			require.Len(t, keyValue.children, 2, "column %q: expected a key and a value", column.name)
+			key, value := keyValue.children[0], keyValue.children[1]
+			require.Equal(t, "key", key.name)
+			require.Equal(t, int32(testRepetitionRequired), key.repetition)
+			require.Equal(t, "value", value.name)
+			require.Len(t, value.values, len(key.values), "column %q: expected a value for each key", column.name)
+
+			i := positions[key]
+			if i == len(key.values) {
+				continue
+			}
+			if row == nil {
+				row = make(map[string]interface{})
+			}
+			require.Equal(t, 0, key.values[i].rep, "column %q: expected a new row", column.name)
+			switch {
+			case key.values[i].def < column.maxDef:
+				row[column.name] = nil
+				i++
+			case key.values[i].def < keyValue.maxDef:
+				row[column.name] = map[string]interface{}{}
+				i++
+			default:
+				m := make(map[string]interface{})
+				for ; i < len(key.values) && (len(m) == 0 || key.values[i].rep > 0); i++ {
+					require.Equal(t, key.maxDef, key.values[i].def, "column %q: expected a key", column.name)
+					require.Equal(t, key.values[i].rep, value.values[i].rep, "column %q: expected the key and value levels to match", column.name)
+					require.True(t, value.values[i].def >= keyValue.maxDef, "column %q: expected a value or null for each key", column.name)
+					m[key.values[i].value.(string)] = value.values[i].value
+				}
+				row[column.name] = m
+			}
+			positions[key], positions[value] = i, i
+		}
+		if row == nil {
+			break
+		}
+		require.Len(t, row, len(root.children), "expected a value for every column")
+		rows = append(rows, row)
+	}
+	return rows
+}
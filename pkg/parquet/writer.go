@@ -0,0 +1,557 @@
+// Package parquet implements a minimal writer for Apache Parquet files.
+//
+// Files are written using the PLAIN encoding without compression, with a
+// single data page per column chunk. Every column is optional, and map
+// columns use the MAP logical type.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Type is the type of a column.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+	Timestamp
+	Map
+)
+
+func (t Type) String() string {
+	switch t {
+	case Boolean:
+		return "boolean"
+	case Int64:
+		return "int64"
+	case Double:
+		return "double"
+	case String:
+		return "string"
+	case Timestamp:
+		return "timestamp"
+	case Map:
+		return "map"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Column is a column of a Parquet file.
+type Column struct {
+	Name string
+	Type Type
+	// KeyType and ValueType are the types of the keys and values of Map
+	// columns. KeyType must be String, and ValueType can't be Map.
+	KeyType   Type
+	ValueType Type
+}
+
+var magic = []byte("PAR1")
+
+// Writer writes rows to a Parquet file. Rows are buffered in memory until
+// Flush is called, which writes them to the file as a row group.
+type Writer struct {
+	w       *countingWriter
+	columns []Column
+	// leaves are the leaf columns of the schema, in the order their column
+	// chunks are written in each row group.
+	leaves    []*leafColumn
+	rowGroups []rowGroup
+	numRows   int64
+	// bufferedRows is the number of rows written since the last row group.
+	bufferedRows int64
+}
+
+// NewWriter returns a Writer which writes a Parquet file with columns to w.
+// Nothing is written to w until Flush or Close are called.
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	writer := &Writer{
+		w:       &countingWriter{w: w},
+		columns: columns,
+	}
+	names := make(map[string]bool)
+	for _, column := range columns {
+		if column.Name == "" {
+			return nil, fmt.Errorf("column names can't be empty")
+		}
+		if names[column.Name] {
+			return nil, fmt.Errorf("duplicate column %q", column.Name)
+		}
+		names[column.Name] = true
+		switch column.Type {
+		case Boolean, Int64, Double, String, Timestamp:
+			writer.leaves = append(writer.leaves, &leafColumn{
+				path:     []string{column.Name},
+				typ:      column.Type,
+				maxDef:   1,
+				maxRep:   0,
+				required: false,
+			})
+		case Map:
+			if column.KeyType != String {
+				return nil, fmt.Errorf("column %q: map keys must be strings, got %s", column.Name, column.KeyType)
+			}
+			if column.ValueType == Map || column.ValueType < Boolean || column.ValueType > Map {
+				return nil, fmt.Errorf("column %q: invalid map value type %s", column.Name, column.ValueType)
+			}
+			// the key of each entry in a map is required, but the map
+			// itself and the values are optional, and the entries are
+			// repeated.
+			writer.leaves = append(writer.leaves,
+				&leafColumn{path: []string{column.Name, "key_value", "key"}, typ: column.KeyType, maxDef: 2, maxRep: 1, required: true},
+				&leafColumn{path: []string{column.Name, "key_value", "value"}, typ: column.ValueType, maxDef: 3, maxRep: 1},
+			)
+		default:
+			return nil, fmt.Errorf("column %q: invalid type %s", column.Name, column.Type)
+		}
+	}
+	return writer, nil
+}
+
+// BufferedRows returns the number of rows which haven't been written to the
+// file as a row group yet.
+func (w *Writer) BufferedRows() int64 {
+	return w.bufferedRows
+}
+
+// Write adds a row to the file. Columns missing from row are null. If an
+// error is returned, none of the row's values are added.
+func (w *Writer) Write(row map[string]interface{}) error {
+	marks := make([]leafMark, len(w.leaves))
+	for i, leaf := range w.leaves {
+		marks[i] = leaf.mark()
+	}
+	if err := w.write(row); err != nil {
+		for i, leaf := range w.leaves {
+			leaf.reset(marks[i])
+		}
+		return err
+	}
+	w.bufferedRows++
+	return nil
+}
+
+func (w *Writer) write(row map[string]interface{}) error {
+	leafIdx := 0
+	for _, column := range w.columns {
+		val := row[column.Name]
+		if column.Type != Map {
+			leaf := w.leaves[leafIdx]
+			leafIdx++
+			if err := leaf.append(val, 0, 0); err != nil {
+				return fmt.Errorf("column %q: %v", column.Name, err)
+			}
+			continue
+		}
+
+		keyLeaf, valueLeaf := w.leaves[leafIdx], w.leaves[leafIdx+1]
+		leafIdx += 2
+		if val == nil {
+			keyLeaf.appendNull(0, 0)
+			valueLeaf.appendNull(0, 0)
+			continue
+		}
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			if sm, isStringMap := val.(map[string]string); isStringMap {
+				m = make(map[string]interface{}, len(sm))
+				for k, v := range sm {
+					m[k] = v
+				}
+			} else {
+				return fmt.Errorf("column %q: cannot convert %v (%T) to map", column.Name, val, val)
+			}
+		}
+		if len(m) == 0 {
+			// the map is defined, but has no entries
+			keyLeaf.appendNull(1, 0)
+			valueLeaf.appendNull(1, 0)
+			continue
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			// the first entry starts a new row, the rest repeat the map
+			var rep uint8
+			if i > 0 {
+				rep = 1
+			}
+			if err := keyLeaf.append(k, 1, rep); err != nil {
+				return fmt.Errorf("column %q: %v", column.Name, err)
+			}
+			if err := valueLeaf.append(m[k], 2, rep); err != nil {
+				return fmt.Errorf("column %q, key %q: %v", column.Name, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Flush writes the buffered rows to the file as a row group.
+func (w *Writer) Flush() error {
+	if w.bufferedRows == 0 {
+		return nil
+	}
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+	group := rowGroup{numRows: w.bufferedRows}
+	for _, leaf := range w.leaves {
+		chunk, err := leaf.writeChunk(w.w)
+		if err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		group.totalByteSize += chunk.totalSize
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += w.bufferedRows
+	w.bufferedRows = 0
+	return nil
+}
+
+// Close writes any buffered rows and the file's footer. It doesn't close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+	footer, err := encodeFileMetaData(w.schema(), w.numRows, w.rowGroups)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(w.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	_, err = w.w.Write(magic)
+	return err
+}
+
+// writeMagic writes the magic number at the start of the file, if it hasn't
+// been written yet.
+func (w *Writer) writeMagic() error {
+	if w.w.n != 0 {
+		return nil
+	}
+	_, err := w.w.Write(magic)
+	return err
+}
+
+// schema returns the flattened schema of the file, in depth first order
+// starting with the root.
+func (w *Writer) schema() []schemaElement {
+	elements := []schemaElement{{name: "schema", repetition: -1, numChildren: int32(len(w.columns)), convertedType: -1}}
+	for _, column := range w.columns {
+		if column.Type != Map {
+			elements = append(elements, primitiveSchemaElement(column.Name, column.Type, repetitionOptional))
+			continue
+		}
+		elements = append(elements,
+			schemaElement{name: column.Name, repetition: repetitionOptional, numChildren: 1, convertedType: convertedTypeMap},
+			schemaElement{name: "key_value", repetition: repetitionRepeated, numChildren: 2, convertedType: -1},
+			primitiveSchemaElement("key", column.KeyType, repetitionRequired),
+			primitiveSchemaElement("value", column.ValueType, repetitionOptional),
+		)
+	}
+	return elements
+}
+
+func primitiveSchemaElement(name string, typ Type, repetition int32) schemaElement {
+	element := schemaElement{name: name, repetition: repetition, hasType: true, convertedType: -1}
+	switch typ {
+	case Boolean:
+		element.physicalType = physicalTypeBoolean
+	case Int64:
+		element.physicalType = physicalTypeInt64
+	case Double:
+		element.physicalType = physicalTypeDouble
+	case String:
+		element.physicalType = physicalTypeByteArray
+		element.convertedType = convertedTypeUTF8
+	case Timestamp:
+		element.physicalType = physicalTypeInt64
+		element.convertedType = convertedTypeTimestampMillis
+	}
+	return element
+}
+
+// leafColumn buffers the levels and values of a leaf column of the schema
+// until they're written as a column chunk.
+type leafColumn struct {
+	path     []string
+	typ      Type
+	maxDef   uint8
+	maxRep   uint8
+	required bool
+
+	defLevels []uint8
+	repLevels []uint8
+	// values are the PLAIN encoded non-null values, except for booleans
+	// which are bit packed when the chunk is written.
+	values   bytes.Buffer
+	booleans []bool
+}
+
+// leafMark records the amount of data buffered by a leafColumn.
+type leafMark struct {
+	levels, values, booleans int
+}
+
+func (c *leafColumn) mark() leafMark {
+	return leafMark{levels: len(c.defLevels), values: c.values.Len(), booleans: len(c.booleans)}
+}
+
+// reset discards the data buffered after m was recorded.
+func (c *leafColumn) reset(m leafMark) {
+	c.defLevels = c.defLevels[:m.levels]
+	c.repLevels = c.repLevels[:m.levels]
+	c.values.Truncate(m.values)
+	c.booleans = c.booleans[:m.booleans]
+}
+
+// appendNull adds a null value, or an undefined ancestor of the value, at
+// the given definition and repetition levels.
+func (c *leafColumn) appendNull(def, rep uint8) {
+	c.defLevels = append(c.defLevels, def)
+	c.repLevels = append(c.repLevels, rep)
+}
+
+// append adds val, where parentDef is the definition level of the value's
+// parent.
+func (c *leafColumn) append(val interface{}, parentDef, rep uint8) error {
+	if val == nil {
+		if c.required {
+			return fmt.Errorf("value is required")
+		}
+		c.appendNull(parentDef, rep)
+		return nil
+	}
+	if err := c.encodeValue(val); err != nil {
+		return err
+	}
+	c.defLevels = append(c.defLevels, c.maxDef)
+	c.repLevels = append(c.repLevels, rep)
+	return nil
+}
+
+func (c *leafColumn) encodeValue(val interface{}) error {
+	var b [8]byte
+	switch c.typ {
+	case Boolean:
+		v, ok := val.(bool)
+		if !ok {
+			return fmt.Errorf("cannot convert %v (%T) to %s", val, val, c.typ)
+		}
+		c.booleans = append(c.booleans, v)
+	case Int64:
+		v, ok := toInt64(val)
+		if !ok {
+			return fmt.Errorf("cannot convert %v (%T) to %s", val, val, c.typ)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(v))
+		c.values.Write(b[:])
+	case Double:
+		v, ok := toFloat64(val)
+		if !ok {
+			return fmt.Errorf("cannot convert %v (%T) to %s", val, val, c.typ)
+		}
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+		c.values.Write(b[:])
+	case String:
+		var v string
+		switch s := val.(type) {
+		case string:
+			v = s
+		case []byte:
+			v = string(s)
+		default:
+			return fmt.Errorf("cannot convert %v (%T) to %s", val, val, c.typ)
+		}
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(v)))
+		c.values.Write(b[:4])
+		c.values.WriteString(v)
+	case Timestamp:
+		var t time.Time
+		switch v := val.(type) {
+		case time.Time:
+			t = v
+		case string:
+			var err error
+			t, err = time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return fmt.Errorf("cannot convert %q to %s: %v", v, c.typ, err)
+			}
+		default:
+			return fmt.Errorf("cannot convert %v (%T) to %s", val, val, c.typ)
+		}
+		millis := t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+		binary.LittleEndian.PutUint64(b[:], uint64(millis))
+		c.values.Write(b[:])
+	default:
+		return fmt.Errorf("invalid type %s", c.typ)
+	}
+	return nil
+}
+
+// writeChunk writes the buffered values as a column chunk containing a
+// single data page, and resets the buffers.
+func (c *leafColumn) writeChunk(w *countingWriter) (columnChunk, error) {
+	var page bytes.Buffer
+	if c.maxRep > 0 {
+		writeLevels(&page, c.repLevels, c.maxRep)
+	}
+	if c.maxDef > 0 {
+		writeLevels(&page, c.defLevels, c.maxDef)
+	}
+	if c.typ == Boolean {
+		page.Write(bitPackBooleans(c.booleans))
+	} else {
+		page.Write(c.values.Bytes())
+	}
+
+	numValues := len(c.defLevels)
+	header, err := encodeDataPageHeader(int32(numValues), int32(page.Len()))
+	if err != nil {
+		return columnChunk{}, err
+	}
+	chunk := columnChunk{
+		path:      c.path,
+		typ:       primitiveSchemaElement("", c.typ, 0).physicalType,
+		offset:    w.n,
+		numValues: int64(numValues),
+		totalSize: int64(len(header) + page.Len()),
+	}
+	if _, err := w.Write(header); err != nil {
+		return columnChunk{}, err
+	}
+	if _, err := w.Write(page.Bytes()); err != nil {
+		return columnChunk{}, err
+	}
+
+	c.defLevels = c.defLevels[:0]
+	c.repLevels = c.repLevels[:0]
+	c.values.Reset()
+	c.booleans = c.booleans[:0]
+	return chunk, nil
+}
+
+// writeLevels writes levels using the RLE/bit-packing hybrid encoding,
+// prefixed with its length as required in data pages. Only RLE runs are
+// used, which is simple and compact for levels, which are mostly repeated.
+func writeLevels(buf *bytes.Buffer, levels []uint8, maxLevel uint8) {
+	bitWidth := 0
+	for v := maxLevel; v > 0; v >>= 1 {
+		bitWidth++
+	}
+	byteWidth := (bitWidth + 7) / 8
+
+	var encoded bytes.Buffer
+	var varint [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(varint[:], uint64(j-i)<<1)
+		encoded.Write(varint[:n])
+		for b := 0; b < byteWidth; b++ {
+			encoded.WriteByte(levels[i] >> uint(8*b))
+		}
+		i = j
+	}
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(encoded.Len()))
+	buf.Write(length[:])
+	buf.Write(encoded.Bytes())
+}
+
+// bitPackBooleans encodes values using the PLAIN encoding for booleans, one
+// bit per value starting with the least significant bit.
+func bitPackBooleans(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return packed
+}
+
+func toInt64(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt64(val); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// countingWriter counts the bytes written, which are used as the offsets of
+// column chunks.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
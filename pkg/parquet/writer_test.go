@@ -0,0 +1,318 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileMetaData is the subset of the FileMetaData decoded by the tests.
+type fileMetaData struct {
+	schemaNames []string
+	numRows     int64
+	// chunkOffsets are the offsets of each column chunk in each row group.
+	chunkOffsets [][]int64
+}
+
+func readFileMetaData(t *testing.T, file []byte) fileMetaData {
+	require.True(t, len(file) > 12, "file is too small")
+	require.Equal(t, magic, file[:4], "expected the file to start with the magic number")
+	require.Equal(t, magic, file[len(file)-4:], "expected the file to end with the magic number")
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8 : len(file)-4]))
+	footer := file[len(file)-8-footerLen : len(file)-8]
+
+	buf := thrift.NewTMemoryBuffer()
+	_, err := buf.Write(footer)
+	require.NoError(t, err)
+	proto := thrift.NewTCompactProtocol(buf)
+
+	var md fileMetaData
+	readStruct(t, proto, func(id int16, typ thrift.TType) {
+		switch id {
+		case 2:
+			readList(t, proto, func() {
+				readStruct(t, proto, func(id int16, typ thrift.TType) {
+					if id == 4 {
+						name, err := proto.ReadString()
+						require.NoError(t, err)
+						md.schemaNames = append(md.schemaNames, name)
+						return
+					}
+					require.NoError(t, proto.Skip(typ))
+				})
+			})
+		case 3:
+			md.numRows, err = proto.ReadI64()
+			require.NoError(t, err)
+		case 4:
+			readList(t, proto, func() {
+				var offsets []int64
+				readStruct(t, proto, func(id int16, typ thrift.TType) {
+					if id != 1 {
+						require.NoError(t, proto.Skip(typ))
+						return
+					}
+					readList(t, proto, func() {
+						readStruct(t, proto, func(id int16, typ thrift.TType) {
+							if id == 2 {
+								offset, err := proto.ReadI64()
+								require.NoError(t, err)
+								offsets = append(offsets, offset)
+								return
+							}
+							require.NoError(t, proto.Skip(typ))
+						})
+					})
+				})
+				md.chunkOffsets = append(md.chunkOffsets, offsets)
+			})
+		default:
+			require.NoError(t, proto.Skip(typ))
+		}
+	})
+	return md
+}
+
+func readStruct(t *testing.T, proto thrift.TProtocol, readField func(id int16, typ thrift.TType)) {
+	_, err := proto.ReadStructBegin()
+	require.NoError(t, err)
+	for {
+		_, typ, id, err := proto.ReadFieldBegin()
+		require.NoError(t, err)
+		if typ == thrift.STOP {
+			break
+		}
+		readField(id, typ)
+		require.NoError(t, proto.ReadFieldEnd())
+	}
+	require.NoError(t, proto.ReadStructEnd())
+}
+
+func readList(t *testing.T, proto thrift.TProtocol, readElem func()) {
+	_, size, err := proto.ReadListBegin()
+	require.NoError(t, err)
+	for i := 0; i < size; i++ {
+		readElem()
+	}
+	require.NoError(t, proto.ReadListEnd())
+}
+
+var (
+	testColumns = []Column{
+		{Name: "namespace", Type: String},
+		{Name: "pods", Type: Int64},
+		{Name: "cpu", Type: Double},
+		{Name: "running", Type: Boolean},
+		{Name: "period_start", Type: Timestamp},
+		{Name: "labels", Type: Map, KeyType: String, ValueType: String},
+	}
+	testPeriodStart = time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testRows        = []map[string]interface{}{
+		{
+			"namespace":    "default",
+			"pods":         int64(2),
+			"cpu":          1.5,
+			"running":      true,
+			"period_start": testPeriodStart,
+			"labels":       map[string]interface{}{"app": "foo", "tier": "web"},
+		},
+		{
+			"namespace":    "kube-system",
+			"pods":         int64(5),
+			"cpu":          float64(3),
+			"running":      false,
+			"period_start": testPeriodStart,
+			"labels":       map[string]interface{}{},
+		},
+		{
+			"namespace": "metering",
+		},
+	}
+)
+
+func TestWriter(t *testing.T) {
+	rows := testRows
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+	for i, row := range rows {
+		require.NoError(t, w.Write(row))
+		// write the last row in a second row group
+		if i == 1 {
+			assert.Equal(t, int64(2), w.BufferedRows())
+			require.NoError(t, w.Flush())
+			assert.Equal(t, int64(0), w.BufferedRows())
+		}
+	}
+	require.NoError(t, w.Close())
+
+	md := readFileMetaData(t, buf.Bytes())
+	assert.Equal(t, int64(len(rows)), md.numRows)
+	assert.Equal(t, []string{"schema", "namespace", "pods", "cpu", "running", "period_start", "labels", "key_value", "key", "value"}, md.schemaNames)
+	require.Len(t, md.chunkOffsets, 2, "expected 2 row groups")
+	for _, offsets := range md.chunkOffsets {
+		assert.Len(t, offsets, 7, "expected a column chunk for each leaf column")
+	}
+
+	// the pods column of the first row group should contain the definition
+	// levels, followed by the PLAIN encoded values
+	offset := md.chunkOffsets[0][1]
+	pageBuf := thrift.NewTMemoryBuffer()
+	_, err = pageBuf.Write(buf.Bytes()[offset:])
+	require.NoError(t, err)
+	proto := thrift.NewTCompactProtocol(pageBuf)
+	var pageSize int32
+	readStruct(t, proto, func(id int16, typ thrift.TType) {
+		if id == 3 {
+			pageSize, err = proto.ReadI32()
+			require.NoError(t, err)
+			return
+		}
+		require.NoError(t, proto.Skip(typ))
+	})
+	page := pageBuf.Bytes()[:pageSize]
+	expectedPage := []byte{
+		// definition levels: length, then a run of 2 defined values
+		2, 0, 0, 0, 2 << 1, 1,
+		// values
+		2, 0, 0, 0, 0, 0, 0, 0,
+		5, 0, 0, 0, 0, 0, 0, 0,
+	}
+	assert.Equal(t, expectedPage, page)
+}
+
+func TestWriterReadBack(t *testing.T) {
+	rows := append([]map[string]interface{}{}, testRows...)
+	rows = append(rows, map[string]interface{}{
+		"namespace": "openshift",
+		"labels":    map[string]interface{}{"app": nil, "team": "metering"},
+	})
+	// enough rows for runs of levels which need multi-byte lengths
+	for i := 0; i < 300; i++ {
+		rows = append(rows, map[string]interface{}{
+			"namespace": fmt.Sprintf("ns-%d", i),
+			"pods":      i,
+			"running":   i%3 == 0,
+			"labels":    map[string]string{"index": fmt.Sprint(i)},
+		})
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+	for i, row := range rows {
+		require.NoError(t, w.Write(row))
+		if i == 1 || i == 100 {
+			require.NoError(t, w.Flush())
+		}
+	}
+	require.NoError(t, w.Close())
+
+	expected := []map[string]interface{}{
+		{
+			"namespace":    "default",
+			"pods":         int64(2),
+			"cpu":          1.5,
+			"running":      true,
+			"period_start": testPeriodStart,
+			"labels":       map[string]interface{}{"app": "foo", "tier": "web"},
+		},
+		{
+			"namespace":    "kube-system",
+			"pods":         int64(5),
+			"cpu":          float64(3),
+			"running":      false,
+			"period_start": testPeriodStart,
+			"labels":       map[string]interface{}{},
+		},
+		{
+			"namespace":    "metering",
+			"pods":         nil,
+			"cpu":          nil,
+			"running":      nil,
+			"period_start": nil,
+			"labels":       nil,
+		},
+		{
+			"namespace":    "openshift",
+			"pods":         nil,
+			"cpu":          nil,
+			"running":      nil,
+			"period_start": nil,
+			"labels":       map[string]interface{}{"app": nil, "team": "metering"},
+		},
+	}
+	for i := 0; i < 300; i++ {
+		expected = append(expected, map[string]interface{}{
+			"namespace":    fmt.Sprintf("ns-%d", i),
+			"pods":         int64(i),
+			"cpu":          nil,
+			"running":      i%3 == 0,
+			"period_start": nil,
+			"labels":       map[string]interface{}{"index": fmt.Sprint(i)},
+		})
+	}
+	assert.Equal(t, expected, readTestFile(t, buf.Bytes()))
+}
+
+// TestWriterFixture compares the file written for testRows with
+// testdata/rows.parquet, to catch unintended changes to the writer's
+// output. The fixture's contents are checked by reading it with the reader
+// in reader_test.go, so it can be regenerated if the output changes on
+// purpose.
+func TestWriterFixture(t *testing.T) {
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", "rows.parquet"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+	for i, row := range testRows {
+		require.NoError(t, w.Write(row))
+		// write the last row in a second row group
+		if i == 1 {
+			require.NoError(t, w.Flush())
+		}
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, expected, buf.Bytes())
+
+	rows := readTestFile(t, expected)
+	require.Len(t, rows, len(testRows))
+	assert.Equal(t, "metering", rows[2]["namespace"])
+	assert.Nil(t, rows[2]["labels"], "expected a missing map to be null")
+	assert.Equal(t, map[string]interface{}{}, rows[1]["labels"], "expected an empty map to be empty rather than null")
+}
+
+func TestWriterErrors(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, []Column{{Name: "a", Type: String}, {Name: "a", Type: Int64}})
+	assert.Error(t, err, "expected duplicate columns to be rejected")
+	_, err = NewWriter(&bytes.Buffer{}, []Column{{Name: "a", Type: Map, KeyType: Int64, ValueType: String}})
+	assert.Error(t, err, "expected maps with non string keys to be rejected")
+	_, err = NewWriter(&bytes.Buffer{}, []Column{{Name: "a", Type: Map, KeyType: String, ValueType: Map}})
+	assert.Error(t, err, "expected nested maps to be rejected")
+
+	w, err := NewWriter(&bytes.Buffer{}, []Column{{Name: "pods", Type: Int64}})
+	require.NoError(t, err)
+	assert.Error(t, w.Write(map[string]interface{}{"pods": "two"}))
+	assert.Error(t, w.Write(map[string]interface{}{"pods": 1.5}))
+	assert.Equal(t, int64(0), w.BufferedRows())
+
+	// values of a row are discarded if any of its columns are invalid
+	var buf bytes.Buffer
+	w, err = NewWriter(&buf, []Column{{Name: "pods", Type: Int64}, {Name: "cpu", Type: Double}})
+	require.NoError(t, err)
+	assert.Error(t, w.Write(map[string]interface{}{"pods": 1, "cpu": "lots"}))
+	require.NoError(t, w.Write(map[string]interface{}{"pods": 2, "cpu": 1.0}))
+	require.NoError(t, w.Close())
+	md := readFileMetaData(t, buf.Bytes())
+	assert.Equal(t, int64(1), md.numRows)
+}
@@ -1,6 +1,7 @@
 package presto
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -12,6 +13,8 @@ import (
 const (
 	// TimestampFormat is the time format string used to produce Presto timestamps.
 	TimestampFormat = "2006-01-02 15:04:05.000"
+
+	rowNumberColumnName = "__row_number"
 )
 
 func DeleteFrom(queryer db.Queryer, tableName string) error {
@@ -19,14 +22,67 @@ func DeleteFrom(queryer db.Queryer, tableName string) error {
 	return err
 }
 
+// DeleteFromWhere deletes the rows of tableName matching the where clause.
+// Presto's Hive connector only supports deleting whole partitions, so the
+// where clause must only use partition columns.
+func DeleteFromWhere(queryer db.Queryer, tableName, where string) error {
+	return execQuery(queryer, fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, where))
+}
+
 func InsertInto(queryer db.Queryer, tableName, query string) error {
 	return execQuery(queryer, FormatInsertQuery(tableName, query))
 }
 
+// InsertIntoWithRowCount is like InsertInto, but returns the number of rows
+// Presto reports as inserted. If columns is non-empty, the results of query
+// are inserted into those columns of tableName, and any other columns are
+// null.
+func InsertIntoWithRowCount(queryer db.Queryer, tableName string, columns []Column, query string) (int64, error) {
+	results, err := ExecuteSelect(queryer, FormatInsertColumnsQuery(tableName, columns, query))
+	if err != nil {
+		return 0, fmt.Errorf("presto SQL error: %v", err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	// Presto returns a single row with a single column named rows containing
+	// the number of rows inserted
+	return rowCount(results[0], "rows")
+}
+
+// CountRows returns the number of rows in tableName.
+func CountRows(queryer db.Queryer, tableName string) (int64, error) {
+	results, err := ExecuteSelect(queryer, fmt.Sprintf("SELECT count(*) AS count FROM %s", tableName))
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, fmt.Errorf("no results counting the rows of %s", tableName)
+	}
+	return rowCount(results[0], "count")
+}
+
+func rowCount(row Row, column string) (int64, error) {
+	switch count := row[column].(type) {
+	case int64:
+		return count, nil
+	case float64:
+		return int64(count), nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T for rows count", count)
+	}
+}
+
 func GetRows(queryer db.Queryer, tableName string, columns []Column) ([]Row, error) {
 	return ExecuteSelect(queryer, GenerateGetRowsSQL(tableName, columns))
 }
 
+// GetRowsIterator is like GetRows, but returns a RowIterator over the rows
+// instead of loading them all into memory.
+func GetRowsIterator(queryer db.Queryer, tableName string, columns []Column) (RowIterator, error) {
+	return ExecuteSelectIterator(queryer, GenerateGetRowsSQL(tableName, columns))
+}
+
 func GetRowsWhere(queryer db.Queryer, tableName string, columns []Column, whereClause string) ([]Row, error) {
 	return ExecuteSelect(queryer, GenerateGetRowsSQLWithWhere(tableName, columns, whereClause))
 }
@@ -42,6 +98,50 @@ func CreateView(queryer db.Queryer, viewName string, query string, replace bool)
 	return err
 }
 
+// DropView drops the view viewName. If ignoreNotExists is true, dropping a
+// view which doesn't exist isn't an error.
+func DropView(queryer db.Queryer, viewName string, ignoreNotExists bool) error {
+	query := "DROP VIEW "
+	if ignoreNotExists {
+		query += "IF EXISTS "
+	}
+	return execQuery(queryer, query+viewName)
+}
+
+// ListViews returns the names of the views in schema.
+func ListViews(queryer db.Queryer, schema string) ([]string, error) {
+	rows, err := ExecuteSelect(queryer, fmt.Sprintf("SELECT table_name FROM information_schema.views WHERE table_schema = %s", FormatStringLiteral(schema)))
+	if err != nil {
+		return nil, err
+	}
+	views := make([]string, 0, len(rows))
+	for _, row := range rows {
+		viewName, ok := row["table_name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for view name", row["table_name"])
+		}
+		views = append(views, viewName)
+	}
+	return views, nil
+}
+
+// ListTables returns the names of the tables in schema, excluding views.
+func ListTables(queryer db.Queryer, schema string) ([]string, error) {
+	rows, err := ExecuteSelect(queryer, fmt.Sprintf("SELECT table_name FROM information_schema.tables WHERE table_schema = %s AND table_type = 'BASE TABLE'", FormatStringLiteral(schema)))
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(rows))
+	for _, row := range rows {
+		tableName, ok := row["table_name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for table name", row["table_name"])
+		}
+		tables = append(tables, tableName)
+	}
+	return tables, nil
+}
+
 func GenerateGetRowsSQL(tableName string, columns []Column) string {
 	columnsSQL := GenerateQuotedColumnsListSQL(columns)
 	orderBySQL := GenerateOrderBySQL(columns)
@@ -66,25 +166,81 @@ func GenerateQuotedColumnsListSQL(columns []Column) string {
 func GenerateOrderBySQL(columns []Column) string {
 	var quotedColumns []string
 	for _, col := range columns {
-		colName := col.Name
-		// if we detect a map(...) in the column, use map_entries to do
-		// ordering. we detect a map column using a best effort approach by
-		// checking if the column type contains the string "map(" , and is
-		// followed by a ")" after that string.
-		colType := strings.ToLower(col.Type)
-		if mapIndex := strings.Index(colType, "map("); mapIndex != -1 && strings.Index(colType, ")") > mapIndex {
-			quotedColumns = append(quotedColumns, fmt.Sprintf(`map_entries("%s")`, colName))
-		} else {
-			quotedColumns = append(quotedColumns, quoteColumn(col))
-		}
+		quotedColumns = append(quotedColumns, orderByExpression(col))
 	}
 	return fmt.Sprintf("%s ASC", strings.Join(quotedColumns, ", "))
 }
 
+// OrderByColumn is a column to order rows by.
+type OrderByColumn struct {
+	Column
+	Descending bool
+}
+
+// GenerateOrderByColumnsSQL is like GenerateOrderBySQL, but allows choosing
+// the direction each column is ordered in.
+func GenerateOrderByColumnsSQL(columns []OrderByColumn) string {
+	var orderByColumns []string
+	for _, col := range columns {
+		direction := "ASC"
+		if col.Descending {
+			direction = "DESC"
+		}
+		orderByColumns = append(orderByColumns, fmt.Sprintf("%s %s", orderByExpression(col.Column), direction))
+	}
+	return strings.Join(orderByColumns, ", ")
+}
+
+// GenerateGetRowsPageSQL returns a query selecting columns from tableName,
+// filtered by whereClause unless it's empty, and ordered by orderBy. If
+// limit is greater than 0, only up to limit rows after the first offset rows
+// are selected.
+func GenerateGetRowsPageSQL(tableName string, columns []Column, whereClause string, orderBy []OrderByColumn, offset, limit int64) string {
+	columnsSQL := GenerateQuotedColumnsListSQL(columns)
+	orderBySQL := GenerateOrderByColumnsSQL(orderBy)
+	if whereClause != "" {
+		whereClause = "WHERE " + whereClause
+	}
+	if limit <= 0 {
+		return fmt.Sprintf("SELECT %s FROM %s %s ORDER BY %s", columnsSQL, tableName, whereClause, orderBySQL)
+	}
+	// row_number() is used rather than OFFSET, which isn't supported by
+	// every version of Presto.
+	return fmt.Sprintf(`SELECT %[1]s FROM (SELECT %[1]s, row_number() OVER (ORDER BY %[2]s) AS "%[3]s" FROM %[4]s %[5]s) WHERE "%[3]s" > %[6]d AND "%[3]s" <= %[7]d ORDER BY "%[3]s" ASC`,
+		columnsSQL, orderBySQL, rowNumberColumnName, tableName, whereClause, offset, offset+limit)
+}
+
+// orderByExpression returns the expression used to order rows by col.
+func orderByExpression(col Column) string {
+	// if we detect a map(...) in the column, use map_entries to do
+	// ordering. we detect a map column using a best effort approach by
+	// checking if the column type contains the string "map(" , and is
+	// followed by a ")" after that string.
+	colType := strings.ToLower(col.Type)
+	if mapIndex := strings.Index(colType, "map("); mapIndex != -1 && strings.Index(colType, ")") > mapIndex {
+		return fmt.Sprintf(`map_entries("%s")`, col.Name)
+	}
+	return quoteColumn(col)
+}
+
+// FormatStringLiteral returns s as a Presto string literal.
+func FormatStringLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
 func FormatInsertQuery(target, query string) string {
 	return fmt.Sprintf("INSERT INTO %s %s", target, query)
 }
 
+// FormatInsertColumnsQuery is like FormatInsertQuery, but inserts into only
+// columns of target, if columns is non-empty.
+func FormatInsertColumnsQuery(target string, columns []Column, query string) string {
+	if len(columns) == 0 {
+		return FormatInsertQuery(target, query)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) %s", target, GenerateQuotedColumnsListSQL(columns), query)
+}
+
 func quoteColumn(col Column) string {
 	return `"` + col.Name + `"`
 }
@@ -106,45 +262,132 @@ type TablePartition struct {
 // ExecuteSelectQuery performs the query on the table target. It's expected
 // target has the correct schema.
 func ExecuteSelect(queryer db.Queryer, query string) ([]Row, error) {
-	rows, err := queryer.Query(query)
+	rows, err := ExecuteSelectIterator(queryer, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+
+	var results []Row
+	for rows.Next() {
+		results = append(results, rows.Row())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RowIterator iterates over the results of a query one row at a time, so
+// large results don't need to be held in memory all at once.
+type RowIterator interface {
+	// Next advances the iterator to the next row, returning false when
+	// there are no rows left or an error occurred.
+	Next() bool
+	// Row returns the current row.
+	Row() Row
+	// Err returns the error, if any, that was encountered during iteration.
+	Err() error
+	// Close releases the resources held by the iterator.
+	Close() error
+}
+
+// ExecuteSelectIterator performs the query and returns a RowIterator over
+// its results. The caller must Close the iterator when it's done with it.
+func ExecuteSelectIterator(queryer db.Queryer, query string) (RowIterator, error) {
+	rows, err := queryer.Query(query)
+	if err != nil {
+		return nil, err
+	}
 	cols, err := rows.Columns()
 	if err != nil {
+		rows.Close()
 		return nil, err
 	}
+	return &sqlRowIterator{rows: rows, cols: cols}, nil
+}
 
-	var results []Row
-	for rows.Next() {
-		// Create a slice of interface{}'s to represent each column,
-		// and a second slice to contain pointers to each item in the columns slice.
-		columns := make([]interface{}, len(cols))
-		columnPointers := make([]interface{}, len(cols))
-		for i := range columns {
-			columnPointers[i] = &columns[i]
-		}
+type sqlRowIterator struct {
+	rows *sql.Rows
+	cols []string
+	row  Row
+	err  error
+}
 
-		// Scan the result into the column pointers...
-		if err := rows.Scan(columnPointers...); err != nil {
-			return nil, err
-		}
+func (it *sqlRowIterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	// Create a slice of interface{}'s to represent each column,
+	// and a second slice to contain pointers to each item in the columns slice.
+	columns := make([]interface{}, len(it.cols))
+	columnPointers := make([]interface{}, len(it.cols))
+	for i := range columns {
+		columnPointers[i] = &columns[i]
+	}
 
-		// Create our map, and retrieve the value for each column from the pointers slice,
-		// storing it in the map with the name of the column as the key.
-		m := make(map[string]interface{})
-		for i, colName := range cols {
-			val := columnPointers[i].(*interface{})
-			m[colName] = *val
-		}
-		results = append(results, Row(m))
+	// Scan the result into the column pointers...
+	if err := it.rows.Scan(columnPointers...); err != nil {
+		it.err = err
+		return false
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+	// Create our map, and retrieve the value for each column from the pointers slice,
+	// storing it in the map with the name of the column as the key.
+	m := make(map[string]interface{})
+	for i, colName := range it.cols {
+		val := columnPointers[i].(*interface{})
+		m[colName] = *val
 	}
+	it.row = Row(m)
+	return true
+}
 
-	return results, nil
+func (it *sqlRowIterator) Row() Row {
+	return it.row
+}
+
+func (it *sqlRowIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+func (it *sqlRowIterator) Close() error {
+	return it.rows.Close()
+}
+
+// NewRowSliceIterator returns a RowIterator over rows which have already
+// been loaded into memory.
+func NewRowSliceIterator(rows []Row) RowIterator {
+	return &rowSliceIterator{rows: rows, index: -1}
+}
+
+type rowSliceIterator struct {
+	rows  []Row
+	index int
+}
+
+func (it *rowSliceIterator) Next() bool {
+	if it.index+1 >= len(it.rows) {
+		return false
+	}
+	it.index++
+	return true
+}
+
+func (it *rowSliceIterator) Row() Row {
+	return it.rows[it.index]
+}
+
+func (it *rowSliceIterator) Err() error {
+	return nil
+}
+
+func (it *rowSliceIterator) Close() error {
+	return nil
 }
 
 func execQuery(queryer db.Queryer, query string) error {
@@ -0,0 +1,182 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file is derived from pkg/relabel/relabel.go in
+// github.com/prometheus/prometheus, modified to relabel maps of labels
+// instead of labels.Labels, and without the YAML configuration handling.
+
+// Package promrelabel implements Prometheus' relabel_config rules for
+// rewriting the labels of series, and dropping series by their labels.
+package promrelabel
+
+import (
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Action string
+
+const (
+	Replace   Action = "replace"
+	Keep      Action = "keep"
+	Drop      Action = "drop"
+	HashMod   Action = "hashmod"
+	LabelDrop Action = "labeldrop"
+	LabelKeep Action = "labelkeep"
+
+	DefaultSeparator   = ";"
+	DefaultRegex       = "(.*)"
+	DefaultReplacement = "$1"
+)
+
+var (
+	labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+	// templatedLabelNameRegexp matches target labels which refer to
+	// capture groups, and are only valid label names after expansion.
+	templatedLabelNameRegexp = regexp.MustCompile(`^(?:(?:[a-zA-Z_]|\$(?:\{\w+\}|\w+))+\w*)+$`)
+)
+
+// Config is a single relabeling rule. Regex must be anchored, which NewRegexp
+// takes care of.
+type Config struct {
+	SourceLabels []string
+	Separator    string
+	Regex        *regexp.Regexp
+	Modulus      uint64
+	TargetLabel  string
+	Replacement  string
+	Action       Action
+}
+
+// NewRegexp compiles expr anchored at both ends, the same as Prometheus does
+// for relabeling regular expressions.
+func NewRegexp(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// Validate returns an error if the fields required by the Config's Action
+// aren't set.
+func (c *Config) Validate() error {
+	if c.Regex == nil {
+		return fmt.Errorf("regex must be set")
+	}
+	switch c.Action {
+	case Replace:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("targetLabel is required for the %s action", c.Action)
+		}
+		if !templatedLabelNameRegexp.MatchString(c.TargetLabel) {
+			return fmt.Errorf("%q is an invalid targetLabel for the %s action", c.TargetLabel, c.Action)
+		}
+	case HashMod:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("targetLabel is required for the %s action", c.Action)
+		}
+		if !labelNameRegexp.MatchString(c.TargetLabel) {
+			return fmt.Errorf("%q is an invalid targetLabel for the %s action", c.TargetLabel, c.Action)
+		}
+		if c.Modulus == 0 {
+			return fmt.Errorf("modulus is required for the %s action", c.Action)
+		}
+	case LabelDrop, LabelKeep:
+		if len(c.SourceLabels) != 0 || c.TargetLabel != "" {
+			return fmt.Errorf("sourceLabels and targetLabel are not allowed for the %s action", c.Action)
+		}
+	case Keep, Drop:
+	default:
+		return fmt.Errorf("unknown action %q", c.Action)
+	}
+	for _, name := range c.SourceLabels {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf("%q is an invalid source label", name)
+		}
+	}
+	return nil
+}
+
+// Process applies the relabeling rules in order to labels, modifying it. It
+// returns nil if the series is dropped, or labels otherwise. Rules such as
+// labeldrop can give distinct series the same labels, so callers storing
+// the results must combine the series which do.
+func Process(labels map[string]string, cfgs []*Config) map[string]string {
+	for _, cfg := range cfgs {
+		if labels = relabel(labels, cfg); labels == nil {
+			return nil
+		}
+	}
+	return labels
+}
+
+func relabel(labels map[string]string, cfg *Config) map[string]string {
+	values := make([]string, len(cfg.SourceLabels))
+	for i, name := range cfg.SourceLabels {
+		values[i] = labels[name]
+	}
+	val := strings.Join(values, cfg.Separator)
+
+	switch cfg.Action {
+	case Drop:
+		if cfg.Regex.MatchString(val) {
+			return nil
+		}
+	case Keep:
+		if !cfg.Regex.MatchString(val) {
+			return nil
+		}
+	case Replace:
+		indexes := cfg.Regex.FindStringSubmatchIndex(val)
+		// if there is no match no replacement should take place
+		if indexes == nil {
+			break
+		}
+		target := string(cfg.Regex.ExpandString(nil, cfg.TargetLabel, val, indexes))
+		if !labelNameRegexp.MatchString(target) {
+			break
+		}
+		res := cfg.Regex.ExpandString(nil, cfg.Replacement, val, indexes)
+		if len(res) == 0 {
+			delete(labels, target)
+			break
+		}
+		labels[target] = string(res)
+	case HashMod:
+		mod := sum64(md5.Sum([]byte(val))) % cfg.Modulus
+		labels[cfg.TargetLabel] = fmt.Sprintf("%d", mod)
+	case LabelDrop:
+		for name := range labels {
+			if cfg.Regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case LabelKeep:
+		for name := range labels {
+			if !cfg.Regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return labels
+}
+
+// sum64 sums the md5 hash to an uint64, the same as Prometheus does so that
+// hashmod gives the same results.
+func sum64(hash [md5.Size]byte) uint64 {
+	var s uint64
+	for i, b := range hash {
+		shift := uint64((md5.Size - 1 - i) * 8)
+		s |= uint64(b) << shift
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package promrelabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig(t *testing.T, expr string) *Config {
+	regex, err := NewRegexp(expr)
+	require.NoError(t, err)
+	return &Config{Separator: DefaultSeparator, Regex: regex, Replacement: DefaultReplacement, Action: Replace}
+}
+
+func TestProcess(t *testing.T) {
+	tests := map[string]struct {
+		labels    map[string]string
+		configure func(cfg *Config)
+		regex     string
+		expected  map[string]string
+	}{
+		"keep matching series": {
+			labels: map[string]string{"namespace": "default", "pod": "pod-1"},
+			regex:  "default|openshift-.*",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"namespace"}
+				cfg.Action = Keep
+			},
+			expected: map[string]string{"namespace": "default", "pod": "pod-1"},
+		},
+		"keep drops series which don't match": {
+			labels: map[string]string{"namespace": "kube-system", "pod": "pod-1"},
+			regex:  "default",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"namespace"}
+				cfg.Action = Keep
+			},
+			expected: nil,
+		},
+		"drop matching series using multiple source labels": {
+			labels: map[string]string{"namespace": "default", "pod": "pod-1"},
+			regex:  "default;pod-.*",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"namespace", "pod"}
+				cfg.Action = Drop
+			},
+			expected: nil,
+		},
+		"drop keeps series which don't match since the regex is anchored": {
+			labels: map[string]string{"namespace": "default", "pod": "pod-1"},
+			regex:  "fault",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"namespace"}
+				cfg.Action = Drop
+			},
+			expected: map[string]string{"namespace": "default", "pod": "pod-1"},
+		},
+		"labeldrop": {
+			labels: map[string]string{"namespace": "default", "pod_template_hash": "abc", "uid": "123"},
+			regex:  "pod_template_hash|uid",
+			configure: func(cfg *Config) {
+				cfg.Action = LabelDrop
+			},
+			expected: map[string]string{"namespace": "default"},
+		},
+		"labelkeep": {
+			labels: map[string]string{"namespace": "default", "pod": "pod-1", "instance": "10.0.0.1:8080"},
+			regex:  "namespace|pod",
+			configure: func(cfg *Config) {
+				cfg.Action = LabelKeep
+			},
+			expected: map[string]string{"namespace": "default", "pod": "pod-1"},
+		},
+		"replace with capture groups": {
+			labels: map[string]string{"instance": "10.0.0.1:8080"},
+			regex:  "(.*):(\\d+)",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"instance"}
+				cfg.TargetLabel = "host"
+			},
+			expected: map[string]string{"instance": "10.0.0.1:8080", "host": "10.0.0.1"},
+		},
+		"replace with an empty value deletes the target label": {
+			labels: map[string]string{"instance": "10.0.0.1:8080", "host": "node-1"},
+			regex:  "(.*)",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"missing"}
+				cfg.TargetLabel = "host"
+			},
+			expected: map[string]string{"instance": "10.0.0.1:8080"},
+		},
+		"replace doesn't change anything if the regex doesn't match": {
+			labels: map[string]string{"instance": "10.0.0.1"},
+			regex:  "(.*):(\\d+)",
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"instance"}
+				cfg.TargetLabel = "host"
+			},
+			expected: map[string]string{"instance": "10.0.0.1"},
+		},
+		"hashmod": {
+			labels: map[string]string{"pod": "baz"},
+			regex:  DefaultRegex,
+			configure: func(cfg *Config) {
+				cfg.SourceLabels = []string{"pod"}
+				cfg.TargetLabel = "shard"
+				cfg.Modulus = 1000
+				cfg.Action = HashMod
+			},
+			// the same as Prometheus' hashmod of "baz" with a modulus of 1000
+			expected: map[string]string{"pod": "baz", "shard": "976"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			cfg := newTestConfig(t, test.regex)
+			test.configure(cfg)
+			require.NoError(t, cfg.Validate())
+			assert.Equal(t, test.expected, Process(test.labels, []*Config{cfg}))
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]func(cfg *Config){
+		"replace without a target label":    func(cfg *Config) {},
+		"replace with invalid target label": func(cfg *Config) { cfg.TargetLabel = "not-valid" },
+		"hashmod without a modulus": func(cfg *Config) {
+			cfg.Action = HashMod
+			cfg.TargetLabel = "shard"
+		},
+		"labeldrop with source labels": func(cfg *Config) {
+			cfg.Action = LabelDrop
+			cfg.SourceLabels = []string{"pod"}
+		},
+		"unknown action": func(cfg *Config) { cfg.Action = "labelmap" },
+	}
+
+	for name, configure := range tests {
+		configure := configure
+		t.Run(name, func(t *testing.T) {
+			cfg := newTestConfig(t, DefaultRegex)
+			configure(cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
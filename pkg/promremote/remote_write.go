@@ -0,0 +1,98 @@
+// Package promremote implements decoding of Prometheus remote write
+// requests.
+package promremote
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+const (
+	// MetricNameLabel is the label containing the name of a series' metric.
+	MetricNameLabel = "__name__"
+
+	// DefaultMaxRequestSize is the default limit on the size of a decoded
+	// remote write request.
+	DefaultMaxRequestSize = 32 * 1024 * 1024
+)
+
+// WriteRequest, TimeSeries, Label and Sample mirror the protobuf messages of
+// the same name in Prometheus' prompb package. Fields we don't use are
+// ignored when decoding.
+type WriteRequest struct {
+	Timeseries []*TimeSeries `protobuf:"bytes,1,rep,name=timeseries" json:"timeseries,omitempty"`
+}
+
+func (m *WriteRequest) Reset()         { *m = WriteRequest{} }
+func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
+func (*WriteRequest) ProtoMessage()    {}
+
+type TimeSeries struct {
+	Labels  []*Label  `protobuf:"bytes,1,rep,name=labels" json:"labels,omitempty"`
+	Samples []*Sample `protobuf:"bytes,2,rep,name=samples" json:"samples,omitempty"`
+}
+
+func (m *TimeSeries) Reset()         { *m = TimeSeries{} }
+func (m *TimeSeries) String() string { return proto.CompactTextString(m) }
+func (*TimeSeries) ProtoMessage()    {}
+
+type Label struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *Label) Reset()         { *m = Label{} }
+func (m *Label) String() string { return proto.CompactTextString(m) }
+func (*Label) ProtoMessage()    {}
+
+type Sample struct {
+	Value float64 `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	// Timestamp is in milliseconds since the Unix epoch.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+}
+
+func (m *Sample) Reset()         { *m = Sample{} }
+func (m *Sample) String() string { return proto.CompactTextString(m) }
+func (*Sample) ProtoMessage()    {}
+
+// LabelsMap returns the labels of the series as a map.
+func (m *TimeSeries) LabelsMap() map[string]string {
+	labels := make(map[string]string, len(m.Labels))
+	for _, l := range m.Labels {
+		labels[l.Name] = l.Value
+	}
+	return labels
+}
+
+// DecodeWriteRequest reads a snappy compressed, protobuf encoded remote
+// write request from r. Requests larger than maxSize bytes, compressed or
+// decompressed, are rejected.
+func DecodeWriteRequest(r io.Reader, maxSize int) (*WriteRequest, error) {
+	compressed, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %v", err)
+	}
+	if len(compressed) > maxSize {
+		return nil, fmt.Errorf("request body exceeds the maximum of %d bytes", maxSize)
+	}
+	decodedLen, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding snappy compressed request body: %v", err)
+	}
+	if decodedLen > maxSize {
+		return nil, fmt.Errorf("decoded request body exceeds the maximum of %d bytes", maxSize)
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding snappy compressed request body: %v", err)
+	}
+	var req WriteRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("error decoding remote write request: %v", err)
+	}
+	return &req, nil
+}
@@ -0,0 +1,48 @@
+package promremote
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeWriteRequest(t *testing.T) {
+	expected := &WriteRequest{
+		Timeseries: []*TimeSeries{
+			{
+				Labels: []*Label{
+					{Name: MetricNameLabel, Value: "kube_pod_container_resource_requests_cpu_cores"},
+					{Name: "namespace", Value: "default"},
+				},
+				Samples: []*Sample{
+					{Value: 0.5, Timestamp: 1546300800000},
+					{Value: 1.5, Timestamp: 1546300860000},
+				},
+			},
+		},
+	}
+	data, err := proto.Marshal(expected)
+	require.NoError(t, err)
+
+	req, err := DecodeWriteRequest(bytes.NewReader(snappy.Encode(nil, data)), DefaultMaxRequestSize)
+	require.NoError(t, err)
+	assert.Equal(t, expected, req)
+	assert.Equal(t, map[string]string{
+		MetricNameLabel: "kube_pod_container_resource_requests_cpu_cores",
+		"namespace":     "default",
+	}, req.Timeseries[0].LabelsMap())
+
+	_, err = DecodeWriteRequest(bytes.NewReader(snappy.Encode(nil, data)), 10)
+	assert.Error(t, err, "expected requests larger than the maximum size to be rejected")
+
+	// compresses to well under the maximum size, but decodes to more than it
+	_, err = DecodeWriteRequest(bytes.NewReader(snappy.Encode(nil, make([]byte, 4096))), 1024)
+	assert.Error(t, err, "expected requests which decode to more than the maximum size to be rejected")
+
+	_, err = DecodeWriteRequest(bytes.NewReader([]byte{6, 4 << 2, 'h', 'e', 'l', 'l', 'o'}), DefaultMaxRequestSize)
+	assert.Error(t, err, "expected corrupt snappy input to be rejected")
+}